@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	kapi "k8s.io/api/core/v1"
+	knet "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn"
+)
+
+// objects holds the namespaces, pods and network policies the evaluator works on
+type objects struct {
+	namespaces []*kapi.Namespace
+	pods       []*kapi.Pod
+	policies   []*knet.NetworkPolicy
+}
+
+// add adds a decoded object, expanding lists
+func (o *objects) add(obj runtime.Object) error {
+	switch t := obj.(type) {
+	case *kapi.Namespace:
+		o.namespaces = append(o.namespaces, t)
+	case *kapi.Pod:
+		o.pods = append(o.pods, t)
+	case *knet.NetworkPolicy:
+		o.policies = append(o.policies, t)
+	case *kapi.NamespaceList:
+		for i := range t.Items {
+			o.namespaces = append(o.namespaces, &t.Items[i])
+		}
+	case *kapi.PodList:
+		for i := range t.Items {
+			o.pods = append(o.pods, &t.Items[i])
+		}
+	case *knet.NetworkPolicyList:
+		for i := range t.Items {
+			o.policies = append(o.policies, &t.Items[i])
+		}
+	case *kapi.List:
+		for _, item := range t.Items {
+			if err := o.decode(item.Raw); err != nil {
+				return err
+			}
+		}
+	default:
+		klog.V(5).Infof("Ignoring object of kind %s", obj.GetObjectKind().GroupVersionKind().Kind)
+	}
+	return nil
+}
+
+func (o *objects) decode(data []byte) error {
+	obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(data, nil, nil)
+	if err != nil {
+		return err
+	}
+	return o.add(obj)
+}
+
+// loadFile loads all the YAML or JSON documents of a file, as written by
+// 'kubectl get -o yaml'
+func (o *objects) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(f))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		if len(strings.TrimSpace(string(doc))) == 0 {
+			continue
+		}
+		if err := o.decode(doc); err != nil {
+			return fmt.Errorf("failed to decode %s: %v", path, err)
+		}
+	}
+}
+
+// loadCluster loads all namespaces, pods and network policies from the cluster
+func (o *objects) loadCluster(restconfig *rest.Config) error {
+	clientset, err := kubernetes.NewForConfig(restconfig)
+	if err != nil {
+		return err
+	}
+	namespaces, err := clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	pods, err := clientset.CoreV1().Pods(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	policies, err := clientset.NetworkingV1().NetworkPolicies(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, list := range []runtime.Object{namespaces, pods, policies} {
+		if err := o.add(list); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getEvaluatorEndpoint returns the connection endpoint for either a pod or an IP
+func getEvaluatorEndpoint(evaluator *ovn.NetworkPolicyEvaluator, namespace, podName, ip string) (ovn.PolicyEndpoint, error) {
+	if podName != "" {
+		pod, err := evaluator.GetPod(namespace, podName)
+		if err != nil {
+			return ovn.PolicyEndpoint{}, err
+		}
+		return ovn.PolicyEndpoint{Pod: pod}, nil
+	}
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return ovn.PolicyEndpoint{}, fmt.Errorf("invalid IP %q", ip)
+	}
+	return ovn.PolicyEndpoint{IP: parsedIP}, nil
+}
+
+func main() {
+	srcNamespace := flag.String("src-namespace", "default", "k8s namespace of source pod")
+	dstNamespace := flag.String("dst-namespace", "default", "k8s namespace of dest pod")
+	srcPodName := flag.String("src", "", "src: source pod name")
+	dstPodName := flag.String("dst", "", "dest: destination pod name")
+	srcIP := flag.String("src-ip", "", "src-ip: source IP, when no source pod is given")
+	dstIP := flag.String("dst-ip", "", "dst-ip: destination IP, when no destination pod is given")
+	dstPort := flag.Int("dst-port", 80, "dst-port: destination port")
+	protocol := flag.String("protocol", "tcp", "protocol: tcp, udp or sctp")
+	files := flag.String("f", "", "comma separated list of YAML or JSON files holding namespaces, pods "+
+		"and network policies; the cluster is queried when unset")
+	cliConfig := flag.String("kubeconfig", "", "absolute path to the kubeconfig file")
+
+	klog.InitFlags(nil)
+	flag.Parse()
+	klog.SetOutput(os.Stderr)
+
+	if (*srcPodName == "") == (*srcIP == "") {
+		fmt.Printf("Usage: exactly one of source pod or source IP must be specified\n")
+		os.Exit(-1)
+	}
+	if (*dstPodName == "") == (*dstIP == "") {
+		fmt.Printf("Usage: exactly one of destination pod or destination IP must be specified\n")
+		os.Exit(-1)
+	}
+	proto := kapi.Protocol(strings.ToUpper(*protocol))
+	if proto != kapi.ProtocolTCP && proto != kapi.ProtocolUDP && proto != kapi.ProtocolSCTP {
+		fmt.Printf("Usage: protocol must be one of tcp, udp or sctp\n")
+		os.Exit(-1)
+	}
+
+	objs := &objects{}
+	if *files != "" {
+		for _, path := range strings.Split(*files, ",") {
+			if err := objs.loadFile(path); err != nil {
+				klog.Errorf("Unexpected error: %v", err)
+				os.Exit(-1)
+			}
+		}
+	} else {
+		var restconfig *rest.Config
+		var err error
+		// When supplied the kubeconfig supplied via cli takes precedence
+		if *cliConfig != "" {
+			restconfig, err = clientcmd.BuildConfigFromFlags("", *cliConfig)
+		} else {
+			restconfig, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+				clientcmd.NewDefaultClientConfigLoadingRules(),
+				&clientcmd.ConfigOverrides{},
+			).ClientConfig()
+		}
+		if err != nil {
+			klog.Errorf("Unexpected error: %v", err)
+			os.Exit(-1)
+		}
+		if err := objs.loadCluster(restconfig); err != nil {
+			klog.Errorf("Unexpected error: %v", err)
+			os.Exit(-1)
+		}
+	}
+	klog.V(5).Infof("Loaded %d namespaces, %d pods and %d network policies",
+		len(objs.namespaces), len(objs.pods), len(objs.policies))
+
+	evaluator := ovn.NewNetworkPolicyEvaluator(objs.namespaces, objs.pods, objs.policies)
+	src, err := getEvaluatorEndpoint(evaluator, *srcNamespace, *srcPodName, *srcIP)
+	if err != nil {
+		klog.Errorf("Unexpected error: %v", err)
+		os.Exit(-1)
+	}
+	dst, err := getEvaluatorEndpoint(evaluator, *dstNamespace, *dstPodName, *dstIP)
+	if err != nil {
+		klog.Errorf("Unexpected error: %v", err)
+		os.Exit(-1)
+	}
+
+	verdict, err := evaluator.Evaluate(&ovn.PolicyConnection{
+		Src:      src,
+		Dst:      dst,
+		Protocol: proto,
+		Port:     int32(*dstPort),
+	})
+	if err != nil {
+		klog.Errorf("Unexpected error: %v", err)
+		os.Exit(-1)
+	}
+	fmt.Print(verdict.String())
+	if !verdict.Allowed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,218 @@
+package bgp
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+
+	"k8s.io/klog/v2"
+	kexec "k8s.io/utils/exec"
+	utilnet "k8s.io/utils/net"
+)
+
+const (
+	// frrExportRouteMap is the route-map the neighbors export the advertised
+	// prefixes through. eBGP neighbors don't export anything without one.
+	frrExportRouteMap = "OVNKUBE-EXPORT"
+	// frrPrefixListV4 and frrPrefixListV6 hold the advertised prefixes the
+	// export route-map matches
+	frrPrefixListV4 = "OVNKUBE-ADVERTISE-V4"
+	frrPrefixListV6 = "OVNKUBE-ADVERTISE-V6"
+)
+
+// frrSpeaker advertises the routes by rendering the BGP configuration of the
+// node in a file of its own, and applying it on top of the running
+// configuration of FRR, which it never replaces. The prefixes are advertised
+// with network statements, which do not need a matching route in the routing
+// table of the node, and exported to the neighbors through an explicit
+// route-map.
+type frrSpeaker struct {
+	sync.Mutex
+	asn        uint32
+	neighbors  []config.BGPNeighbor
+	configFile string
+	// applyCommand makes FRR apply the configuration file, which is passed
+	// as its last argument
+	applyCommand []string
+	exec         kexec.Interface
+	// advertised are the prefixes FRR was last configured with, which are
+	// withdrawn when they are no longer advertised
+	advertised []string
+}
+
+func newFRRSpeaker(asn uint32, neighbors []config.BGPNeighbor, configFile string, applyCommand []string, exec kexec.Interface) *frrSpeaker {
+	return &frrSpeaker{
+		asn:          asn,
+		neighbors:    neighbors,
+		configFile:   configFile,
+		applyCommand: applyCommand,
+		exec:         exec,
+		// the prefixes advertised before a restart of ovnkube-node
+		advertised: readAdvertisedPrefixes(configFile),
+	}
+}
+
+// Advertise renders and applies the configuration every time, so that FRR
+// gets the routes back after a restart on the next call
+func (s *frrSpeaker) Advertise(prefixes []*net.IPNet) error {
+	s.Lock()
+	defer s.Unlock()
+	sorted := sortPrefixes(prefixes)
+	if err := writeFileAtomic(s.configFile, s.render(sorted, s.advertised)); err != nil {
+		return err
+	}
+	if err := s.apply(); err != nil {
+		return err
+	}
+	if strings.Join(sorted, ",") != strings.Join(s.advertised, ",") {
+		klog.Infof("Configured FRR with %d advertised prefixes", len(sorted))
+	}
+	s.advertised = sorted
+	return nil
+}
+
+// apply runs the apply command on the configuration file
+func (s *frrSpeaker) apply() error {
+	if len(s.applyCommand) == 0 {
+		return fmt.Errorf("no FRR apply command")
+	}
+	cmdPath, err := s.exec.LookPath(s.applyCommand[0])
+	if err != nil {
+		return fmt.Errorf("failed to find %s: %v", s.applyCommand[0], err)
+	}
+	args := append(append([]string{}, s.applyCommand[1:]...), s.configFile)
+	out, err := s.exec.Command(cmdPath, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to apply %s to FRR: %v: %q", s.configFile, err, string(out))
+	}
+	return nil
+}
+
+// render returns the FRR configuration advertising the sorted prefixes to the
+// neighbors, each neighbor in the address family of its IP, and withdrawing
+// the previously advertised prefixes that are no longer in them
+func (s *frrSpeaker) render(prefixes, withdrawn []string) string {
+	advertised := map[string]bool{}
+	for _, prefix := range prefixes {
+		advertised[prefix] = true
+	}
+
+	var b strings.Builder
+	b.WriteString("! Rendered by ovnkube-node, do not edit\n")
+	for _, family := range []struct {
+		name       string
+		prefixList string
+		isIPv6     bool
+	}{{"ip", frrPrefixListV4, false}, {"ipv6", frrPrefixListV6, true}} {
+		for _, prefix := range withdrawn {
+			if !advertised[prefix] && utilnet.IsIPv6CIDRString(prefix) == family.isIPv6 {
+				fmt.Fprintf(&b, "no %s prefix-list %s permit %s\n", family.name, family.prefixList, prefix)
+			}
+		}
+		for _, prefix := range prefixes {
+			if utilnet.IsIPv6CIDRString(prefix) == family.isIPv6 {
+				fmt.Fprintf(&b, "%s prefix-list %s permit %s\n", family.name, family.prefixList, prefix)
+			}
+		}
+	}
+	b.WriteString("!\n")
+	fmt.Fprintf(&b, "route-map %s permit 10\n", frrExportRouteMap)
+	fmt.Fprintf(&b, " match ip address prefix-list %s\n", frrPrefixListV4)
+	b.WriteString("exit\n")
+	fmt.Fprintf(&b, "route-map %s permit 20\n", frrExportRouteMap)
+	fmt.Fprintf(&b, " match ipv6 address prefix-list %s\n", frrPrefixListV6)
+	b.WriteString("exit\n")
+	b.WriteString("!\n")
+
+	fmt.Fprintf(&b, "router bgp %d\n", s.asn)
+	b.WriteString(" no bgp network import-check\n")
+	for _, neighbor := range s.neighbors {
+		fmt.Fprintf(&b, " neighbor %s remote-as %d\n", neighbor.IP, neighbor.ASN)
+	}
+	for _, family := range []struct {
+		name   string
+		isIPv6 bool
+	}{{"ipv4", false}, {"ipv6", true}} {
+		var lines []string
+		for _, prefix := range withdrawn {
+			if !advertised[prefix] && utilnet.IsIPv6CIDRString(prefix) == family.isIPv6 {
+				lines = append(lines, fmt.Sprintf("  no network %s\n", prefix))
+			}
+		}
+		for _, prefix := range prefixes {
+			if utilnet.IsIPv6CIDRString(prefix) == family.isIPv6 {
+				lines = append(lines, fmt.Sprintf("  network %s\n", prefix))
+			}
+		}
+		for _, neighbor := range s.neighbors {
+			if utilnet.IsIPv6(neighbor.IP) == family.isIPv6 {
+				lines = append(lines, fmt.Sprintf("  neighbor %s activate\n", neighbor.IP))
+				lines = append(lines, fmt.Sprintf("  neighbor %s route-map %s out\n", neighbor.IP, frrExportRouteMap))
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, " !\n address-family %s unicast\n", family.name)
+		for _, line := range lines {
+			b.WriteString(line)
+		}
+		b.WriteString(" exit-address-family\n")
+	}
+	b.WriteString("exit\n")
+	b.WriteString("!\n")
+	return b.String()
+}
+
+// readAdvertisedPrefixes returns the prefixes advertised by the configuration
+// file rendered last, if any
+func readAdvertisedPrefixes(configFile string) []string {
+	f, err := os.Open(configFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.Warningf("Failed to read the previously advertised prefixes from %s: %v", configFile, err)
+		}
+		return nil
+	}
+	defer f.Close()
+	prefixes := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "network" {
+			prefixes = append(prefixes, fields[1])
+		}
+	}
+	return prefixes
+}
+
+// writeFileAtomic replaces the content of path with data, so that readers
+// never see a partially written file
+func writeFileAtomic(path, data string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary file for %s: %v", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %v", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %v", tmp.Name(), err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to set the mode of %s: %v", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package bgp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	ovntest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFRRRender(t *testing.T) {
+	neighbors := []config.BGPNeighbor{
+		{IP: net.ParseIP("172.18.0.1"), ASN: 64500},
+		{IP: net.ParseIP("fd00::1"), ASN: 64500},
+	}
+	tests := []struct {
+		desc      string
+		neighbors []config.BGPNeighbor
+		prefixes  []string
+		withdrawn []string
+		expected  string
+	}{
+		{
+			desc:      "dual-stack neighbors and prefixes",
+			neighbors: neighbors,
+			prefixes:  []string{"10.244.1.0/24", "172.20.0.10/32", "fd00:10:244:1::/64"},
+			expected: `! Rendered by ovnkube-node, do not edit
+ip prefix-list OVNKUBE-ADVERTISE-V4 permit 10.244.1.0/24
+ip prefix-list OVNKUBE-ADVERTISE-V4 permit 172.20.0.10/32
+ipv6 prefix-list OVNKUBE-ADVERTISE-V6 permit fd00:10:244:1::/64
+!
+route-map OVNKUBE-EXPORT permit 10
+ match ip address prefix-list OVNKUBE-ADVERTISE-V4
+exit
+route-map OVNKUBE-EXPORT permit 20
+ match ipv6 address prefix-list OVNKUBE-ADVERTISE-V6
+exit
+!
+router bgp 64512
+ no bgp network import-check
+ neighbor 172.18.0.1 remote-as 64500
+ neighbor fd00::1 remote-as 64500
+ !
+ address-family ipv4 unicast
+  network 10.244.1.0/24
+  network 172.20.0.10/32
+  neighbor 172.18.0.1 activate
+  neighbor 172.18.0.1 route-map OVNKUBE-EXPORT out
+ exit-address-family
+ !
+ address-family ipv6 unicast
+  network fd00:10:244:1::/64
+  neighbor fd00::1 activate
+  neighbor fd00::1 route-map OVNKUBE-EXPORT out
+ exit-address-family
+exit
+!
+`,
+		},
+		{
+			desc:      "withdrawn prefixes",
+			neighbors: neighbors[:1],
+			prefixes:  []string{"10.244.1.0/24"},
+			withdrawn: []string{"10.244.1.0/24", "172.20.0.10/32"},
+			expected: `! Rendered by ovnkube-node, do not edit
+no ip prefix-list OVNKUBE-ADVERTISE-V4 permit 172.20.0.10/32
+ip prefix-list OVNKUBE-ADVERTISE-V4 permit 10.244.1.0/24
+!
+route-map OVNKUBE-EXPORT permit 10
+ match ip address prefix-list OVNKUBE-ADVERTISE-V4
+exit
+route-map OVNKUBE-EXPORT permit 20
+ match ipv6 address prefix-list OVNKUBE-ADVERTISE-V6
+exit
+!
+router bgp 64512
+ no bgp network import-check
+ neighbor 172.18.0.1 remote-as 64500
+ !
+ address-family ipv4 unicast
+  no network 172.20.0.10/32
+  network 10.244.1.0/24
+  neighbor 172.18.0.1 activate
+  neighbor 172.18.0.1 route-map OVNKUBE-EXPORT out
+ exit-address-family
+exit
+!
+`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			s := newFRRSpeaker(64512, tc.neighbors, "", nil, nil)
+			assert.Equal(t, tc.expected, s.render(tc.prefixes, tc.withdrawn))
+		})
+	}
+}
+
+func TestFRRAdvertise(t *testing.T) {
+	dir, err := ioutil.TempDir("", "frr")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "ovnkube-bgp.conf")
+	fexec := ovntest.NewFakeExec()
+	neighbors := []config.BGPNeighbor{{IP: net.ParseIP("172.18.0.1"), ASN: 64500}}
+	s := newFRRSpeaker(64512, neighbors, configFile, []string{"vtysh", "-f"}, fexec)
+
+	prefixes := []*net.IPNet{
+		ovntest.MustParseIPNet("172.20.0.10/32"),
+		ovntest.MustParseIPNet("10.244.1.0/24"),
+	}
+	fexec.AddFakeCmdsNoOutputNoError([]string{"vtysh -f " + configFile})
+	if !assert.NoError(t, s.Advertise(prefixes)) {
+		t.FailNow()
+	}
+	data, err := ioutil.ReadFile(configFile)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Contains(t, string(data), "  network 10.244.1.0/24\n  network 172.20.0.10/32\n")
+
+	// a failure to apply the configuration keeps the prefixes to withdraw
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "vtysh -f " + configFile, Err: fmt.Errorf("vtysh failed")})
+	assert.Error(t, s.Advertise(prefixes[1:]))
+	fexec.AddFakeCmdsNoOutputNoError([]string{"vtysh -f " + configFile})
+	if !assert.NoError(t, s.Advertise(prefixes[1:])) {
+		t.FailNow()
+	}
+	data, err = ioutil.ReadFile(configFile)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Contains(t, string(data), "  no network 172.20.0.10/32\n")
+	assert.True(t, fexec.CalledMatchesExpected(), fexec.ErrorDesc())
+
+	// a restarted speaker withdraws the prefixes of the file it rendered last
+	s = newFRRSpeaker(64512, neighbors, configFile, []string{"vtysh", "-f"}, fexec)
+	assert.Equal(t, []string{"10.244.1.0/24"}, s.advertised)
+}
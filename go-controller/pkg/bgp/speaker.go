@@ -0,0 +1,52 @@
+// Package bgp advertises the routes of a node to its BGP peers, through a
+// routing daemon running on the node.
+package bgp
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+
+	kexec "k8s.io/utils/exec"
+)
+
+// Speaker advertises routes to the BGP peers of the node. Implementations
+// drive a local routing daemon, e.g. by rendering its configuration or over
+// its API.
+type Speaker interface {
+	// Advertise makes prefixes the set of routes advertised by the node,
+	// withdrawing the previously advertised ones that are not in it. It is
+	// called again periodically with the same prefixes, so that a restarted
+	// routing daemon gets them back.
+	Advertise(prefixes []*net.IPNet) error
+}
+
+// NewSpeaker returns the speaker of the configured routing daemon
+func NewSpeaker() (Speaker, error) {
+	switch config.BGP.Speaker {
+	case config.BGPSpeakerFRR:
+		return newFRRSpeaker(uint32(config.BGP.ASN), config.BGP.Neighbors, config.BGP.FRRConfigFile,
+			strings.Fields(config.BGP.FRRApplyCommand), kexec.New()), nil
+	default:
+		return nil, fmt.Errorf("unsupported BGP speaker %q", config.BGP.Speaker)
+	}
+}
+
+// sortPrefixes returns the canonical forms of prefixes, sorted and without
+// duplicates
+func sortPrefixes(prefixes []*net.IPNet) []string {
+	seen := map[string]bool{}
+	sorted := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		p := (&net.IPNet{IP: prefix.IP.Mask(prefix.Mask), Mask: prefix.Mask}).String()
+		if !seen[p] {
+			seen[p] = true
+			sorted = append(sorted, p)
+		}
+	}
+	sort.Strings(sorted)
+	return sorted
+}
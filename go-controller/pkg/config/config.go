@@ -114,6 +114,7 @@ var (
 	Gateway = GatewayConfig{
 		V4JoinSubnet: "100.64.0.0/16",
 		V6JoinSubnet: "fd98::/64",
+		RuleBackend:  GatewayRuleBackendIPTables,
 	}
 
 	// MasterHA holds master HA related config options.
@@ -147,6 +148,13 @@ var (
 	OvnKubeNode = OvnKubeNodeConfig{
 		Mode: types.NodeModeFull,
 	}
+
+	// BGP holds BGP advertisement parsed config file parameters and command-line overrides
+	BGP = BGPConfig{
+		Speaker:          BGPSpeakerFRR,
+		FRRConfigFile:   "/etc/frr/ovnkube-bgp.conf",
+		FRRApplyCommand: "vtysh -f",
+	}
 )
 
 const (
@@ -265,12 +273,30 @@ type KubernetesConfig struct {
 	RawNoHostSubnetNodes  string `gcfg:"no-hostsubnet-nodes"`
 	NoHostSubnetNodes     *metav1.LabelSelector
 	HostNetworkNamespace  string `gcfg:"host-network-namespace"`
+	// RawLoadBalancerIPPools is a comma-separated list of CIDRs the master
+	// allocates the ingress IPs of LoadBalancer services from
+	RawLoadBalancerIPPools string `gcfg:"lb-ip-pools"`
+	LoadBalancerIPPools    []*net.IPNet
 }
 
 // OVNKubernetesFeatureConfig holds OVN-Kubernetes feature enhancement config file parameters and command-line overrides
 type OVNKubernetesFeatureConfig struct {
 	EnableEgressIP       bool `gcfg:"enable-egress-ip"`
 	EnableEgressFirewall bool `gcfg:"enable-egress-firewall"`
+	// EnableSharedPeerAddressSets makes NetworkPolicy rules with identical pod
+	// selector peers share a single reference-counted address set.
+	EnableSharedPeerAddressSets bool `gcfg:"enable-shared-peer-address-sets"`
+	// EnableHostNetworkPolicy replaces the blanket allow of traffic from the
+	// node's management port with per-node address sets of host network
+	// addresses, which NetworkPolicies reference by selecting host network pods.
+	EnableHostNetworkPolicy bool `gcfg:"enable-host-network-policy"`
+	// EnableServiceHealthChecks reserves an address on every node subnet for
+	// OVN to probe service endpoints from, so that services can opt in to
+	// load balancer health checks.
+	EnableServiceHealthChecks bool `gcfg:"enable-service-health-checks"`
+	// EnableHostFirewall makes the nodes restrict the sources of the traffic
+	// to their ports as set by the HostNetworkPolicies selecting them.
+	EnableHostFirewall bool `gcfg:"enable-host-firewall"`
 }
 
 // GatewayMode holds the node gateway mode
@@ -285,6 +311,16 @@ const (
 	GatewayModeLocal GatewayMode = "local"
 )
 
+// GatewayRuleBackend holds the backend the node gateway programs its host rules with
+type GatewayRuleBackend string
+
+const (
+	// GatewayRuleBackendIPTables programs the host rules with iptables
+	GatewayRuleBackendIPTables GatewayRuleBackend = "iptables"
+	// GatewayRuleBackendNFTables programs the host rules with nftables
+	GatewayRuleBackendNFTables GatewayRuleBackend = "nftables"
+)
+
 // GatewayConfig holds node gateway-related parsed config file parameters and command-line overrides
 type GatewayConfig struct {
 	// Mode is the gateway mode; if may be either empty (disabled), "shared", or "local"
@@ -293,6 +329,11 @@ type GatewayConfig struct {
 	Interface string `gcfg:"interface"`
 	// Exgress gateway interface is the optional network interface to use for external gw pods traffic.
 	EgressGWInterface string `gcfg:"egw-interface"`
+	// RawEgressInterfaces holds the unparsed named secondary interfaces that
+	// namespaces can select for their egress traffic in "shared" mode
+	RawEgressInterfaces string `gcfg:"egress-interfaces"`
+	// EgressInterfaces holds the parsed named secondary egress interfaces
+	EgressInterfaces []EgressInterfaceEntry
 	// NextHop is the gateway IP address of Interface; will be autodetected if not given
 	NextHop string `gcfg:"next-hop"`
 	// VLANID is the option VLAN tag to apply to gateway traffic for "shared" mode
@@ -313,6 +354,18 @@ type GatewayConfig struct {
 	// RouterSubnet is the subnet to be used for the GR external port. auto-detected if not given.
 	// Must match the the kube node IP address. Currently valid for Smart-NICs only.
 	RouterSubnet string `gcfg:"router-subnet"`
+	// AnnounceServiceIPs makes the nodes send gratuitous ARPs and unsolicited
+	// neighbor advertisements for the external and load balancer IPs of
+	// services, one node per IP, in "shared" mode
+	AnnounceServiceIPs bool `gcfg:"announce-service-ips"`
+	// RuleBackend is the backend to program the host NAT and filter rules of
+	// the gateway with; either "iptables" or "nftables"
+	RuleBackend GatewayRuleBackend `gcfg:"rule-backend"`
+	// VRF is the optional Linux VRF the management port and the egress traffic
+	// of the pods are put in, in "local" mode
+	VRF string `gcfg:"vrf"`
+	// VRFTable is the routing table of VRF
+	VRFTable uint `gcfg:"vrf-table"`
 }
 
 // OvnAuthConfig holds client authentication and location details for
@@ -361,6 +414,38 @@ type OvnKubeNodeConfig struct {
 	DisableOVNIfaceIdVer bool   `gcfg:"disable-ovn-iface-id-ver"`
 }
 
+// BGPSpeaker holds the routing daemon the node advertises its routes through
+type BGPSpeaker string
+
+const (
+	// BGPSpeakerFRR advertises the routes by rendering the configuration of
+	// the FRR routing daemon
+	BGPSpeakerFRR BGPSpeaker = "frr"
+)
+
+// BGPConfig holds the configuration of the advertisement of the routes of the
+// node over BGP
+type BGPConfig struct {
+	// Enabled makes the node advertise its pod subnets, and the external and
+	// load balancer IPs of the services with local endpoints
+	Enabled bool `gcfg:"enabled"`
+	// Speaker is the routing daemon the routes are advertised through
+	Speaker BGPSpeaker `gcfg:"speaker"`
+	// ASN is the autonomous system number of the node
+	ASN uint `gcfg:"asn"`
+	// RawNeighbors holds the unparsed BGP peers of the node. Should only be used inside the config module.
+	RawNeighbors string `gcfg:"neighbors"`
+	// Neighbors holds the parsed BGP peers of the node
+	Neighbors []BGPNeighbor
+	// FRRConfigFile is the file the node renders its BGP configuration in,
+	// apart from the configuration file of FRR
+	FRRConfigFile string `gcfg:"frr-config-file"`
+	// FRRApplyCommand is the command that applies the rendered configuration
+	// on top of the running configuration of FRR. The file is passed as its
+	// last argument.
+	FRRApplyCommand string `gcfg:"frr-apply-command"`
+}
+
 // OvnDBScheme describes the OVN database connection transport method
 type OvnDBScheme string
 
@@ -387,6 +472,7 @@ type config struct {
 	MasterHA             MasterHAConfig
 	HybridOverlay        HybridOverlayConfig
 	OvnKubeNode          OvnKubeNodeConfig
+	BGP                  BGPConfig
 }
 
 var (
@@ -402,6 +488,7 @@ var (
 	savedMasterHA             MasterHAConfig
 	savedHybridOverlay        HybridOverlayConfig
 	savedOvnKubeNode          OvnKubeNodeConfig
+	savedBGP                  BGPConfig
 	// legacy service-cluster-ip-range CLI option
 	serviceClusterIPRange string
 	// legacy cluster-subnet CLI option
@@ -426,6 +513,7 @@ func init() {
 	savedMasterHA = MasterHA
 	savedHybridOverlay = HybridOverlay
 	savedOvnKubeNode = OvnKubeNode
+	savedBGP = BGP
 	cli.VersionPrinter = func(c *cli.Context) {
 		fmt.Printf("Version: %s\n", Version)
 		fmt.Printf("Git commit: %s\n", Commit)
@@ -445,6 +533,7 @@ func init() {
 	Flags = append(Flags, HybridOverlayFlags...)
 	Flags = append(Flags, MonitoringFlags...)
 	Flags = append(Flags, OvnKubeNodeFlags...)
+	Flags = append(Flags, BGPFlags...)
 }
 
 // PrepareTestConfig restores default config values. Used by testcases to
@@ -463,6 +552,7 @@ func PrepareTestConfig() {
 	MasterHA = savedMasterHA
 	HybridOverlay = savedHybridOverlay
 	OvnKubeNode = savedOvnKubeNode
+	BGP = savedBGP
 
 	// Don't pick up defaults from the environment
 	os.Unsetenv("KUBECONFIG")
@@ -764,6 +854,32 @@ var OVNK8sFeatureFlags = []cli.Flag{
 		Destination: &cliConfig.OVNKubernetesFeature.EnableEgressFirewall,
 		Value:       OVNKubernetesFeature.EnableEgressFirewall,
 	},
+	&cli.BoolFlag{
+		Name:        "enable-shared-peer-address-sets",
+		Usage:       "Configure NetworkPolicy peers with identical selectors to share a single address set.",
+		Destination: &cliConfig.OVNKubernetesFeature.EnableSharedPeerAddressSets,
+		Value:       OVNKubernetesFeature.EnableSharedPeerAddressSets,
+	},
+	&cli.BoolFlag{
+		Name: "enable-host-network-policy",
+		Usage: "Configure NetworkPolicy to only allow traffic from nodes and host network pods " +
+			"that are explicitly selected, instead of always allowing traffic from the local node.",
+		Destination: &cliConfig.OVNKubernetesFeature.EnableHostNetworkPolicy,
+		Value:       OVNKubernetesFeature.EnableHostNetworkPolicy,
+	},
+	&cli.BoolFlag{
+		Name: "enable-service-health-checks",
+		Usage: "Allow services to configure OVN load balancer health checks with the " +
+			"k8s.ovn.org/service-health-check annotation (IPv4 only).",
+		Destination: &cliConfig.OVNKubernetesFeature.EnableServiceHealthChecks,
+		Value:       OVNKubernetesFeature.EnableServiceHealthChecks,
+	},
+	&cli.BoolFlag{
+		Name:        "enable-host-firewall",
+		Usage:       "Configure to use HostNetworkPolicy CRD feature with ovn-kubernetes.",
+		Destination: &cliConfig.OVNKubernetesFeature.EnableHostFirewall,
+		Value:       OVNKubernetesFeature.EnableHostFirewall,
+	},
 }
 
 // K8sFlags capture Kubernetes-related options
@@ -852,6 +968,13 @@ var K8sFlags = []cli.Flag{
 		Destination: &cliConfig.Kubernetes.HostNetworkNamespace,
 		Value:       Kubernetes.HostNetworkNamespace,
 	},
+	&cli.StringFlag{
+		Name: "lb-ip-pools",
+		Usage: "A comma-separated set of CIDR notation IP ranges from which ovnkube-master " +
+			"allocates the ingress IPs of LoadBalancer services. Leave empty to leave " +
+			"allocating them to another controller.",
+		Destination: &cliConfig.Kubernetes.RawLoadBalancerIPPools,
+	},
 }
 
 // OvnNBFlags capture OVN northbound database options
@@ -959,6 +1082,14 @@ var OVNGatewayFlags = []cli.Flag{
 			"If none specified, ovnk will use the default interface",
 		Destination: &cliConfig.Gateway.EgressGWInterface,
 	},
+	&cli.StringFlag{
+		Name: "egress-interfaces",
+		Usage: "A comma separated set of named secondary interfaces on nodes, each of the form " +
+			"<name>=<interface>@<next-hop>[@<next-hop>], with one next hop per IP family. The egress " +
+			"traffic of the pods of a namespace annotated with k8s.ovn.org/egress-interface=<name> " +
+			"leaves the node through that interface. Valid only for Shared Gateway interface mode.",
+		Destination: &cliConfig.Gateway.RawEgressInterfaces,
+	},
 	&cli.StringFlag{
 		Name: "gateway-nexthop",
 		Usage: "The external default gateway which is used as a next hop by " +
@@ -1009,6 +1140,30 @@ var OVNGatewayFlags = []cli.Flag{
 		Destination: &cliConfig.Gateway.RouterSubnet,
 		Value:       Gateway.RouterSubnet,
 	},
+	&cli.BoolFlag{
+		Name: "gateway-announce-service-ips",
+		Usage: "Announce the external and load balancer IPs of services on the gateway " +
+			"interface with gratuitous ARPs and unsolicited neighbor advertisements, " +
+			"from a single node per IP (shared mode only)",
+		Destination: &cliConfig.Gateway.AnnounceServiceIPs,
+	},
+	&cli.StringFlag{
+		Name: "gateway-rule-backend",
+		Usage: "The backend to program the host rules of the gateway with, " +
+			"one of \"iptables\" or \"nftables\" (default: iptables)",
+	},
+	&cli.StringFlag{
+		Name: "gateway-vrf",
+		Usage: "The Linux VRF to put the management port and the egress traffic of the pods in, " +
+			"so that it is routed with the routing table of the VRF instead of the main one. " +
+			"Valid only for Local Gateway interface mode.",
+		Destination: &cliConfig.Gateway.VRF,
+	},
+	&cli.UintFlag{
+		Name:        "gateway-vrf-table",
+		Usage:       "The routing table of the gateway VRF.",
+		Destination: &cliConfig.Gateway.VRFTable,
+	},
 	// Deprecated CLI options
 	&cli.BoolFlag{
 		Name:        "init-gateways",
@@ -1095,6 +1250,44 @@ var OvnKubeNodeFlags = []cli.Flag{
 	},
 }
 
+// BGPFlags capture the BGP advertisement options
+var BGPFlags = []cli.Flag{
+	&cli.BoolFlag{
+		Name: "enable-bgp",
+		Usage: "Advertise the pod subnets of the node, and the external and load balancer " +
+			"IPs of the services with endpoints on the node, over BGP",
+		Destination: &cliConfig.BGP.Enabled,
+	},
+	&cli.StringFlag{
+		Name:  "bgp-speaker",
+		Usage: "The routing daemon to advertise the routes through. Only \"frr\" is supported",
+		Value: string(BGP.Speaker),
+	},
+	&cli.UintFlag{
+		Name:        "bgp-asn",
+		Usage:       "The autonomous system number of the nodes",
+		Destination: &cliConfig.BGP.ASN,
+	},
+	&cli.StringFlag{
+		Name: "bgp-neighbors",
+		Usage: "A comma separated set of BGP peers of the nodes, each of the form " +
+			"<ip>@<asn> (eg, \"172.18.0.1@64500,fd00::1@64500\")",
+		Destination: &cliConfig.BGP.RawNeighbors,
+	},
+	&cli.StringFlag{
+		Name:        "bgp-frr-config-file",
+		Usage:       "The file to render the BGP configuration of the node in, apart from the configuration file of FRR",
+		Value:       BGP.FRRConfigFile,
+		Destination: &cliConfig.BGP.FRRConfigFile,
+	},
+	&cli.StringFlag{
+		Name:        "bgp-frr-apply-command",
+		Usage:       "The command that applies the rendered BGP configuration to FRR, passed the file as its last argument",
+		Value:       BGP.FRRApplyCommand,
+		Destination: &cliConfig.BGP.FRRApplyCommand,
+	},
+}
+
 // Flags are general command-line flags. Apps should add these flags to their
 // own urfave/cli flags and call InitConfig() early in the application.
 var Flags []cli.Flag
@@ -1113,6 +1306,7 @@ func GetFlags(customFlags []cli.Flag) []cli.Flag {
 	flags = append(flags, HybridOverlayFlags...)
 	flags = append(flags, MonitoringFlags...)
 	flags = append(flags, OvnKubeNodeFlags...)
+	flags = append(flags, BGPFlags...)
 	flags = append(flags, customFlags...)
 	return flags
 }
@@ -1282,6 +1476,18 @@ func buildKubernetesConfig(exec kexec.Interface, cli, file *config, saPath strin
 		return fmt.Errorf("kubernetes service-cidrs must contain either a single CIDR or else an IPv4/IPv6 pair")
 	}
 
+	Kubernetes.LoadBalancerIPPools = nil
+	if Kubernetes.RawLoadBalancerIPPools != "" {
+		for _, cidrString := range strings.Split(Kubernetes.RawLoadBalancerIPPools, ",") {
+			_, pool, err := net.ParseCIDR(strings.TrimSpace(cidrString))
+			if err != nil {
+				return fmt.Errorf("load balancer IP pool %q invalid: %v", cidrString, err)
+			}
+			Kubernetes.LoadBalancerIPPools = append(Kubernetes.LoadBalancerIPPools, pool)
+			allSubnets.append(configSubnetLoadBalancer, pool)
+		}
+	}
+
 	if Kubernetes.RawNoHostSubnetNodes != "" {
 		if nodeSelector, err := metav1.ParseToLabelSelector(Kubernetes.RawNoHostSubnetNodes); err == nil {
 			Kubernetes.NoHostSubnetNodes = nodeSelector
@@ -1308,6 +1514,7 @@ func buildGatewayConfig(ctx *cli.Context, cli, file *config, allSubnets *configS
 			}
 		}
 	}
+	cli.Gateway.RuleBackend = GatewayRuleBackend(ctx.String("gateway-rule-backend"))
 	// And CLI overrides over config file and default values
 	if err := overrideFields(&Gateway, &cli.Gateway, &savedGateway); err != nil {
 		return err
@@ -1337,6 +1544,49 @@ func buildGatewayConfig(ctx *cli.Context, cli, file *config, allSubnets *configS
 		}
 	}
 
+	switch Gateway.RuleBackend {
+	case "":
+		Gateway.RuleBackend = GatewayRuleBackendIPTables
+	case GatewayRuleBackendIPTables, GatewayRuleBackendNFTables:
+	default:
+		return fmt.Errorf("invalid gateway rule backend %q: expect one of %s,%s", Gateway.RuleBackend,
+			GatewayRuleBackendIPTables, GatewayRuleBackendNFTables)
+	}
+
+	Gateway.EgressInterfaces = nil
+	if Gateway.RawEgressInterfaces != "" {
+		if Gateway.Mode != GatewayModeShared {
+			return fmt.Errorf("gateway egress interfaces option %q is supported only in shared gateway mode",
+				Gateway.RawEgressInterfaces)
+		}
+		entries, err := ParseEgressInterfaceEntries(Gateway.RawEgressInterfaces)
+		if err != nil {
+			return fmt.Errorf("egress interfaces invalid: %v", err)
+		}
+		for _, entry := range entries {
+			if entry.Interface == Gateway.Interface || entry.Interface == Gateway.EgressGWInterface {
+				return fmt.Errorf("egress interface %s must not be the gateway or external gateway interface", entry.Name)
+			}
+		}
+		Gateway.EgressInterfaces = entries
+	}
+
+	if Gateway.VRF != "" {
+		if Gateway.Mode != GatewayModeLocal {
+			return fmt.Errorf("gateway VRF option %q is supported only in local gateway mode", Gateway.VRF)
+		}
+		if len(Gateway.VRF) > 15 {
+			return fmt.Errorf("gateway VRF name %q is longer than 15 characters", Gateway.VRF)
+		}
+		// 6 is the routing table of the external IPs in local gateway mode,
+		// and 253 to 255 are reserved for the default, main and local tables
+		if Gateway.VRFTable == 0 || Gateway.VRFTable == 6 || (Gateway.VRFTable >= 253 && Gateway.VRFTable <= 255) {
+			return fmt.Errorf("invalid gateway VRF table %d: expect a free routing table", Gateway.VRFTable)
+		}
+	} else if Gateway.VRFTable != 0 {
+		return fmt.Errorf("gateway VRF table option %d not allowed without a gateway VRF", Gateway.VRFTable)
+	}
+
 	if Gateway.Mode != GatewayModeShared && Gateway.VLANID != 0 {
 		return fmt.Errorf("gateway VLAN ID option: %d is supported only in shared gateway mode", Gateway.VLANID)
 	}
@@ -1367,6 +1617,10 @@ func buildOVNKubernetesFeatureConfig(ctx *cli.Context, cli, file *config) error
 	if err := overrideFields(&OVNKubernetesFeature, &cli.OVNKubernetesFeature, &savedOVNKubernetesFeature); err != nil {
 		return err
 	}
+
+	if OVNKubernetesFeature.EnableHostNetworkPolicy && OVNKubernetesFeature.EnableSharedPeerAddressSets {
+		return fmt.Errorf("host network policy is not supported with shared peer address sets")
+	}
 	return nil
 }
 
@@ -1425,6 +1679,38 @@ func buildMonitoringConfig(ctx *cli.Context, cli, file *config) error {
 	return nil
 }
 
+func buildBGPConfig(ctx *cli.Context, cli, file *config) error {
+	var err error
+	if err = overrideFields(&BGP, &file.BGP, &savedBGP); err != nil {
+		return err
+	}
+	cli.BGP.Speaker = BGPSpeaker(ctx.String("bgp-speaker"))
+	if err = overrideFields(&BGP, &cli.BGP, &savedBGP); err != nil {
+		return err
+	}
+
+	if !BGP.Enabled {
+		return nil
+	}
+	if Gateway.Mode == GatewayModeDisabled {
+		return fmt.Errorf("BGP advertisement is not supported when gateway is disabled")
+	}
+	if BGP.Speaker != BGPSpeakerFRR {
+		return fmt.Errorf("invalid BGP speaker %q: expect %s", BGP.Speaker, BGPSpeakerFRR)
+	}
+	if BGP.ASN == 0 || BGP.ASN > 4294967295 {
+		return fmt.Errorf("invalid BGP ASN %d", BGP.ASN)
+	}
+	if BGP.RawNeighbors == "" {
+		return fmt.Errorf("BGP advertisement needs at least one neighbor")
+	}
+	BGP.Neighbors, err = ParseBGPNeighbors(BGP.RawNeighbors)
+	if err != nil {
+		return fmt.Errorf("BGP neighbors invalid: %v", err)
+	}
+	return nil
+}
+
 func buildHybridOverlayConfig(ctx *cli.Context, cli, file *config, allSubnets *configSubnets) error {
 	// Copy config file values over default values
 	if err := overrideFields(&HybridOverlay, &file.HybridOverlay, &savedHybridOverlay); err != nil {
@@ -1531,6 +1817,7 @@ func initConfigWithPath(ctx *cli.Context, exec kexec.Interface, saPath string, d
 		MasterHA:             savedMasterHA,
 		HybridOverlay:        savedHybridOverlay,
 		OvnKubeNode:          savedOvnKubeNode,
+		BGP:                  savedBGP,
 	}
 
 	allSubnets := newConfigSubnets()
@@ -1658,6 +1945,10 @@ func initConfigWithPath(ctx *cli.Context, exec kexec.Interface, saPath string, d
 		return "", err
 	}
 
+	if err = buildBGPConfig(ctx, &cliConfig, &cfg); err != nil {
+		return "", err
+	}
+
 	klog.V(5).Infof("Default config: %+v", Default)
 	klog.V(5).Infof("Logging config: %+v", Logging)
 	klog.V(5).Infof("Monitoring config: %+v", Monitoring)
@@ -1668,6 +1959,7 @@ func initConfigWithPath(ctx *cli.Context, exec kexec.Interface, saPath string, d
 	klog.V(5).Infof("OVN South config: %+v", OvnSouth)
 	klog.V(5).Infof("Hybrid Overlay config: %+v", HybridOverlay)
 	klog.V(5).Infof("Ovnkube Node config: %+v", OvnKubeNode)
+	klog.V(5).Infof("BGP config: %+v", BGP)
 
 	return retConfigFile, nil
 }
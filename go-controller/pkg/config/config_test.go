@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
@@ -722,6 +723,41 @@ service-cidr=172.18.0.0/24
 		gomega.Expect(err).NotTo(gomega.HaveOccurred())
 	})
 
+	It("parses load balancer IP pools", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(Kubernetes.LoadBalancerIPPools).To(gomega.Equal([]*net.IPNet{
+				ovntest.MustParseIPNet("192.168.100.0/28"),
+				ovntest.MustParseIPNet("fd03::/120"),
+			}))
+			// the pools don't make a single stack cluster dual-stack
+			gomega.Expect(IPv4Mode).To(gomega.Equal(true))
+			gomega.Expect(IPv6Mode).To(gomega.Equal(false))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-lb-ip-pools=192.168.100.0/28,fd03::/120",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+
+	It("returns an error when a load balancer IP pool overlaps the service CIDRs", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("illegal network configuration: load balancer IP pool \"172.16.1.128/25\" overlaps service subnet \"172.16.1.0/24\""))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-lb-ip-pools=172.16.1.128/25",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+
 	It("overrides config file and defaults with CLI legacy cluster-subnet option", func() {
 		err := ioutil.WriteFile(cfgFile.Name(), []byte(`[default]
 cluster-subnets=172.18.0.0/23
@@ -839,6 +875,159 @@ mode=shared
 		gomega.Expect(err).NotTo(gomega.HaveOccurred())
 	})
 
+	It("overrides the gateway rule backend from the config file with the CLI", func() {
+		err := ioutil.WriteFile(cfgFile.Name(), []byte(`[gateway]
+rule-backend=iptables
+`), 0644)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(Gateway.RuleBackend).To(gomega.Equal(GatewayRuleBackendNFTables))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-config-file=" + cfgFile.Name(),
+			"-gateway-rule-backend=nftables",
+		}
+		err = app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+
+	It("returns an error when the gateway rule backend is invalid", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid gateway rule backend \"ebtables\": expect one of iptables,nftables"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-rule-backend=ebtables",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+
+	It("parses the gateway egress interfaces", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(Gateway.EgressInterfaces).To(gomega.Equal([]EgressInterfaceEntry{
+				{Name: "compliance", Interface: "eth2", NextHops: []net.IP{net.ParseIP("192.168.20.1")}},
+			}))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-mode=shared",
+			"-egress-interfaces=compliance=eth2@192.168.20.1",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+
+	It("returns an error when the egress interfaces are specified for mode other than shared gateway mode", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("gateway egress interfaces option \"compliance=eth2@192.168.20.1\" is supported only in shared gateway mode"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-mode=local",
+			"-egress-interfaces=compliance=eth2@192.168.20.1",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+
+	It("returns an error when the gateway VRF is specified for mode other than local gateway mode", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("gateway VRF option \"ovn-vrf\" is supported only in local gateway mode"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-mode=shared",
+			"-gateway-vrf=ovn-vrf",
+			"-gateway-vrf-table=100",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+
+	It("returns an error when the gateway VRF table is reserved", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("invalid gateway VRF table 254: expect a free routing table"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-mode=local",
+			"-gateway-vrf=ovn-vrf",
+			"-gateway-vrf-table=254",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+
+	It("parses the BGP advertisement options", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(BGP.Enabled).To(gomega.BeTrue())
+			gomega.Expect(BGP.Speaker).To(gomega.Equal(BGPSpeakerFRR))
+			gomega.Expect(BGP.ASN).To(gomega.Equal(uint(64512)))
+			gomega.Expect(BGP.Neighbors).To(gomega.Equal([]BGPNeighbor{{IP: net.ParseIP("172.18.0.1"), ASN: 64500}}))
+			gomega.Expect(BGP.FRRConfigFile).To(gomega.Equal("/etc/frr/ovnkube-bgp.conf"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-gateway-mode=shared",
+			"-enable-bgp",
+			"-bgp-asn=64512",
+			"-bgp-neighbors=172.18.0.1@64500",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+
+	It("returns an error when host network policy is enabled with shared peer address sets", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("host network policy is not supported with shared peer address sets"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-enable-host-network-policy",
+			"-enable-shared-peer-address-sets",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+
+	It("returns an error when BGP advertisement is enabled without a gateway", func() {
+		app.Action = func(ctx *cli.Context) error {
+			_, err := InitConfig(ctx, kexec.New(), nil)
+			gomega.Expect(err).To(gomega.MatchError("BGP advertisement is not supported when gateway is disabled"))
+			return nil
+		}
+		cliArgs := []string{
+			app.Name,
+			"-enable-bgp",
+			"-bgp-asn=64512",
+			"-bgp-neighbors=172.18.0.1@64500",
+		}
+		err := app.Run(cliArgs)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+
 	It("returns an error when the vlan-id is specified for mode other than shared gateway mode", func() {
 		app.Action = func(ctx *cli.Context) error {
 			_, err := InitConfig(ctx, kexec.New(), nil)
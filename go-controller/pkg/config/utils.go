@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/util/validation"
 	utilnet "k8s.io/utils/net"
 )
 
@@ -22,6 +23,20 @@ type CIDRNetworkEntry struct {
 	HostSubnetLength int
 }
 
+// EgressInterfaceEntry is the object that holds the definition of a named
+// secondary gateway interface that namespaces can select for their egress traffic
+type EgressInterfaceEntry struct {
+	Name      string
+	Interface string
+	NextHops  []net.IP
+}
+
+// BGPNeighbor is a BGP peer of the nodes
+type BGPNeighbor struct {
+	IP  net.IP
+	ASN uint32
+}
+
 // ParseClusterSubnetEntries returns the parsed set of CIDRNetworkEntries passed by the user on the command line
 // These entries define the clusters network space by specifying a set of CIDR and netmasks the SDN can allocate
 // addresses from.
@@ -80,6 +95,82 @@ func ParseClusterSubnetEntries(clusterSubnetCmd string) ([]CIDRNetworkEntry, err
 	return parsedClusterList, nil
 }
 
+// ParseEgressInterfaceEntries returns the parsed set of EgressInterfaceEntries passed by the user on the command line
+// Entries are of the form <name>=<interface>@<next-hop>[@<next-hop>], with at most one next hop per IP family.
+func ParseEgressInterfaceEntries(egressInterfaces string) ([]EgressInterfaceEntry, error) {
+	var parsedEntries []EgressInterfaceEntry
+	names := map[string]bool{}
+	interfaces := map[string]bool{}
+
+	for _, entry := range strings.Split(egressInterfaces, ",") {
+		nameAndRest := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(nameAndRest) != 2 {
+			return nil, fmt.Errorf("egress interface %q not properly formatted", entry)
+		}
+		name := nameAndRest[0]
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid egress interface name %q: %s", name, strings.Join(errs, ", "))
+		}
+		if names[name] {
+			return nil, fmt.Errorf("egress interface name %q is used more than once", name)
+		}
+		names[name] = true
+
+		fields := strings.Split(nameAndRest[1], "@")
+		if fields[0] == "" || len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("egress interface %q must have an interface and one next hop per IP family", entry)
+		}
+		if interfaces[fields[0]] {
+			return nil, fmt.Errorf("interface %s is used by more than one egress interface", fields[0])
+		}
+		interfaces[fields[0]] = true
+
+		parsedEntry := EgressInterfaceEntry{Name: name, Interface: fields[0]}
+		for _, nextHopStr := range fields[1:] {
+			nextHop := net.ParseIP(nextHopStr)
+			if nextHop == nil {
+				return nil, fmt.Errorf("egress interface %s next hop %q is not a valid IP", name, nextHopStr)
+			}
+			if len(parsedEntry.NextHops) > 0 && utilnet.IsIPv6(parsedEntry.NextHops[0]) == utilnet.IsIPv6(nextHop) {
+				return nil, fmt.Errorf("egress interface %s has more than one next hop of the same IP family", name)
+			}
+			parsedEntry.NextHops = append(parsedEntry.NextHops, nextHop)
+		}
+		parsedEntries = append(parsedEntries, parsedEntry)
+	}
+
+	return parsedEntries, nil
+}
+
+// ParseBGPNeighbors returns the parsed set of BGP peers passed by the user on
+// the command line, each of the form <ip>@<asn>
+func ParseBGPNeighbors(neighbors string) ([]BGPNeighbor, error) {
+	var parsedNeighbors []BGPNeighbor
+	ips := map[string]bool{}
+
+	for _, entry := range strings.Split(neighbors, ",") {
+		fields := strings.Split(strings.TrimSpace(entry), "@")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("BGP neighbor %q not properly formatted", entry)
+		}
+		ip := net.ParseIP(fields[0])
+		if ip == nil {
+			return nil, fmt.Errorf("BGP neighbor IP %q is not a valid IP", fields[0])
+		}
+		if ips[ip.String()] {
+			return nil, fmt.Errorf("BGP neighbor %s is given more than once", ip)
+		}
+		ips[ip.String()] = true
+		asn, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil || asn == 0 {
+			return nil, fmt.Errorf("BGP neighbor %s ASN %q is not a valid ASN", ip, fields[1])
+		}
+		parsedNeighbors = append(parsedNeighbors, BGPNeighbor{IP: ip, ASN: uint32(asn)})
+	}
+
+	return parsedNeighbors, nil
+}
+
 // ParseFlowCollectors returns the parsed set of HostPorts passed by the user on the command line
 // These entries define the flow collectors OVS will send flow metadata by using NetFlow/SFlow/IPFIX.
 func ParseFlowCollectors(flowCollectors string) ([]HostPort, error) {
@@ -112,6 +203,9 @@ const (
 	configSubnetCluster configSubnetType = "cluster subnet"
 	configSubnetService configSubnetType = "service subnet"
 	configSubnetHybrid  configSubnetType = "hybrid overlay subnet"
+	// load balancer IP pools are checked for overlaps, but don't determine
+	// the IP families of the cluster
+	configSubnetLoadBalancer configSubnetType = "load balancer IP pool"
 )
 
 type configSubnet struct {
@@ -137,7 +231,7 @@ func newConfigSubnets() *configSubnets {
 // append adds a single subnet to cs
 func (cs *configSubnets) append(subnetType configSubnetType, subnet *net.IPNet) {
 	cs.subnets = append(cs.subnets, configSubnet{subnetType: subnetType, subnet: subnet})
-	if subnetType != configSubnetJoin {
+	if subnetType != configSubnetJoin && subnetType != configSubnetLoadBalancer {
 		if utilnet.IsIPv6CIDR(subnet) {
 			cs.v6[subnetType] = true
 		} else {
@@ -2,6 +2,7 @@ package config
 
 import (
 	"net"
+	"reflect"
 	"testing"
 
 	ovntest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing"
@@ -130,6 +131,135 @@ func TestParseClusterSubnetEntries(t *testing.T) {
 	}
 }
 
+func TestParseEgressInterfaceEntries(t *testing.T) {
+	tests := []struct {
+		name        string
+		cmdLineArg  string
+		entries     []EgressInterfaceEntry
+		expectedErr bool
+	}{
+		{
+			name:       "Single interface",
+			cmdLineArg: "compliance=eth2@192.168.20.1",
+			entries: []EgressInterfaceEntry{
+				{Name: "compliance", Interface: "eth2", NextHops: []net.IP{net.ParseIP("192.168.20.1")}},
+			},
+		},
+		{
+			name:       "Dual-stack interface and a bridge",
+			cmdLineArg: "compliance=eth2@192.168.20.1@fd00:20::1,dmz=breth3@192.168.30.1",
+			entries: []EgressInterfaceEntry{
+				{Name: "compliance", Interface: "eth2", NextHops: []net.IP{net.ParseIP("192.168.20.1"), net.ParseIP("fd00:20::1")}},
+				{Name: "dmz", Interface: "breth3", NextHops: []net.IP{net.ParseIP("192.168.30.1")}},
+			},
+		},
+		{
+			name:        "Missing name",
+			cmdLineArg:  "eth2@192.168.20.1",
+			expectedErr: true,
+		},
+		{
+			name:        "Invalid name",
+			cmdLineArg:  "Compliance_Zone=eth2@192.168.20.1",
+			expectedErr: true,
+		},
+		{
+			name:        "Missing next hop",
+			cmdLineArg:  "compliance=eth2",
+			expectedErr: true,
+		},
+		{
+			name:        "Invalid next hop",
+			cmdLineArg:  "compliance=eth2@192.168.20",
+			expectedErr: true,
+		},
+		{
+			name:        "Two next hops of the same IP family",
+			cmdLineArg:  "compliance=eth2@192.168.20.1@192.168.20.2",
+			expectedErr: true,
+		},
+		{
+			name:        "Duplicate name",
+			cmdLineArg:  "compliance=eth2@192.168.20.1,compliance=eth3@192.168.30.1",
+			expectedErr: true,
+		},
+		{
+			name:        "Duplicate interface",
+			cmdLineArg:  "compliance=eth2@192.168.20.1,dmz=eth2@192.168.30.1",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		entries, err := ParseEgressInterfaceEntries(tc.cmdLineArg)
+		if err != nil && !tc.expectedErr {
+			t.Errorf("Test case \"%s\" expected no errors, got %v", tc.name, err)
+		}
+		if err == nil && tc.expectedErr {
+			t.Errorf("Test case \"%s\" expected an error", tc.name)
+		}
+		if !reflect.DeepEqual(entries, tc.entries) {
+			t.Errorf("Test case \"%s\" expected entries %v, got %v", tc.name, tc.entries, entries)
+		}
+	}
+}
+
+func TestParseBGPNeighbors(t *testing.T) {
+	tests := []struct {
+		name        string
+		cmdLineArg  string
+		neighbors   []BGPNeighbor
+		expectedErr bool
+	}{
+		{
+			name:       "Single neighbor",
+			cmdLineArg: "172.18.0.1@64500",
+			neighbors:  []BGPNeighbor{{IP: net.ParseIP("172.18.0.1"), ASN: 64500}},
+		},
+		{
+			name:       "Dual-stack neighbors",
+			cmdLineArg: "172.18.0.1@64500, fd00::1@4200000000",
+			neighbors: []BGPNeighbor{
+				{IP: net.ParseIP("172.18.0.1"), ASN: 64500},
+				{IP: net.ParseIP("fd00::1"), ASN: 4200000000},
+			},
+		},
+		{
+			name:        "Missing ASN",
+			cmdLineArg:  "172.18.0.1",
+			expectedErr: true,
+		},
+		{
+			name:        "Invalid IP",
+			cmdLineArg:  "172.18.0@64500",
+			expectedErr: true,
+		},
+		{
+			name:        "Invalid ASN",
+			cmdLineArg:  "172.18.0.1@4294967296",
+			expectedErr: true,
+		},
+		{
+			name:        "Duplicate neighbor",
+			cmdLineArg:  "172.18.0.1@64500,172.18.0.1@64501",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		neighbors, err := ParseBGPNeighbors(tc.cmdLineArg)
+		if err != nil && !tc.expectedErr {
+			t.Errorf("Test case \"%s\" expected no errors, got %v", tc.name, err)
+		}
+		if err == nil && tc.expectedErr {
+			t.Errorf("Test case \"%s\" expected an error", tc.name)
+		}
+		if !reflect.DeepEqual(neighbors, tc.neighbors) {
+			t.Errorf("Test case \"%s\" expected neighbors %v, got %v", tc.name, tc.neighbors, neighbors)
+		}
+	}
+}
+
 func Test_checkForOverlap(t *testing.T) {
 	tests := []struct {
 		name               string
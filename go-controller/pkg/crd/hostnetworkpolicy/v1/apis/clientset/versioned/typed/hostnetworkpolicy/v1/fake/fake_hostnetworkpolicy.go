@@ -0,0 +1,122 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	hostnetworkpolicyv1 "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/hostnetworkpolicy/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeHostNetworkPolicies implements HostNetworkPolicyInterface
+type FakeHostNetworkPolicies struct {
+	Fake *FakeK8sV1
+}
+
+var hostnetworkpoliciesResource = schema.GroupVersionResource{Group: "k8s.ovn.org", Version: "v1", Resource: "hostnetworkpolicies"}
+
+var hostnetworkpoliciesKind = schema.GroupVersionKind{Group: "k8s.ovn.org", Version: "v1", Kind: "HostNetworkPolicy"}
+
+// Get takes name of the hostNetworkPolicy, and returns the corresponding hostNetworkPolicy object, and an error if there is any.
+func (c *FakeHostNetworkPolicies) Get(ctx context.Context, name string, options v1.GetOptions) (result *hostnetworkpolicyv1.HostNetworkPolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootGetAction(hostnetworkpoliciesResource, name), &hostnetworkpolicyv1.HostNetworkPolicy{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*hostnetworkpolicyv1.HostNetworkPolicy), err
+}
+
+// List takes label and field selectors, and returns the list of HostNetworkPolicies that match those selectors.
+func (c *FakeHostNetworkPolicies) List(ctx context.Context, opts v1.ListOptions) (result *hostnetworkpolicyv1.HostNetworkPolicyList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootListAction(hostnetworkpoliciesResource, hostnetworkpoliciesKind, opts), &hostnetworkpolicyv1.HostNetworkPolicyList{})
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &hostnetworkpolicyv1.HostNetworkPolicyList{ListMeta: obj.(*hostnetworkpolicyv1.HostNetworkPolicyList).ListMeta}
+	for _, item := range obj.(*hostnetworkpolicyv1.HostNetworkPolicyList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested hostNetworkPolicies.
+func (c *FakeHostNetworkPolicies) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewRootWatchAction(hostnetworkpoliciesResource, opts))
+}
+
+// Create takes the representation of a hostNetworkPolicy and creates it.  Returns the server's representation of the hostNetworkPolicy, and an error, if there is any.
+func (c *FakeHostNetworkPolicies) Create(ctx context.Context, hostNetworkPolicy *hostnetworkpolicyv1.HostNetworkPolicy, opts v1.CreateOptions) (result *hostnetworkpolicyv1.HostNetworkPolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootCreateAction(hostnetworkpoliciesResource, hostNetworkPolicy), &hostnetworkpolicyv1.HostNetworkPolicy{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*hostnetworkpolicyv1.HostNetworkPolicy), err
+}
+
+// Update takes the representation of a hostNetworkPolicy and updates it. Returns the server's representation of the hostNetworkPolicy, and an error, if there is any.
+func (c *FakeHostNetworkPolicies) Update(ctx context.Context, hostNetworkPolicy *hostnetworkpolicyv1.HostNetworkPolicy, opts v1.UpdateOptions) (result *hostnetworkpolicyv1.HostNetworkPolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateAction(hostnetworkpoliciesResource, hostNetworkPolicy), &hostnetworkpolicyv1.HostNetworkPolicy{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*hostnetworkpolicyv1.HostNetworkPolicy), err
+}
+
+// Delete takes name of the hostNetworkPolicy and deletes it. Returns an error if one occurs.
+func (c *FakeHostNetworkPolicies) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewRootDeleteAction(hostnetworkpoliciesResource, name), &hostnetworkpolicyv1.HostNetworkPolicy{})
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeHostNetworkPolicies) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewRootDeleteCollectionAction(hostnetworkpoliciesResource, listOpts)
+
+	_, err := c.Fake.Invokes(action, &hostnetworkpolicyv1.HostNetworkPolicyList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched hostNetworkPolicy.
+func (c *FakeHostNetworkPolicies) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *hostnetworkpolicyv1.HostNetworkPolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootPatchSubresourceAction(hostnetworkpoliciesResource, name, pt, data, subresources...), &hostnetworkpolicyv1.HostNetworkPolicy{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*hostnetworkpolicyv1.HostNetworkPolicy), err
+}
@@ -0,0 +1,168 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	"context"
+	"time"
+
+	v1 "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/hostnetworkpolicy/v1"
+	scheme "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/hostnetworkpolicy/v1/apis/clientset/versioned/scheme"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// HostNetworkPoliciesGetter has a method to return a HostNetworkPolicyInterface.
+// A group's client should implement this interface.
+type HostNetworkPoliciesGetter interface {
+	HostNetworkPolicies() HostNetworkPolicyInterface
+}
+
+// HostNetworkPolicyInterface has methods to work with HostNetworkPolicy resources.
+type HostNetworkPolicyInterface interface {
+	Create(ctx context.Context, hostNetworkPolicy *v1.HostNetworkPolicy, opts metav1.CreateOptions) (*v1.HostNetworkPolicy, error)
+	Update(ctx context.Context, hostNetworkPolicy *v1.HostNetworkPolicy, opts metav1.UpdateOptions) (*v1.HostNetworkPolicy, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.HostNetworkPolicy, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1.HostNetworkPolicyList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *v1.HostNetworkPolicy, err error)
+	HostNetworkPolicyExpansion
+}
+
+// hostNetworkPolicies implements HostNetworkPolicyInterface
+type hostNetworkPolicies struct {
+	client rest.Interface
+}
+
+// newHostNetworkPolicies returns a HostNetworkPolicies
+func newHostNetworkPolicies(c *K8sV1Client) *hostNetworkPolicies {
+	return &hostNetworkPolicies{
+		client: c.RESTClient(),
+	}
+}
+
+// Get takes name of the hostNetworkPolicy, and returns the corresponding hostNetworkPolicy object, and an error if there is any.
+func (c *hostNetworkPolicies) Get(ctx context.Context, name string, options metav1.GetOptions) (result *v1.HostNetworkPolicy, err error) {
+	result = &v1.HostNetworkPolicy{}
+	err = c.client.Get().
+		Resource("hostnetworkpolicies").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of HostNetworkPolicies that match those selectors.
+func (c *hostNetworkPolicies) List(ctx context.Context, opts metav1.ListOptions) (result *v1.HostNetworkPolicyList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1.HostNetworkPolicyList{}
+	err = c.client.Get().
+		Resource("hostnetworkpolicies").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested hostNetworkPolicies.
+func (c *hostNetworkPolicies) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Resource("hostnetworkpolicies").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a hostNetworkPolicy and creates it.  Returns the server's representation of the hostNetworkPolicy, and an error, if there is any.
+func (c *hostNetworkPolicies) Create(ctx context.Context, hostNetworkPolicy *v1.HostNetworkPolicy, opts metav1.CreateOptions) (result *v1.HostNetworkPolicy, err error) {
+	result = &v1.HostNetworkPolicy{}
+	err = c.client.Post().
+		Resource("hostnetworkpolicies").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(hostNetworkPolicy).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a hostNetworkPolicy and updates it. Returns the server's representation of the hostNetworkPolicy, and an error, if there is any.
+func (c *hostNetworkPolicies) Update(ctx context.Context, hostNetworkPolicy *v1.HostNetworkPolicy, opts metav1.UpdateOptions) (result *v1.HostNetworkPolicy, err error) {
+	result = &v1.HostNetworkPolicy{}
+	err = c.client.Put().
+		Resource("hostnetworkpolicies").
+		Name(hostNetworkPolicy.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(hostNetworkPolicy).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the hostNetworkPolicy and deletes it. Returns an error if one occurs.
+func (c *hostNetworkPolicies) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return c.client.Delete().
+		Resource("hostnetworkpolicies").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *hostNetworkPolicies) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Resource("hostnetworkpolicies").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched hostNetworkPolicy.
+func (c *hostNetworkPolicies) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *v1.HostNetworkPolicy, err error) {
+	result = &v1.HostNetworkPolicy{}
+	err = c.client.Patch(pt).
+		Resource("hostnetworkpolicies").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
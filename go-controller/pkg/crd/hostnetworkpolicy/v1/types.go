@@ -0,0 +1,76 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +resource:path=hostnetworkpolicy
+// +kubebuilder:resource:shortName=hnp,scope=Cluster
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// HostNetworkPolicy is a CRD restricting the sources of the traffic to some
+// ports of the nodes it selects, such as NodePorts or the ports of host network
+// services. Traffic from outside the node to a port listed in the ingress rules
+// of the policies selecting the node is dropped, unless its source is allowed
+// by one of the rules listing the port. Traffic to the other ports of the node
+// is not affected.
+type HostNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Specification of the desired behavior of HostNetworkPolicy.
+	Spec HostNetworkPolicySpec `json:"spec"`
+}
+
+// HostNetworkPolicySpec is a desired state description of HostNetworkPolicy.
+type HostNetworkPolicySpec struct {
+	// NodeSelector applies the policy only to the nodes whose label matches
+	// this definition. An empty selector selects all the nodes. This field is
+	// mandatory.
+	NodeSelector metav1.LabelSelector `json:"nodeSelector"`
+	// Ingress is the list of ingress rules of the policy.
+	Ingress []HostNetworkPolicyIngressRule `json:"ingress"`
+}
+
+// HostNetworkPolicyIngressRule allows the traffic from a list of sources to a
+// list of ports
+type HostNetworkPolicyIngressRule struct {
+	// ports specify what ports and protocols the rule applies to. This field
+	// is mandatory.
+	Ports []HostNetworkPolicyPort `json:"ports"`
+	// from is the list of sources allowed to reach the ports. A rule without
+	// sources allows no traffic to its ports.
+	// +optional
+	From []HostNetworkPolicyPeer `json:"from,omitempty"`
+}
+
+// HostNetworkPolicyPort specifies a port of the node
+type HostNetworkPolicyPort struct {
+	// protocol (TCP, UDP, SCTP) that the traffic must match.
+	// +kubebuilder:validation:Pattern=^(TCP|UDP|SCTP)$
+	Protocol string `json:"protocol"`
+	// port that the traffic must match
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=65535
+	Port int32 `json:"port"`
+}
+
+// HostNetworkPolicyPeer is a source of traffic allowed by a rule
+type HostNetworkPolicyPeer struct {
+	// cidrSelector is the CIDR range of the sources, IPv4 or IPv6.
+	// +kubebuilder:validation:Format=cidr
+	CIDRSelector string `json:"cidrSelector"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +resource:path=hostnetworkpolicy
+// HostNetworkPolicyList is the list of HostNetworkPolicies.
+type HostNetworkPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	// List of HostNetworkPolicies.
+	Items []HostNetworkPolicy `json:"items"`
+}
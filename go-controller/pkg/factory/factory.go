@@ -17,7 +17,13 @@ import (
 	egressipscheme "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/egressip/v1/apis/clientset/versioned/scheme"
 	egressipinformerfactory "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/egressip/v1/apis/informers/externalversions"
 
+	hostnetworkpolicyapi "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/hostnetworkpolicy/v1"
+	hostnetworkpolicyscheme "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/hostnetworkpolicy/v1/apis/clientset/versioned/scheme"
+	hostnetworkpolicyinformerfactory "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/hostnetworkpolicy/v1/apis/informers/externalversions"
+	hostnetworkpolicylister "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/hostnetworkpolicy/v1/apis/listers/hostnetworkpolicy/v1"
+
 	kapi "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
 	knet "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
@@ -25,8 +31,10 @@ import (
 	"k8s.io/apimachinery/pkg/selection"
 	informerfactory "k8s.io/client-go/informers"
 	v1coreinformers "k8s.io/client-go/informers/core/v1"
+	discoveryinformers "k8s.io/client-go/informers/discovery/v1beta1"
 	"k8s.io/client-go/kubernetes"
 	listers "k8s.io/client-go/listers/core/v1"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1beta1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 )
@@ -40,6 +48,7 @@ type WatchFactory struct {
 	iFactory   informerfactory.SharedInformerFactory
 	eipFactory egressipinformerfactory.SharedInformerFactory
 	efFactory  egressfirewallinformerfactory.SharedInformerFactory
+	hnpFactory hostnetworkpolicyinformerfactory.SharedInformerFactory
 	informers  map[reflect.Type]*informer
 
 	stopChan chan struct{}
@@ -64,14 +73,16 @@ const (
 )
 
 var (
-	podType            reflect.Type = reflect.TypeOf(&kapi.Pod{})
-	serviceType        reflect.Type = reflect.TypeOf(&kapi.Service{})
-	endpointsType      reflect.Type = reflect.TypeOf(&kapi.Endpoints{})
-	policyType         reflect.Type = reflect.TypeOf(&knet.NetworkPolicy{})
-	namespaceType      reflect.Type = reflect.TypeOf(&kapi.Namespace{})
-	nodeType           reflect.Type = reflect.TypeOf(&kapi.Node{})
-	egressFirewallType reflect.Type = reflect.TypeOf(&egressfirewallapi.EgressFirewall{})
-	egressIPType       reflect.Type = reflect.TypeOf(&egressipapi.EgressIP{})
+	podType               reflect.Type = reflect.TypeOf(&kapi.Pod{})
+	serviceType           reflect.Type = reflect.TypeOf(&kapi.Service{})
+	endpointsType         reflect.Type = reflect.TypeOf(&kapi.Endpoints{})
+	endpointSliceType     reflect.Type = reflect.TypeOf(&discovery.EndpointSlice{})
+	policyType            reflect.Type = reflect.TypeOf(&knet.NetworkPolicy{})
+	namespaceType         reflect.Type = reflect.TypeOf(&kapi.Namespace{})
+	nodeType              reflect.Type = reflect.TypeOf(&kapi.Node{})
+	egressFirewallType    reflect.Type = reflect.TypeOf(&egressfirewallapi.EgressFirewall{})
+	egressIPType          reflect.Type = reflect.TypeOf(&egressipapi.EgressIP{})
+	hostNetworkPolicyType reflect.Type = reflect.TypeOf(&hostnetworkpolicyapi.HostNetworkPolicy{})
 )
 
 // NewMasterWatchFactory initializes a new watch factory for the master or master+node processes.
@@ -117,6 +128,17 @@ func NewMasterWatchFactory(ovnClientset *util.OVNClientset) (*WatchFactory, erro
 			noHeadlessServiceSelector())
 	})
 
+	// the node of a master+node process watches the EndpointSlices for the
+	// shared gateway
+	wf.iFactory.InformerFor(&discovery.EndpointSlice{}, func(c kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+		return discoveryinformers.NewFilteredEndpointSliceInformer(
+			c,
+			kapi.NamespaceAll,
+			resyncPeriod,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+			noHeadlessServiceSelector())
+	})
+
 	var err error
 
 	// Create our informer-wrapper informer (and underlying shared informer) for types we need
@@ -133,6 +155,10 @@ func NewMasterWatchFactory(ovnClientset *util.OVNClientset) (*WatchFactory, erro
 	if err != nil {
 		return nil, err
 	}
+	wf.informers[endpointSliceType], err = newInformer(endpointSliceType, wf.iFactory.Discovery().V1beta1().EndpointSlices().Informer())
+	if err != nil {
+		return nil, err
+	}
 	wf.informers[policyType], err = newInformer(policyType, wf.iFactory.Networking().V1().NetworkPolicies().Informer())
 	if err != nil {
 		return nil, err
@@ -160,6 +186,10 @@ func NewMasterWatchFactory(ovnClientset *util.OVNClientset) (*WatchFactory, erro
 			return nil, err
 		}
 	}
+	// the node of a master+node process uses this factory
+	if err := wf.addHostNetworkPolicyInformer(ovnClientset); err != nil {
+		return nil, err
+	}
 
 	return wf, nil
 }
@@ -188,6 +218,14 @@ func (wf *WatchFactory) Start() error {
 			}
 		}
 	}
+	if config.OVNKubernetesFeature.EnableHostFirewall && wf.hnpFactory != nil {
+		wf.hnpFactory.Start(wf.stopChan)
+		for oType, synced := range wf.hnpFactory.WaitForCacheSync(wf.stopChan) {
+			if !synced {
+				return fmt.Errorf("error in syncing cache for %v informer", oType)
+			}
+		}
+	}
 
 	return nil
 }
@@ -220,6 +258,15 @@ func NewNodeWatchFactory(ovnClientset *util.OVNClientset, nodeName string) (*Wat
 			noHeadlessServiceSelector())
 	})
 
+	wf.iFactory.InformerFor(&discovery.EndpointSlice{}, func(c kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+		return discoveryinformers.NewFilteredEndpointSliceInformer(
+			c,
+			kapi.NamespaceAll,
+			resyncPeriod,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+			noHeadlessServiceSelector())
+	})
+
 	// For Pods, only select pods scheduled to this node
 	wf.iFactory.InformerFor(&kapi.Pod{}, func(c kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
 		return v1coreinformers.NewFilteredPodInformer(
@@ -246,15 +293,39 @@ func NewNodeWatchFactory(ovnClientset *util.OVNClientset, nodeName string) (*Wat
 	if err != nil {
 		return nil, err
 	}
+	wf.informers[endpointSliceType], err = newInformer(endpointSliceType, wf.iFactory.Discovery().V1beta1().EndpointSlices().Informer())
+	if err != nil {
+		return nil, err
+	}
 
 	wf.informers[nodeType], err = newInformer(nodeType, wf.iFactory.Core().V1().Nodes().Informer())
 	if err != nil {
 		return nil, err
 	}
 
+	if err := wf.addHostNetworkPolicyInformer(ovnClientset); err != nil {
+		return nil, err
+	}
+
 	return wf, nil
 }
 
+// addHostNetworkPolicyInformer adds the HostNetworkPolicy informer when the
+// host firewall is enabled
+func (wf *WatchFactory) addHostNetworkPolicyInformer(ovnClientset *util.OVNClientset) error {
+	if !config.OVNKubernetesFeature.EnableHostFirewall {
+		return nil
+	}
+	if err := hostnetworkpolicyapi.AddToScheme(hostnetworkpolicyscheme.Scheme); err != nil {
+		return err
+	}
+	wf.hnpFactory = hostnetworkpolicyinformerfactory.NewSharedInformerFactory(ovnClientset.HostNetworkPolicyClient, resyncInterval)
+	var err error
+	wf.informers[hostNetworkPolicyType], err = newInformer(hostNetworkPolicyType,
+		wf.hnpFactory.K8s().V1().HostNetworkPolicies().Informer())
+	return err
+}
+
 func (wf *WatchFactory) Shutdown() {
 	close(wf.stopChan)
 
@@ -278,6 +349,10 @@ func getObjectMeta(objType reflect.Type, obj interface{}) (*metav1.ObjectMeta, e
 		if endpoints, ok := obj.(*kapi.Endpoints); ok {
 			return &endpoints.ObjectMeta, nil
 		}
+	case endpointSliceType:
+		if endpointSlice, ok := obj.(*discovery.EndpointSlice); ok {
+			return &endpointSlice.ObjectMeta, nil
+		}
 	case policyType:
 		if policy, ok := obj.(*knet.NetworkPolicy); ok {
 			return &policy.ObjectMeta, nil
@@ -298,6 +373,10 @@ func getObjectMeta(objType reflect.Type, obj interface{}) (*metav1.ObjectMeta, e
 		if egressIP, ok := obj.(*egressipapi.EgressIP); ok {
 			return &egressIP.ObjectMeta, nil
 		}
+	case hostNetworkPolicyType:
+		if hostNetworkPolicy, ok := obj.(*hostnetworkpolicyapi.HostNetworkPolicy); ok {
+			return &hostNetworkPolicy.ObjectMeta, nil
+		}
 	}
 	return nil, fmt.Errorf("cannot get ObjectMeta from type %v", objType)
 }
@@ -397,6 +476,16 @@ func (wf *WatchFactory) RemoveEndpointsHandler(handler *Handler) {
 	wf.removeHandler(endpointsType, handler)
 }
 
+// AddEndpointSliceHandler adds a handler function that will be executed on EndpointSlice object changes
+func (wf *WatchFactory) AddEndpointSliceHandler(handlerFuncs cache.ResourceEventHandler, processExisting func([]interface{})) *Handler {
+	return wf.addHandler(endpointSliceType, "", nil, handlerFuncs, processExisting)
+}
+
+// RemoveEndpointSliceHandler removes a EndpointSlice object event handler function
+func (wf *WatchFactory) RemoveEndpointSliceHandler(handler *Handler) {
+	wf.removeHandler(endpointSliceType, handler)
+}
+
 // AddPolicyHandler adds a handler function that will be executed on NetworkPolicy object changes
 func (wf *WatchFactory) AddPolicyHandler(handlerFuncs cache.ResourceEventHandler, processExisting func([]interface{})) *Handler {
 	return wf.addHandler(policyType, "", nil, handlerFuncs, processExisting)
@@ -427,6 +516,16 @@ func (wf *WatchFactory) RemoveEgressIPHandler(handler *Handler) {
 	wf.removeHandler(egressIPType, handler)
 }
 
+// AddHostNetworkPolicyHandler adds a handler function that will be executed on HostNetworkPolicy object changes
+func (wf *WatchFactory) AddHostNetworkPolicyHandler(handlerFuncs cache.ResourceEventHandler, processExisting func([]interface{})) *Handler {
+	return wf.addHandler(hostNetworkPolicyType, "", nil, handlerFuncs, processExisting)
+}
+
+// RemoveHostNetworkPolicyHandler removes a HostNetworkPolicy object event handler function
+func (wf *WatchFactory) RemoveHostNetworkPolicyHandler(handler *Handler) {
+	wf.removeHandler(hostNetworkPolicyType, handler)
+}
+
 // AddNamespaceHandler adds a handler function that will be executed on Namespace object changes
 func (wf *WatchFactory) AddNamespaceHandler(handlerFuncs cache.ResourceEventHandler, processExisting func([]interface{})) *Handler {
 	return wf.addHandler(namespaceType, "", nil, handlerFuncs, processExisting)
@@ -487,6 +586,12 @@ func (wf *WatchFactory) GetNodes() ([]*kapi.Node, error) {
 	return nodeLister.List(labels.Everything())
 }
 
+// GetHostNetworkPolicies returns all the HostNetworkPolicies in the cluster
+func (wf *WatchFactory) GetHostNetworkPolicies() ([]*hostnetworkpolicyapi.HostNetworkPolicy, error) {
+	hostNetworkPolicyLister := wf.informers[hostNetworkPolicyType].lister.(hostnetworkpolicylister.HostNetworkPolicyLister)
+	return hostNetworkPolicyLister.List(labels.Everything())
+}
+
 // GetNode returns the node spec of a given node by name
 func (wf *WatchFactory) GetNode(name string) (*kapi.Node, error) {
 	nodeLister := wf.informers[nodeType].lister.(listers.NodeLister)
@@ -511,6 +616,12 @@ func (wf *WatchFactory) GetEndpoint(namespace, name string) (*kapi.Endpoints, er
 	return endpointsLister.Endpoints(namespace).Get(name)
 }
 
+// GetEndpointSlices returns the EndpointSlices of a service in a given namespace
+func (wf *WatchFactory) GetEndpointSlices(namespace, svcName string) ([]*discovery.EndpointSlice, error) {
+	endpointSliceLister := wf.informers[endpointSliceType].lister.(discoverylisters.EndpointSliceLister)
+	return endpointSliceLister.EndpointSlices(namespace).List(labels.Set{discovery.LabelServiceName: svcName}.AsSelector())
+}
+
 // GetNamespace returns a specific namespace
 func (wf *WatchFactory) GetNamespace(name string) (*kapi.Namespace, error) {
 	namespaceLister := wf.informers[namespaceType].lister.(listers.NamespaceLister)
@@ -552,7 +663,7 @@ func (wf *WatchFactory) ServiceInformer() cache.SharedIndexInformer {
 }
 
 // noHeadlessServiceSelector is a LabelSelector added to the watch for
-// Endpoints (and, eventually, EndpointSlices) that excludes endpoints
+// Endpoints and EndpointSlices that excludes endpoints
 // for headless services.
 // This matches the behavior of kube-proxy
 func noHeadlessServiceSelector() func(options *metav1.ListOptions) {
@@ -25,6 +25,8 @@ import (
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
 	egressip "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/egressip/v1"
 	egressipfake "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/egressip/v1/apis/clientset/versioned/fake"
+	hostnetworkpolicy "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/hostnetworkpolicy/v1"
+	hostnetworkpolicyfake "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/hostnetworkpolicy/v1/apis/clientset/versioned/fake"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -135,6 +137,19 @@ func newEgressIP(name, namespace string) *egressip.EgressIP {
 
 }
 
+func newHostNetworkPolicy(name string) *hostnetworkpolicy.HostNetworkPolicy {
+	return &hostnetworkpolicy.HostNetworkPolicy{
+		ObjectMeta: newObjectMeta(name, ""),
+		Spec: hostnetworkpolicy.HostNetworkPolicySpec{
+			Ingress: []hostnetworkpolicy.HostNetworkPolicyIngressRule{
+				{
+					Ports: []hostnetworkpolicy.HostNetworkPolicyPort{{Protocol: "TCP", Port: 30080}},
+				},
+			},
+		},
+	}
+}
+
 func objSetup(c *fake.Clientset, objType string, listFn func(core.Action) (bool, runtime.Object, error)) *watch.FakeWatcher {
 	w := watch.NewFake()
 	c.AddWatchReactor(objType, core.DefaultWatchReactor(w, nil))
@@ -149,6 +164,13 @@ func egressFirewallObjSetup(c *egressfirewallfake.Clientset, objType string, lis
 	return w
 }
 
+func hostNetworkPolicyObjSetup(c *hostnetworkpolicyfake.Clientset, objType string, listFn func(core.Action) (bool, runtime.Object, error)) *watch.FakeWatcher {
+	w := watch.NewFake()
+	c.AddWatchReactor(objType, core.DefaultWatchReactor(w, nil))
+	c.AddReactor("list", objType, listFn)
+	return w
+}
+
 func egressIPObjSetup(c *egressipfake.Clientset, objType string, listFn func(core.Action) (bool, runtime.Object, error)) *watch.FakeWatcher {
 	w := watch.NewFake()
 	c.AddWatchReactor(objType, core.DefaultWatchReactor(w, nil))
@@ -180,10 +202,12 @@ var _ = Describe("Watch Factory Operations", func() {
 		fakeClient                                *fake.Clientset
 		egressIPFakeClient                        *egressipfake.Clientset
 		egressFirewallFakeClient                  *egressfirewallfake.Clientset
+		hostNetworkPolicyFakeClient               *hostnetworkpolicyfake.Clientset
 		podWatch, namespaceWatch, nodeWatch       *watch.FakeWatcher
 		policyWatch, endpointsWatch, serviceWatch *watch.FakeWatcher
 		egressFirewallWatch                       *watch.FakeWatcher
 		egressIPWatch                             *watch.FakeWatcher
+		hostNetworkPolicyWatch                    *watch.FakeWatcher
 		pods                                      []*v1.Pod
 		namespaces                                []*v1.Namespace
 		nodes                                     []*v1.Node
@@ -191,6 +215,7 @@ var _ = Describe("Watch Factory Operations", func() {
 		endpoints                                 []*v1.Endpoints
 		services                                  []*v1.Service
 		egressIPs                                 []*egressip.EgressIP
+		hostNetworkPolicies                       []*hostnetworkpolicy.HostNetworkPolicy
 		wf                                        *WatchFactory
 		egressFirewalls                           []*egressfirewall.EgressFirewall
 		err                                       error
@@ -202,15 +227,18 @@ var _ = Describe("Watch Factory Operations", func() {
 		config.PrepareTestConfig()
 		config.OVNKubernetesFeature.EnableEgressIP = true
 		config.OVNKubernetesFeature.EnableEgressFirewall = true
+		config.OVNKubernetesFeature.EnableHostFirewall = true
 
 		fakeClient = &fake.Clientset{}
 		egressFirewallFakeClient = &egressfirewallfake.Clientset{}
 		egressIPFakeClient = &egressipfake.Clientset{}
+		hostNetworkPolicyFakeClient = &hostnetworkpolicyfake.Clientset{}
 
 		ovnClientset = &util.OVNClientset{
-			KubeClient:           fakeClient,
-			EgressIPClient:       egressIPFakeClient,
-			EgressFirewallClient: egressFirewallFakeClient,
+			KubeClient:              fakeClient,
+			EgressIPClient:          egressIPFakeClient,
+			EgressFirewallClient:    egressFirewallFakeClient,
+			HostNetworkPolicyClient: hostNetworkPolicyFakeClient,
 		}
 
 		pods = make([]*v1.Pod, 0)
@@ -284,6 +312,15 @@ var _ = Describe("Watch Factory Operations", func() {
 			}
 			return true, obj, nil
 		})
+
+		hostNetworkPolicies = make([]*hostnetworkpolicy.HostNetworkPolicy, 0)
+		hostNetworkPolicyWatch = hostNetworkPolicyObjSetup(hostNetworkPolicyFakeClient, "hostnetworkpolicies", func(core.Action) (bool, runtime.Object, error) {
+			obj := &hostnetworkpolicy.HostNetworkPolicyList{}
+			for _, p := range hostNetworkPolicies {
+				obj.Items = append(obj.Items, *p)
+			}
+			return true, obj, nil
+		})
 	})
 
 	AfterEach(func() {
@@ -344,6 +381,10 @@ var _ = Describe("Watch Factory Operations", func() {
 			egressIPs = append(egressIPs, newEgressIP("myEgressIP", "default"))
 			testExisting(egressIPType, "", nil)
 		})
+		It("is called for each existing hostNetworkPolicy", func() {
+			hostNetworkPolicies = append(hostNetworkPolicies, newHostNetworkPolicy("myHostNetworkPolicy"))
+			testExisting(hostNetworkPolicyType, "", nil)
+		})
 
 		It("is called for each existing pod that matches a given namespace and label", func() {
 			pod := newPod("pod1", "default")
@@ -440,6 +481,16 @@ var _ = Describe("Watch Factory Operations", func() {
 			testExisting(egressIPType)
 		})
 	})
+	Context("when HostNetworkPolicy is disabled", func() {
+		It("does not contain HostNetworkPolicy informer", func() {
+			config.OVNKubernetesFeature.EnableHostFirewall = false
+			wf, err = NewMasterWatchFactory(ovnClientset)
+			Expect(err).NotTo(HaveOccurred())
+			err = wf.Start()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(wf.informers).NotTo(HaveKey(hostNetworkPolicyType))
+		})
+	})
 	Context("when EgressFirewall is disabled", func() {
 		testExisting := func(objType reflect.Type) {
 			wf, err = NewMasterWatchFactory(ovnClientset)
@@ -1102,6 +1153,44 @@ var _ = Describe("Watch Factory Operations", func() {
 
 		wf.RemoveEgressIPHandler(h)
 	})
+	It("responds to hostNetworkPolicy add/update/delete events", func() {
+		wf, err = NewMasterWatchFactory(ovnClientset)
+		Expect(err).NotTo(HaveOccurred())
+		err = wf.Start()
+		Expect(err).NotTo(HaveOccurred())
+
+		added := newHostNetworkPolicy("myHostNetworkPolicy")
+		h, c := addHandler(wf, hostNetworkPolicyType, cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				hostNetworkPolicy := obj.(*hostnetworkpolicy.HostNetworkPolicy)
+				Expect(reflect.DeepEqual(hostNetworkPolicy, added)).To(BeTrue())
+			},
+			UpdateFunc: func(old, new interface{}) {
+				newHostNetworkPolicy := new.(*hostnetworkpolicy.HostNetworkPolicy)
+				Expect(reflect.DeepEqual(newHostNetworkPolicy, added)).To(BeTrue())
+				Expect(newHostNetworkPolicy.Spec.Ingress[0].Ports[0].Port).To(Equal(int32(30443)))
+			},
+			DeleteFunc: func(obj interface{}) {
+				hostNetworkPolicy := obj.(*hostnetworkpolicy.HostNetworkPolicy)
+				Expect(reflect.DeepEqual(hostNetworkPolicy, added)).To(BeTrue())
+			},
+		})
+
+		hostNetworkPolicies = append(hostNetworkPolicies, added)
+		hostNetworkPolicyWatch.Add(added)
+		Eventually(c.getAdded, 2).Should(Equal(1))
+		policies, err := wf.GetHostNetworkPolicies()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(policies).To(HaveLen(1))
+		added.Spec.Ingress[0].Ports[0].Port = 30443
+		hostNetworkPolicyWatch.Modify(added)
+		Eventually(c.getUpdated, 2).Should(Equal(1))
+		hostNetworkPolicies = hostNetworkPolicies[:0]
+		hostNetworkPolicyWatch.Delete(added)
+		Eventually(c.getDeleted, 2).Should(Equal(1))
+
+		wf.RemoveHostNetworkPolicyHandler(h)
+	})
 	It("stops processing events after the handler is removed", func() {
 		wf, err = NewMasterWatchFactory(ovnClientset)
 		Expect(err).NotTo(HaveOccurred())
@@ -14,8 +14,11 @@ import (
 
 	egressiplister "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/egressip/v1/apis/listers/egressip/v1"
 
+	hostnetworkpolicylister "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/hostnetworkpolicy/v1/apis/listers/hostnetworkpolicy/v1"
+
 	ktypes "k8s.io/apimachinery/pkg/types"
 	listers "k8s.io/client-go/listers/core/v1"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1beta1"
 	netlisters "k8s.io/client-go/listers/networking/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
@@ -366,6 +369,8 @@ func newInformerLister(oType reflect.Type, sharedInformer cache.SharedIndexInfor
 		return listers.NewServiceLister(sharedInformer.GetIndexer()), nil
 	case endpointsType:
 		return listers.NewEndpointsLister(sharedInformer.GetIndexer()), nil
+	case endpointSliceType:
+		return discoverylisters.NewEndpointSliceLister(sharedInformer.GetIndexer()), nil
 	case namespaceType:
 		return listers.NewNamespaceLister(sharedInformer.GetIndexer()), nil
 	case nodeType:
@@ -376,6 +381,8 @@ func newInformerLister(oType reflect.Type, sharedInformer cache.SharedIndexInfor
 		return egressfirewalllister.NewEgressFirewallLister(sharedInformer.GetIndexer()), nil
 	case egressIPType:
 		return egressiplister.NewEgressIPLister(sharedInformer.GetIndexer()), nil
+	case hostNetworkPolicyType:
+		return hostnetworkpolicylister.NewHostNetworkPolicyLister(sharedInformer.GetIndexer()), nil
 	}
 
 	return nil, fmt.Errorf("cannot create lister from type %v", oType)
@@ -1,7 +1,10 @@
 package factory
 
 import (
+	hostnetworkpolicyapi "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/hostnetworkpolicy/v1"
+
 	kapi "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/cache"
 )
@@ -39,9 +42,18 @@ type NodeWatchFactory interface {
 	AddFilteredEndpointsHandler(namespace string, sel labels.Selector, handlerFuncs cache.ResourceEventHandler, processExisting func([]interface{})) *Handler
 	RemoveEndpointsHandler(handler *Handler)
 
+	AddEndpointSliceHandler(handlerFuncs cache.ResourceEventHandler, processExisting func([]interface{})) *Handler
+	RemoveEndpointSliceHandler(handler *Handler)
+
 	AddPodHandler(handlerFuncs cache.ResourceEventHandler, processExisting func([]interface{})) *Handler
 	RemovePodHandler(handler *Handler)
 
+	AddNodeHandler(handlerFuncs cache.ResourceEventHandler, processExisting func([]interface{})) *Handler
+	RemoveNodeHandler(handler *Handler)
+
+	AddHostNetworkPolicyHandler(handlerFuncs cache.ResourceEventHandler, processExisting func([]interface{})) *Handler
+	RemoveHostNetworkPolicyHandler(handler *Handler)
+
 	NodeInformer() cache.SharedIndexInformer
 	LocalPodInformer() cache.SharedIndexInformer
 
@@ -49,6 +61,8 @@ type NodeWatchFactory interface {
 
 	GetService(namespace, name string) (*kapi.Service, error)
 	GetEndpoint(namespace, name string) (*kapi.Endpoints, error)
+	GetEndpointSlices(namespace, svcName string) ([]*discovery.EndpointSlice, error)
+	GetHostNetworkPolicies() ([]*hostnetworkpolicyapi.HostNetworkPolicy, error)
 }
 
 type Shutdownable interface {
@@ -89,16 +89,20 @@ func NewSBClientWithConfig(cfg config.OvnAuthConfig, stopCh <-chan struct{}) (cl
 	}()
 
 	// Only Monitor Required SBDB tables to reduce memory overhead
-	_, err = c.Monitor(ctx,
-		c.NewMonitor(
-			// used by unidling controller
-			client.WithTable(&sbdb.ControllerEvent{}),
-			// used for gateway
-			client.WithTable(&sbdb.MACBinding{}),
-			// used by libovsdbops
-			client.WithTable(&sbdb.Chassis{}),
-		),
-	)
+	monitorOptions := []client.MonitorOption{
+		// used by unidling controller
+		client.WithTable(&sbdb.ControllerEvent{}),
+		// used for gateway
+		client.WithTable(&sbdb.MACBinding{}),
+		// used by libovsdbops
+		client.WithTable(&sbdb.Chassis{}),
+	}
+	if config.EnableMulticast {
+		// used for multicast group membership, the datapath and port
+		// bindings of the group members are selected on demand
+		monitorOptions = append(monitorOptions, client.WithTable(&sbdb.IGMPGroup{}))
+	}
+	_, err = c.Monitor(ctx, c.NewMonitor(monitorOptions...))
 	if err != nil {
 		c.Close()
 		return nil, err
@@ -191,6 +191,26 @@ var metricEgressFirewallCount = prometheus.NewGauge(prometheus.GaugeOpts{
 	Help:      "The number of egress firewall policies",
 })
 
+// metricMulticastGroupMembers is the number of pods of a namespace that joined
+// a multicast group, as learnt by IGMP/MLD snooping
+var metricMulticastGroupMembers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: MetricOvnkubeNamespace,
+	Subsystem: MetricOvnkubeSubsystemMaster,
+	Name:      "multicast_group_members",
+	Help:      "The number of pods of a namespace that joined a multicast group, as learnt by IGMP/MLD snooping"},
+	[]string{
+		"namespace",
+		"group",
+	},
+)
+
+var metricMulticastGroupCount = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: MetricOvnkubeNamespace,
+	Subsystem: MetricOvnkubeSubsystemMaster,
+	Name:      "num_multicast_groups",
+	Help:      "The number of multicast groups joined by at least one port, as learnt by IGMP/MLD snooping",
+})
+
 var registerMasterMetricsOnce sync.Once
 var startMasterMetricUpdaterOnce sync.Once
 
@@ -269,6 +289,8 @@ func RegisterMasterMetrics(nbClient, sbClient goovn.Client) {
 		prometheus.MustRegister(metricEgressFirewallRuleCount)
 		prometheus.MustRegister(metricIPsecEnabled)
 		prometheus.MustRegister(metricEgressFirewallCount)
+		prometheus.MustRegister(metricMulticastGroupMembers)
+		prometheus.MustRegister(metricMulticastGroupCount)
 		registerWorkqueueMetrics(MetricOvnkubeNamespace, MetricOvnkubeSubsystemMaster)
 	})
 }
@@ -332,6 +354,18 @@ func RecordEgressIPCount(count float64) {
 	metricEgressIPCount.Set(count)
 }
 
+// RecordMulticastGroupMembers records the number of pods that joined each
+// multicast group, per namespace, along with the number of groups
+func RecordMulticastGroupMembers(members map[string]map[string]int, groupCount int) {
+	metricMulticastGroupMembers.Reset()
+	for namespace, groups := range members {
+		for group, count := range groups {
+			metricMulticastGroupMembers.WithLabelValues(namespace, group).Set(float64(count))
+		}
+	}
+	metricMulticastGroupCount.Set(float64(groupCount))
+}
+
 // UpdateEgressFirewallRuleCount records the number of Egress firewall rules.
 func UpdateEgressFirewallRuleCount(count float64) {
 	metricEgressFirewallRuleCount.Add(count)
@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
@@ -364,12 +365,43 @@ func checkPodRunsOnGivenNode(clientset kubernetes.Interface, label, k8sNodeName
 	return false, fmt.Errorf("the Pod matching the label %q doesn't exist on this node %s", label, k8sNodeName)
 }
 
+// debugHandlerPrefix is the path under which the handlers registered with
+// RegisterDebugHandler are served by the metrics server
+const debugHandlerPrefix = "/debug/ovnkube/"
+
+var debugHandlers = struct {
+	sync.RWMutex
+	handlers map[string]http.Handler
+}{handlers: map[string]http.Handler{}}
+
+// RegisterDebugHandler registers a read-only troubleshooting handler that the
+// metrics server serves at /debug/ovnkube/<name>, along with the pprof
+// handlers, when it is started with them. Handlers may be registered before or
+// after the metrics server is started.
+func RegisterDebugHandler(name string, handler http.Handler) {
+	debugHandlers.Lock()
+	defer debugHandlers.Unlock()
+	debugHandlers.handlers[name] = handler
+}
+
+func serveDebugHandler(w http.ResponseWriter, r *http.Request) {
+	debugHandlers.RLock()
+	handler, ok := debugHandlers.handlers[strings.TrimPrefix(r.URL.Path, debugHandlerPrefix)]
+	debugHandlers.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
 // StartMetricsServer runs the prometheus listener so that OVN K8s metrics can be collected
 func StartMetricsServer(bindAddress string, enablePprof bool) {
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
 
 	if enablePprof {
+		mux.HandleFunc(debugHandlerPrefix, serveDebugHandler)
 		mux.HandleFunc("/debug/pprof/", pprof.Index)
 		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
 		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
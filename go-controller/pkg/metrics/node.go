@@ -2,7 +2,9 @@ package metrics
 
 import (
 	"runtime"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
 	"github.com/prometheus/client_golang/prometheus"
@@ -34,6 +36,23 @@ var metricOvnNodePortEnabled = prometheus.NewGauge(prometheus.GaugeOpts{
 	Help:      "Specifies if the node port is enabled on this node(1) or not(0).",
 })
 
+var metricOpenFlowSyncDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: MetricOvnkubeNamespace,
+	Subsystem: MetricOvnkubeSubsystemNode,
+	Name:      "openflow_sync_duration_seconds",
+	Help:      "The duration of the syncs of the OpenFlow flows of the gateway bridges, full or incremental.",
+	Buckets:   prometheus.ExponentialBuckets(.001, 2, 15)},
+	//labels
+	[]string{"type", "err"},
+)
+
+var metricOpenFlowFlowMods = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: MetricOvnkubeNamespace,
+	Subsystem: MetricOvnkubeSubsystemNode,
+	Name:      "openflow_flow_mods_total",
+	Help:      "The number of flows added or deleted by incremental syncs of the gateway bridges.",
+})
+
 var registerNodeMetricsOnce sync.Once
 
 func RegisterNodeMetrics() {
@@ -42,6 +61,8 @@ func RegisterNodeMetrics() {
 		prometheus.MustRegister(MetricCNIRequestDuration)
 		prometheus.MustRegister(MetricNodeReadyDuration)
 		prometheus.MustRegister(metricOvnNodePortEnabled)
+		prometheus.MustRegister(metricOpenFlowSyncDuration)
+		prometheus.MustRegister(metricOpenFlowFlowMods)
 		prometheus.MustRegister(prometheus.NewGaugeFunc(
 			prometheus.GaugeOpts{
 				Namespace: MetricOvnkubeNamespace,
@@ -63,3 +84,12 @@ func RegisterNodeMetrics() {
 		registerWorkqueueMetrics(MetricOvnkubeNamespace, MetricOvnkubeSubsystemNode)
 	})
 }
+
+// RecordOpenFlowSync records the duration of a full or incremental sync of the
+// flows of a gateway bridge, and the number of flows it modified
+func RecordOpenFlowSync(syncType string, duration time.Duration, flowMods int, err error) {
+	metricOpenFlowSyncDuration.WithLabelValues(syncType, strconv.FormatBool(err != nil)).Observe(duration.Seconds())
+	if err == nil {
+		metricOpenFlowFlowMods.Add(float64(flowMods))
+	}
+}
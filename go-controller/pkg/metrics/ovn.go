@@ -358,20 +358,12 @@ func RegisterOvnControllerMetrics() {
 			Name:      "integration_bridge_openflow_total",
 			Help:      "The total number of OpenFlow flows in the integration bridge.",
 		}, func() float64 {
-			stdout, stderr, err := util.RunOVSOfctl("-t", "5", "dump-aggregate", "br-int")
+			flowCount, err := util.GetOFFlowCount("br-int")
 			if err != nil {
-				klog.Errorf("Failed to get flow count for br-int, stderr(%s): (%v)",
-					stderr, err)
+				klog.Errorf("%v", err)
 				return 0
 			}
-			for _, kvPair := range strings.Fields(stdout) {
-				if strings.HasPrefix(kvPair, "flow_count=") {
-					value := strings.Split(kvPair, "=")[1]
-					return parseMetricToFloat(MetricOvnSubsystemController, "integration_bridge_openflow_total",
-						value)
-				}
-			}
-			return 0
+			return float64(flowCount)
 		}))
 	ovnRegistry.MustRegister(prometheus.NewGaugeFunc(
 		prometheus.GaugeOpts{
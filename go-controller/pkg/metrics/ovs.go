@@ -428,22 +428,12 @@ func ovsDatapathMetricsUpdate() {
 // getOvsBridgeOpenFlowsCount returns the number of openflow flows
 // in an ovs-bridge
 func getOvsBridgeOpenFlowsCount(bridgeName string) float64 {
-	stdout, stderr, err := util.RunOVSOfctl("-t", "5", "dump-aggregate", bridgeName)
+	flowCount, err := util.GetOFFlowCount(bridgeName)
 	if err != nil {
-		klog.Errorf("Failed to get flow count for %s, stderr(%s): (%v)",
-			bridgeName, stderr, err)
+		klog.Errorf("%v", err)
 		return 0
 	}
-	for _, kvPair := range strings.Fields(stdout) {
-		if strings.HasPrefix(kvPair, "flow_count=") {
-			value := strings.Split(kvPair, "=")[1]
-			metricName := bridgeName + "flows_total"
-			return parseMetricToFloat(MetricOvsSubsystemVswitchd, metricName, value)
-		}
-	}
-	klog.Errorf("ovs-ofctl dump-aggregate %s output didn't contain "+
-		"flow_count field", bridgeName)
-	return 0
+	return float64(flowCount)
 }
 
 type interfaceDetails struct {
@@ -5,14 +5,15 @@ package nbdb
 
 // LogicalSwitch defines an object in Logical_Switch table
 type LogicalSwitch struct {
-	UUID             string            `ovsdb:"_uuid"`
-	ACLs             []string          `ovsdb:"acls"`
-	DNSRecords       []string          `ovsdb:"dns_records"`
-	ExternalIDs      map[string]string `ovsdb:"external_ids"`
-	ForwardingGroups []string          `ovsdb:"forwarding_groups"`
-	LoadBalancer     []string          `ovsdb:"load_balancer"`
-	Name             string            `ovsdb:"name"`
-	OtherConfig      map[string]string `ovsdb:"other_config"`
-	Ports            []string          `ovsdb:"ports"`
-	QOSRules         []string          `ovsdb:"qos_rules"`
+	UUID              string            `ovsdb:"_uuid"`
+	ACLs              []string          `ovsdb:"acls"`
+	DNSRecords        []string          `ovsdb:"dns_records"`
+	ExternalIDs       map[string]string `ovsdb:"external_ids"`
+	ForwardingGroups  []string          `ovsdb:"forwarding_groups"`
+	LoadBalancer      []string          `ovsdb:"load_balancer"`
+	LoadBalancerGroup []string          `ovsdb:"load_balancer_group"`
+	Name              string            `ovsdb:"name"`
+	OtherConfig       map[string]string `ovsdb:"other_config"`
+	Ports             []string          `ovsdb:"ports"`
+	QOSRules          []string          `ovsdb:"qos_rules"`
 }
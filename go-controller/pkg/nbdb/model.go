@@ -24,6 +24,7 @@ func FullDatabaseModel() (model.ClientDBModel, error) {
 		"HA_Chassis":                  &HAChassis{},
 		"HA_Chassis_Group":            &HAChassisGroup{},
 		"Load_Balancer":               &LoadBalancer{},
+		"Load_Balancer_Group":         &LoadBalancerGroup{},
 		"Load_Balancer_Health_Check":  &LoadBalancerHealthCheck{},
 		"Logical_Router":              &LogicalRouter{},
 		"Logical_Router_Policy":       &LogicalRouterPolicy{},
@@ -43,7 +44,7 @@ func FullDatabaseModel() (model.ClientDBModel, error) {
 
 var schema = `{
   "name": "OVN_Northbound",
-  "version": "5.32.0",
+  "version": "5.32.1",
   "tables": {
     "ACL": {
       "columns": {
@@ -648,6 +649,29 @@ var schema = `{
         }
       }
     },
+    "Load_Balancer_Group": {
+      "columns": {
+        "load_balancer": {
+          "type": {
+            "key": {
+              "type": "uuid",
+              "refTable": "Load_Balancer",
+              "refType": "weak"
+            },
+            "min": 0,
+            "max": "unlimited"
+          }
+        },
+        "name": {
+          "type": "string"
+        }
+      },
+      "indexes": [
+        [
+          "name"
+        ]
+      ]
+    },
     "Load_Balancer_Health_Check": {
       "columns": {
         "external_ids": {
@@ -713,6 +737,17 @@ var schema = `{
             "max": "unlimited"
           }
         },
+        "load_balancer_group": {
+          "type": {
+            "key": {
+              "type": "uuid",
+              "refTable": "Load_Balancer_Group",
+              "refType": "strong"
+            },
+            "min": 0,
+            "max": "unlimited"
+          }
+        },
         "name": {
           "type": "string"
         },
@@ -1084,6 +1119,17 @@ var schema = `{
             "max": "unlimited"
           }
         },
+        "load_balancer_group": {
+          "type": {
+            "key": {
+              "type": "uuid",
+              "refTable": "Load_Balancer_Group",
+              "refType": "strong"
+            },
+            "min": 0,
+            "max": "unlimited"
+          }
+        },
         "name": {
           "type": "string"
         },
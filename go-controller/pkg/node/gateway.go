@@ -13,6 +13,7 @@ import (
 	util "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
 	"github.com/pkg/errors"
 	kapi "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 )
@@ -39,10 +40,16 @@ type gateway struct {
 	nodePortWatcher informer.ServiceAndEndpointsEventHandler
 	// localPortWatcher is used in Local GW mode to handle iptables rules and routes for services
 	localPortWatcher informer.ServiceEventHandler
-	openflowManager  *openflowManager
-	nodeIPManager    *addressManager
-	initFunc         func() error
-	readyFunc        func() (bool, error)
+	// serviceIPAnnouncer is used in Shared GW mode to announce the external and load balancer IPs of services
+	serviceIPAnnouncer *serviceIPAnnouncer
+	// routeAdvertiser advertises the pod subnets and the service IPs of the node over BGP
+	routeAdvertiser *routeAdvertiser
+	// hostFirewall renders the HostNetworkPolicies selecting the node into host rules and bridge flows
+	hostFirewall    *hostFirewall
+	openflowManager *openflowManager
+	nodeIPManager   *addressManager
+	initFunc        func() error
+	readyFunc       func() (bool, error)
 }
 
 func (g *gateway) AddService(svc *kapi.Service) {
@@ -61,6 +68,12 @@ func (g *gateway) AddService(svc *kapi.Service) {
 	if g.localPortWatcher != nil {
 		g.localPortWatcher.AddService(svc)
 	}
+	if g.serviceIPAnnouncer != nil {
+		g.serviceIPAnnouncer.AddService(svc)
+	}
+	if g.routeAdvertiser != nil {
+		g.routeAdvertiser.AddService(svc)
+	}
 }
 
 func (g *gateway) UpdateService(old, new *kapi.Service) {
@@ -79,6 +92,12 @@ func (g *gateway) UpdateService(old, new *kapi.Service) {
 	if g.localPortWatcher != nil {
 		g.localPortWatcher.UpdateService(old, new)
 	}
+	if g.serviceIPAnnouncer != nil {
+		g.serviceIPAnnouncer.UpdateService(old, new)
+	}
+	if g.routeAdvertiser != nil {
+		g.routeAdvertiser.UpdateService(old, new)
+	}
 }
 
 func (g *gateway) DeleteService(svc *kapi.Service) {
@@ -97,6 +116,12 @@ func (g *gateway) DeleteService(svc *kapi.Service) {
 	if g.localPortWatcher != nil {
 		g.localPortWatcher.DeleteService(svc)
 	}
+	if g.serviceIPAnnouncer != nil {
+		g.serviceIPAnnouncer.DeleteService(svc)
+	}
+	if g.routeAdvertiser != nil {
+		g.routeAdvertiser.DeleteService(svc)
+	}
 }
 
 func (g *gateway) SyncServices(objs []interface{}) {
@@ -115,6 +140,12 @@ func (g *gateway) SyncServices(objs []interface{}) {
 	if g.localPortWatcher != nil {
 		g.localPortWatcher.SyncServices(objs)
 	}
+	if g.serviceIPAnnouncer != nil {
+		g.serviceIPAnnouncer.SyncServices(objs)
+	}
+	if g.routeAdvertiser != nil {
+		g.routeAdvertiser.SyncServices(objs)
+	}
 }
 
 func (g *gateway) AddEndpoints(ep *kapi.Endpoints) {
@@ -124,6 +155,12 @@ func (g *gateway) AddEndpoints(ep *kapi.Endpoints) {
 	if g.nodePortWatcher != nil {
 		g.nodePortWatcher.AddEndpoints(ep)
 	}
+	if g.serviceIPAnnouncer != nil {
+		g.serviceIPAnnouncer.AddEndpoints(ep)
+	}
+	if g.routeAdvertiser != nil {
+		g.routeAdvertiser.AddEndpoints(ep)
+	}
 }
 
 func (g *gateway) UpdateEndpoints(old, new *kapi.Endpoints) {
@@ -133,6 +170,12 @@ func (g *gateway) UpdateEndpoints(old, new *kapi.Endpoints) {
 	if g.nodePortWatcher != nil {
 		g.nodePortWatcher.UpdateEndpoints(old, new)
 	}
+	if g.serviceIPAnnouncer != nil {
+		g.serviceIPAnnouncer.UpdateEndpoints(old, new)
+	}
+	if g.routeAdvertiser != nil {
+		g.routeAdvertiser.UpdateEndpoints(old, new)
+	}
 }
 
 func (g *gateway) DeleteEndpoints(ep *kapi.Endpoints) {
@@ -142,6 +185,12 @@ func (g *gateway) DeleteEndpoints(ep *kapi.Endpoints) {
 	if g.nodePortWatcher != nil {
 		g.nodePortWatcher.DeleteEndpoints(ep)
 	}
+	if g.serviceIPAnnouncer != nil {
+		g.serviceIPAnnouncer.DeleteEndpoints(ep)
+	}
+	if g.routeAdvertiser != nil {
+		g.routeAdvertiser.DeleteEndpoints(ep)
+	}
 }
 
 func (g *gateway) Init(wf factory.NodeWatchFactory) error {
@@ -180,6 +229,20 @@ func (g *gateway) Init(wf factory.NodeWatchFactory) error {
 			g.DeleteEndpoints(ep)
 		},
 	}, nil)
+
+	if npw, ok := g.nodePortWatcher.(*nodePortWatcher); ok {
+		wf.AddEndpointSliceHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				npw.SyncEndpointSlice(obj.(*discovery.EndpointSlice))
+			},
+			UpdateFunc: func(old, new interface{}) {
+				npw.SyncEndpointSlice(new.(*discovery.EndpointSlice))
+			},
+			DeleteFunc: func(obj interface{}) {
+				npw.SyncEndpointSlice(obj.(*discovery.EndpointSlice))
+			},
+		}, nil)
+	}
 	return nil
 }
 
@@ -188,6 +251,32 @@ func (g *gateway) Run(stopChan <-chan struct{}, wg *sync.WaitGroup) {
 		g.nodeIPManager.Run(stopChan)
 	}
 
+	if g.serviceIPAnnouncer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.serviceIPAnnouncer.Run(stopChan)
+		}()
+	}
+
+	if g.routeAdvertiser != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.routeAdvertiser.Run(stopChan)
+		}()
+	}
+
+	if g.hostFirewall != nil {
+		// the flows of the gateway bridge are managed once the gateway is initialized
+		g.hostFirewall.ofm = g.openflowManager
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.hostFirewall.Run(stopChan)
+		}()
+	}
+
 	if g.openflowManager != nil {
 		klog.Info("Spawning Conntrack Rule Check Thread")
 		wg.Add(1)
@@ -196,35 +285,46 @@ func (g *gateway) Run(stopChan <-chan struct{}, wg *sync.WaitGroup) {
 	}
 }
 
-func gatewayInitInternal(nodeName, gwIntf, egressGatewayIntf string, subnets []*net.IPNet, gwNextHops []net.IP, gwIPs []*net.IPNet, nodeAnnotator kube.Annotator) (
-	*bridgeConfiguration, *bridgeConfiguration, error) {
+func gatewayInitInternal(nodeName, gwIntf, egressGatewayIntf string, egressIntfs []config.EgressInterfaceEntry, subnets []*net.IPNet, gwNextHops []net.IP, gwIPs []*net.IPNet, nodeAnnotator kube.Annotator) (
+	*bridgeConfiguration, *bridgeConfiguration, map[string]*bridgeConfiguration, error) {
 	gatewayBridge, err := bridgeForInterface(gwIntf, nodeName, types.PhysicalNetworkName, gwIPs)
 	if err != nil {
-		return nil, nil, errors.Wrapf(err, "Bridge for interface failed for %s", gwIntf)
+		return nil, nil, nil, errors.Wrapf(err, "Bridge for interface failed for %s", gwIntf)
 	}
 	var egressGWBridge *bridgeConfiguration
 	if egressGatewayIntf != "" {
 		egressGWBridge, err = bridgeForInterface(egressGatewayIntf, nodeName, types.PhysicalNetworkExGwName, nil)
 		if err != nil {
-			return nil, nil, errors.Wrapf(err, "Bridge for interface failed for %s", egressGatewayIntf)
+			return nil, nil, nil, errors.Wrapf(err, "Bridge for interface failed for %s", egressGatewayIntf)
+		}
+	}
+	// the named egress interfaces get a bridge each, on a physical network
+	// of their own
+	egressBridges := make(map[string]*bridgeConfiguration, len(egressIntfs))
+	for _, egressIntf := range egressIntfs {
+		intfName := interfaceForEXGW(egressIntf.Interface)
+		egressBridges[egressIntf.Name], err = bridgeForInterface(intfName, nodeName,
+			types.PhysicalNetworkEgressPrefix+egressIntf.Name, nil)
+		if err != nil {
+			return nil, nil, nil, errors.Wrapf(err, "Bridge for egress interface %s failed for %s", egressIntf.Name, intfName)
 		}
 	}
 
 	if config.Gateway.Mode == config.GatewayModeLocal {
 		err = setupLocalNodeAccessBridge(nodeName, subnets)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 	}
 	chassisID, err := util.GetNodeChassisID()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	if !config.Gateway.DisablePacketMTUCheck {
 		chkPktLengthSupported, err := util.DetectCheckPktLengthSupport(gatewayBridge.bridgeName)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 
 		if !chkPktLengthSupported {
@@ -250,9 +350,21 @@ func gatewayInitInternal(nodeName, gwIntf, egressGatewayIntf string, subnets []*
 		l3GwConfig.EgressGWMACAddress = egressGWBridge.macAddress
 		l3GwConfig.EgressGWIPAddresses = egressGWBridge.ips
 	}
+	if len(egressIntfs) > 0 {
+		l3GwConfig.EgressInterfaces = make(map[string]*util.L3GatewayEgressInterface, len(egressIntfs))
+		for _, egressIntf := range egressIntfs {
+			egressBridge := egressBridges[egressIntf.Name]
+			l3GwConfig.EgressInterfaces[egressIntf.Name] = &util.L3GatewayEgressInterface{
+				InterfaceID: egressBridge.interfaceID,
+				MACAddress:  egressBridge.macAddress,
+				IPAddresses: egressBridge.ips,
+				NextHops:    egressIntf.NextHops,
+			}
+		}
+	}
 
 	err = util.SetL3GatewayConfig(nodeAnnotator, &l3GwConfig)
-	return gatewayBridge, egressGWBridge, err
+	return gatewayBridge, egressGWBridge, egressBridges, err
 }
 
 func gatewayReady(patchPort string) (bool, error) {
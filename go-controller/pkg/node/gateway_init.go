@@ -8,6 +8,7 @@ import (
 	"k8s.io/klog/v2"
 	utilnet "k8s.io/utils/net"
 
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/bgp"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/kube"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
@@ -306,6 +307,27 @@ func (n *OvnNode) initGateway(subnets []*net.IPNet, nodeAnnotator kube.Annotator
 		klog.Errorf("Unable to set primary IP net label on node, err: %v", err)
 	}
 
+	// a gateway last built in another mode is torn down before the gateway of
+	// the configured mode is built, while the master leaves the gateway router
+	// of the node alone
+	var migration *util.GatewayModeMigration
+	node, err := n.Kube.GetNode(n.name)
+	if err != nil {
+		return fmt.Errorf("error retrieving node %s: %v", n.name, err)
+	}
+	if migration = getGatewayModeMigration(node); migration != nil {
+		klog.Infof("Switching the gateway from %s to %s mode", migration.From, migration.To)
+		if err := n.setGatewayModeMigrationState(migration, util.GatewayModeMigrationCleanup); err != nil {
+			return err
+		}
+		if err := cleanupGatewayMode(migration.From, managementPortConfig); err != nil {
+			return fmt.Errorf("failed to clean up the %s mode gateway: %v", migration.From, err)
+		}
+		if err := n.setGatewayModeMigrationState(migration, util.GatewayModeMigrationSetup); err != nil {
+			return err
+		}
+	}
+
 	var gw *gateway
 	switch config.Gateway.Mode {
 	case config.GatewayModeLocal:
@@ -345,9 +367,28 @@ func (n *OvnNode) initGateway(subnets []*net.IPNet, nodeAnnotator kube.Annotator
 	if portClaimWatcher != nil {
 		gw.portClaimWatcher = portClaimWatcher
 	}
+	if config.BGP.Enabled {
+		speaker, err := bgp.NewSpeaker()
+		if err != nil {
+			return err
+		}
+		gw.routeAdvertiser = newRouteAdvertiser(n.name, subnets, n.watchFactory, speaker)
+	}
+	if config.OVNKubernetesFeature.EnableHostFirewall {
+		gw.hostFirewall = newHostFirewall(n.name, n.watchFactory)
+	} else if err := getGatewayRuleManager().syncHostFirewallRules(nil); err != nil {
+		// rules left behind while the feature was enabled would still apply
+		return err
+	}
 
 	initGwFunc := func() error {
-		return gw.Init(n.watchFactory)
+		if err := gw.Init(n.watchFactory); err != nil {
+			return err
+		}
+		if migration != nil {
+			return n.setGatewayModeMigrationState(migration, util.GatewayModeMigrationDone)
+		}
+		return nil
 	}
 
 	readyGwFunc := func() (bool, error) {
@@ -421,7 +462,7 @@ func (n *OvnNode) initGatewaySmartNicHost(kubeNodeIP net.IP) error {
 	// TODO(adrianc): revisit if support for nodeIPManager is needed.
 
 	if config.Gateway.NodeportEnable {
-		if err := initSharedGatewayIPTables(); err != nil {
+		if err := getGatewayRuleManager().initServiceRules(config.GatewayModeShared); err != nil {
 			return err
 		}
 		gw.nodePortWatcherIptables = newNodePortWatcherIptables()
@@ -17,8 +17,9 @@ import (
 )
 
 const (
-	iptableNodePortChain   = "OVN-KUBE-NODEPORT"
-	iptableExternalIPChain = "OVN-KUBE-EXTERNALIP"
+	iptableNodePortChain     = "OVN-KUBE-NODEPORT"
+	iptableExternalIPChain   = "OVN-KUBE-EXTERNALIP"
+	iptableHostFirewallChain = "OVN-KUBE-HOST-FIREWALL"
 )
 
 func clusterIPTablesProtocols() []iptables.Protocol {
@@ -137,98 +138,52 @@ func getLegacySharedGatewayInitRules(chain string, proto iptables.Protocol) []ip
 	}
 }
 
-func getNodePortIPTRules(svcPort kapi.ServicePort, targetIP string, targetPort int32) []iptRule {
+// serviceIPTRule returns the iptables rule of a service rule
+func serviceIPTRule(r serviceNATRule) iptRule {
 	var protocol iptables.Protocol
-	if utilnet.IsIPv6String(targetIP) {
+	if r.ipv6 {
 		protocol = iptables.ProtocolIPv6
 	} else {
 		protocol = iptables.ProtocolIPv4
 	}
-	return []iptRule{
-		{
-			table: "nat",
-			chain: iptableNodePortChain,
-			args: []string{
-				"-p", string(svcPort.Protocol),
-				"-m", "addrtype",
-				"--dst-type", "LOCAL",
-				"--dport", fmt.Sprintf("%d", svcPort.NodePort),
-				"-j", "DNAT",
-				"--to-destination", util.JoinHostPortInt32(targetIP, targetPort),
-			},
-			protocol: protocol,
-		},
+	rule := iptRule{
+		table:    "nat",
+		protocol: protocol,
 	}
-}
-
-func getNodePortLocalIPTRules(svcPort kapi.ServicePort, targetIP string, targetPort int32) []iptRule {
-	var protocol iptables.Protocol
-	if utilnet.IsIPv6String(targetIP) {
-		protocol = iptables.ProtocolIPv6
+	if r.dstIP == "" {
+		rule.chain = iptableNodePortChain
+		rule.args = []string{
+			"-p", string(r.protocol),
+			"-m", "addrtype",
+			"--dst-type", "LOCAL",
+			"--dport", fmt.Sprintf("%d", r.dstPort),
+		}
 	} else {
-		protocol = iptables.ProtocolIPv4
+		rule.chain = iptableExternalIPChain
+		rule.args = []string{
+			"-p", string(r.protocol),
+			"-d", r.dstIP,
+			"--dport", fmt.Sprintf("%v", r.dstPort),
+		}
 	}
-	return []iptRule{
-		{
-			table: "nat",
-			chain: iptableNodePortChain,
-			args: []string{
-				"-p", string(svcPort.Protocol),
-				"-m", "addrtype",
-				"--dst-type", "LOCAL",
-				"--dport", fmt.Sprintf("%d", svcPort.NodePort),
-				"-j", "REDIRECT",
-				"--to-port", fmt.Sprintf("%d", targetPort),
-			},
-			protocol: protocol,
-		},
+	if r.toIP != "" {
+		rule.args = append(rule.args, "-j", "DNAT", "--to-destination", util.JoinHostPortInt32(r.toIP, r.toPort))
+	} else {
+		rule.args = append(rule.args, "-j", "REDIRECT", "--to-port", fmt.Sprintf("%v", r.toPort))
 	}
+	return rule
 }
 
-func getExternalIPTRules(svcPort kapi.ServicePort, externalIP, dstIP string) []iptRule {
-	var protocol iptables.Protocol
-	if utilnet.IsIPv6String(externalIP) {
-		protocol = iptables.ProtocolIPv6
-	} else {
-		protocol = iptables.ProtocolIPv4
-	}
-	return []iptRule{
-		{
-			table: "nat",
-			chain: iptableExternalIPChain,
-			args: []string{
-				"-p", string(svcPort.Protocol),
-				"-d", externalIP,
-				"--dport", fmt.Sprintf("%v", svcPort.Port),
-				"-j", "DNAT",
-				"--to-destination", util.JoinHostPortInt32(dstIP, svcPort.Port),
-			},
-			protocol: protocol,
-		},
+func serviceIPTRules(rules []serviceNATRule) []iptRule {
+	iptRules := make([]iptRule, 0, len(rules))
+	for _, r := range rules {
+		iptRules = append(iptRules, serviceIPTRule(r))
 	}
+	return iptRules
 }
 
-func getExternalLocalIPTRules(svcPort kapi.ServicePort, externalIP string, targetPort int32) []iptRule {
-	var protocol iptables.Protocol
-	if utilnet.IsIPv6String(externalIP) {
-		protocol = iptables.ProtocolIPv6
-	} else {
-		protocol = iptables.ProtocolIPv4
-	}
-	return []iptRule{
-		{
-			table: "nat",
-			chain: iptableExternalIPChain,
-			args: []string{
-				"-p", string(svcPort.Protocol),
-				"-d", externalIP,
-				"--dport", fmt.Sprintf("%v", svcPort.Port),
-				"-j", "REDIRECT",
-				"--to-port", fmt.Sprintf("%v", targetPort),
-			},
-			protocol: protocol,
-		},
-	}
+func getExternalIPTRules(svcPort kapi.ServicePort, externalIP, dstIP string) []iptRule {
+	return []iptRule{serviceIPTRule(externalIPServiceRule(svcPort, externalIP, dstIP))}
 }
 
 func getLocalGatewayNATRules(ifname string, cidr *net.IPNet) []iptRule {
@@ -281,11 +236,6 @@ func getLocalGatewayNATRules(ifname string, cidr *net.IPNet) []iptRule {
 	}
 }
 
-// initLocalGatewayNATRules sets up iptables rules for interfaces
-func initLocalGatewayNATRules(ifname string, cidr *net.IPNet) error {
-	return addIptRules(getLocalGatewayNATRules(ifname, cidr))
-}
-
 func handleGatewayIPTables(iptCallback func(rules []iptRule) error, genGatewayChainRules func(chain string, proto iptables.Protocol) []iptRule) error {
 	rules := make([]iptRule, 0)
 	for _, chain := range []string{iptableNodePortChain, iptableExternalIPChain} {
@@ -340,6 +290,79 @@ func cleanupSharedGatewayIPTChains() {
 	}
 }
 
+// cleanupGatewayIPTables deletes the service chains and the jumps to them of
+// any gateway mode, ignoring errors
+func cleanupGatewayIPTables() {
+	for _, genRules := range []func(chain string, proto iptables.Protocol) []iptRule{
+		getSharedGatewayInitRules, getLegacySharedGatewayInitRules, getLegacyLocalGatewayInitRules} {
+		_ = handleGatewayIPTables(delIptRules, genRules)
+	}
+	cleanupSharedGatewayIPTChains()
+	cleanupHostFirewallIPTChain()
+}
+
+// getHostFirewallJumpRule returns the rule sending the new connections from
+// outside the node to a local address through the host firewall chain. It's in
+// the mangle table so that it sees the NodePort traffic before it's DNATed.
+func getHostFirewallJumpRule(proto iptables.Protocol) iptRule {
+	return iptRule{
+		table: "mangle",
+		chain: "PREROUTING",
+		args: []string{
+			"!", "-i", "lo",
+			"-m", "addrtype", "--dst-type", "LOCAL",
+			"-m", "conntrack", "--ctstate", "NEW",
+			"-j", iptableHostFirewallChain,
+		},
+		protocol: proto,
+	}
+}
+
+// hostFirewallIPTRules returns the rules of the host firewall chain for rules.
+// The rules are inserted in reverse order, so the sources allowed to a port
+// come before the rule dropping the traffic to it.
+func hostFirewallIPTRules(rules []hostFirewallRule) []iptRule {
+	var iptRules []iptRule
+	for _, r := range rules {
+		protocol := strings.ToLower(string(r.protocol))
+		port := fmt.Sprintf("%d", r.port)
+		for _, proto := range clusterIPTablesProtocols() {
+			iptRules = append(iptRules, iptRule{
+				table:    "mangle",
+				chain:    iptableHostFirewallChain,
+				args:     []string{"-p", protocol, "--dport", port, "-j", "DROP"},
+				protocol: proto,
+			})
+			for _, cidr := range r.allowed {
+				if utilnet.IsIPv6CIDR(cidr) != (proto == iptables.ProtocolIPv6) {
+					continue
+				}
+				iptRules = append(iptRules, iptRule{
+					table:    "mangle",
+					chain:    iptableHostFirewallChain,
+					args:     []string{"-p", protocol, "--dport", port, "-s", cidr.String(), "-j", "RETURN"},
+					protocol: proto,
+				})
+			}
+		}
+	}
+	return iptRules
+}
+
+// cleanupHostFirewallIPTChain deletes the host firewall chain and the jump to
+// it, ignoring errors
+func cleanupHostFirewallIPTChain() {
+	for _, proto := range []iptables.Protocol{iptables.ProtocolIPv4, iptables.ProtocolIPv6} {
+		ipt, err := util.GetIPTablesHelper(proto)
+		if err != nil {
+			return
+		}
+		_ = delIptRules([]iptRule{getHostFirewallJumpRule(proto)})
+		_ = ipt.ClearChain("mangle", iptableHostFirewallChain)
+		_ = ipt.DeleteChain("mangle", iptableHostFirewallChain)
+	}
+}
+
 func recreateIPTRules(table, chain string, keepIPTRules []iptRule) {
 	for _, proto := range clusterIPTablesProtocols() {
 		ipt, _ := util.GetIPTablesHelper(proto)
@@ -352,51 +375,64 @@ func recreateIPTRules(table, chain string, keepIPTRules []iptRule) {
 	}
 }
 
-// getGatewayIPTRules returns NodePort and ExternalIP iptables rules for service. If nodeIP is non-nil, then
-// only incoming traffic on that IP will be accepted for NodePort rules; otherwise incoming traffic on the NodePort
-// on all IPs will be accepted. If gatewayIP is "", then NodePort traffic will be DNAT'ed to the service port on
-// the service's ClusterIP. Otherwise, it will be DNAT'ed to the NodePort on the gatewayIP.
-func getGatewayIPTRules(service *kapi.Service, hasLocalHostEndpoint bool) []iptRule {
-	rules := make([]iptRule, 0)
-	clusterIPs := util.GetClusterIPs(service)
-	for _, svcPort := range service.Spec.Ports {
-		if util.ServiceTypeHasNodePort(service) {
-			err := util.ValidatePort(svcPort.Protocol, svcPort.NodePort)
-			if err != nil {
-				klog.Errorf("Skipping service: %s, invalid service NodePort: %v", svcPort.Name, err)
-				continue
-			}
-			err = util.ValidatePort(svcPort.Protocol, svcPort.Port)
-			if err != nil {
-				klog.Errorf("Skipping service: %s, invalid service port %v", svcPort.Name, err)
-				continue
-			}
-			if !hasLocalHostEndpoint {
-				for _, clusterIP := range clusterIPs {
-					rules = append(rules, getNodePortIPTRules(svcPort, clusterIP, svcPort.Port)...)
-				}
-			} else {
-				// Port redirect host -> Nodeport -> host traffic directly to endpoint
-				for _, clusterIP := range clusterIPs {
-					rules = append(rules, getNodePortLocalIPTRules(svcPort, clusterIP, int32(svcPort.TargetPort.IntValue()))...)
-				}
-			}
+// iptablesRuleManager programs the gateway rules with iptables, one rule at a
+// time. Service rules go to the OVN-KUBE-NODEPORT and OVN-KUBE-EXTERNALIP
+// chains of the nat table.
+type iptablesRuleManager struct{}
+
+func (m *iptablesRuleManager) initServiceRules(mode config.GatewayMode) error {
+	// rules left behind by the nftables backend would still apply
+	cleanupNFTablesRules()
+	if mode == config.GatewayModeLocal {
+		return initLocalGatewayIPTables()
+	}
+	return initSharedGatewayIPTables()
+}
+
+func (m *iptablesRuleManager) initLocalGatewayNATRules(ifname string, cidr *net.IPNet) error {
+	return addIptRules(getLocalGatewayNATRules(ifname, cidr))
+}
+
+func (m *iptablesRuleManager) cleanupLocalGatewayNATRules(ifname string, cidr *net.IPNet) error {
+	return delIptRules(getLocalGatewayNATRules(ifname, cidr))
+}
+
+func (m *iptablesRuleManager) addServiceRules(rules []serviceNATRule) error {
+	return addIptRules(serviceIPTRules(rules))
+}
+
+func (m *iptablesRuleManager) delServiceRules(rules []serviceNATRule) error {
+	return delIptRules(serviceIPTRules(rules))
+}
+
+func (m *iptablesRuleManager) syncServiceRules(rules []serviceNATRule) error {
+	keepIPTRules := serviceIPTRules(rules)
+	for _, chain := range []string{iptableNodePortChain, iptableExternalIPChain} {
+		recreateIPTRules("nat", chain, keepIPTRules)
+	}
+	return nil
+}
+
+func (m *iptablesRuleManager) cleanupServiceRules() {
+	cleanupSharedGatewayIPTChains()
+}
+
+func (m *iptablesRuleManager) syncHostFirewallRules(rules []hostFirewallRule) error {
+	if len(rules) == 0 {
+		cleanupHostFirewallIPTChain()
+		return nil
+	}
+	var jumpRules []iptRule
+	for _, proto := range clusterIPTablesProtocols() {
+		ipt, err := util.GetIPTablesHelper(proto)
+		if err != nil {
+			return err
 		}
-		for _, externalIP := range service.Spec.ExternalIPs {
-			err := util.ValidatePort(svcPort.Protocol, svcPort.Port)
-			if err != nil {
-				klog.Errorf("Skipping service: %s, invalid service port %v", svcPort.Name, err)
-				continue
-			}
-			if clusterIP, err := util.MatchIPStringFamily(utilnet.IsIPv6String(externalIP), clusterIPs); err == nil {
-				if hasLocalHostEndpoint {
-					// Port redirect host -> ExternalIP -> host
-					rules = append(rules, getExternalLocalIPTRules(svcPort, externalIP, int32(svcPort.TargetPort.IntValue()))...)
-				} else {
-					rules = append(rules, getExternalIPTRules(svcPort, externalIP, clusterIP)...)
-				}
-			}
+		if err := ipt.NewChain("mangle", iptableHostFirewallChain); err != nil {
+			klog.V(5).Infof("Chain: \"%s\" in table: \"%s\" already exists, skipping creation", "mangle", iptableHostFirewallChain)
 		}
+		jumpRules = append(jumpRules, getHostFirewallJumpRule(proto))
 	}
-	return rules
+	recreateIPTRules("mangle", iptableHostFirewallChain, hostFirewallIPTRules(rules))
+	return addIptRules(jumpRules)
 }
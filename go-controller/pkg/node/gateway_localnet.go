@@ -4,6 +4,7 @@ package node
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
 	"reflect"
 	"strings"
@@ -14,6 +15,7 @@ import (
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
 
+	"golang.org/x/sys/unix"
 	kapi "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
@@ -27,8 +29,20 @@ const (
 
 	// Routing table for ExternalIP communication
 	localnetGatewayExternalIDTable = "6"
+
+	// localnetGatewayVRFLocalRulePriority is the priority of the rule looking
+	// up the local table for the traffic of the gateway VRF, right before the
+	// l3mdev rule of the VRFs
+	localnetGatewayVRFLocalRulePriority = 999
 )
 
+// l3mdevAcceptSysctls let the sockets of the host, bound to no VRF, accept the
+// connections from the pods in the gateway VRF
+var l3mdevAcceptSysctls = []string{
+	"/proc/sys/net/ipv4/tcp_l3mdev_accept",
+	"/proc/sys/net/ipv4/udp_l3mdev_accept",
+}
+
 func newLocalGateway(nodeName string, hostSubnets []*net.IPNet, gwNextHops []net.IP, gwIntf string, nodeAnnotator kube.Annotator, recorder record.EventRecorder, cfg *managementPortConfig) (*gateway, error) {
 	gw := &gateway{}
 	var gatewayIfAddrs []*net.IPNet
@@ -46,20 +60,27 @@ func newLocalGateway(nodeName string, hostSubnets []*net.IPNet, gwNextHops []net
 		// add iptables masquerading for mp0 to exit the host for egress
 		cidr := nextHop.IP.Mask(nextHop.Mask)
 		cidrNet := &net.IPNet{IP: cidr, Mask: nextHop.Mask}
-		err := initLocalGatewayNATRules(types.K8sMgmtIntfName, cidrNet)
+		natIntf := localGatewayNATInterface()
+		err := getGatewayRuleManager().initLocalGatewayNATRules(natIntf, cidrNet)
 		if err != nil {
-			return nil, fmt.Errorf("failed to add local NAT rules for: %s, err: %v", types.K8sMgmtIntfName, err)
+			return nil, fmt.Errorf("failed to add local NAT rules for: %s, err: %v", natIntf, err)
 		}
 	}
 
-	gwBridge, _, err := gatewayInitInternal(
-		nodeName, gwIntf, "", hostSubnets, gwNextHops, nil, nodeAnnotator)
+	gwBridge, _, _, err := gatewayInitInternal(
+		nodeName, gwIntf, "", nil, hostSubnets, gwNextHops, nil, nodeAnnotator)
 	if err != nil {
 		return nil, err
 	}
 
+	if config.Gateway.VRF != "" {
+		if err := addLocalGatewayVRFRoutes(gwBridge.bridgeName, gwNextHops); err != nil {
+			return nil, err
+		}
+	}
+
 	if config.Gateway.NodeportEnable {
-		if err := initLocalGatewayIPTables(); err != nil {
+		if err := getGatewayRuleManager().initServiceRules(config.GatewayModeLocal); err != nil {
 			return nil, err
 		}
 		gw.localPortWatcher = newLocalPortWatcher(gatewayIfAddrs, recorder)
@@ -82,6 +103,47 @@ func newLocalGateway(nodeName string, hostSubnets []*net.IPNet, gwNextHops []net
 	return gw, nil
 }
 
+// localGatewayNATInterface returns the interface the local NAT rules of the
+// management port match. With a gateway VRF, the traffic of mp0 enters and
+// leaves the host through the VRF device, so the rules match it instead.
+func localGatewayNATInterface() string {
+	if config.Gateway.VRF != "" {
+		return config.Gateway.VRF
+	}
+	return types.K8sMgmtIntfName
+}
+
+// addLocalGatewayVRFRoutes routes the egress traffic of the pods in the gateway
+// VRF out of the gateway bridge, through the next hops of the node, and leaks
+// the host routes to the VRF: the subnets connected to the gateway bridge are
+// added to the table of the VRF, and the traffic of the VRF to the addresses of
+// the host is delivered locally
+func addLocalGatewayVRFRoutes(bridgeName string, gwNextHops []net.IP) error {
+	link, err := util.LinkSetUp(bridgeName)
+	if err != nil {
+		return err
+	}
+	for _, nextHop := range gwNextHops {
+		if err := util.LinkDefaultRouteReplaceInTable(link, nextHop, int(config.Gateway.VRFTable)); err != nil {
+			return fmt.Errorf("failed to add the default route of VRF %s: %v", config.Gateway.VRF, err)
+		}
+	}
+	if err := util.LinkConnectedRoutesReplaceInTable(link, int(config.Gateway.VRFTable)); err != nil {
+		return fmt.Errorf("failed to add the connected routes of VRF %s: %v", config.Gateway.VRF, err)
+	}
+	// the local table is usually looked up after the l3mdev rule on hosts
+	// with VRFs, so that the addresses of the host are not reachable from them
+	if err := util.RuleIifLookupEnsure(config.Gateway.VRF, unix.RT_TABLE_LOCAL, localnetGatewayVRFLocalRulePriority); err != nil {
+		return fmt.Errorf("failed to add the local rule of VRF %s: %v", config.Gateway.VRF, err)
+	}
+	for _, sysctl := range l3mdevAcceptSysctls {
+		if err := ioutil.WriteFile(sysctl, []byte("1"), 0640); err != nil {
+			return fmt.Errorf("failed to enable %s: %v", sysctl, err)
+		}
+	}
+	return nil
+}
+
 func getGatewayFamilyAddrs(gatewayIfAddrs []*net.IPNet) (string, string) {
 	var gatewayIPv4, gatewayIPv6 string
 	for _, gatewayIfAddr := range gatewayIfAddrs {
@@ -168,7 +230,7 @@ func (l *localPortWatcher) addService(svc *kapi.Service) error {
 	}
 
 	for _, ip := range util.GetClusterIPs(svc) {
-		iptRules := []iptRule{}
+		rules := []serviceNATRule{}
 		isIPv6Service := utilnet.IsIPv6String(ip)
 		gatewayIP := l.gatewayIPv4
 		if isIPv6Service {
@@ -177,11 +239,11 @@ func (l *localPortWatcher) addService(svc *kapi.Service) error {
 		for _, port := range svc.Spec.Ports {
 			// Fix Azure/GCP LoadBalancers. They will forward traffic directly to the node with the
 			// dest address as the load-balancer ingress IP and port
-			iptRules = append(iptRules, getLoadBalancerIPTRules(svc, port, ip, port.Port)...)
+			rules = append(rules, loadBalancerServiceRules(svc, port, ip, port.Port)...)
 
 			if port.NodePort > 0 {
 				if gatewayIP != "" {
-					iptRules = append(iptRules, getNodePortIPTRules(port, ip, port.Port)...)
+					rules = append(rules, nodePortServiceRule(port, ip, port.Port))
 					klog.V(5).Infof("Will add iptables rule for NodePort: %v and "+
 						"protocol: %v", port.NodePort, port.Protocol)
 				} else {
@@ -194,14 +256,14 @@ func (l *localPortWatcher) addService(svc *kapi.Service) error {
 					continue
 				}
 
-				iptRules = append(iptRules, getExternalIPTRules(port, externalIP, ip)...)
+				rules = append(rules, externalIPServiceRule(port, externalIP, ip))
 				klog.V(5).Infof("Adding iptables rules for service: %s with external IP: %s", svc.Name, externalIP)
 
 			}
 		}
-		klog.Infof("Adding iptables rules: %v for service: %v", iptRules, svc.Name)
-		if err := addIptRules(iptRules); err != nil {
-			klog.Errorf("Error adding iptables rules: %v for service: %v err: %v", iptRules, svc.Name, err)
+		klog.Infof("Adding iptables rules: %v for service: %v", rules, svc.Name)
+		if err := getGatewayRuleManager().addServiceRules(rules); err != nil {
+			klog.Errorf("Error adding iptables rules: %v for service: %v err: %v", rules, svc.Name, err)
 		}
 	}
 	return nil
@@ -214,7 +276,7 @@ func (l *localPortWatcher) deleteService(svc *kapi.Service) error {
 	}
 
 	for _, ip := range util.GetClusterIPs(svc) {
-		iptRules := []iptRule{}
+		rules := []serviceNATRule{}
 		isIPv6Service := utilnet.IsIPv6String(ip)
 		gatewayIP := l.gatewayIPv4
 		if isIPv6Service {
@@ -224,10 +286,10 @@ func (l *localPortWatcher) deleteService(svc *kapi.Service) error {
 		for _, port := range svc.Spec.Ports {
 			// Fix Azure/GCP LoadBalancers. They will forward traffic directly to the node with the
 			// dest address as the load-balancer ingress IP and port
-			iptRules = append(iptRules, getLoadBalancerIPTRules(svc, port, ip, port.Port)...)
+			rules = append(rules, loadBalancerServiceRules(svc, port, ip, port.Port)...)
 			if port.NodePort > 0 {
 				if gatewayIP != "" {
-					iptRules = append(iptRules, getNodePortIPTRules(port, ip, port.Port)...)
+					rules = append(rules, nodePortServiceRule(port, ip, port.Port))
 					klog.V(5).Infof("Will delete iptables rule for NodePort: %v and "+
 						"protocol: %v", port.NodePort, port.Protocol)
 				}
@@ -237,32 +299,32 @@ func (l *localPortWatcher) deleteService(svc *kapi.Service) error {
 					continue
 				}
 
-				iptRules = append(iptRules, getExternalIPTRules(port, externalIP, ip)...)
+				rules = append(rules, externalIPServiceRule(port, externalIP, ip))
 				klog.V(5).Infof("Will delete iptables rule for ExternalIP: %s", externalIP)
 
 			}
 		}
 
-		klog.Infof("Deleting iptables rules: %v for service: %v", iptRules, svc.Name)
-		if err := delIptRules(iptRules); err != nil {
-			klog.Errorf("Error deleting iptables rules: %v for service: %v err: %v", iptRules, svc.Name, err)
+		klog.Infof("Deleting iptables rules: %v for service: %v", rules, svc.Name)
+		if err := getGatewayRuleManager().delServiceRules(rules); err != nil {
+			klog.Errorf("Error deleting iptables rules: %v for service: %v err: %v", rules, svc.Name, err)
 		}
 	}
 	return nil
 }
 
 func (l *localPortWatcher) SyncServices(serviceInterface []interface{}) {
-	keepIPTRules := []iptRule{}
+	keepRules := []serviceNATRule{}
 	for _, service := range serviceInterface {
 		svc, ok := service.(*kapi.Service)
 		if !ok {
 			klog.Errorf("Spurious object in syncServices: %v", serviceInterface)
 			continue
 		}
-		keepIPTRules = append(keepIPTRules, getGatewayIPTRules(svc, false)...)
+		keepRules = append(keepRules, getGatewayServiceRules(svc, false)...)
 	}
-	for _, chain := range []string{iptableNodePortChain, iptableExternalIPChain} {
-		recreateIPTRules("nat", chain, keepIPTRules)
+	if err := getGatewayRuleManager().syncServiceRules(keepRules); err != nil {
+		klog.Errorf("Failed to sync service rules: %v", err)
 	}
 
 	// Previously LGW used routes in the localnetGatewayExternalIDTable, to handle
@@ -294,26 +356,6 @@ func cleanupLocalnetGateway(physnet string) error {
 	return err
 }
 
-func getLoadBalancerIPTRules(svc *kapi.Service, svcPort kapi.ServicePort, gatewayIP string, targetPort int32) []iptRule {
-	var rules []iptRule
-	ingPort := fmt.Sprintf("%d", svcPort.Port)
-	for _, ing := range svc.Status.LoadBalancer.Ingress {
-		if ing.IP == "" {
-			continue
-		}
-		rules = append(rules, iptRule{
-			table: "nat",
-			chain: iptableNodePortChain,
-			args: []string{
-				"-d", ing.IP,
-				"-p", string(svcPort.Protocol), "--dport", ingPort,
-				"-j", "DNAT", "--to-destination", util.JoinHostPortInt32(gatewayIP, targetPort),
-			},
-		})
-	}
-	return rules
-}
-
 // since we share the host's k8s node IP, add OpenFlow flows
 // -- to steer the NodePort traffic arriving on the host to the OVN logical topology and
 // -- to also connection track the outbound north-south traffic through l3 gateway so that
@@ -448,6 +490,7 @@ func newLocalGatewayOpenflowManager(gwBridge *bridgeConfiguration) (*openflowMan
 		defaultBridge: gwBridge,
 		flowCache:     make(map[string][]string),
 		flowMutex:     sync.Mutex{},
+		flowSync:      newFlowSyncTracker(),
 		flowChan:      make(chan struct{}, 1),
 	}
 	ofm.updateFlowCacheEntry("NORMAL", []string{fmt.Sprintf("table=0,priority=0,actions=%s\n", util.NormalAction)})
@@ -0,0 +1,166 @@
+// +build linux
+
+package node
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+
+	kapi "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// getGatewayModeMigration returns the gateway mode migration the node has to
+// go through before building its gateway in the configured mode, or nil if the
+// gateway of the node was last built in that mode. The previous mode is the
+// one of the l3-gateway-config annotation of the node, unless the node was
+// restarted in the middle of a migration to the configured mode, which is
+// then resumed.
+func getGatewayModeMigration(node *kapi.Node) *util.GatewayModeMigration {
+	if migration, err := util.ParseNodeGatewayModeMigration(node); err == nil &&
+		migration.State != util.GatewayModeMigrationDone && migration.To == config.Gateway.Mode {
+		return migration
+	}
+
+	l3GatewayConfig, err := util.ParseNodeL3GatewayAnnotation(node)
+	if err != nil {
+		return nil
+	}
+	if !isMigratableGatewayMode(l3GatewayConfig.Mode) || !isMigratableGatewayMode(config.Gateway.Mode) ||
+		l3GatewayConfig.Mode == config.Gateway.Mode {
+		return nil
+	}
+	return &util.GatewayModeMigration{
+		From: l3GatewayConfig.Mode,
+		To:   config.Gateway.Mode,
+	}
+}
+
+// isMigratableGatewayMode returns true if a node can switch its gateway from or
+// to mode without a full cleanup
+func isMigratableGatewayMode(mode config.GatewayMode) bool {
+	return mode == config.GatewayModeLocal || mode == config.GatewayModeShared
+}
+
+// setGatewayModeMigrationState reports the step of the migration the node is
+// at in its gateway mode migration annotation
+func (n *OvnNode) setGatewayModeMigrationState(migration *util.GatewayModeMigration, state util.GatewayModeMigrationState) error {
+	migration.State = state
+	if err := util.SetNodeGatewayModeMigration(n.Kube, n.name, migration); err != nil {
+		return fmt.Errorf("failed to set the gateway mode migration annotation of node %s: %v", n.name, err)
+	}
+	klog.Infof("Gateway mode migration of node %s from %s to %s: %s", n.name, migration.From, migration.To, state)
+	return nil
+}
+
+// cleanupGatewayMode tears down what the gateway of mode left on the host, and
+// that the gateway of the other mode would not replace: the service rules, the
+// routes, host rules and local node access bridge of mode alone, and the flows
+// of the gateway bridge, which forwards normally until the gateway of the other
+// mode programs it. The gateway bridge, its ports and its bridge mapping are
+// kept, as both modes use them.
+func cleanupGatewayMode(mode config.GatewayMode, mgmtPortConfig *managementPortConfig) error {
+	bridgeName, err := getPhysicalNetworkBridge()
+	if err != nil {
+		return err
+	}
+	if bridgeName != "" {
+		_, stderr, err := util.AddOFFlowWithSpecificAction(bridgeName, util.NormalAction)
+		if err != nil {
+			return fmt.Errorf("failed to replace-flows on bridge %q stderr:%s (%v)", bridgeName, stderr, err)
+		}
+	}
+
+	getGatewayRuleManager().cleanupServiceRules()
+
+	switch mode {
+	case config.GatewayModeShared:
+		if bridgeName != "" {
+			if err := delMasqueradeRoute(bridgeName); err != nil {
+				return err
+			}
+		}
+	case config.GatewayModeLocal:
+		if err := cleanupLocalGatewayNATRules(mgmtPortConfig); err != nil {
+			return err
+		}
+		if err := deleteLocalNodeAccessBridge(); err != nil {
+			return err
+		}
+		if _, stderr, err := util.RunIP("route", "flush", "table", localnetGatewayExternalIDTable); err != nil {
+			return fmt.Errorf("failed to flush host's routing table: %s stderr: %s err: %v",
+				localnetGatewayExternalIDTable, stderr, err)
+		}
+	}
+	return nil
+}
+
+// cleanupLocalGatewayNATRules deletes the NAT rules the local gateway added for
+// the management port and for the port of the local node access bridge
+func cleanupLocalGatewayNATRules(mgmtPortConfig *managementPortConfig) error {
+	type natRule struct {
+		ifname string
+		cidr   *net.IPNet
+	}
+	var natRules []natRule
+	if mgmtPortConfig != nil {
+		for _, cfg := range []*managementPortIPFamilyConfig{mgmtPortConfig.ipv4, mgmtPortConfig.ipv6} {
+			if cfg == nil {
+				continue
+			}
+			cidr := &net.IPNet{IP: cfg.ifAddr.IP.Mask(cfg.ifAddr.Mask), Mask: cfg.ifAddr.Mask}
+			natRules = append(natRules, natRule{ifname: localGatewayNATInterface(), cidr: cidr})
+		}
+	}
+	if config.IPv4Mode {
+		natRules = append(natRules, natRule{ifname: localnetGatewayNextHopPort, cidr: localNodeAccessNextHopCIDR(false)})
+	}
+	if config.IPv6Mode {
+		natRules = append(natRules, natRule{ifname: localnetGatewayNextHopPort, cidr: localNodeAccessNextHopCIDR(true)})
+	}
+	for _, r := range natRules {
+		if err := getGatewayRuleManager().cleanupLocalGatewayNATRules(r.ifname, r.cidr); err != nil {
+			return fmt.Errorf("failed to delete local NAT rules for: %s, err: %v", r.ifname, err)
+		}
+	}
+	return nil
+}
+
+// getPhysicalNetworkBridge returns the bridge mapped to the physical network
+// of the gateway in ovn-bridge-mappings, or an empty string if there is none
+func getPhysicalNetworkBridge() (string, error) {
+	stdout, stderr, err := util.RunOVSVsctl("--if-exists", "get", "Open_vSwitch", ".",
+		"external_ids:ovn-bridge-mappings")
+	if err != nil {
+		return "", fmt.Errorf("failed to get ovn-bridge-mappings stderr:%s (%v)", stderr, err)
+	}
+	for _, bridgeMapping := range strings.Split(stdout, ",") {
+		m := strings.Split(bridgeMapping, ":")
+		if len(m) == 2 && m[0] == types.PhysicalNetworkName {
+			return m[1], nil
+		}
+	}
+	return "", nil
+}
+
+// delMasqueradeRoute deletes the route to the masquerade subnet added by
+// addMasqueradeRoute
+func delMasqueradeRoute(netIfaceName string) error {
+	if !config.IPv4Mode {
+		return nil
+	}
+	link, err := util.GetNetLinkOps().LinkByName(netIfaceName)
+	if err != nil {
+		return fmt.Errorf("unable to find shared gw bridge interface: %s", netIfaceName)
+	}
+	_, masqIPNet, _ := net.ParseCIDR(types.V4MasqueradeSubnet)
+	if err := util.LinkRoutesDel(link, []*net.IPNet{masqIPNet}); err != nil {
+		return fmt.Errorf("unable to delete OVN masquerade route from host, error: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+// +build linux
+
+package node
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	ovntest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing"
+	linkMock "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing/mocks/github.com/vishvananda/netlink"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+	utilMock "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util/mocks"
+
+	"github.com/vishvananda/netlink"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Node Operations gateway mode migration", func() {
+	nodeWith := func(annotations map[string]string) *v1.Node {
+		annotations["k8s.ovn.org/node-chassis-id"] = "79fdcfc4-6fe6-4cd3-8242-c0f85a4668ec"
+		return &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1", Annotations: annotations},
+		}
+	}
+	l3GatewayConfig := func(mode string) string {
+		return `{"default":{"mode":"` + mode + `","mac-address":"7e:57:f8:f0:3c:49","ip-address":"169.254.33.2/24","next-hop":"169.254.33.1"}}`
+	}
+
+	BeforeEach(func() {
+		config.PrepareTestConfig()
+		config.Gateway.Mode = config.GatewayModeLocal
+	})
+
+	It("does not migrate a new node", func() {
+		Expect(getGatewayModeMigration(nodeWith(map[string]string{}))).To(BeNil())
+	})
+
+	It("does not migrate a gateway built in the configured mode", func() {
+		node := nodeWith(map[string]string{"k8s.ovn.org/l3-gateway-config": l3GatewayConfig("local")})
+		Expect(getGatewayModeMigration(node)).To(BeNil())
+	})
+
+	It("does not migrate a gateway from or to the disabled mode", func() {
+		node := nodeWith(map[string]string{"k8s.ovn.org/l3-gateway-config": l3GatewayConfig("disabled")})
+		Expect(getGatewayModeMigration(node)).To(BeNil())
+
+		config.Gateway.Mode = config.GatewayModeDisabled
+		node = nodeWith(map[string]string{"k8s.ovn.org/l3-gateway-config": l3GatewayConfig("shared")})
+		Expect(getGatewayModeMigration(node)).To(BeNil())
+	})
+
+	It("migrates a gateway built in another mode", func() {
+		node := nodeWith(map[string]string{"k8s.ovn.org/l3-gateway-config": l3GatewayConfig("shared")})
+		Expect(getGatewayModeMigration(node)).To(Equal(&util.GatewayModeMigration{
+			From: config.GatewayModeShared,
+			To:   config.GatewayModeLocal,
+		}))
+	})
+
+	It("resumes an unfinished migration to the configured mode", func() {
+		node := nodeWith(map[string]string{
+			"k8s.ovn.org/l3-gateway-config":      l3GatewayConfig("local"),
+			"k8s.ovn.org/gateway-mode-migration": `{"from":"shared","to":"local","state":"setup"}`,
+		})
+		Expect(getGatewayModeMigration(node)).To(Equal(&util.GatewayModeMigration{
+			From:  config.GatewayModeShared,
+			To:    config.GatewayModeLocal,
+			State: util.GatewayModeMigrationSetup,
+		}))
+
+		node.Annotations["k8s.ovn.org/gateway-mode-migration"] = `{"from":"shared","to":"local","state":"done"}`
+		Expect(getGatewayModeMigration(node)).To(BeNil())
+	})
+
+	Context("between the local and shared modes", func() {
+		const bridgeMappingsCmd = "ovs-vsctl --timeout=15 --if-exists get Open_vSwitch . external_ids:ovn-bridge-mappings"
+		var (
+			fexec          *ovntest.FakeExec
+			fakeNFT        *util.FakeNFTables
+			netlinkMock    *utilMock.NetLinkOps
+			origNetlinkOps = util.GetNetLinkOps()
+			mgmtPortConfig *managementPortConfig
+		)
+
+		BeforeEach(func() {
+			config.IPv4Mode = true
+			config.Gateway.RuleBackend = config.GatewayRuleBackendNFTables
+			nftablesRules = nil
+			fakeNFT = util.SetFakeNFTablesHelper()
+			fexec = ovntest.NewFakeExec()
+			Expect(util.SetExec(fexec)).To(Succeed())
+			netlinkMock = &utilMock.NetLinkOps{}
+			util.SetNetLinkOpMockInst(netlinkMock)
+			mgmtPortConfig = &managementPortConfig{
+				ipv4: &managementPortIPFamilyConfig{ifAddr: ovntest.MustParseIPNet("10.1.1.2/24")},
+			}
+		})
+
+		AfterEach(func() {
+			util.SetNetLinkOpMockInst(origNetlinkOps)
+			util.SetNFTablesHelper(nil)
+			nftablesRules = nil
+		})
+
+		// setupLocalGatewayRules adds the host rules of a local gateway, as
+		// newLocalGateway and setupLocalNodeAccessBridge do, and returns the
+		// resulting tables
+		setupLocalGatewayRules := func() string {
+			m := getGatewayRuleManager()
+			Expect(m.initLocalGatewayNATRules(localGatewayNATInterface(), ovntest.MustParseIPNet("10.1.1.0/24"))).To(Succeed())
+			Expect(m.initLocalGatewayNATRules(localnetGatewayNextHopPort, localNodeAccessNextHopCIDR(false))).To(Succeed())
+			Expect(m.initServiceRules(config.GatewayModeLocal)).To(Succeed())
+			return fakeNFT.LastScript()
+		}
+
+		cleanupLocalGatewayCmds := func() {
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    bridgeMappingsCmd,
+				Output: types.PhysicalNetworkName + ":breth0," + types.LocalNetworkName + ":" + types.LocalBridgeName,
+			})
+			fexec.AddFakeCmdsNoOutputNoError([]string{
+				"ovs-ofctl -O OpenFlow13 replace-flows breth0 -",
+				"ovs-vsctl --timeout=15 --if-exists del-br " + types.LocalBridgeName,
+			})
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    bridgeMappingsCmd,
+				Output: types.PhysicalNetworkName + ":breth0," + types.LocalNetworkName + ":" + types.LocalBridgeName,
+			})
+			fexec.AddFakeCmdsNoOutputNoError([]string{
+				"ovs-vsctl --timeout=15 set Open_vSwitch . external_ids:ovn-bridge-mappings=" + types.PhysicalNetworkName + ":breth0",
+				"ip route flush table " + localnetGatewayExternalIDTable,
+			})
+		}
+
+		It("removes the local gateway and builds it again", func() {
+			localTables := setupLocalGatewayRules()
+			Expect(localTables).To(ContainSubstring(`iifname "` + types.K8sMgmtIntfName + `" accept`))
+			Expect(localTables).To(ContainSubstring(`iifname "` + localnetGatewayNextHopPort + `" accept`))
+
+			// local to shared: the local NAT rules, br-local and its bridge
+			// mapping are removed
+			fakeNFT.Tables = []string{"ip ovn-kubernetes"}
+			cleanupLocalGatewayCmds()
+			Expect(cleanupGatewayMode(config.GatewayModeLocal, mgmtPortConfig)).To(Succeed())
+			Expect(fexec.CalledMatchesExpected()).To(BeTrue(), fexec.ErrorDesc)
+			Expect(fakeNFT.LastScript()).To(Equal("delete table ip ovn-kubernetes\n"))
+			Expect(nftablesRules.localNATRules).To(BeEmpty())
+
+			// shared to local: the masquerade route of the shared gateway is
+			// removed, and the local gateway gets the same rules as before
+			fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+				Cmd:    bridgeMappingsCmd,
+				Output: types.PhysicalNetworkName + ":breth0",
+			})
+			fexec.AddFakeCmdsNoOutputNoError([]string{
+				"ovs-ofctl -O OpenFlow13 replace-flows breth0 -",
+			})
+			link := &linkMock.Link{}
+			link.On("Attrs").Return(&netlink.LinkAttrs{Name: "breth0"})
+			masqueradeRoute := netlink.Route{Dst: ovntest.MustParseIPNet(types.V4MasqueradeSubnet)}
+			otherRoute := netlink.Route{Dst: ovntest.MustParseIPNet("192.168.1.0/24")}
+			netlinkMock.On("LinkByName", "breth0").Return(link, nil)
+			netlinkMock.On("RouteList", link, netlink.FAMILY_ALL).Return([]netlink.Route{masqueradeRoute, otherRoute}, nil)
+			netlinkMock.On("RouteDel", &masqueradeRoute).Return(nil)
+			Expect(cleanupGatewayMode(config.GatewayModeShared, mgmtPortConfig)).To(Succeed())
+			Expect(fexec.CalledMatchesExpected()).To(BeTrue(), fexec.ErrorDesc)
+			netlinkMock.AssertExpectations(GinkgoT())
+
+			Expect(setupLocalGatewayRules()).To(Equal(localTables))
+		})
+
+		It("removes the local NAT rules left by a previous process", func() {
+			config.Gateway.VRF = "mp0-vrf"
+			fakeNFT.Tables = []string{"ip filter", "ip ovn-kubernetes", "ip6 ovn-kubernetes"}
+			cleanupLocalGatewayCmds()
+			Expect(cleanupGatewayMode(config.GatewayModeLocal, mgmtPortConfig)).To(Succeed())
+			Expect(fexec.CalledMatchesExpected()).To(BeTrue(), fexec.ErrorDesc)
+			// nothing was added by this process, the tables of the previous
+			// one are deleted
+			Expect(fakeNFT.Scripts).NotTo(BeEmpty())
+			for _, script := range fakeNFT.Scripts {
+				Expect(script).To(Equal("delete table ip ovn-kubernetes\ndelete table ip6 ovn-kubernetes\n"))
+			}
+		})
+	})
+})
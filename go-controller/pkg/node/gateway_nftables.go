@@ -0,0 +1,410 @@
+// +build linux
+
+package node
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+	"k8s.io/klog/v2"
+	utilnet "k8s.io/utils/net"
+)
+
+const (
+	// nftablesTable is the name of the nftables tables, one per IP family,
+	// holding all the gateway rules
+	nftablesTable = "ovn-kubernetes"
+
+	nftNodePortDNATMap       = "nodeport-dnat"
+	nftNodePortRedirectMap   = "nodeport-redirect"
+	nftExternalIPDNATMap     = "externalip-dnat"
+	nftExternalIPRedirectMap = "externalip-redirect"
+)
+
+// nftablesRuleManager programs the gateway rules with nftables. Each IP family
+// has its own table, where the service rules are elements of four maps,
+// keyed by NodePort or by external IP and port, and looked up by a handful
+// of fixed rules. Syncing replaces the whole tables atomically in a single
+// transaction, and adding or deleting the rules of a service only adds or
+// deletes map elements.
+type nftablesRuleManager struct {
+	sync.Mutex
+	// initialized is true once the tables exist
+	initialized bool
+	// rules are the service rules in the maps, by map element key
+	rules map[nftMapKey]serviceNATRule
+	// localNATRules are the local gateway interfaces and their subnets
+	localNATRules []nftLocalNATRule
+	// hostFirewallRules are the rules of the host firewall chain
+	hostFirewallRules []hostFirewallRule
+}
+
+// nftMapKey identifies a service rule by the key of its map element. A
+// NodePort or external IP and port has a single rule, in whichever map
+// matches its target.
+type nftMapKey struct {
+	family string
+	key    string
+}
+
+type nftLocalNATRule struct {
+	ifname string
+	cidr   *net.IPNet
+}
+
+func newNFTablesRuleManager() *nftablesRuleManager {
+	return &nftablesRuleManager{
+		rules: map[nftMapKey]serviceNATRule{},
+	}
+}
+
+func (m *nftablesRuleManager) initServiceRules(mode config.GatewayMode) error {
+	// rules left behind by the iptables backend would still apply
+	cleanupGatewayIPTables()
+
+	m.Lock()
+	defer m.Unlock()
+	return m.apply(m.tablesScript(m.rules, m.localNATRules, m.hostFirewallRules), m.rules)
+}
+
+func (m *nftablesRuleManager) initLocalGatewayNATRules(ifname string, cidr *net.IPNet) error {
+	m.Lock()
+	defer m.Unlock()
+	for _, r := range m.localNATRules {
+		if r.ifname == ifname && r.cidr.String() == cidr.String() {
+			return nil
+		}
+	}
+	localNATRules := append(append([]nftLocalNATRule{}, m.localNATRules...), nftLocalNATRule{ifname: ifname, cidr: cidr})
+	if err := m.apply(m.tablesScript(m.rules, localNATRules, m.hostFirewallRules), m.rules); err != nil {
+		return err
+	}
+	m.localNATRules = localNATRules
+	return nil
+}
+
+func (m *nftablesRuleManager) cleanupLocalGatewayNATRules(ifname string, cidr *net.IPNet) error {
+	m.Lock()
+	defer m.Unlock()
+	if !m.initialized {
+		// the rules of the tables were not added by this process, but may
+		// have been left by a previous one: the tables are added back with
+		// the rules of this process on initServiceRules
+		cleanupNFTablesRules()
+		return nil
+	}
+	localNATRules := []nftLocalNATRule{}
+	for _, r := range m.localNATRules {
+		if r.ifname != ifname || r.cidr.String() != cidr.String() {
+			localNATRules = append(localNATRules, r)
+		}
+	}
+	if len(localNATRules) == len(m.localNATRules) {
+		return nil
+	}
+	if err := m.apply(m.tablesScript(m.rules, localNATRules, m.hostFirewallRules), m.rules); err != nil {
+		return err
+	}
+	m.localNATRules = localNATRules
+	return nil
+}
+
+func (m *nftablesRuleManager) addServiceRules(rules []serviceNATRule) error {
+	m.Lock()
+	defer m.Unlock()
+	if !m.initialized {
+		return fmt.Errorf("nftables tables %s are not initialized", nftablesTable)
+	}
+	newRules := m.copyRules()
+	var script strings.Builder
+	for _, r := range rules {
+		key, mapName, value := nftMapElement(r)
+		if existing, ok := newRules[key]; ok {
+			if existing == r {
+				continue
+			}
+			_, existingMapName, existingValue := nftMapElement(existing)
+			fmt.Fprintf(&script, "delete element %s %s %s { %s : %s }\n",
+				key.family, nftablesTable, existingMapName, key.key, existingValue)
+		}
+		fmt.Fprintf(&script, "add element %s %s %s { %s : %s }\n", key.family, nftablesTable, mapName, key.key, value)
+		newRules[key] = r
+	}
+	return m.apply(script.String(), newRules)
+}
+
+func (m *nftablesRuleManager) delServiceRules(rules []serviceNATRule) error {
+	m.Lock()
+	defer m.Unlock()
+	if !m.initialized {
+		return nil
+	}
+	newRules := m.copyRules()
+	var script strings.Builder
+	for _, r := range rules {
+		key, mapName, value := nftMapElement(r)
+		if existing, ok := newRules[key]; !ok || existing != r {
+			continue
+		}
+		fmt.Fprintf(&script, "delete element %s %s %s { %s : %s }\n", key.family, nftablesTable, mapName, key.key, value)
+		delete(newRules, key)
+	}
+	return m.apply(script.String(), newRules)
+}
+
+func (m *nftablesRuleManager) syncServiceRules(rules []serviceNATRule) error {
+	m.Lock()
+	defer m.Unlock()
+	newRules := map[nftMapKey]serviceNATRule{}
+	for _, r := range rules {
+		key, _, _ := nftMapElement(r)
+		if existing, ok := newRules[key]; ok && existing != r {
+			klog.Warningf("Skipping service rule %+v, conflicting with %+v", r, existing)
+			continue
+		}
+		newRules[key] = r
+	}
+	return m.apply(m.tablesScript(newRules, m.localNATRules, m.hostFirewallRules), newRules)
+}
+
+func (m *nftablesRuleManager) syncHostFirewallRules(rules []hostFirewallRule) error {
+	m.Lock()
+	defer m.Unlock()
+	if !m.initialized {
+		// the rules are added with the tables
+		m.hostFirewallRules = rules
+		return nil
+	}
+	if err := m.apply(m.tablesScript(m.rules, m.localNATRules, rules), m.rules); err != nil {
+		return err
+	}
+	m.hostFirewallRules = rules
+	return nil
+}
+
+func (m *nftablesRuleManager) cleanupServiceRules() {
+	m.Lock()
+	defer m.Unlock()
+	m.rules = map[nftMapKey]serviceNATRule{}
+	m.localNATRules = nil
+	m.initialized = false
+	cleanupNFTablesRules()
+}
+
+// apply applies script and, if it succeeds, records rules as the service
+// rules in the maps
+func (m *nftablesRuleManager) apply(script string, rules map[nftMapKey]serviceNATRule) error {
+	if script != "" {
+		nft, err := util.GetNFTablesHelper()
+		if err != nil {
+			return err
+		}
+		klog.V(5).Infof("Applying nftables script:\n%s", script)
+		if err := nft.Apply(script); err != nil {
+			return err
+		}
+	}
+	m.rules = rules
+	if !m.initialized {
+		m.initialized = script != ""
+	}
+	return nil
+}
+
+func (m *nftablesRuleManager) copyRules() map[nftMapKey]serviceNATRule {
+	rules := make(map[nftMapKey]serviceNATRule, len(m.rules))
+	for k, v := range m.rules {
+		rules[k] = v
+	}
+	return rules
+}
+
+// nftFamilies returns the nftables families of the cluster IP families
+func nftFamilies() []string {
+	var families []string
+	if config.IPv4Mode {
+		families = append(families, "ip")
+	}
+	if config.IPv6Mode {
+		families = append(families, "ip6")
+	}
+	return families
+}
+
+// tablesScript returns the script replacing the tables of all cluster IP
+// families with ones holding rules, localNATRules and hostFirewallRules.
+// Adding a table before deleting it makes the deletion succeed whether it
+// existed or not.
+func (m *nftablesRuleManager) tablesScript(rules map[nftMapKey]serviceNATRule, localNATRules []nftLocalNATRule,
+	hostFirewallRules []hostFirewallRule) string {
+	var script strings.Builder
+	for _, family := range nftFamilies() {
+		addrType, addrMatch := "ipv4_addr", "ip"
+		if family == "ip6" {
+			addrType, addrMatch = "ipv6_addr", "ip6"
+		}
+
+		fmt.Fprintf(&script, "add table %s %s\n", family, nftablesTable)
+		fmt.Fprintf(&script, "delete table %s %s\n", family, nftablesTable)
+		fmt.Fprintf(&script, "table %s %s {\n", family, nftablesTable)
+
+		mapTypes := []struct{ name, keyType, valueType string }{
+			{nftNodePortDNATMap, "inet_proto . inet_service", addrType + " . inet_service"},
+			{nftNodePortRedirectMap, "inet_proto . inet_service", "inet_service"},
+			{nftExternalIPDNATMap, addrType + " . inet_proto . inet_service", addrType + " . inet_service"},
+			{nftExternalIPRedirectMap, addrType + " . inet_proto . inet_service", "inet_service"},
+		}
+		for _, mt := range mapTypes {
+			fmt.Fprintf(&script, "\tmap %s {\n\t\ttype %s : %s\n", mt.name, mt.keyType, mt.valueType)
+			var elements []string
+			for key, r := range rules {
+				if _, mapName, value := nftMapElement(r); key.family == family && mapName == mt.name {
+					elements = append(elements, key.key+" : "+value)
+				}
+			}
+			if len(elements) > 0 {
+				sort.Strings(elements)
+				fmt.Fprintf(&script, "\t\telements = { %s }\n", strings.Join(elements, ", "))
+			}
+			script.WriteString("\t}\n")
+		}
+
+		l4 := "meta l4proto { tcp, udp, sctp }"
+		script.WriteString("\tchain services {\n")
+		fmt.Fprintf(&script, "\t\t%s dnat %s addr . port to %s daddr . meta l4proto . th dport map @%s\n",
+			l4, addrMatch, addrMatch, nftExternalIPDNATMap)
+		fmt.Fprintf(&script, "\t\t%s redirect to : %s daddr . meta l4proto . th dport map @%s\n",
+			l4, addrMatch, nftExternalIPRedirectMap)
+		fmt.Fprintf(&script, "\t\t%s fib daddr type local dnat %s addr . port to meta l4proto . th dport map @%s\n",
+			l4, addrMatch, nftNodePortDNATMap)
+		fmt.Fprintf(&script, "\t\t%s fib daddr type local redirect to : meta l4proto . th dport map @%s\n",
+			l4, nftNodePortRedirectMap)
+		script.WriteString("\t}\n")
+		script.WriteString("\tchain nat-prerouting {\n\t\ttype nat hook prerouting priority -100; policy accept;\n\t\tjump services\n\t}\n")
+		script.WriteString("\tchain nat-output {\n\t\ttype nat hook output priority -100; policy accept;\n\t\tjump services\n\t}\n")
+
+		var forward, input, postrouting []string
+		for _, r := range localNATRules {
+			if (family == "ip6") != utilnet.IsIPv6CIDR(r.cidr) {
+				continue
+			}
+			forward = append(forward,
+				fmt.Sprintf("iifname %q accept", r.ifname),
+				fmt.Sprintf("oifname %q ct state related,established accept", r.ifname))
+			input = append(input, fmt.Sprintf("iifname %q accept comment \"from OVN to localhost\"", r.ifname))
+			subnet := &net.IPNet{IP: r.cidr.IP.Mask(r.cidr.Mask), Mask: r.cidr.Mask}
+			postrouting = append(postrouting, fmt.Sprintf("%s saddr %s masquerade", addrMatch, subnet))
+		}
+		if len(localNATRules) > 0 {
+			writeNFTChain(&script, "filter-forward", "type filter hook forward priority 0; policy accept;", forward)
+			writeNFTChain(&script, "filter-input", "type filter hook input priority 0; policy accept;", input)
+			writeNFTChain(&script, "nat-postrouting", "type nat hook postrouting priority 100; policy accept;", postrouting)
+		}
+		if len(hostFirewallRules) > 0 {
+			// the mangle priority sees the NodePort traffic before it's DNATed
+			writeNFTChain(&script, "host-firewall", "type filter hook prerouting priority -150; policy accept;",
+				nftHostFirewallRules(family, hostFirewallRules))
+		}
+		script.WriteString("}\n")
+	}
+	return script.String()
+}
+
+func writeNFTChain(script *strings.Builder, name, hook string, rules []string) {
+	fmt.Fprintf(script, "\tchain %s {\n\t\t%s\n", name, hook)
+	for _, rule := range rules {
+		fmt.Fprintf(script, "\t\t%s\n", rule)
+	}
+	script.WriteString("\t}\n")
+}
+
+// nftHostFirewallRules returns the rules of the host firewall chain of family,
+// dropping the new connections from outside the node to the ports of rules
+// unless their source is allowed
+func nftHostFirewallRules(family string, rules []hostFirewallRule) []string {
+	var nftRules []string
+	for _, r := range rules {
+		match := fmt.Sprintf("iifname != \"lo\" fib daddr type local ct state new meta l4proto %s th dport %d",
+			strings.ToLower(string(r.protocol)), r.port)
+		var allowed []string
+		for _, cidr := range mergeCIDRs(r.allowed) {
+			if (family == "ip6") == utilnet.IsIPv6CIDR(cidr) {
+				allowed = append(allowed, cidr.String())
+			}
+		}
+		if len(allowed) > 0 {
+			match += fmt.Sprintf(" %s saddr != { %s }", family, strings.Join(allowed, ", "))
+		}
+		nftRules = append(nftRules, match+" drop")
+	}
+	return nftRules
+}
+
+// nftMapElement returns the map element key, the map and the value of a
+// service rule
+func nftMapElement(r serviceNATRule) (nftMapKey, string, string) {
+	key := nftMapKey{family: "ip"}
+	if r.ipv6 {
+		key.family = "ip6"
+	}
+	proto := strings.ToLower(string(r.protocol))
+	if r.dstIP == "" {
+		key.key = fmt.Sprintf("%s . %d", proto, r.dstPort)
+	} else {
+		key.key = fmt.Sprintf("%s . %s . %d", r.dstIP, proto, r.dstPort)
+	}
+
+	var value string
+	if r.toIP != "" {
+		value = fmt.Sprintf("%s . %d", r.toIP, r.toPort)
+	} else {
+		value = fmt.Sprintf("%d", r.toPort)
+	}
+
+	var mapName string
+	switch {
+	case r.dstIP == "" && r.toIP != "":
+		mapName = nftNodePortDNATMap
+	case r.dstIP == "":
+		mapName = nftNodePortRedirectMap
+	case r.toIP != "":
+		mapName = nftExternalIPDNATMap
+	default:
+		mapName = nftExternalIPRedirectMap
+	}
+	return key, mapName, value
+}
+
+// cleanupNFTablesRules deletes the nftables tables of the gateway, if nft is
+// available and a previous nftables backend left them
+func cleanupNFTablesRules() {
+	nft, err := util.GetNFTablesHelper()
+	if err != nil {
+		klog.V(5).Infof("Not cleaning up nftables tables %s: %v", nftablesTable, err)
+		return
+	}
+	tables, err := nft.ListTables()
+	if err != nil {
+		klog.V(5).Infof("Not cleaning up nftables tables %s: %v", nftablesTable, err)
+		return
+	}
+	var script strings.Builder
+	for _, table := range tables {
+		for _, family := range []string{"ip", "ip6"} {
+			if table == family+" "+nftablesTable {
+				fmt.Fprintf(&script, "delete table %s %s\n", family, nftablesTable)
+			}
+		}
+	}
+	if script.Len() == 0 {
+		return
+	}
+	if err := nft.Apply(script.String()); err != nil {
+		klog.Errorf("Failed to clean up nftables tables %s: %v", nftablesTable, err)
+	}
+}
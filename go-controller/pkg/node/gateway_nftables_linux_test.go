@@ -0,0 +1,135 @@
+// +build linux
+
+package node
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	ovntest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var _ = Describe("Node Operations nftables gateway rules", func() {
+	var (
+		fakeNFT *util.FakeNFTables
+		m       *nftablesRuleManager
+	)
+
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "ns"},
+		Spec: v1.ServiceSpec{
+			Type:        v1.ServiceTypeNodePort,
+			ClusterIP:   "10.96.0.10",
+			ClusterIPs:  []string{"10.96.0.10"},
+			ExternalIPs: []string{"192.168.1.100"},
+			Ports: []v1.ServicePort{{
+				Protocol:   v1.ProtocolTCP,
+				Port:       80,
+				NodePort:   30080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+
+	BeforeEach(func() {
+		config.PrepareTestConfig()
+		config.IPv4Mode = true
+		config.IPv6Mode = false
+		config.Gateway.RuleBackend = config.GatewayRuleBackendNFTables
+		fakeNFT = util.SetFakeNFTablesHelper()
+		m = newNFTablesRuleManager()
+	})
+
+	AfterEach(func() {
+		util.SetNFTablesHelper(nil)
+	})
+
+	It("replaces the tables with the service rules in maps", func() {
+		Expect(m.initServiceRules(config.GatewayModeShared)).To(Succeed())
+		Expect(m.syncServiceRules(getGatewayServiceRules(service, false))).To(Succeed())
+
+		script := fakeNFT.LastScript()
+		Expect(script).To(ContainSubstring("add table ip ovn-kubernetes\ndelete table ip ovn-kubernetes\n"))
+		Expect(script).NotTo(ContainSubstring("ip6"))
+		Expect(script).To(ContainSubstring("elements = { tcp . 30080 : 10.96.0.10 . 80 }"))
+		Expect(script).To(ContainSubstring("elements = { 192.168.1.100 . tcp . 80 : 10.96.0.10 . 80 }"))
+		Expect(script).To(ContainSubstring("type nat hook prerouting priority -100"))
+		Expect(script).NotTo(ContainSubstring("masquerade"))
+	})
+
+	It("redirects to the target port when the service has a local host network endpoint", func() {
+		Expect(m.initServiceRules(config.GatewayModeShared)).To(Succeed())
+		Expect(m.syncServiceRules(getGatewayServiceRules(service, true))).To(Succeed())
+
+		script := fakeNFT.LastScript()
+		Expect(script).To(ContainSubstring(fmt.Sprintf("map %s {\n\t\ttype inet_proto . inet_service : inet_service\n\t\telements = { tcp . 30080 : 8080 }", nftNodePortRedirectMap)))
+		Expect(script).To(ContainSubstring("elements = { 192.168.1.100 . tcp . 80 : 8080 }"))
+	})
+
+	It("adds and deletes the map elements of a service", func() {
+		Expect(m.initServiceRules(config.GatewayModeShared)).To(Succeed())
+		rules := getGatewayServiceRules(service, false)
+
+		Expect(m.addServiceRules(rules)).To(Succeed())
+		Expect(fakeNFT.LastScript()).To(Equal(
+			"add element ip ovn-kubernetes nodeport-dnat { tcp . 30080 : 10.96.0.10 . 80 }\n" +
+				"add element ip ovn-kubernetes externalip-dnat { 192.168.1.100 . tcp . 80 : 10.96.0.10 . 80 }\n"))
+
+		// adding them again is a no-op
+		scripts := len(fakeNFT.Scripts)
+		Expect(m.addServiceRules(rules)).To(Succeed())
+		Expect(fakeNFT.Scripts).To(HaveLen(scripts))
+
+		// switching to a local host network endpoint replaces the elements
+		Expect(m.addServiceRules(getGatewayServiceRules(service, true)[:1])).To(Succeed())
+		Expect(fakeNFT.LastScript()).To(Equal(
+			"delete element ip ovn-kubernetes nodeport-dnat { tcp . 30080 : 10.96.0.10 . 80 }\n" +
+				"add element ip ovn-kubernetes nodeport-redirect { tcp . 30080 : 8080 }\n"))
+
+		Expect(m.delServiceRules(rules[1:])).To(Succeed())
+		Expect(fakeNFT.LastScript()).To(Equal(
+			"delete element ip ovn-kubernetes externalip-dnat { 192.168.1.100 . tcp . 80 : 10.96.0.10 . 80 }\n"))
+		Expect(m.rules).To(HaveLen(1))
+	})
+
+	It("fails to add service rules before the tables are initialized", func() {
+		Expect(m.addServiceRules(getGatewayServiceRules(service, false))).NotTo(Succeed())
+		Expect(fakeNFT.Scripts).To(BeEmpty())
+	})
+
+	It("keeps the previous rules when a transaction fails", func() {
+		Expect(m.initServiceRules(config.GatewayModeShared)).To(Succeed())
+		fakeNFT.Err = fmt.Errorf("nft failure")
+		Expect(m.addServiceRules(getGatewayServiceRules(service, false))).NotTo(Succeed())
+		Expect(m.rules).To(BeEmpty())
+	})
+
+	It("lets the traffic of the local gateway interface through", func() {
+		config.IPv6Mode = true
+		Expect(m.initLocalGatewayNATRules(localnetGatewayNextHopPort, ovntest.MustParseIPNet("169.254.33.2/24"))).To(Succeed())
+		Expect(m.initServiceRules(config.GatewayModeLocal)).To(Succeed())
+
+		script := fakeNFT.LastScript()
+		Expect(script).To(ContainSubstring(fmt.Sprintf("iifname %q accept", localnetGatewayNextHopPort)))
+		Expect(script).To(ContainSubstring("ip saddr 169.254.33.0/24 masquerade"))
+		Expect(script).To(ContainSubstring("table ip6 ovn-kubernetes {"))
+		Expect(script).NotTo(ContainSubstring("ip6 saddr"))
+	})
+
+	It("only deletes the tables left by a previous nftables backend", func() {
+		cleanupNFTablesRules()
+		Expect(fakeNFT.Scripts).To(BeEmpty())
+
+		fakeNFT.Tables = []string{"ip nat", "ip6 ovn-kubernetes"}
+		cleanupNFTablesRules()
+		Expect(fakeNFT.Scripts).To(Equal([]string{"delete table ip6 ovn-kubernetes\n"}))
+	})
+})
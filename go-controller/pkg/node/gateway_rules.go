@@ -0,0 +1,193 @@
+// +build linux
+
+package node
+
+import (
+	"net"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+	kapi "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	utilnet "k8s.io/utils/net"
+)
+
+// serviceNATRule is a host NAT rule that sends the traffic to a NodePort,
+// external IP or load balancer IP of a service to the service, or to a host
+// network endpoint of it
+type serviceNATRule struct {
+	protocol kapi.Protocol
+	ipv6     bool
+	// dstIP is the destination IP of the traffic. It's empty for NodePort
+	// rules, which match the traffic to any local address.
+	dstIP   string
+	dstPort int32
+	// toIP is the IP to DNAT the traffic to. When it's empty the traffic is
+	// redirected to toPort on the host.
+	toIP   string
+	toPort int32
+}
+
+// hostFirewallRule drops the new connections from outside the node to a local
+// port, unless their source is in one of the allowed CIDRs
+type hostFirewallRule struct {
+	protocol kapi.Protocol
+	port     int32
+	allowed  []*net.IPNet
+}
+
+// gatewayRuleManager programs the host rules of the node gateway: the NAT
+// rules of services, the rules letting the traffic of the local gateway
+// interfaces through, and the host firewall rules
+type gatewayRuleManager interface {
+	// initServiceRules sets up the host to hand the traffic it receives or
+	// sends to the service rules, for a gateway in the given mode
+	initServiceRules(mode config.GatewayMode) error
+	// initLocalGatewayNATRules accepts the traffic from and to the local
+	// gateway interface ifname, and masquerades the traffic from cidr
+	initLocalGatewayNATRules(ifname string, cidr *net.IPNet) error
+	// cleanupLocalGatewayNATRules deletes the rules added by
+	// initLocalGatewayNATRules for ifname and cidr
+	cleanupLocalGatewayNATRules(ifname string, cidr *net.IPNet) error
+	// addServiceRules adds service rules
+	addServiceRules(rules []serviceNATRule) error
+	// delServiceRules deletes service rules
+	delServiceRules(rules []serviceNATRule) error
+	// syncServiceRules replaces all the service rules with rules
+	syncServiceRules(rules []serviceNATRule) error
+	// cleanupServiceRules deletes all the service rules and stops handing
+	// them traffic
+	cleanupServiceRules()
+	// syncHostFirewallRules replaces all the host firewall rules with rules
+	syncHostFirewallRules(rules []hostFirewallRule) error
+}
+
+var nftablesRules *nftablesRuleManager
+
+// getGatewayRuleManager returns the rule manager of the configured backend
+func getGatewayRuleManager() gatewayRuleManager {
+	if config.Gateway.RuleBackend == config.GatewayRuleBackendNFTables {
+		if nftablesRules == nil {
+			nftablesRules = newNFTablesRuleManager()
+		}
+		return nftablesRules
+	}
+	return &iptablesRuleManager{}
+}
+
+// nodePortServiceRule DNATs the traffic to the NodePort of svcPort to
+// targetIP:targetPort
+func nodePortServiceRule(svcPort kapi.ServicePort, targetIP string, targetPort int32) serviceNATRule {
+	return serviceNATRule{
+		protocol: svcPort.Protocol,
+		ipv6:     utilnet.IsIPv6String(targetIP),
+		dstPort:  svcPort.NodePort,
+		toIP:     targetIP,
+		toPort:   targetPort,
+	}
+}
+
+// nodePortLocalServiceRule redirects the traffic to the NodePort of svcPort
+// to targetPort on the host; clusterIP selects the IP family
+func nodePortLocalServiceRule(svcPort kapi.ServicePort, clusterIP string, targetPort int32) serviceNATRule {
+	return serviceNATRule{
+		protocol: svcPort.Protocol,
+		ipv6:     utilnet.IsIPv6String(clusterIP),
+		dstPort:  svcPort.NodePort,
+		toPort:   targetPort,
+	}
+}
+
+// externalIPServiceRule DNATs the traffic to externalIP on the port of
+// svcPort to dstIP on the same port
+func externalIPServiceRule(svcPort kapi.ServicePort, externalIP, dstIP string) serviceNATRule {
+	return serviceNATRule{
+		protocol: svcPort.Protocol,
+		ipv6:     utilnet.IsIPv6String(externalIP),
+		dstIP:    externalIP,
+		dstPort:  svcPort.Port,
+		toIP:     dstIP,
+		toPort:   svcPort.Port,
+	}
+}
+
+// externalIPLocalServiceRule redirects the traffic to externalIP on the port
+// of svcPort to targetPort on the host
+func externalIPLocalServiceRule(svcPort kapi.ServicePort, externalIP string, targetPort int32) serviceNATRule {
+	return serviceNATRule{
+		protocol: svcPort.Protocol,
+		ipv6:     utilnet.IsIPv6String(externalIP),
+		dstIP:    externalIP,
+		dstPort:  svcPort.Port,
+		toPort:   targetPort,
+	}
+}
+
+// loadBalancerServiceRules DNATs the traffic to the load balancer IPs of svc
+// of the IP family of gatewayIP, on the port of svcPort, to
+// gatewayIP:targetPort
+func loadBalancerServiceRules(svc *kapi.Service, svcPort kapi.ServicePort, gatewayIP string, targetPort int32) []serviceNATRule {
+	var rules []serviceNATRule
+	isIPv6 := utilnet.IsIPv6String(gatewayIP)
+	for _, ing := range svc.Status.LoadBalancer.Ingress {
+		if ing.IP == "" || utilnet.IsIPv6String(ing.IP) != isIPv6 {
+			continue
+		}
+		rules = append(rules, serviceNATRule{
+			protocol: svcPort.Protocol,
+			ipv6:     isIPv6,
+			dstIP:    ing.IP,
+			dstPort:  svcPort.Port,
+			toIP:     gatewayIP,
+			toPort:   targetPort,
+		})
+	}
+	return rules
+}
+
+// getGatewayServiceRules returns the NodePort and ExternalIP rules of a
+// service. NodePort and ExternalIP traffic is DNAT'ed to the service port on
+// the service's ClusterIP, or redirected to the target port on the host if
+// the service has a local host network endpoint.
+func getGatewayServiceRules(service *kapi.Service, hasLocalHostEndpoint bool) []serviceNATRule {
+	rules := make([]serviceNATRule, 0)
+	clusterIPs := util.GetClusterIPs(service)
+	for _, svcPort := range service.Spec.Ports {
+		if util.ServiceTypeHasNodePort(service) {
+			err := util.ValidatePort(svcPort.Protocol, svcPort.NodePort)
+			if err != nil {
+				klog.Errorf("Skipping service: %s, invalid service NodePort: %v", svcPort.Name, err)
+				continue
+			}
+			err = util.ValidatePort(svcPort.Protocol, svcPort.Port)
+			if err != nil {
+				klog.Errorf("Skipping service: %s, invalid service port %v", svcPort.Name, err)
+				continue
+			}
+			for _, clusterIP := range clusterIPs {
+				if !hasLocalHostEndpoint {
+					rules = append(rules, nodePortServiceRule(svcPort, clusterIP, svcPort.Port))
+				} else {
+					// Port redirect host -> Nodeport -> host traffic directly to endpoint
+					rules = append(rules, nodePortLocalServiceRule(svcPort, clusterIP, int32(svcPort.TargetPort.IntValue())))
+				}
+			}
+		}
+		for _, externalIP := range service.Spec.ExternalIPs {
+			err := util.ValidatePort(svcPort.Protocol, svcPort.Port)
+			if err != nil {
+				klog.Errorf("Skipping service: %s, invalid service port %v", svcPort.Name, err)
+				continue
+			}
+			if clusterIP, err := util.MatchIPStringFamily(utilnet.IsIPv6String(externalIP), clusterIPs); err == nil {
+				if hasLocalHostEndpoint {
+					// Port redirect host -> ExternalIP -> host
+					rules = append(rules, externalIPLocalServiceRule(svcPort, externalIP, int32(svcPort.TargetPort.IntValue())))
+				} else {
+					rules = append(rules, externalIPServiceRule(svcPort, externalIP, clusterIP))
+				}
+			}
+		}
+	}
+	return rules
+}
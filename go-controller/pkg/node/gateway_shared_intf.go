@@ -16,6 +16,7 @@ import (
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
 
 	kapi "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
 	ktypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
 	utilnet "k8s.io/utils/net"
@@ -436,7 +437,7 @@ func (npw *nodePortWatcher) AddService(service *kapi.Service) {
 		// No endpoints exist yet so default to false
 		etpHostRules = false
 	} else {
-		etpHostRules = hasHostNetworkEndpoints(ep, &npw.nodeIPManager.addresses)
+		etpHostRules = npw.hasHostNetworkEndpoints(ep)
 	}
 
 	// If something didn't already do it add correct Service rules
@@ -494,7 +495,7 @@ func (npw *nodePortWatcher) DeleteService(service *kapi.Service) {
 }
 
 func (npw *nodePortWatcher) SyncServices(services []interface{}) {
-	keepIPTRules := []iptRule{}
+	keepRules := []serviceNATRule{}
 	for _, serviceInterface := range services {
 		name := ktypes.NamespacedName{Namespace: serviceInterface.(*kapi.Service).Namespace, Name: serviceInterface.(*kapi.Service).Name}
 
@@ -511,22 +512,22 @@ func (npw *nodePortWatcher) SyncServices(services []interface{}) {
 			continue
 		}
 
-		hasHostNet := hasHostNetworkEndpoints(ep, &npw.nodeIPManager.addresses)
+		hasHostNet := npw.hasHostNetworkEndpoints(ep)
 		npw.getAndSetServiceInfo(name, service, hasHostNet)
 		// Delete OF rules for service if they exist
 		npw.updateServiceFlowCache(service, false, hasHostNet)
 		npw.updateServiceFlowCache(service, true, hasHostNet)
 		// Add correct iptables rules only for Full mode
 		if !npw.smartNICMode {
-			keepIPTRules = append(keepIPTRules, getGatewayIPTRules(service, hasHostNet)...)
+			keepRules = append(keepRules, getGatewayServiceRules(service, hasHostNet)...)
 		}
 	}
 	// sync OF rules once
 	npw.ofm.requestFlowSync()
 	// sync IPtables rules once only for Full mode
 	if !npw.smartNICMode {
-		for _, chain := range []string{iptableNodePortChain, iptableExternalIPChain} {
-			recreateIPTRules("nat", chain, keepIPTRules)
+		if err := getGatewayRuleManager().syncServiceRules(keepRules); err != nil {
+			klog.Errorf("Failed to sync service rules: %v", err)
 		}
 	}
 }
@@ -548,7 +549,7 @@ func (npw *nodePortWatcher) AddEndpoints(ep *kapi.Endpoints) {
 	}
 
 	klog.V(5).Infof("Adding endpoints %s in namespace %s", ep.Name, ep.Namespace)
-	etpHostRules = hasHostNetworkEndpoints(ep, &npw.nodeIPManager.addresses)
+	etpHostRules = npw.hasHostNetworkEndpoints(ep)
 
 	// Here we make sure the correct rules are programmed whenever an AddEndpoint
 	// event is received, only alter flows if we need to, i.e if cache wasn't
@@ -568,6 +569,69 @@ func (npw *nodePortWatcher) AddEndpoints(ep *kapi.Endpoints) {
 
 }
 
+// hasHostNetworkEndpoints returns true if the service has host network endpoints on this node.
+// As kube-proxy does with ProxyTerminatingEndpoints, when none of the endpoints on this node
+// are ready, the ones still serving while they terminate are considered instead.
+func (npw *nodePortWatcher) hasHostNetworkEndpoints(ep *kapi.Endpoints) bool {
+	if hasHostNetworkEndpoints(ep, &npw.nodeIPManager.addresses) {
+		return true
+	}
+	if countLocalEndpoints(ep, npw.nodeIPManager.nodeName) > 0 {
+		return false
+	}
+	return npw.hasServingTerminatingHostNetworkEndpoints(ep.Namespace, ep.Name)
+}
+
+// hasServingTerminatingHostNetworkEndpoints returns true if the service has host network
+// endpoints on this node that are terminating but still serving. The Endpoints API does
+// not list terminating endpoints, so they are taken from the EndpointSlices of the service.
+func (npw *nodePortWatcher) hasServingTerminatingHostNetworkEndpoints(namespace, name string) bool {
+	slices, err := npw.watchFactory.GetEndpointSlices(namespace, name)
+	if err != nil {
+		klog.Errorf("Failed to get the EndpointSlices of service %s/%s: %v", namespace, name, err)
+		return false
+	}
+	for _, slice := range slices {
+		for _, endpoint := range slice.Endpoints {
+			if !util.IsServingTerminatingEndpoint(endpoint.Conditions) {
+				continue
+			}
+			for _, ip := range endpoint.Addresses {
+				if npw.nodeIPManager.addresses.Has(ip) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// SyncEndpointSlice updates the rules of the service of slice if its host network
+// endpoints changed. The endpoints turning serving and terminating do not change the
+// Endpoints of the service, only its EndpointSlices.
+func (npw *nodePortWatcher) SyncEndpointSlice(slice *discovery.EndpointSlice) {
+	svcName := slice.Labels[discovery.LabelServiceName]
+	if svcName == "" {
+		return
+	}
+	name := ktypes.NamespacedName{Namespace: slice.Namespace, Name: svcName}
+	svcConfig, exists := npw.getServiceInfo(name)
+	if !exists {
+		return
+	}
+	ep, err := npw.watchFactory.GetEndpoint(slice.Namespace, svcName)
+	if err != nil {
+		// the rules are updated on the Endpoints events
+		return
+	}
+	if npw.hasHostNetworkEndpoints(ep) != svcConfig.etpHostRules {
+		klog.V(5).Infof("EndpointSlice %s in namespace %s is updating the rules of service %s",
+			slice.Name, slice.Namespace, svcName)
+		npw.DeleteEndpoints(ep)
+		npw.AddEndpoints(ep)
+	}
+}
+
 func (npw *nodePortWatcher) DeleteEndpoints(ep *kapi.Endpoints) {
 	var etpHostRules = false
 
@@ -602,8 +666,8 @@ func (npw *nodePortWatcher) UpdateEndpoints(old *kapi.Endpoints, new *kapi.Endpo
 	}
 
 	// Update rules if hasHostNetworkEndpoints status changed
-	etpHostRulesNew := hasHostNetworkEndpoints(new, &npw.nodeIPManager.addresses)
-	if hasHostNetworkEndpoints(old, &npw.nodeIPManager.addresses) != etpHostRulesNew {
+	etpHostRulesNew := npw.hasHostNetworkEndpoints(new)
+	if npw.hasHostNetworkEndpoints(old) != etpHostRulesNew {
 		npw.DeleteEndpoints(old)
 		npw.AddEndpoints(new)
 	}
@@ -642,7 +706,7 @@ func (npwipt *nodePortWatcherIptables) DeleteService(service *kapi.Service) {
 }
 
 func (npwipt *nodePortWatcherIptables) SyncServices(services []interface{}) {
-	keepIPTRules := []iptRule{}
+	keepRules := []serviceNATRule{}
 	for _, serviceInterface := range services {
 		service, ok := serviceInterface.(*kapi.Service)
 		if !ok {
@@ -651,12 +715,12 @@ func (npwipt *nodePortWatcherIptables) SyncServices(services []interface{}) {
 			continue
 		}
 		// Add correct iptables rules
-		keepIPTRules = append(keepIPTRules, getGatewayIPTRules(service, false)...)
+		keepRules = append(keepRules, getGatewayServiceRules(service, false)...)
 	}
 
 	// sync IPtables rules once
-	for _, chain := range []string{iptableNodePortChain, iptableExternalIPChain} {
-		recreateIPTRules("nat", chain, keepIPTRules)
+	if err := getGatewayRuleManager().syncServiceRules(keepRules); err != nil {
+		klog.Errorf("Failed to sync service rules: %v", err)
 	}
 }
 
@@ -666,7 +730,7 @@ func (npwipt *nodePortWatcherIptables) SyncServices(services []interface{}) {
 //    the return traffic can be steered back to OVN logical topology
 // -- to handle host -> service access, via masquerading from the host to OVN GR
 // -- to handle external -> service(ExternalTrafficPolicy: Local) -> host access without SNAT
-func newSharedGatewayOpenFlowManager(gwBridge, exGWBridge *bridgeConfiguration) (*openflowManager, error) {
+func newSharedGatewayOpenFlowManager(gwBridge, exGWBridge *bridgeConfiguration, egressBridges map[string]*bridgeConfiguration) (*openflowManager, error) {
 	dftFlows, err := flowsForDefaultBridge(gwBridge.ofPortPhys, gwBridge.macAddress.String(), gwBridge.ofPortPatch,
 		gwBridge.ofPortHost, gwBridge.ips)
 	if err != nil {
@@ -684,6 +748,9 @@ func newSharedGatewayOpenFlowManager(gwBridge, exGWBridge *bridgeConfiguration)
 		flowMutex:             sync.Mutex{},
 		exGWFlowCache:         make(map[string][]string),
 		exGWFlowMutex:         sync.Mutex{},
+		flowSync:              newFlowSyncTracker(),
+		exGWFlowSync:          newFlowSyncTracker(),
+		egressBridges:         make(map[string]*egressBridgeFlows, len(egressBridges)),
 		flowChan:              make(chan struct{}, 1),
 	}
 
@@ -698,6 +765,15 @@ func newSharedGatewayOpenFlowManager(gwBridge, exGWBridge *bridgeConfiguration)
 		ofm.updateExBridgeFlowCacheEntry("DEFAULT", exGWBridgeDftFlows)
 	}
 
+	// the bridges of the named egress interfaces only carry the egress
+	// traffic of pods out and its replies back, like the ex gw bridge
+	for name, egressBridge := range egressBridges {
+		ofm.egressBridges[name] = newEgressBridgeFlows(egressBridge)
+		ofm.updateEgressBridgeFlowCacheEntry(name, "NORMAL", []string{fmt.Sprintf("table=0,priority=0,actions=%s\n", util.NormalAction)})
+		ofm.updateEgressBridgeFlowCacheEntry(name, "DEFAULT", commonFlows(egressBridge.ofPortPhys,
+			egressBridge.macAddress.String(), egressBridge.ofPortPatch, egressBridge.ofPortHost))
+	}
+
 	// defer flowSync until syncService() to prevent the existing service OpenFlows being deleted
 	return ofm, nil
 }
@@ -1073,8 +1149,8 @@ func newSharedGateway(nodeName string, subnets []*net.IPNet, gwNextHops []net.IP
 	klog.Info("Creating new shared gateway")
 	gw := &gateway{}
 
-	gwBridge, exGwBridge, err := gatewayInitInternal(
-		nodeName, gwIntf, egressGWIntf, subnets, gwNextHops, gwIPs, nodeAnnotator)
+	gwBridge, exGwBridge, egressBridges, err := gatewayInitInternal(
+		nodeName, gwIntf, egressGWIntf, config.Gateway.EgressInterfaces, subnets, gwNextHops, gwIPs, nodeAnnotator)
 	if err != nil {
 		return nil, err
 	}
@@ -1087,17 +1163,25 @@ func newSharedGateway(nodeName string, subnets []*net.IPNet, gwNextHops []net.IP
 		}
 	}
 
-	if exGwBridge != nil {
+	if exGwBridge != nil || len(egressBridges) > 0 {
 		gw.readyFunc = func() (bool, error) {
 			ready, err := gatewayReady(gwBridge.patchPort)
-			if err != nil {
+			if err != nil || !ready {
 				return false, err
 			}
-			exGWReady, err := gatewayReady(exGwBridge.patchPort)
-			if err != nil {
-				return false, err
+			if exGwBridge != nil {
+				exGWReady, err := gatewayReady(exGwBridge.patchPort)
+				if err != nil || !exGWReady {
+					return false, err
+				}
 			}
-			return ready && exGWReady, nil
+			for _, egressBridge := range egressBridges {
+				egressReady, err := gatewayReady(egressBridge.patchPort)
+				if err != nil || !egressReady {
+					return false, err
+				}
+			}
+			return true, nil
 		}
 	} else {
 		gw.readyFunc = func() (bool, error) {
@@ -1119,7 +1203,13 @@ func newSharedGateway(nodeName string, subnets []*net.IPNet, gwNextHops []net.IP
 				return err
 			}
 		}
-		gw.openflowManager, err = newSharedGatewayOpenFlowManager(gwBridge, exGwBridge)
+		for _, egressBridge := range egressBridges {
+			err = setBridgeOfPorts(egressBridge)
+			if err != nil {
+				return err
+			}
+		}
+		gw.openflowManager, err = newSharedGatewayOpenFlowManager(gwBridge, exGwBridge, egressBridges)
 		if err != nil {
 			return err
 		}
@@ -1132,6 +1222,10 @@ func newSharedGateway(nodeName string, subnets []*net.IPNet, gwNextHops []net.IP
 			if err != nil {
 				return err
 			}
+			if config.Gateway.AnnounceServiceIPs {
+				klog.Info("Creating Shared Gateway Service IP Announcer")
+				gw.serviceIPAnnouncer = newServiceIPAnnouncer(nodeName, gwBridge, watchFactory)
+			}
 		} else {
 			// no service OpenFlows, request to sync flows now.
 			gw.openflowManager.requestFlowSync()
@@ -1168,7 +1262,7 @@ func newNodePortWatcher(patchPort, gwBridge, gwIntf string, ips []*net.IPNet, of
 	// NodePortIP:NodePort to ClusterServiceIP:Port. We don't need to do this while
 	// running on Smart-NIC or on Smart-NIC-Host.
 	if config.OvnKubeNode.Mode == types.NodeModeFull {
-		if err := initSharedGatewayIPTables(); err != nil {
+		if err := getGatewayRuleManager().initServiceRules(config.GatewayModeShared); err != nil {
 			return nil, err
 		}
 	}
@@ -1213,21 +1307,9 @@ func cleanupSharedGateway() error {
 	}
 
 	// Get the OVS bridge name from ovn-bridge-mappings
-	stdout, stderr, err = util.RunOVSVsctl("--if-exists", "get", "Open_vSwitch", ".",
-		"external_ids:ovn-bridge-mappings")
+	bridgeName, err := getPhysicalNetworkBridge()
 	if err != nil {
-		return fmt.Errorf("failed to get ovn-bridge-mappings stderr:%s (%v)", stderr, err)
-	}
-
-	// skip the existing mapping setting for the specified physicalNetworkName
-	bridgeName := ""
-	bridgeMappings := strings.Split(stdout, ",")
-	for _, bridgeMapping := range bridgeMappings {
-		m := strings.Split(bridgeMapping, ":")
-		if network := m[0]; network == types.PhysicalNetworkName {
-			bridgeName = m[1]
-			break
-		}
+		return err
 	}
 	if len(bridgeName) == 0 {
 		return nil
@@ -1238,7 +1320,7 @@ func cleanupSharedGateway() error {
 		return fmt.Errorf("failed to replace-flows on bridge %q stderr:%s (%v)", bridgeName, stderr, err)
 	}
 
-	cleanupSharedGatewayIPTChains()
+	getGatewayRuleManager().cleanupServiceRules()
 	return nil
 }
 
@@ -63,17 +63,7 @@ func setupLocalNodeAccessBridge(nodeName string, subnets []*net.IPNet) error {
 
 	var gatewayIfAddrs []*net.IPNet
 	for _, subnet := range subnets {
-		var gatewayNextHop net.IP
-		var gatewaySubnetMask net.IPMask
-		isSubnetIPv6 := utilnet.IsIPv6CIDR(subnet)
-		if isSubnetIPv6 {
-			gatewayNextHop = net.ParseIP(types.V6NodeLocalNATSubnetNextHop)
-			gatewaySubnetMask = net.CIDRMask(types.V6NodeLocalNATSubnetPrefix, 128)
-		} else {
-			gatewayNextHop = net.ParseIP(types.V4NodeLocalNATSubnetNextHop)
-			gatewaySubnetMask = net.CIDRMask(types.V4NodeLocalNATSubnetPrefix, 32)
-		}
-		gatewayNextHopCIDR := &net.IPNet{IP: gatewayNextHop, Mask: gatewaySubnetMask}
+		gatewayNextHopCIDR := localNodeAccessNextHopCIDR(utilnet.IsIPv6CIDR(subnet))
 		if err = util.LinkAddrAdd(link, gatewayNextHopCIDR); err != nil {
 			return err
 		}
@@ -83,7 +73,7 @@ func setupLocalNodeAccessBridge(nodeName string, subnets []*net.IPNet) error {
 	if config.Gateway.Mode == config.GatewayModeLocal {
 		// need to add masquerading for ovn-k8s-gw0 port for hostA -> service -> hostB via DGP
 		for _, ifaddr := range gatewayIfAddrs {
-			err = initLocalGatewayNATRules(localnetGatewayNextHopPort, ifaddr)
+			err = getGatewayRuleManager().initLocalGatewayNATRules(localnetGatewayNextHopPort, ifaddr)
 			if err != nil {
 				return fmt.Errorf("failed to add NAT rules for localnet gateway (%v)", err)
 			}
@@ -93,6 +83,20 @@ func setupLocalNodeAccessBridge(nodeName string, subnets []*net.IPNet) error {
 	return nil
 }
 
+// localNodeAccessNextHopCIDR returns the address of ovn-k8s-gw0 of an IP family
+func localNodeAccessNextHopCIDR(ipv6 bool) *net.IPNet {
+	if ipv6 {
+		return &net.IPNet{
+			IP:   net.ParseIP(types.V6NodeLocalNATSubnetNextHop),
+			Mask: net.CIDRMask(types.V6NodeLocalNATSubnetPrefix, 128),
+		}
+	}
+	return &net.IPNet{
+		IP:   net.ParseIP(types.V4NodeLocalNATSubnetNextHop),
+		Mask: net.CIDRMask(types.V4NodeLocalNATSubnetPrefix, 32),
+	}
+}
+
 // deletes the local bridge used for DGP and removes the corresponding iface, as well as OVS bridge mappings
 func deleteLocalNodeAccessBridge() error {
 	// remove br-local bridge
@@ -136,17 +140,17 @@ func deleteLocalNodeAccessBridge() error {
 // If A Service External Traffic Policy==Local and backend is a host-networked pod
 // we must steer traffic from host -> svc straight to the host instead of into OVN
 func addSharedGatewayIptRules(service *kapi.Service, hasLocalHostEndpoint bool) {
-	rules := getGatewayIPTRules(service, hasLocalHostEndpoint)
+	rules := getGatewayServiceRules(service, hasLocalHostEndpoint)
 
-	if err := addIptRules(rules); err != nil {
+	if err := getGatewayRuleManager().addServiceRules(rules); err != nil {
 		klog.Errorf("Failed to add iptables rules for service %s/%s: %v", service.Namespace, service.Name, err)
 	}
 }
 
 func delSharedGatewayIptRules(service *kapi.Service, hasLocalHostEndpoint bool) {
-	rules := getGatewayIPTRules(service, hasLocalHostEndpoint)
+	rules := getGatewayServiceRules(service, hasLocalHostEndpoint)
 
-	if err := delIptRules(rules); err != nil {
+	if err := getGatewayRuleManager().delServiceRules(rules); err != nil {
 		klog.Errorf("Failed to delete iptables rules for service %s/%s: %v", service.Namespace, service.Name, err)
 	}
 }
@@ -0,0 +1,152 @@
+// +build linux
+
+package node
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/factory"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes/fake"
+	utilpointer "k8s.io/utils/pointer"
+)
+
+var _ = Describe("Node Operations host network endpoints", func() {
+	const (
+		nodeName = "node1"
+		nodeIP   = "192.168.1.10"
+	)
+
+	var (
+		wf  *factory.WatchFactory
+		npw *nodePortWatcher
+	)
+
+	endpoints := func(ready ...v1.EndpointAddress) *v1.Endpoints {
+		return &v1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "ns"},
+			Subsets: []v1.EndpointSubset{
+				{Addresses: ready},
+			},
+		}
+	}
+
+	address := func(ip, node string) v1.EndpointAddress {
+		return v1.EndpointAddress{IP: ip, NodeName: &node}
+	}
+
+	endpoint := func(ip string, ready, serving, terminating bool) discovery.Endpoint {
+		return discovery.Endpoint{
+			Addresses: []string{ip},
+			Conditions: discovery.EndpointConditions{
+				Ready:       utilpointer.BoolPtr(ready),
+				Serving:     utilpointer.BoolPtr(serving),
+				Terminating: utilpointer.BoolPtr(terminating),
+			},
+			NodeName: utilpointer.StringPtr(nodeName),
+		}
+	}
+
+	start := func(endpoints ...discovery.Endpoint) {
+		fakeClient := &util.OVNClientset{
+			KubeClient: fake.NewSimpleClientset(&discovery.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "svc-ab23",
+					Namespace: "ns",
+					Labels:    map[string]string{discovery.LabelServiceName: "svc"},
+				},
+				AddressType: discovery.AddressTypeIPv4,
+				Endpoints:   endpoints,
+			}),
+		}
+		var err error
+		wf, err = factory.NewNodeWatchFactory(fakeClient, nodeName)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wf.Start()).To(Succeed())
+
+		npw = &nodePortWatcher{
+			nodeIPManager: &addressManager{
+				nodeName:  nodeName,
+				addresses: sets.NewString(nodeIP),
+			},
+			serviceInfo:  map[ktypes.NamespacedName]*serviceConfig{},
+			watchFactory: wf,
+		}
+	}
+
+	AfterEach(func() {
+		wf.Shutdown()
+	})
+
+	It("uses ready host network endpoints", func() {
+		start(endpoint(nodeIP, true, true, false))
+		Expect(npw.hasHostNetworkEndpoints(endpoints(address(nodeIP, nodeName)))).To(BeTrue())
+	})
+
+	It("falls back to host network endpoints still serving while they terminate", func() {
+		// the Endpoints do not list the terminating endpoints
+		start(endpoint(nodeIP, false, true, true))
+		Expect(npw.hasHostNetworkEndpoints(endpoints())).To(BeTrue())
+	})
+
+	It("ignores terminating endpoints when the node has ready endpoints", func() {
+		start(endpoint("10.244.0.5", true, true, false), endpoint(nodeIP, false, true, true))
+		Expect(npw.hasHostNetworkEndpoints(endpoints(address("10.244.0.5", nodeName)))).To(BeFalse())
+	})
+
+	It("ignores host network endpoints that are terminating and not serving", func() {
+		start(endpoint(nodeIP, false, false, true))
+		Expect(npw.hasHostNetworkEndpoints(endpoints())).To(BeFalse())
+	})
+
+	It("ignores the EndpointSlices of the services without rules", func() {
+		start(endpoint(nodeIP, false, true, true))
+		slices, err := wf.GetEndpointSlices("ns", "svc")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(slices).To(HaveLen(1))
+		npw.SyncEndpointSlice(slices[0])
+		Expect(npw.serviceInfo).To(BeEmpty())
+	})
+
+	It("starts the gateway on the watch factory of a master+node process", func() {
+		fakeClient := &util.OVNClientset{
+			KubeClient: fake.NewSimpleClientset(&discovery.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "svc-ab23",
+					Namespace: "ns",
+					Labels:    map[string]string{discovery.LabelServiceName: "svc"},
+				},
+				AddressType: discovery.AddressTypeIPv4,
+				Endpoints:   []discovery.Endpoint{endpoint(nodeIP, true, true, false)},
+			}),
+		}
+		var err error
+		wf, err = factory.NewMasterWatchFactory(fakeClient)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wf.Start()).To(Succeed())
+
+		npw = &nodePortWatcher{
+			ofm:          &openflowManager{flowChan: make(chan struct{}, 1)},
+			serviceInfo:  map[ktypes.NamespacedName]*serviceConfig{},
+			watchFactory: wf,
+			// no host rules, only the handlers are tested
+			smartNICMode: true,
+		}
+		g := &gateway{
+			initFunc:        func() error { return nil },
+			nodePortWatcher: npw,
+		}
+		Expect(g.Init(wf)).To(Succeed())
+
+		slices, err := wf.GetEndpointSlices("ns", "svc")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(slices).To(HaveLen(1))
+	})
+})
@@ -8,6 +8,7 @@ import (
 
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/factory"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/kube/healthcheck"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/metrics"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
 	"github.com/pkg/errors"
 
@@ -232,28 +233,60 @@ type openflowManager struct {
 	flowMutex     sync.Mutex
 	exGWFlowCache map[string][]string
 	exGWFlowMutex sync.Mutex
+	// flowSync and exGWFlowSync track the flows installed on the bridges
+	flowSync     *flowSyncTracker
+	exGWFlowSync *flowSyncTracker
+	// egressBridges are the bridges of the named egress interfaces, by name
+	egressBridges map[string]*egressBridgeFlows
 	// channel to indicate we need to update flows immediately
 	flowChan chan struct{}
 }
 
+// egressBridgeFlows holds the flow cache of the bridge of a named egress interface
+type egressBridgeFlows struct {
+	bridge    *bridgeConfiguration
+	flowCache map[string][]string
+	flowMutex sync.Mutex
+	flowSync  *flowSyncTracker
+}
+
+func newEgressBridgeFlows(bridge *bridgeConfiguration) *egressBridgeFlows {
+	return &egressBridgeFlows{
+		bridge:    bridge,
+		flowCache: make(map[string][]string),
+		flowSync:  newFlowSyncTracker(),
+	}
+}
+
 func (c *openflowManager) updateFlowCacheEntry(key string, flows []string) {
 	c.flowMutex.Lock()
 	defer c.flowMutex.Unlock()
+	c.flowSync.update(key, c.flowCache[key], flows)
 	c.flowCache[key] = flows
 }
 
 func (c *openflowManager) deleteFlowsByKey(key string) {
 	c.flowMutex.Lock()
 	defer c.flowMutex.Unlock()
+	c.flowSync.update(key, c.flowCache[key], nil)
 	delete(c.flowCache, key)
 }
 
 func (c *openflowManager) updateExBridgeFlowCacheEntry(key string, flows []string) {
 	c.exGWFlowMutex.Lock()
 	defer c.exGWFlowMutex.Unlock()
+	c.exGWFlowSync.update(key, c.exGWFlowCache[key], flows)
 	c.exGWFlowCache[key] = flows
 }
 
+func (c *openflowManager) updateEgressBridgeFlowCacheEntry(name, key string, flows []string) {
+	egressBridge := c.egressBridges[name]
+	egressBridge.flowMutex.Lock()
+	defer egressBridge.flowMutex.Unlock()
+	egressBridge.flowSync.update(key, egressBridge.flowCache[key], flows)
+	egressBridge.flowCache[key] = flows
+}
+
 func (c *openflowManager) requestFlowSync() {
 	select {
 	case c.flowChan <- struct{}{}:
@@ -263,34 +296,89 @@ func (c *openflowManager) requestFlowSync() {
 	}
 }
 
+// syncFlows replaces all the flows of the bridges with the cached ones
 func (c *openflowManager) syncFlows() {
 	c.flowMutex.Lock()
 	defer c.flowMutex.Unlock()
 
-	flows := []string{}
-	for _, entry := range c.flowCache {
-		flows = append(flows, entry...)
+	syncBridgeFlows(c.defaultBridge.bridgeName, c.flowCache, c.flowSync)
+
+	if c.externalGatewayBridge != nil {
+		c.exGWFlowMutex.Lock()
+		defer c.exGWFlowMutex.Unlock()
+
+		syncBridgeFlows(c.externalGatewayBridge.bridgeName, c.exGWFlowCache, c.exGWFlowSync)
 	}
 
-	_, stderr, err := util.ReplaceOFFlows(c.defaultBridge.bridgeName, flows)
-	if err != nil {
-		klog.Errorf("Failed to add flows, error: %v, stderr, %s, flows: %s", err, stderr, c.flowCache)
+	for _, egressBridge := range c.egressBridges {
+		egressBridge.flowMutex.Lock()
+		syncBridgeFlows(egressBridge.bridge.bridgeName, egressBridge.flowCache, egressBridge.flowSync)
+		egressBridge.flowMutex.Unlock()
 	}
+}
+
+// syncFlowChanges applies the changes to the cached flows since the last sync
+// to the bridges, in an atomic bundle per bridge. A bridge whose flows were
+// never fully synced, or whose changes fail to apply, is fully synced instead.
+func (c *openflowManager) syncFlowChanges() {
+	c.flowMutex.Lock()
+	defer c.flowMutex.Unlock()
+
+	syncBridgeFlowChanges(c.defaultBridge.bridgeName, c.flowCache, c.flowSync)
 
 	if c.externalGatewayBridge != nil {
 		c.exGWFlowMutex.Lock()
 		defer c.exGWFlowMutex.Unlock()
 
-		flows := []string{}
-		for _, entry := range c.exGWFlowCache {
-			flows = append(flows, entry...)
-		}
+		syncBridgeFlowChanges(c.externalGatewayBridge.bridgeName, c.exGWFlowCache, c.exGWFlowSync)
+	}
 
-		_, stderr, err := util.ReplaceOFFlows(c.externalGatewayBridge.bridgeName, flows)
-		if err != nil {
-			klog.Errorf("Failed to add flows, error: %v, stderr, %s, flows: %s", err, stderr, c.exGWFlowCache)
-		}
+	for _, egressBridge := range c.egressBridges {
+		egressBridge.flowMutex.Lock()
+		syncBridgeFlowChanges(egressBridge.bridge.bridgeName, egressBridge.flowCache, egressBridge.flowSync)
+		egressBridge.flowMutex.Unlock()
+	}
+}
+
+func syncBridgeFlows(bridgeName string, flowCache map[string][]string, tracker *flowSyncTracker) {
+	flows := []string{}
+	for _, entry := range flowCache {
+		flows = append(flows, entry...)
+	}
+
+	start := time.Now()
+	_, stderr, err := util.ReplaceOFFlows(bridgeName, flows)
+	metrics.RecordOpenFlowSync("full", time.Since(start), 0, err)
+	if err != nil {
+		klog.Errorf("Failed to add flows, error: %v, stderr, %s, flows: %s", err, stderr, flowCache)
+		tracker.synced = false
+		return
+	}
+	tracker.fullSynced()
+}
+
+func syncBridgeFlowChanges(bridgeName string, flowCache map[string][]string, tracker *flowSyncTracker) {
+	if !tracker.synced {
+		syncBridgeFlows(bridgeName, flowCache, tracker)
+		return
+	}
+	flowMods, changes := tracker.flowMods()
+	if len(flowMods) == 0 {
+		tracker.commit(changes)
+		return
 	}
+
+	start := time.Now()
+	_, stderr, err := util.ModifyOFFlows(bridgeName, flowMods)
+	metrics.RecordOpenFlowSync("incremental", time.Since(start), len(flowMods), err)
+	if err != nil {
+		klog.Errorf("Failed to modify flows of bridge %s, falling back to a full sync, error: %v, stderr, %s, flow mods: %s",
+			bridgeName, err, stderr, flowMods)
+		syncBridgeFlows(bridgeName, flowCache, tracker)
+		return
+	}
+	klog.V(5).Infof("Applied %d flow modifications to bridge %s", len(flowMods), bridgeName)
+	tracker.commit(changes)
 }
 
 // checkDefaultOpenFlow checks for the existence of default OpenFlow rules and
@@ -312,15 +400,29 @@ func (c *openflowManager) Run(stopChan <-chan struct{}) {
 					continue
 				}
 			}
+			if err := c.checkEgressBridgePorts(); err != nil {
+				klog.Errorf("Checkports failed %v", err)
+				continue
+			}
 			c.syncFlows()
 		case <-c.flowChan:
-			c.syncFlows()
+			c.syncFlowChanges()
 		case <-stopChan:
 			return
 		}
 	}
 }
 
+func (c *openflowManager) checkEgressBridgePorts() error {
+	for _, egressBridge := range c.egressBridges {
+		if err := checkPorts(egressBridge.bridge.patchPort, egressBridge.bridge.ofPortPatch,
+			egressBridge.bridge.uplinkName, egressBridge.bridge.ofPortPhys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func checkPorts(patchIntf, ofPortPatch, physIntf, ofPortPhys string) error {
 	// it could be that the ovn-controller recreated the patch between the host OVS bridge and
 	// the integration bridge, as a result the ofport number changed for that patch interface
@@ -114,3 +114,7 @@ func getIntfName(gatewayIntf string) (string, error) {
 func deleteConntrack(ip string, port int32, protocol kapi.Protocol) error {
 	return util.DeleteConntrack(ip, port, protocol)
 }
+
+func deleteConntrackServiceBackend(vip string, vipPort int32, backend string, protocol kapi.Protocol) error {
+	return util.DeleteConntrackServiceBackend(vip, vipPort, backend, protocol)
+}
@@ -0,0 +1,312 @@
+// +build linux
+
+package node
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	hostnetworkpolicyapi "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/hostnetworkpolicy/v1"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/factory"
+
+	kapi "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	utilnet "k8s.io/utils/net"
+)
+
+const (
+	// hostFirewallRetryInterval is the time between the attempts to program
+	// the host firewall after a failure
+	hostFirewallRetryInterval = 30 * time.Second
+	// hostFirewallFlowsKey is the key of the host firewall flows in the flow
+	// cache of the gateway bridge
+	hostFirewallFlowsKey = "HostFirewall"
+	// hostFirewallFlowPriority is above the priority of the NodePort flows,
+	// so that the traffic is dropped before it's sent to OVN
+	hostFirewallFlowPriority = 115
+)
+
+// hostFirewall renders the HostNetworkPolicies selecting the node into host
+// rules, which drop the new connections from outside the node to the listed
+// ports unless their source is allowed. In shared gateway mode the traffic to
+// NodePorts goes from the gateway bridge to OVN without the host seeing it,
+// so the policies are also rendered into flows dropping the new connections
+// on the bridge.
+type hostFirewall struct {
+	nodeName     string
+	watchFactory factory.NodeWatchFactory
+	// ofm is the flow manager of the gateway bridge, once the gateway is
+	// initialized
+	ofm *openflowManager
+
+	syncCh chan struct{}
+}
+
+func newHostFirewall(nodeName string, watchFactory factory.NodeWatchFactory) *hostFirewall {
+	return &hostFirewall{
+		nodeName:     nodeName,
+		watchFactory: watchFactory,
+		syncCh:       make(chan struct{}, 1),
+	}
+}
+
+// requestSync requests Run to program the host firewall again
+func (f *hostFirewall) requestSync() {
+	select {
+	case f.syncCh <- struct{}{}:
+	default:
+		// a sync is already pending
+	}
+}
+
+// Run programs the host firewall on changes of the HostNetworkPolicies or of
+// the labels of the node, and retries failed syncs, until stopChan is closed
+func (f *hostFirewall) Run(stopChan <-chan struct{}) {
+	policyHandler := f.watchFactory.AddHostNetworkPolicyHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			f.requestSync()
+		},
+		UpdateFunc: func(old, new interface{}) {
+			f.requestSync()
+		},
+		DeleteFunc: func(obj interface{}) {
+			f.requestSync()
+		},
+	}, nil)
+	defer f.watchFactory.RemoveHostNetworkPolicyHandler(policyHandler)
+	nodeHandler := f.watchFactory.AddNodeHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old, new interface{}) {
+			oldNode, newNode := old.(*kapi.Node), new.(*kapi.Node)
+			if newNode.Name == f.nodeName && !reflect.DeepEqual(oldNode.Labels, newNode.Labels) {
+				f.requestSync()
+			}
+		},
+	}, nil)
+	defer f.watchFactory.RemoveNodeHandler(nodeHandler)
+
+	f.requestSync()
+	ticker := time.NewTicker(hostFirewallRetryInterval)
+	defer ticker.Stop()
+	failed := false
+	for {
+		select {
+		case <-f.syncCh:
+			failed = !f.sync()
+		case <-ticker.C:
+			if failed {
+				failed = !f.sync()
+			}
+		case <-stopChan:
+			return
+		}
+	}
+}
+
+// sync programs the rules of the policies selecting the node, and returns
+// false if it failed to
+func (f *hostFirewall) sync() bool {
+	node, err := f.watchFactory.GetNode(f.nodeName)
+	if err != nil {
+		klog.Errorf("Failed to get node %s for its host firewall: %v", f.nodeName, err)
+		return false
+	}
+	policies, err := f.watchFactory.GetHostNetworkPolicies()
+	if err != nil {
+		klog.Errorf("Failed to list HostNetworkPolicies: %v", err)
+		return false
+	}
+	rules := hostFirewallRules(node, policies)
+	if err := getGatewayRuleManager().syncHostFirewallRules(rules); err != nil {
+		klog.Errorf("Failed to program the host firewall of node %s: %v", f.nodeName, err)
+		return false
+	}
+	if f.ofm != nil {
+		f.ofm.updateFlowCacheEntry(hostFirewallFlowsKey, hostFirewallFlows(f.ofm.defaultBridge, rules))
+		f.ofm.requestFlowSync()
+	}
+	return true
+}
+
+// hostFirewallRules returns a rule for each port listed by the policies
+// selecting node, allowing the sources of all the ingress rules listing it
+func hostFirewallRules(node *kapi.Node, policies []*hostnetworkpolicyapi.HostNetworkPolicy) []hostFirewallRule {
+	allowed := map[hostFirewallPort][]*net.IPNet{}
+	for _, policy := range policies {
+		selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.NodeSelector)
+		if err != nil {
+			klog.Errorf("Invalid node selector of HostNetworkPolicy %s: %v", policy.Name, err)
+			continue
+		}
+		if !selector.Matches(labels.Set(node.Labels)) {
+			continue
+		}
+		policyAllowed, err := hostNetworkPolicyAllowed(policy)
+		if err != nil {
+			klog.Errorf("Ignoring invalid HostNetworkPolicy %s: %v", policy.Name, err)
+			continue
+		}
+		for key, cidrs := range policyAllowed {
+			allowed[key] = append(allowed[key], cidrs...)
+		}
+	}
+
+	rules := make([]hostFirewallRule, 0, len(allowed))
+	for key, cidrs := range allowed {
+		rules = append(rules, hostFirewallRule{protocol: key.protocol, port: key.port, allowed: mergeCIDRs(cidrs)})
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].protocol != rules[j].protocol {
+			return rules[i].protocol < rules[j].protocol
+		}
+		return rules[i].port < rules[j].port
+	})
+	return rules
+}
+
+// hostFirewallPort is a port listed by the ingress rules of HostNetworkPolicies
+type hostFirewallPort struct {
+	protocol kapi.Protocol
+	port     int32
+}
+
+// hostNetworkPolicyAllowed returns the sources policy allows for each port it
+// lists. The apiserver validates the policies, but a policy created before the
+// validation existed may still have an invalid source: it is rejected as a
+// whole rather than dropping the traffic from all the sources of the port.
+func hostNetworkPolicyAllowed(policy *hostnetworkpolicyapi.HostNetworkPolicy) (map[hostFirewallPort][]*net.IPNet, error) {
+	allowed := map[hostFirewallPort][]*net.IPNet{}
+	for _, ingress := range policy.Spec.Ingress {
+		var cidrs []*net.IPNet
+		for _, peer := range ingress.From {
+			_, cidr, err := net.ParseCIDR(peer.CIDRSelector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid source %q: %v", peer.CIDRSelector, err)
+			}
+			cidrs = append(cidrs, cidr)
+		}
+		for _, port := range ingress.Ports {
+			protocol := kapi.Protocol(port.Protocol)
+			if protocol != kapi.ProtocolTCP && protocol != kapi.ProtocolUDP && protocol != kapi.ProtocolSCTP {
+				return nil, fmt.Errorf("invalid protocol %q", port.Protocol)
+			}
+			key := hostFirewallPort{protocol: protocol, port: port.Port}
+			allowed[key] = append(allowed[key], cidrs...)
+		}
+	}
+	return allowed, nil
+}
+
+// hostFirewallFlows returns the flows of the gateway bridge dropping the new
+// connections from the physical port to the IPs of the bridge that rules do
+// not allow. The traffic to a port of the rules goes through conntrack in the
+// zone where the bridge commits the connections of the host and of OVN, so the
+// replies to the connections they opened are not dropped. OpenFlow cannot
+// match a source outside of a set of prefixes, so each rule drops the
+// prefixes covering the complement of its allowed sources.
+func hostFirewallFlows(bridge *bridgeConfiguration, rules []hostFirewallRule) []string {
+	var flows []string
+	for _, r := range rules {
+		protocol := strings.ToLower(string(r.protocol))
+		for _, bridgeIP := range bridge.ips {
+			flowProtocol, nwSrc, nwDst := protocol, "nw_src", "nw_dst"
+			space := &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}
+			if utilnet.IsIPv6CIDR(bridgeIP) {
+				flowProtocol, nwSrc, nwDst = protocol+"6", "ipv6_src", "ipv6_dst"
+				space = &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}
+			}
+			dropped := complementCIDRs(space, r.allowed)
+			if len(dropped) == 0 {
+				continue
+			}
+			flows = append(flows,
+				fmt.Sprintf("cookie=%s, priority=%d, in_port=%s, %s, %s=%s, tp_dst=%d, ct_state=-trk, "+
+					"actions=ct(zone=%d, table=0)",
+					defaultOpenFlowCookie, hostFirewallFlowPriority, bridge.ofPortPhys, flowProtocol,
+					nwDst, bridgeIP.IP, r.port, config.Default.ConntrackZone))
+			for _, src := range dropped {
+				srcMatch := ""
+				if ones, _ := src.Mask.Size(); ones > 0 {
+					srcMatch = fmt.Sprintf("%s=%s, ", nwSrc, src)
+				}
+				flows = append(flows,
+					fmt.Sprintf("cookie=%s, priority=%d, in_port=%s, %s, %s=%s, %stp_dst=%d, ct_state=+trk-est-rel, "+
+						"actions=drop",
+						defaultOpenFlowCookie, hostFirewallFlowPriority, bridge.ofPortPhys, flowProtocol,
+						nwDst, bridgeIP.IP, srcMatch, r.port))
+			}
+		}
+	}
+	return flows
+}
+
+// mergeCIDRs returns the CIDRs of cidrs not contained in another one, sorted.
+// Two prefixes either do not overlap or one contains the other, so the result
+// has no overlapping CIDRs.
+func mergeCIDRs(cidrs []*net.IPNet) []*net.IPNet {
+	var merged []*net.IPNet
+	for i, cidr := range cidrs {
+		ones, bits := cidr.Mask.Size()
+		contained := false
+		for j, other := range cidrs {
+			otherOnes, otherBits := other.Mask.Size()
+			if i == j || bits != otherBits || !other.Contains(cidr.IP) || otherOnes > ones {
+				continue
+			}
+			// of two identical CIDRs, keep the first one
+			if otherOnes < ones || j < i {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			merged = append(merged, &net.IPNet{IP: cidr.IP.Mask(cidr.Mask), Mask: cidr.Mask})
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if c := bytes.Compare(merged[i].IP, merged[j].IP); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(merged[i].Mask, merged[j].Mask) < 0
+	})
+	return merged
+}
+
+// complementCIDRs returns the prefixes covering the addresses of space that
+// are not in cidrs. It splits space in halves until each half is either out of
+// cidrs or in one of them, which takes at most one prefix per bit of each CIDR.
+func complementCIDRs(space *net.IPNet, cidrs []*net.IPNet) []*net.IPNet {
+	spaceOnes, bits := space.Mask.Size()
+	var inside []*net.IPNet
+	for _, cidr := range cidrs {
+		ones, cidrBits := cidr.Mask.Size()
+		if cidrBits != bits {
+			continue
+		}
+		if ones <= spaceOnes && cidr.Contains(space.IP) {
+			return nil
+		}
+		if ones > spaceOnes && space.Contains(cidr.IP) {
+			inside = append(inside, cidr)
+		}
+	}
+	if len(inside) == 0 {
+		return []*net.IPNet{space}
+	}
+
+	mask := net.CIDRMask(spaceOnes+1, bits)
+	low := &net.IPNet{IP: space.IP, Mask: mask}
+	highIP := make(net.IP, len(space.IP))
+	copy(highIP, space.IP)
+	highIP[spaceOnes/8] |= 0x80 >> uint(spaceOnes%8)
+	high := &net.IPNet{IP: highIP, Mask: mask}
+	return append(complementCIDRs(low, inside), complementCIDRs(high, inside)...)
+}
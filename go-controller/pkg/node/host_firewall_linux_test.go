@@ -0,0 +1,171 @@
+// +build linux
+
+package node
+
+import (
+	"net"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	hostnetworkpolicyapi "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/hostnetworkpolicy/v1"
+	ovntest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Node Operations host firewall", func() {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"role": "edge"}},
+	}
+	policy := func(name string, selector map[string]string, ingress ...hostnetworkpolicyapi.HostNetworkPolicyIngressRule) *hostnetworkpolicyapi.HostNetworkPolicy {
+		return &hostnetworkpolicyapi.HostNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: hostnetworkpolicyapi.HostNetworkPolicySpec{
+				NodeSelector: metav1.LabelSelector{MatchLabels: selector},
+				Ingress:      ingress,
+			},
+		}
+	}
+	ingress := func(port int32, cidrs ...string) hostnetworkpolicyapi.HostNetworkPolicyIngressRule {
+		rule := hostnetworkpolicyapi.HostNetworkPolicyIngressRule{
+			Ports: []hostnetworkpolicyapi.HostNetworkPolicyPort{{Protocol: "TCP", Port: port}},
+		}
+		for _, cidr := range cidrs {
+			rule.From = append(rule.From, hostnetworkpolicyapi.HostNetworkPolicyPeer{CIDRSelector: cidr})
+		}
+		return rule
+	}
+	cidrStrings := func(cidrs []*net.IPNet) []string {
+		var s []string
+		for _, cidr := range cidrs {
+			s = append(s, cidr.String())
+		}
+		return s
+	}
+
+	BeforeEach(func() {
+		config.PrepareTestConfig()
+		config.IPv4Mode = true
+		config.IPv6Mode = false
+	})
+
+	It("merges the sources of the policies selecting the node by port", func() {
+		rules := hostFirewallRules(node, []*hostnetworkpolicyapi.HostNetworkPolicy{
+			policy("edge", map[string]string{"role": "edge"}, ingress(30080, "10.0.0.0/8"), ingress(22)),
+			policy("all", nil, ingress(30080, "10.1.0.0/16", "192.168.0.0/24")),
+			policy("other", map[string]string{"role": "worker"}, ingress(30080, "0.0.0.0/0")),
+		})
+		Expect(rules).To(HaveLen(2))
+		Expect(rules[0].protocol).To(Equal(v1.ProtocolTCP))
+		Expect(rules[0].port).To(Equal(int32(22)))
+		Expect(rules[0].allowed).To(BeEmpty())
+		Expect(rules[1].port).To(Equal(int32(30080)))
+		Expect(cidrStrings(rules[1].allowed)).To(Equal([]string{"10.0.0.0/8", "192.168.0.0/24"}))
+	})
+
+	It("ignores the policies with an invalid source or protocol", func() {
+		invalidProtocol := ingress(22)
+		invalidProtocol.Ports[0].Protocol = "tcp"
+		rules := hostFirewallRules(node, []*hostnetworkpolicyapi.HostNetworkPolicy{
+			policy("allowed", nil, ingress(30080, "10.0.0.0/8")),
+			policy("invalid-source", nil, ingress(30080, "10.1.0.0"), ingress(22)),
+			policy("invalid-protocol", nil, invalidProtocol),
+		})
+		Expect(rules).To(HaveLen(1))
+		Expect(rules[0].port).To(Equal(int32(30080)))
+		Expect(cidrStrings(rules[0].allowed)).To(Equal([]string{"10.0.0.0/8"}))
+	})
+
+	It("covers the addresses outside of the allowed sources with prefixes", func() {
+		space := &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}
+		Expect(cidrStrings(complementCIDRs(space, nil))).To(Equal([]string{"0.0.0.0/0"}))
+		Expect(complementCIDRs(space, ovntest.MustParseIPNets("0.0.0.0/0"))).To(BeEmpty())
+		Expect(cidrStrings(complementCIDRs(space, ovntest.MustParseIPNets("128.0.0.0/2")))).To(Equal(
+			[]string{"0.0.0.0/1", "192.0.0.0/2"}))
+		Expect(complementCIDRs(space, ovntest.MustParseIPNets("10.0.0.0/8", "192.168.0.0/16"))).To(HaveLen(7 + 15))
+		Expect(cidrStrings(complementCIDRs(space, ovntest.MustParseIPNets("fd00::/8")))).To(Equal([]string{"0.0.0.0/0"}))
+	})
+
+	It("drops the new connections to the ports from the other sources in the bridge flows", func() {
+		bridge := &bridgeConfiguration{
+			ofPortPhys: "1",
+			ips:        ovntest.MustParseIPNets("172.18.0.2/16", "fc00::2/64"),
+		}
+		flows := hostFirewallFlows(bridge, []hostFirewallRule{
+			{protocol: v1.ProtocolTCP, port: 22},
+			{protocol: v1.ProtocolUDP, port: 30053, allowed: ovntest.MustParseIPNets("128.0.0.0/1", "::/0")},
+		})
+		Expect(flows).To(Equal([]string{
+			"cookie=0xdeff105, priority=115, in_port=1, tcp, nw_dst=172.18.0.2, tp_dst=22, ct_state=-trk, actions=ct(zone=64000, table=0)",
+			"cookie=0xdeff105, priority=115, in_port=1, tcp, nw_dst=172.18.0.2, tp_dst=22, ct_state=+trk-est-rel, actions=drop",
+			"cookie=0xdeff105, priority=115, in_port=1, tcp6, ipv6_dst=fc00::2, tp_dst=22, ct_state=-trk, actions=ct(zone=64000, table=0)",
+			"cookie=0xdeff105, priority=115, in_port=1, tcp6, ipv6_dst=fc00::2, tp_dst=22, ct_state=+trk-est-rel, actions=drop",
+			"cookie=0xdeff105, priority=115, in_port=1, udp, nw_dst=172.18.0.2, tp_dst=30053, ct_state=-trk, actions=ct(zone=64000, table=0)",
+			"cookie=0xdeff105, priority=115, in_port=1, udp, nw_dst=172.18.0.2, nw_src=0.0.0.0/1, tp_dst=30053, ct_state=+trk-est-rel, actions=drop",
+			// all the IPv6 sources are allowed
+		}))
+	})
+
+	It("allows the sources before dropping the traffic to a port in iptables", func() {
+		iptRules := hostFirewallIPTRules([]hostFirewallRule{
+			{protocol: v1.ProtocolTCP, port: 30080, allowed: ovntest.MustParseIPNets("10.0.0.0/8", "fd00::/8")},
+		})
+		var args []string
+		for _, r := range iptRules {
+			Expect(r.table).To(Equal("mangle"))
+			Expect(r.chain).To(Equal(iptableHostFirewallChain))
+			args = append(args, strings.Join(r.args, " "))
+		}
+		// the rules are inserted in reverse order
+		Expect(args).To(Equal([]string{
+			"-p tcp --dport 30080 -j DROP",
+			"-p tcp --dport 30080 -s 10.0.0.0/8 -j RETURN",
+		}))
+	})
+
+	Context("with the nftables backend", func() {
+		var (
+			fakeNFT *util.FakeNFTables
+			m       *nftablesRuleManager
+		)
+
+		BeforeEach(func() {
+			config.Gateway.RuleBackend = config.GatewayRuleBackendNFTables
+			fakeNFT = util.SetFakeNFTablesHelper()
+			m = newNFTablesRuleManager()
+		})
+
+		AfterEach(func() {
+			util.SetNFTablesHelper(nil)
+		})
+
+		It("adds the host firewall chain to the tables", func() {
+			config.IPv6Mode = true
+			rules := []hostFirewallRule{
+				{protocol: v1.ProtocolTCP, port: 22},
+				{protocol: v1.ProtocolTCP, port: 30080, allowed: ovntest.MustParseIPNets("10.0.0.0/8")},
+			}
+			Expect(m.syncHostFirewallRules(rules)).To(Succeed())
+			Expect(fakeNFT.LastScript()).To(BeEmpty())
+
+			Expect(m.initServiceRules(config.GatewayModeShared)).To(Succeed())
+			script := fakeNFT.LastScript()
+			Expect(script).To(ContainSubstring("\tchain host-firewall {\n\t\ttype filter hook prerouting priority -150; policy accept;\n"))
+			Expect(script).To(ContainSubstring(
+				"iifname != \"lo\" fib daddr type local ct state new meta l4proto tcp th dport 22 drop\n"))
+			Expect(script).To(ContainSubstring(
+				"iifname != \"lo\" fib daddr type local ct state new meta l4proto tcp th dport 30080 ip saddr != { 10.0.0.0/8 } drop\n"))
+			// no IPv6 source is allowed
+			Expect(script).To(ContainSubstring(
+				"iifname != \"lo\" fib daddr type local ct state new meta l4proto tcp th dport 30080 drop\n"))
+
+			Expect(m.syncHostFirewallRules(nil)).To(Succeed())
+			Expect(fakeNFT.LastScript()).NotTo(ContainSubstring("host-firewall"))
+		})
+	})
+})
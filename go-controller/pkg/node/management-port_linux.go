@@ -236,10 +236,52 @@ func setupManagementPortIPFamilyConfig(mpcfg *managementPortConfig, cfg *managem
 	return warnings, nil
 }
 
+// setupManagementPortVRF enslaves the management port to the gateway VRF, if
+// one is configured, or releases it from the VRF it was enslaved to otherwise.
+// Enslaving the port flushes its routes, so it must be done before adding them.
+func setupManagementPortVRF(mpcfg *managementPortConfig) ([]string, error) {
+	var warnings []string
+
+	link, err := util.GetNetLinkOps().LinkByName(mpcfg.ifName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup link %s: %v", mpcfg.ifName, err)
+	}
+	var vrf netlink.Link
+	if config.Gateway.VRF != "" {
+		if vrf, err = util.LinkVRFEnsure(config.Gateway.VRF, uint32(config.Gateway.VRFTable)); err != nil {
+			return nil, err
+		}
+		if link.Attrs().MasterIndex == vrf.Attrs().Index {
+			return nil, nil
+		}
+		warnings = append(warnings, fmt.Sprintf("interface %s is not in VRF %s, adding it...",
+			mpcfg.ifName, config.Gateway.VRF))
+	} else {
+		if link.Attrs().MasterIndex == 0 {
+			return nil, nil
+		}
+		master, err := util.GetNetLinkOps().LinkByIndex(link.Attrs().MasterIndex)
+		if err != nil || master.Type() != "vrf" {
+			return nil, err
+		}
+		warnings = append(warnings, fmt.Sprintf("interface %s is in VRF %s while no gateway VRF is configured, removing it...",
+			mpcfg.ifName, master.Attrs().Name))
+	}
+	if err = util.LinkSetMaster(link, vrf); err != nil {
+		return warnings, err
+	}
+	mpcfg.link = link
+	return warnings, nil
+}
+
 func setupManagementPortConfig(cfg *managementPortConfig) ([]string, error) {
 	var warnings, allWarnings []string
 	var err error
 
+	allWarnings, err = setupManagementPortVRF(cfg)
+	if err != nil {
+		return allWarnings, err
+	}
 	if cfg.ipv4 != nil {
 		warnings, err = setupManagementPortIPFamilyConfig(cfg, cfg.ipv4)
 		allWarnings = append(allWarnings, warnings...)
@@ -295,6 +337,7 @@ func DelMgtPortIptRules() {
 }
 
 // checks to make sure that following configurations are present on the k8s node
+// 0. management port in the gateway VRF, if any
 // 1. route entries to cluster CIDR and service CIDR through management port
 // 2. ARP entry for the node subnet's gateway ip
 // 3. IPtables chain and rule for SNATing packets entering the logical topology
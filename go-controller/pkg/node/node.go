@@ -247,14 +247,13 @@ func isOVNControllerReady() (bool, error) {
 		return false, nil
 	}
 
-	// check by dumping br-int flow entries
-	stdout, _, err := util.RunOVSOfctl("dump-aggregate", "br-int")
+	// check by counting br-int flow entries
+	flowCount, err := util.GetOFFlowCount("br-int")
 	if err != nil {
 		klog.V(5).Infof("Error dumping aggregate flows: %v", err)
 		return false, nil
 	}
-	hasFlowCountZero := strings.Contains(stdout, "flow_count=0")
-	if hasFlowCountZero {
+	if flowCount == 0 {
 		klog.V(5).Info("Got a flow count of 0 when dumping flows for node")
 		return false, nil
 	}
@@ -561,6 +560,7 @@ func (n *OvnNode) WatchEndpoints() {
 					if err != nil {
 						klog.Errorf("Failed to delete conntrack entry for %s: %v", item.ip, err)
 					}
+					n.deleteServiceConntrack(epNew.Namespace, epNew.Name, item)
 				}
 			}
 		},
@@ -571,12 +571,71 @@ func (n *OvnNode) WatchEndpoints() {
 				if err != nil {
 					klog.Errorf("Failed to delete conntrack entry for %s: %v", item.ip, err)
 				}
-
+				n.deleteServiceConntrack(ep.Namespace, ep.Name, item)
 			}
 		},
 	}, nil)
 }
 
+// deleteServiceConntrack deletes the conntrack entries of the connections to
+// the vips of a service that were load balanced to a removed endpoint, in the
+// host zone and in the OVN zones alike. deleteConntrack only matches the
+// entries whose original destination port is the endpoint port, which misses
+// the ones to a service port with a different target port, and UDP clients
+// like DNS resolvers keep sending to the dead endpoint until they time out.
+func (n *OvnNode) deleteServiceConntrack(namespace, name string, item epAddressItem) {
+	svc, err := n.watchFactory.GetService(namespace, name)
+	if err != nil {
+		// the service is gone together with its load balancers
+		return
+	}
+	for _, vip := range serviceVIPsForEndpoint(svc, item) {
+		if err := deleteConntrackServiceBackend(vip.ip, vip.port, item.ip, item.protocol); err != nil {
+			klog.Errorf("Failed to delete conntrack entries of service %s/%s for %s:%d to %s: %v",
+				namespace, name, vip.ip, vip.port, item.ip, err)
+		}
+	}
+}
+
+type serviceVIP struct {
+	// ip is empty for node ports, which match any destination IP
+	ip   string
+	port int32
+}
+
+// serviceVIPsForEndpoint returns the vips of the service port an endpoint
+// address and port belong to: its cluster IPs, external IPs and load balancer
+// IPs of the IP family of the endpoint, and its node port
+func serviceVIPsForEndpoint(svc *kapi.Service, item epAddressItem) []serviceVIP {
+	if !util.ServiceTypeHasClusterIP(svc) || !util.IsClusterIPSet(svc) {
+		return nil
+	}
+	isIPv6 := utilnet.IsIPv6String(item.ip)
+	ips := append([]string{}, util.GetClusterIPs(svc)...)
+	ips = append(ips, svc.Spec.ExternalIPs...)
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			ips = append(ips, ingress.IP)
+		}
+	}
+
+	vips := []serviceVIP{}
+	for _, svcPort := range svc.Spec.Ports {
+		if svcPort.Name != item.name || svcPort.Protocol != item.protocol {
+			continue
+		}
+		for _, ip := range ips {
+			if utilnet.IsIPv6String(ip) == isIPv6 {
+				vips = append(vips, serviceVIP{ip: ip, port: svcPort.Port})
+			}
+		}
+		if svcPort.NodePort > 0 {
+			vips = append(vips, serviceVIP{port: svcPort.NodePort})
+		}
+	}
+	return vips
+}
+
 // validateVTEPInterfaceMTU checks if the MTU of the interface that has ovn-encap-ip is big
 // enough to carry the `config.Default.MTU` and the Geneve header. If the MTU is not big
 // enough, it will taint the node with the value of `types.OvnK8sSmallMTUTaintKey`
@@ -624,6 +683,8 @@ type epAddressItem struct {
 	ip       string
 	port     int32
 	protocol kapi.Protocol
+	// name is the name of the port, matching the one of the service port
+	name string
 }
 
 //buildEndpointAddressMap builds a map of all UDP and SCTP ports in the endpoint subset along with that port's IP address
@@ -637,6 +698,7 @@ func buildEndpointAddressMap(epSubsets []kapi.EndpointSubset) map[epAddressItem]
 						ip:       address.IP,
 						port:     port.Port,
 						protocol: port.Protocol,
+						name:     port.Name,
 					}] = struct{}{}
 				}
 			}
@@ -391,4 +391,69 @@ var _ = Describe("Node", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 	})
+
+	Describe("serviceVIPsForEndpoint", func() {
+		svc := &kapi.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "dns", Namespace: "kube-system"},
+			Spec: kapi.ServiceSpec{
+				Type:        kapi.ServiceTypeLoadBalancer,
+				ClusterIP:   "10.96.0.10",
+				ClusterIPs:  []string{"10.96.0.10", "fd00:10:96::10"},
+				ExternalIPs: []string{"192.168.1.100"},
+				Ports: []kapi.ServicePort{
+					{Name: "dns", Protocol: kapi.ProtocolUDP, Port: 53, NodePort: 30053},
+					{Name: "dns-tcp", Protocol: kapi.ProtocolTCP, Port: 53, NodePort: 30054},
+					{Name: "metrics", Protocol: kapi.ProtocolUDP, Port: 9153},
+				},
+			},
+			Status: kapi.ServiceStatus{
+				LoadBalancer: kapi.LoadBalancerStatus{
+					Ingress: []kapi.LoadBalancerIngress{{IP: "192.168.1.101"}, {IP: "fd00::101"}},
+				},
+			},
+		}
+
+		It("returns the vips of the service port of the endpoint in its IP family", func() {
+			endpoints := buildEndpointAddressMap([]kapi.EndpointSubset{{
+				Addresses: []kapi.EndpointAddress{{IP: "10.244.0.5"}},
+				Ports:     []kapi.EndpointPort{{Name: "dns", Protocol: kapi.ProtocolUDP, Port: 5353}},
+			}})
+			item := epAddressItem{ip: "10.244.0.5", port: 5353, protocol: kapi.ProtocolUDP, name: "dns"}
+			Expect(endpoints).To(HaveKey(item))
+
+			Expect(serviceVIPsForEndpoint(svc, item)).To(ConsistOf(
+				serviceVIP{ip: "10.96.0.10", port: 53},
+				serviceVIP{ip: "192.168.1.100", port: 53},
+				serviceVIP{ip: "192.168.1.101", port: 53},
+				serviceVIP{port: 30053},
+			))
+
+			item = epAddressItem{ip: "fd00:10:244::5", port: 5353, protocol: kapi.ProtocolUDP, name: "dns"}
+			Expect(serviceVIPsForEndpoint(svc, item)).To(ConsistOf(
+				serviceVIP{ip: "fd00:10:96::10", port: 53},
+				serviceVIP{ip: "fd00::101", port: 53},
+				serviceVIP{port: 30053},
+			))
+		})
+
+		It("ignores the endpoints of other service ports", func() {
+			item := epAddressItem{ip: "10.244.0.5", port: 9153, protocol: kapi.ProtocolUDP, name: "metrics"}
+			Expect(serviceVIPsForEndpoint(svc, item)).To(ConsistOf(
+				serviceVIP{ip: "10.96.0.10", port: 9153},
+				serviceVIP{ip: "192.168.1.100", port: 9153},
+				serviceVIP{ip: "192.168.1.101", port: 9153},
+			))
+
+			item = epAddressItem{ip: "10.244.0.5", port: 5353, protocol: kapi.ProtocolUDP, name: "other"}
+			Expect(serviceVIPsForEndpoint(svc, item)).To(BeEmpty())
+		})
+
+		It("ignores headless services", func() {
+			headless := svc.DeepCopy()
+			headless.Spec.ClusterIP = kapi.ClusterIPNone
+			headless.Spec.ClusterIPs = []string{kapi.ClusterIPNone}
+			item := epAddressItem{ip: "10.244.0.5", port: 5353, protocol: kapi.ProtocolUDP, name: "dns"}
+			Expect(serviceVIPsForEndpoint(headless, item)).To(BeEmpty())
+		})
+	})
 })
@@ -0,0 +1,160 @@
+package node
+
+import (
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// flowSyncTracker tracks the flows installed on a bridge, so that the changes
+// to its flow cache can be applied incrementally instead of replacing all of
+// its flows. Flows are identified by their match, which includes the table and
+// the priority, as OpenFlow does.
+type flowSyncTracker struct {
+	// flowsByMatch are the cached flows by match, then by flow cache key
+	flowsByMatch map[string]map[string]string
+	// installed are the flows installed on the bridge by match
+	installed map[string]string
+	// dirty are the matches whose cached flows changed since the last sync
+	dirty sets.String
+	// synced is true once a full sync installed all the cached flows
+	synced bool
+}
+
+func newFlowSyncTracker() *flowSyncTracker {
+	return &flowSyncTracker{
+		flowsByMatch: make(map[string]map[string]string),
+		installed:    make(map[string]string),
+		dirty:        sets.NewString(),
+	}
+}
+
+// update records that the cached flows of key changed from oldFlows to newFlows
+func (t *flowSyncTracker) update(key string, oldFlows, newFlows []string) {
+	for _, flow := range oldFlows {
+		match := ofFlowMatch(flow)
+		if flows, ok := t.flowsByMatch[match]; ok {
+			delete(flows, key)
+			if len(flows) == 0 {
+				delete(t.flowsByMatch, match)
+			}
+		}
+		t.dirty.Insert(match)
+	}
+	for _, flow := range newFlows {
+		match := ofFlowMatch(flow)
+		flows, ok := t.flowsByMatch[match]
+		if !ok {
+			flows = make(map[string]string)
+			t.flowsByMatch[match] = flows
+		}
+		flows[key] = strings.TrimSpace(flow)
+		t.dirty.Insert(match)
+	}
+}
+
+// cachedFlow returns the cached flow with match, or "" if there is none. When
+// several cache keys have a flow with the same match, the one of the lowest
+// key wins.
+func (t *flowSyncTracker) cachedFlow(match string) string {
+	flows := t.flowsByMatch[match]
+	if len(flows) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(flows))
+	for key := range flows {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return flows[keys[0]]
+}
+
+// flowMods returns the flow modifications that make the installed flows match
+// the cached ones, and the flows installed by match once they are applied,
+// with "" for the deleted ones
+func (t *flowSyncTracker) flowMods() ([]string, map[string]string) {
+	flowMods := []string{}
+	changes := make(map[string]string)
+	for _, match := range t.dirty.List() {
+		cached := t.cachedFlow(match)
+		installed := t.installed[match]
+		if cached == installed {
+			continue
+		}
+		if cached == "" {
+			flowMods = append(flowMods, "delete_strict "+ofFlowMatchSpec(installed))
+		} else {
+			flowMods = append(flowMods, "add "+cached)
+		}
+		changes[match] = cached
+	}
+	return flowMods, changes
+}
+
+// commit records that the changes returned by flowMods were applied
+func (t *flowSyncTracker) commit(changes map[string]string) {
+	for match, flow := range changes {
+		if flow == "" {
+			delete(t.installed, match)
+		} else {
+			t.installed[match] = flow
+		}
+	}
+	t.dirty = sets.NewString()
+}
+
+// fullSynced records that all the cached flows were installed, replacing any
+// other flow of the bridge
+func (t *flowSyncTracker) fullSynced() {
+	t.installed = make(map[string]string, len(t.flowsByMatch))
+	for match := range t.flowsByMatch {
+		t.installed[match] = t.cachedFlow(match)
+	}
+	t.dirty = sets.NewString()
+	t.synced = true
+}
+
+// ofFlowMatchFields returns the fields of a flow before its actions, without
+// its cookie
+func ofFlowMatchFields(flow string) []string {
+	flow = strings.TrimSpace(flow)
+	if i := strings.Index(flow, "actions="); i >= 0 {
+		flow = flow[:i]
+	}
+	fields := []string{}
+	for _, field := range strings.Split(flow, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || strings.HasPrefix(field, "cookie=") {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// ofFlowMatch returns a normalized form of the match of a flow, including
+// its table and priority, that is the same for all the flows OpenFlow
+// considers identical
+func ofFlowMatch(flow string) string {
+	fields := ofFlowMatchFields(flow)
+	hasTable, hasPriority := false, false
+	for _, field := range fields {
+		hasTable = hasTable || strings.HasPrefix(field, "table=")
+		hasPriority = hasPriority || strings.HasPrefix(field, "priority=")
+	}
+	if !hasTable {
+		fields = append(fields, "table=0")
+	}
+	if !hasPriority {
+		fields = append(fields, "priority=32768")
+	}
+	sort.Strings(fields)
+	return strings.Join(fields, ",")
+}
+
+// ofFlowMatchSpec returns the match of a flow in a form that strict flow
+// deletions accept
+func ofFlowMatchSpec(flow string) string {
+	return strings.Join(ofFlowMatchFields(flow), ", ")
+}
@@ -0,0 +1,72 @@
+package node
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Node Operations OpenFlow sync", func() {
+	var tracker *flowSyncTracker
+
+	const (
+		normalFlow  = "table=0,priority=0,actions=NORMAL\n"
+		nodePortIn  = "cookie=0x1, priority=110, in_port=1, tcp, tp_dst=30080, actions=output:2"
+		nodePortOut = "cookie=0x1, priority=110, in_port=2, tcp, tp_src=30080, actions=output:1"
+	)
+
+	BeforeEach(func() {
+		tracker = newFlowSyncTracker()
+		tracker.update("NORMAL", nil, []string{normalFlow})
+		tracker.fullSynced()
+	})
+
+	It("identifies flows by their table, priority and match", func() {
+		Expect(ofFlowMatch("priority=0, actions=NORMAL")).To(Equal(ofFlowMatch(normalFlow)))
+		Expect(ofFlowMatch("cookie=0x2, tcp, in_port=1, tp_dst=30080, priority=110, actions=drop")).To(Equal(ofFlowMatch(nodePortIn)))
+		Expect(ofFlowMatch(nodePortIn)).NotTo(Equal(ofFlowMatch(nodePortOut)))
+		Expect(ofFlowMatchSpec(nodePortIn)).To(Equal("priority=110, in_port=1, tcp, tp_dst=30080"))
+	})
+
+	It("adds, modifies and deletes only the changed flows", func() {
+		tracker.update("NodePort", nil, []string{nodePortIn, nodePortOut})
+		flowMods, changes := tracker.flowMods()
+		Expect(flowMods).To(ConsistOf("add "+nodePortIn, "add "+nodePortOut))
+		tracker.commit(changes)
+
+		modified := "cookie=0x1, priority=110, in_port=1, tcp, tp_dst=30080, actions=output:3"
+		tracker.update("NodePort", []string{nodePortIn, nodePortOut}, []string{modified})
+		flowMods, changes = tracker.flowMods()
+		Expect(flowMods).To(ConsistOf("add "+modified, "delete_strict priority=110, in_port=2, tcp, tp_src=30080"))
+		tracker.commit(changes)
+
+		// a change back and forth before a sync is a no-op
+		tracker.update("NodePort", []string{modified}, nil)
+		tracker.update("NodePort", nil, []string{modified})
+		flowMods, _ = tracker.flowMods()
+		Expect(flowMods).To(BeEmpty())
+	})
+
+	It("keeps a flow installed while another key still has it", func() {
+		arpFlow := "cookie=0x1, priority=110, in_port=2, arp, arp_tpa=192.168.1.100, actions=output:1"
+		tracker.update("External_80", nil, []string{arpFlow})
+		tracker.update("External_443", nil, []string{arpFlow})
+		_, changes := tracker.flowMods()
+		tracker.commit(changes)
+
+		tracker.update("External_80", []string{arpFlow}, nil)
+		flowMods, _ := tracker.flowMods()
+		Expect(flowMods).To(BeEmpty())
+
+		tracker.update("External_443", []string{arpFlow}, nil)
+		flowMods, _ = tracker.flowMods()
+		Expect(flowMods).To(Equal([]string{"delete_strict priority=110, in_port=2, arp, arp_tpa=192.168.1.100"}))
+	})
+
+	It("considers all the cached flows installed after a full sync", func() {
+		tracker.update("NodePort", nil, []string{nodePortIn})
+		tracker.fullSynced()
+		flowMods, _ := tracker.flowMods()
+		Expect(flowMods).To(BeEmpty())
+		Expect(tracker.installed).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,192 @@
+package node
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/bgp"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/factory"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+
+	kapi "k8s.io/api/core/v1"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	utilnet "k8s.io/utils/net"
+)
+
+// routeAdvertiserResyncInterval is the time between the advertisements of the
+// routes without changes, which retry failures of the speaker and restore the
+// routes of a restarted routing daemon
+const routeAdvertiserResyncInterval = 30 * time.Second
+
+// routeAdvertiser advertises the pod subnets of the node, and the external and
+// load balancer IPs of the services with ready endpoints on the node, to the
+// BGP peers of the node through a speaker. Every node with local endpoints
+// advertises the IPs of a service, so that upstream routers can spread the
+// traffic to them across those nodes.
+type routeAdvertiser struct {
+	nodeName     string
+	subnets      []*net.IPNet
+	watchFactory factory.NodeWatchFactory
+	speaker      bgp.Speaker
+
+	servicesLock sync.Mutex
+	services     map[ktypes.NamespacedName]*kapi.Service
+
+	syncCh chan struct{}
+}
+
+func newRouteAdvertiser(nodeName string, subnets []*net.IPNet, watchFactory factory.NodeWatchFactory, speaker bgp.Speaker) *routeAdvertiser {
+	return &routeAdvertiser{
+		nodeName:     nodeName,
+		subnets:      subnets,
+		watchFactory: watchFactory,
+		speaker:      speaker,
+		services:     map[ktypes.NamespacedName]*kapi.Service{},
+		syncCh:       make(chan struct{}, 1),
+	}
+}
+
+// requestSync requests Run to advertise the routes again
+func (r *routeAdvertiser) requestSync() {
+	select {
+	case r.syncCh <- struct{}{}:
+	default:
+		// a sync is already pending
+	}
+}
+
+func (r *routeAdvertiser) AddService(svc *kapi.Service) {
+	r.servicesLock.Lock()
+	defer r.servicesLock.Unlock()
+	r.services[ktypes.NamespacedName{Namespace: svc.Namespace, Name: svc.Name}] = svc
+	r.requestSync()
+}
+
+func (r *routeAdvertiser) UpdateService(old, new *kapi.Service) {
+	r.AddService(new)
+}
+
+func (r *routeAdvertiser) DeleteService(svc *kapi.Service) {
+	r.servicesLock.Lock()
+	defer r.servicesLock.Unlock()
+	delete(r.services, ktypes.NamespacedName{Namespace: svc.Namespace, Name: svc.Name})
+	r.requestSync()
+}
+
+func (r *routeAdvertiser) SyncServices(svcs []interface{}) {
+	for _, obj := range svcs {
+		if svc, ok := obj.(*kapi.Service); ok {
+			r.AddService(svc)
+		}
+	}
+}
+
+func (r *routeAdvertiser) AddEndpoints(ep *kapi.Endpoints) {
+	r.syncEndpoints(ep)
+}
+
+func (r *routeAdvertiser) UpdateEndpoints(old, new *kapi.Endpoints) {
+	r.syncEndpoints(new)
+}
+
+func (r *routeAdvertiser) DeleteEndpoints(ep *kapi.Endpoints) {
+	r.syncEndpoints(ep)
+}
+
+func (r *routeAdvertiser) syncEndpoints(ep *kapi.Endpoints) {
+	r.servicesLock.Lock()
+	_, ok := r.services[ktypes.NamespacedName{Namespace: ep.Namespace, Name: ep.Name}]
+	r.servicesLock.Unlock()
+	if ok {
+		r.requestSync()
+	}
+}
+
+// Run advertises the routes on changes, and periodically, until stopChan is
+// closed
+func (r *routeAdvertiser) Run(stopChan <-chan struct{}) {
+	r.requestSync()
+	ticker := time.NewTicker(routeAdvertiserResyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.syncCh:
+			r.sync()
+		case <-ticker.C:
+			r.sync()
+		case <-stopChan:
+			return
+		}
+	}
+}
+
+// sync advertises the current routes, and returns false if the speaker failed
+// to advertise them
+func (r *routeAdvertiser) sync() bool {
+	if err := r.speaker.Advertise(r.prefixes()); err != nil {
+		klog.Errorf("Failed to advertise the routes of node %s over BGP: %v", r.nodeName, err)
+		return false
+	}
+	return true
+}
+
+// prefixes returns the pod subnets of the node, and a host route for each
+// external and load balancer IP of the services with ready endpoints on the
+// node
+func (r *routeAdvertiser) prefixes() []*net.IPNet {
+	prefixes := append([]*net.IPNet{}, r.subnets...)
+
+	r.servicesLock.Lock()
+	services := make([]*kapi.Service, 0, len(r.services))
+	for _, svc := range r.services {
+		services = append(services, svc)
+	}
+	r.servicesLock.Unlock()
+
+	for _, svc := range services {
+		if !util.ServiceTypeHasClusterIP(svc) || !util.IsClusterIPSet(svc) {
+			continue
+		}
+		ips := append([]string{}, svc.Spec.ExternalIPs...)
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			if ingress.IP != "" {
+				ips = append(ips, ingress.IP)
+			}
+		}
+		if len(ips) == 0 || !r.hasLocalEndpoints(svc) {
+			continue
+		}
+		for _, ipStr := range ips {
+			ip := net.ParseIP(ipStr)
+			if ip == nil {
+				continue
+			}
+			mask := net.CIDRMask(32, 32)
+			if utilnet.IsIPv6(ip) {
+				mask = net.CIDRMask(128, 128)
+			} else {
+				ip = ip.To4()
+			}
+			prefixes = append(prefixes, &net.IPNet{IP: ip, Mask: mask})
+		}
+	}
+	return prefixes
+}
+
+// hasLocalEndpoints returns true if svc has ready endpoints on the node
+func (r *routeAdvertiser) hasLocalEndpoints(svc *kapi.Service) bool {
+	ep, err := r.watchFactory.GetEndpoint(svc.Namespace, svc.Name)
+	if err != nil {
+		return false
+	}
+	for _, subset := range ep.Subsets {
+		for _, addr := range subset.Addresses {
+			if addr.NodeName != nil && *addr.NodeName == r.nodeName {
+				return true
+			}
+		}
+	}
+	return false
+}
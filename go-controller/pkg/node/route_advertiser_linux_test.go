@@ -0,0 +1,108 @@
+// +build linux
+
+package node
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/factory"
+	ovntest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeSpeaker records the prefixes it is asked to advertise
+type fakeSpeaker struct {
+	advertised []string
+}
+
+func (s *fakeSpeaker) Advertise(prefixes []*net.IPNet) error {
+	s.advertised = []string{}
+	for _, prefix := range prefixes {
+		s.advertised = append(s.advertised, prefix.String())
+	}
+	return nil
+}
+
+var _ = Describe("Node Operations route advertiser", func() {
+	var (
+		wf         *factory.WatchFactory
+		speaker    *fakeSpeaker
+		advertiser *routeAdvertiser
+	)
+
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "ns"},
+		Spec: v1.ServiceSpec{
+			Type:        v1.ServiceTypeLoadBalancer,
+			ClusterIP:   "10.96.0.10",
+			ExternalIPs: []string{"192.168.1.100", "fd00::100"},
+		},
+		Status: v1.ServiceStatus{
+			LoadBalancer: v1.LoadBalancerStatus{
+				Ingress: []v1.LoadBalancerIngress{{IP: "192.168.1.101"}, {Hostname: "lb.example.com"}},
+			},
+		},
+	}
+
+	endpointsOn := func(nodeName string) *v1.Endpoints {
+		return &v1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "ns"},
+			Subsets: []v1.EndpointSubset{{
+				Addresses: []v1.EndpointAddress{{IP: "10.244.1.5", NodeName: &nodeName}},
+			}},
+		}
+	}
+
+	start := func(objects ...runtime.Object) {
+		fakeClient := &util.OVNClientset{
+			KubeClient: fake.NewSimpleClientset(objects...),
+		}
+		var err error
+		wf, err = factory.NewNodeWatchFactory(fakeClient, "node1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wf.Start()).To(Succeed())
+
+		speaker = &fakeSpeaker{}
+		advertiser = newRouteAdvertiser("node1", []*net.IPNet{
+			ovntest.MustParseIPNet("10.244.1.0/24"),
+			ovntest.MustParseIPNet("fd00:10:244:1::/64"),
+		}, wf, speaker)
+	}
+
+	AfterEach(func() {
+		wf.Shutdown()
+	})
+
+	It("advertises the pod subnets of the node", func() {
+		start()
+		Expect(advertiser.sync()).To(BeTrue())
+		Expect(speaker.advertised).To(ConsistOf("10.244.1.0/24", "fd00:10:244:1::/64"))
+	})
+
+	It("advertises the service IPs with endpoints on the node", func() {
+		start(endpointsOn("node1"))
+		advertiser.AddService(service)
+		Expect(advertiser.sync()).To(BeTrue())
+		Expect(speaker.advertised).To(ConsistOf("10.244.1.0/24", "fd00:10:244:1::/64",
+			"192.168.1.100/32", "fd00::100/128", "192.168.1.101/32"))
+
+		advertiser.DeleteService(service)
+		Expect(advertiser.sync()).To(BeTrue())
+		Expect(speaker.advertised).To(ConsistOf("10.244.1.0/24", "fd00:10:244:1::/64"))
+	})
+
+	It("does not advertise the service IPs without endpoints on the node", func() {
+		start(endpointsOn("node2"))
+		advertiser.AddService(service)
+		Expect(advertiser.sync()).To(BeTrue())
+		Expect(speaker.advertised).To(ConsistOf("10.244.1.0/24", "fd00:10:244:1::/64"))
+	})
+})
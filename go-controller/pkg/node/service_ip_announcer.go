@@ -0,0 +1,315 @@
+package node
+
+import (
+	"hash/fnv"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/factory"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+
+	kapi "k8s.io/api/core/v1"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// serviceIPAnnouncementCount is the number of announcements a node sends
+	// when it becomes the owner of a service IP
+	serviceIPAnnouncementCount = 3
+	// serviceIPAnnouncementInterval is the time between those announcements
+	serviceIPAnnouncementInterval = time.Second
+)
+
+// serviceIPAnnouncer announces the external and load balancer IPs of services
+// that are on the subnets of the gateway bridge, with gratuitous ARPs and
+// unsolicited neighbor advertisements, so that upstream routers send the
+// traffic to them to the nodes. Each IP is announced by a single node, picked
+// by rendezvous hashing of the IP with the names of the ready nodes with a
+// shared gateway, or of the nodes with local endpoints for services with
+// externalTrafficPolicy=Local. When the picked node changes, the new one
+// announces the IP again.
+//
+// This is not a leader election: there is no lease, and the nodes only agree
+// on the owner of an IP as far as their informers agree on the nodes. A node
+// that dies keeps owning its IPs until it is marked not ready, after the node
+// monitor grace period of the node lifecycle controller, and a node that is
+// partitioned from the API server doesn't see its IPs move to another node,
+// so two nodes may have announced an IP. Upstream routers then use the latest
+// announcement.
+type serviceIPAnnouncer struct {
+	nodeName     string
+	bridgeName   string
+	macAddress   net.HardwareAddr
+	subnets      []*net.IPNet
+	watchFactory factory.NodeWatchFactory
+	// announce sends a single announcement of ip
+	announce func(ip net.IP) error
+
+	servicesLock sync.Mutex
+	services     map[ktypes.NamespacedName]*kapi.Service
+
+	// owned maps the IPs this node announces to the number of announcements
+	// left to send. It is only accessed by Run.
+	owned  map[string]int
+	syncCh chan struct{}
+}
+
+func newServiceIPAnnouncer(nodeName string, gwBridge *bridgeConfiguration, watchFactory factory.NodeWatchFactory) *serviceIPAnnouncer {
+	a := &serviceIPAnnouncer{
+		nodeName:     nodeName,
+		bridgeName:   gwBridge.bridgeName,
+		macAddress:   gwBridge.macAddress,
+		subnets:      gwBridge.ips,
+		watchFactory: watchFactory,
+		services:     map[ktypes.NamespacedName]*kapi.Service{},
+		owned:        map[string]int{},
+		syncCh:       make(chan struct{}, 1),
+	}
+	a.announce = func(ip net.IP) error {
+		return util.SendNeighborAnnouncement(a.bridgeName, a.macAddress, ip)
+	}
+	return a
+}
+
+// requestSync requests Run to re-elect the owners of the service IPs
+func (a *serviceIPAnnouncer) requestSync() {
+	select {
+	case a.syncCh <- struct{}{}:
+	default:
+		// a sync is already pending
+	}
+}
+
+func (a *serviceIPAnnouncer) AddService(svc *kapi.Service) {
+	a.servicesLock.Lock()
+	defer a.servicesLock.Unlock()
+	a.services[ktypes.NamespacedName{Namespace: svc.Namespace, Name: svc.Name}] = svc
+	a.requestSync()
+}
+
+func (a *serviceIPAnnouncer) UpdateService(old, new *kapi.Service) {
+	a.AddService(new)
+}
+
+func (a *serviceIPAnnouncer) DeleteService(svc *kapi.Service) {
+	a.servicesLock.Lock()
+	defer a.servicesLock.Unlock()
+	delete(a.services, ktypes.NamespacedName{Namespace: svc.Namespace, Name: svc.Name})
+	a.requestSync()
+}
+
+func (a *serviceIPAnnouncer) SyncServices(svcs []interface{}) {
+	for _, obj := range svcs {
+		if svc, ok := obj.(*kapi.Service); ok {
+			a.AddService(svc)
+		}
+	}
+}
+
+// endpoints only matter to services with externalTrafficPolicy=Local
+func (a *serviceIPAnnouncer) AddEndpoints(ep *kapi.Endpoints) {
+	a.syncEndpoints(ep)
+}
+
+func (a *serviceIPAnnouncer) UpdateEndpoints(old, new *kapi.Endpoints) {
+	a.syncEndpoints(new)
+}
+
+func (a *serviceIPAnnouncer) DeleteEndpoints(ep *kapi.Endpoints) {
+	a.syncEndpoints(ep)
+}
+
+func (a *serviceIPAnnouncer) syncEndpoints(ep *kapi.Endpoints) {
+	a.servicesLock.Lock()
+	svc := a.services[ktypes.NamespacedName{Namespace: ep.Namespace, Name: ep.Name}]
+	a.servicesLock.Unlock()
+	if svc != nil && util.ServiceExternalTrafficPolicyLocal(svc) {
+		a.requestSync()
+	}
+}
+
+// Run elects the owners of the service IPs on changes, and sends the
+// announcements of the IPs this node owns until stopChan is closed
+func (a *serviceIPAnnouncer) Run(stopChan <-chan struct{}) {
+	a.watchFactory.NodeInformer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			a.requestSync()
+		},
+		UpdateFunc: func(old, new interface{}) {
+			oldNode, newNode := old.(*kapi.Node), new.(*kapi.Node)
+			if nodeCanAnnounceServiceIPs(oldNode) != nodeCanAnnounceServiceIPs(newNode) {
+				a.requestSync()
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			a.requestSync()
+		},
+	})
+
+	ticker := time.NewTicker(serviceIPAnnouncementInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.syncCh:
+			a.sync()
+			a.sendAnnouncements()
+		case <-ticker.C:
+			a.sendAnnouncements()
+		case <-stopChan:
+			return
+		}
+	}
+}
+
+// sync elects the owners of the service IPs, and updates the IPs owned by
+// this node
+func (a *serviceIPAnnouncer) sync() {
+	nodes := []string{}
+	for _, obj := range a.watchFactory.NodeInformer().GetStore().List() {
+		if node, ok := obj.(*kapi.Node); ok && nodeCanAnnounceServiceIPs(node) {
+			nodes = append(nodes, node.Name)
+		}
+	}
+
+	a.servicesLock.Lock()
+	services := make([]*kapi.Service, 0, len(a.services))
+	for _, svc := range a.services {
+		services = append(services, svc)
+	}
+	a.servicesLock.Unlock()
+
+	owned := sets.NewString()
+	for _, svc := range services {
+		ips := a.serviceIPs(svc)
+		if len(ips) == 0 {
+			continue
+		}
+		candidates := nodes
+		if util.ServiceExternalTrafficPolicyLocal(svc) {
+			candidates = a.nodesWithLocalEndpoints(svc, nodes)
+		}
+		for _, ip := range ips {
+			if electServiceIPOwner(ip, candidates) == a.nodeName {
+				owned.Insert(ip)
+			}
+		}
+	}
+
+	for ip := range a.owned {
+		if !owned.Has(ip) {
+			klog.Infof("No longer announcing service IP %s", ip)
+			delete(a.owned, ip)
+		}
+	}
+	for _, ip := range owned.List() {
+		if _, ok := a.owned[ip]; !ok {
+			klog.Infof("Announcing service IP %s on %s", ip, a.bridgeName)
+			a.owned[ip] = serviceIPAnnouncementCount
+		}
+	}
+}
+
+// sendAnnouncements sends the pending announcements of the owned IPs
+func (a *serviceIPAnnouncer) sendAnnouncements() {
+	for ip, left := range a.owned {
+		if left == 0 {
+			continue
+		}
+		if err := a.announce(net.ParseIP(ip)); err != nil {
+			klog.Warningf("Failed to announce service IP %s: %v", ip, err)
+		}
+		a.owned[ip] = left - 1
+	}
+}
+
+// serviceIPs returns the external and load balancer IPs of a service that are
+// on the subnets of the gateway bridge
+func (a *serviceIPAnnouncer) serviceIPs(svc *kapi.Service) []string {
+	if !util.ServiceTypeHasClusterIP(svc) || !util.IsClusterIPSet(svc) {
+		return nil
+	}
+	candidates := append([]string{}, svc.Spec.ExternalIPs...)
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			candidates = append(candidates, ingress.IP)
+		}
+	}
+
+	ips := []string{}
+	for _, candidate := range candidates {
+		ip := net.ParseIP(candidate)
+		if ip == nil {
+			continue
+		}
+		for _, subnet := range a.subnets {
+			if subnet.Contains(ip) {
+				ips = append(ips, ip.String())
+				break
+			}
+		}
+	}
+	return ips
+}
+
+// nodesWithLocalEndpoints returns the nodes that have ready endpoints of svc
+func (a *serviceIPAnnouncer) nodesWithLocalEndpoints(svc *kapi.Service, nodes []string) []string {
+	ep, err := a.watchFactory.GetEndpoint(svc.Namespace, svc.Name)
+	if err != nil {
+		return nil
+	}
+	endpointNodes := sets.NewString()
+	for _, subset := range ep.Subsets {
+		for _, addr := range subset.Addresses {
+			if addr.NodeName != nil {
+				endpointNodes.Insert(*addr.NodeName)
+			}
+		}
+	}
+	out := []string{}
+	for _, node := range nodes {
+		if endpointNodes.Has(node) {
+			out = append(out, node)
+		}
+	}
+	return out
+}
+
+// nodeCanAnnounceServiceIPs returns true if a node is ready, has a shared
+// gateway and isn't excluded from external load balancers
+func nodeCanAnnounceServiceIPs(node *kapi.Node) bool {
+	if _, ok := node.Labels[kapi.LabelNodeExcludeBalancers]; ok {
+		return false
+	}
+	l3GatewayConfig, err := util.ParseNodeL3GatewayAnnotation(node)
+	if err != nil || l3GatewayConfig.Mode != config.GatewayModeShared {
+		return false
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == kapi.NodeReady {
+			return condition.Status == kapi.ConditionTrue
+		}
+	}
+	return false
+}
+
+// electServiceIPOwner returns the node that announces ip, or "" if there are
+// no nodes, by rendezvous hashing. All nodes seeing the same nodes pick the
+// same owner, and only the IPs owned by a node that goes away move to other
+// nodes.
+func electServiceIPOwner(ip string, nodes []string) string {
+	owner := ""
+	var ownerHash uint64
+	for _, node := range nodes {
+		h := fnv.New64a()
+		h.Write([]byte(node + "#" + ip))
+		if hash := h.Sum64(); owner == "" || hash < ownerHash || hash == ownerHash && node < owner {
+			owner, ownerHash = node, hash
+		}
+	}
+	return owner
+}
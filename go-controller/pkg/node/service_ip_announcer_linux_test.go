@@ -0,0 +1,196 @@
+// +build linux
+
+package node
+
+import (
+	"fmt"
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/factory"
+	ovntest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Node Operations service IP announcer", func() {
+	const (
+		externalIP = "192.168.1.100"
+		lbIP       = "192.168.1.101"
+	)
+
+	var (
+		wf         *factory.WatchFactory
+		announcers map[string]*serviceIPAnnouncer
+		announced  map[string][]string
+	)
+
+	gatewayNode := func(name string, ready bool) *v1.Node {
+		status := v1.ConditionFalse
+		if ready {
+			status = v1.ConditionTrue
+		}
+		return &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+				Annotations: map[string]string{
+					"k8s.ovn.org/l3-gateway-config": `{"default":{"mode":"shared","interface-id":"breth0_` + name +
+						`","mac-address":"00:00:00:55:66:77","ip-addresses":["192.168.1.10/24"],"next-hops":["192.168.1.1"],"node-port-enable":"true","vlan-id":"0"}}`,
+					"k8s.ovn.org/node-chassis-id": "79fdcfc4-6fe6-4cd3-8242-c0f85a4668ec",
+				},
+			},
+			Status: v1.NodeStatus{
+				Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: status}},
+			},
+		}
+	}
+
+	service := func(etpLocal bool) *v1.Service {
+		svc := &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "ns"},
+			Spec: v1.ServiceSpec{
+				Type:        v1.ServiceTypeLoadBalancer,
+				ClusterIP:   "10.96.0.10",
+				ExternalIPs: []string{externalIP, "10.1.1.1"},
+			},
+			Status: v1.ServiceStatus{
+				LoadBalancer: v1.LoadBalancerStatus{
+					Ingress: []v1.LoadBalancerIngress{{IP: lbIP}},
+				},
+			},
+		}
+		if etpLocal {
+			svc.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyTypeLocal
+		}
+		return svc
+	}
+
+	// syncAll syncs the announcers of all nodes and sends their announcements
+	syncAll := func() {
+		for _, a := range announcers {
+			a.sync()
+			a.sendAnnouncements()
+		}
+	}
+
+	ownersOf := func(ip string) []string {
+		owners := []string{}
+		for name, a := range announcers {
+			if _, ok := a.owned[ip]; ok {
+				owners = append(owners, name)
+			}
+		}
+		return owners
+	}
+
+	start := func(svc *v1.Service, endpoints ...runtime.Object) {
+		objects := append([]runtime.Object{
+			gatewayNode("node1", true),
+			gatewayNode("node2", true),
+			gatewayNode("node3", true),
+		}, endpoints...)
+		fakeClient := &util.OVNClientset{
+			KubeClient: fake.NewSimpleClientset(objects...),
+		}
+		var err error
+		wf, err = factory.NewNodeWatchFactory(fakeClient, "node1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wf.Start()).To(Succeed())
+
+		announcers = map[string]*serviceIPAnnouncer{}
+		announced = map[string][]string{}
+		for _, name := range []string{"node1", "node2", "node3"} {
+			name := name
+			a := newServiceIPAnnouncer(name, &bridgeConfiguration{
+				bridgeName: "breth0",
+				macAddress: ovntest.MustParseMAC("00:00:00:55:66:77"),
+				ips:        []*net.IPNet{ovntest.MustParseIPNet("192.168.1.10/24")},
+			}, wf)
+			a.announce = func(ip net.IP) error {
+				announced[name] = append(announced[name], ip.String())
+				return nil
+			}
+			a.AddService(svc)
+			announcers[name] = a
+		}
+	}
+
+	AfterEach(func() {
+		wf.Shutdown()
+	})
+
+	It("elects a single node to announce each service IP on the gateway subnet", func() {
+		start(service(false))
+		syncAll()
+
+		for _, ip := range []string{externalIP, lbIP} {
+			owners := ownersOf(ip)
+			Expect(owners).To(HaveLen(1), fmt.Sprintf("owners of %s", ip))
+			Expect(announced[owners[0]]).To(ContainElement(ip))
+		}
+		Expect(ownersOf("10.1.1.1")).To(BeEmpty())
+
+		// announcements are repeated a few times, then stop
+		for i := 0; i < serviceIPAnnouncementCount+2; i++ {
+			for _, a := range announcers {
+				a.sendAnnouncements()
+			}
+		}
+		owner := ownersOf(externalIP)[0]
+		count := 0
+		for _, ip := range announced[owner] {
+			if ip == externalIP {
+				count++
+			}
+		}
+		Expect(count).To(Equal(serviceIPAnnouncementCount))
+	})
+
+	It("moves a service IP to another node when its owner becomes not ready", func() {
+		start(service(false))
+		syncAll()
+		owner := ownersOf(externalIP)[0]
+
+		Expect(wf.NodeInformer().GetStore().Update(gatewayNode(owner, false))).To(Succeed())
+		announced = map[string][]string{}
+		syncAll()
+
+		owners := ownersOf(externalIP)
+		Expect(owners).To(HaveLen(1))
+		Expect(owners[0]).NotTo(Equal(owner))
+		Expect(announced[owners[0]]).To(ContainElement(externalIP))
+	})
+
+	It("only announces the IPs of externalTrafficPolicy=Local services from nodes with endpoints", func() {
+		node := "node3"
+		start(service(true), &v1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "ns"},
+			Subsets: []v1.EndpointSubset{{
+				Addresses: []v1.EndpointAddress{{IP: "10.244.2.5", NodeName: &node}},
+			}},
+		})
+		syncAll()
+
+		Expect(ownersOf(externalIP)).To(Equal([]string{node}))
+		Expect(ownersOf(lbIP)).To(Equal([]string{node}))
+	})
+
+	It("stops announcing the IPs of deleted services", func() {
+		svc := service(false)
+		start(svc)
+		syncAll()
+		for _, a := range announcers {
+			a.DeleteService(svc)
+		}
+		syncAll()
+
+		Expect(ownersOf(externalIP)).To(BeEmpty())
+		Expect(ownersOf(lbIP)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,194 @@
+package openflow
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout is the default timeout of the requests of a client
+const DefaultTimeout = 10 * time.Second
+
+// supportedVersions are the OpenFlow versions of the client, from the highest
+var supportedVersions = []uint8{Version15, Version13}
+
+// Client is an OpenFlow connection to a bridge. Its requests are serialized.
+type Client struct {
+	sync.Mutex
+	conn    net.Conn
+	version uint8
+	xid     uint32
+	// Timeout is the timeout of each request
+	Timeout time.Duration
+}
+
+// Dial connects to the OpenFlow management socket of a bridge, the
+// <bridge>.mgmt unix socket in the OVS run directory
+func Dial(socketPath string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", socketPath, DefaultTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the OpenFlow management socket %s: %v", socketPath, err)
+	}
+	c, err := NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to negotiate OpenFlow on %s: %v", socketPath, err)
+	}
+	return c, nil
+}
+
+// NewClient negotiates the OpenFlow version over conn and returns a client
+// using it
+func NewClient(conn net.Conn) (*Client, error) {
+	c := &Client{conn: conn, Timeout: DefaultTimeout}
+	if err := c.hello(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Version returns the negotiated OpenFlow version
+func (c *Client) Version() uint8 {
+	return c.version
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// AggregateStats returns the statistics of the flows selected by filter
+func (c *Client) AggregateStats(filter *FlowFilter) (*AggregateStats, error) {
+	c.Lock()
+	defer c.Unlock()
+	msg := multipartRequest(c.version, c.nextXid(), multipartAggregate, filter)
+	body, err := c.multipart(msg, multipartAggregate)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalAggregateStats(c.version, body)
+}
+
+func (c *Client) nextXid() uint32 {
+	c.xid++
+	return c.xid
+}
+
+// hello exchanges hello messages, offering the supported versions, and
+// picks the highest one both ends support
+func (c *Client) hello() error {
+	if err := c.conn.SetDeadline(time.Now().Add(c.Timeout)); err != nil {
+		return err
+	}
+	var bitmap uint32
+	for _, version := range supportedVersions {
+		bitmap |= 1 << version
+	}
+	msg := header(supportedVersions[0], typeHello, 16, c.nextXid())
+	elem := make([]byte, 8)
+	binary.BigEndian.PutUint16(elem[0:], helloElemVersionBitmap)
+	binary.BigEndian.PutUint16(elem[2:], 8)
+	binary.BigEndian.PutUint32(elem[4:], bitmap)
+	if _, err := c.conn.Write(append(msg, elem...)); err != nil {
+		return err
+	}
+
+	version, msgType, _, body, err := c.read()
+	if err != nil {
+		return err
+	}
+	if msgType != typeHello {
+		return fmt.Errorf("expected hello, got message type %d", msgType)
+	}
+	peerBitmap := uint32(0)
+	for len(body) >= 4 {
+		elemType := binary.BigEndian.Uint16(body[0:])
+		elemLen := int(binary.BigEndian.Uint16(body[2:]))
+		if elemLen < 4 || elemLen > len(body) {
+			break
+		}
+		if elemType == helloElemVersionBitmap && elemLen >= 8 {
+			peerBitmap = binary.BigEndian.Uint32(body[4:])
+		}
+		body = body[(elemLen+7)/8*8:]
+	}
+	for _, v := range supportedVersions {
+		if (peerBitmap != 0 && peerBitmap&(1<<v) != 0) || (peerBitmap == 0 && v <= version) {
+			c.version = v
+			return nil
+		}
+	}
+	return fmt.Errorf("no common OpenFlow version, the switch supports up to 0x%02x", version)
+}
+
+// read reads a message, answering echo requests in between
+func (c *Client) read() (uint8, uint8, uint32, []byte, error) {
+	for {
+		h := make([]byte, headerLen)
+		if _, err := io.ReadFull(c.conn, h); err != nil {
+			return 0, 0, 0, nil, err
+		}
+		length := int(binary.BigEndian.Uint16(h[2:]))
+		if length < headerLen {
+			return 0, 0, 0, nil, fmt.Errorf("invalid OpenFlow message length %d", length)
+		}
+		body := make([]byte, length-headerLen)
+		if _, err := io.ReadFull(c.conn, body); err != nil {
+			return 0, 0, 0, nil, err
+		}
+		version, msgType, xid := h[0], h[1], binary.BigEndian.Uint32(h[4:])
+		if msgType == typeEchoRequest {
+			reply := header(version, typeEchoReply, headerLen+len(body), xid)
+			if _, err := c.conn.Write(append(reply, body...)); err != nil {
+				return 0, 0, 0, nil, err
+			}
+			continue
+		}
+		return version, msgType, xid, body, nil
+	}
+}
+
+func unmarshalError(body []byte) error {
+	if len(body) < 4 {
+		return fmt.Errorf("OpenFlow error message too short")
+	}
+	return &Error{Type: binary.BigEndian.Uint16(body[0:]), Code: binary.BigEndian.Uint16(body[2:])}
+}
+
+// multipart sends a multipart request and returns the concatenated bodies of
+// its replies
+func (c *Client) multipart(msg []byte, multipartType uint16) ([]byte, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(c.Timeout)); err != nil {
+		return nil, err
+	}
+	reqXid := binary.BigEndian.Uint32(msg[4:])
+	if _, err := c.conn.Write(msg); err != nil {
+		return nil, err
+	}
+	var bodies []byte
+	for {
+		_, msgType, xid, body, err := c.read()
+		if err != nil {
+			return nil, err
+		}
+		if xid != reqXid {
+			continue
+		}
+		if msgType == typeError {
+			return nil, unmarshalError(body)
+		}
+		if msgType != typeMultipartReply || len(body) < 8 {
+			return nil, fmt.Errorf("unexpected reply of type %d to a multipart request", msgType)
+		}
+		if replyType := binary.BigEndian.Uint16(body[0:]); replyType != multipartType {
+			return nil, fmt.Errorf("unexpected multipart reply type %d", replyType)
+		}
+		bodies = append(bodies, body[8:]...)
+		if binary.BigEndian.Uint16(body[2:])&multipartReplyMore == 0 {
+			return bodies, nil
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package openflow
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSwitch answers the hello of a client with versions, then calls handle
+// with each message it receives and sends back the messages it returns
+func fakeSwitch(t *testing.T, versions []uint8, handle func(version, msgType uint8, xid uint32, body []byte) [][]byte) *Client {
+	clientConn, switchConn := net.Pipe()
+	t.Cleanup(func() {
+		clientConn.Close()
+		switchConn.Close()
+	})
+
+	// net.Pipe is unbuffered, so writes go through a queue to not block the
+	// reads
+	out := make(chan []byte, 100)
+	go func() {
+		for msg := range out {
+			if _, err := switchConn.Write(msg); err != nil {
+				return
+			}
+		}
+	}()
+	go func() {
+		defer close(out)
+		var bitmap uint32
+		for _, v := range versions {
+			bitmap |= 1 << v
+		}
+		hello := header(versions[len(versions)-1], typeHello, 16, 1)
+		elem := make([]byte, 8)
+		binary.BigEndian.PutUint16(elem[0:], helloElemVersionBitmap)
+		binary.BigEndian.PutUint16(elem[2:], 8)
+		binary.BigEndian.PutUint32(elem[4:], bitmap)
+		out <- append(hello, elem...)
+		for {
+			h := make([]byte, headerLen)
+			if _, err := io.ReadFull(switchConn, h); err != nil {
+				return
+			}
+			body := make([]byte, int(binary.BigEndian.Uint16(h[2:]))-headerLen)
+			if _, err := io.ReadFull(switchConn, body); err != nil {
+				return
+			}
+			if h[1] == typeHello {
+				continue
+			}
+			for _, msg := range handle(h[0], h[1], binary.BigEndian.Uint32(h[4:]), body) {
+				out <- msg
+			}
+		}
+	}()
+
+	c, err := NewClient(clientConn)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	c.Timeout = 5 * time.Second
+	return c
+}
+
+func pad8(b []byte) []byte {
+	if n := len(b) % 8; n != 0 {
+		b = append(b, make([]byte, 8-n)...)
+	}
+	return b
+}
+
+func multipartReply(version uint8, xid uint32, multipartType uint16, more bool, body []byte) []byte {
+	msg := header(version, typeMultipartReply, headerLen, xid)
+	mp := make([]byte, 8)
+	binary.BigEndian.PutUint16(mp[0:], multipartType)
+	if more {
+		binary.BigEndian.PutUint16(mp[2:], multipartReplyMore)
+	}
+	return finishMessage(append(append(msg, mp...), body...))
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	tests := []struct {
+		desc     string
+		versions []uint8
+		expected uint8
+	}{
+		{"picks OpenFlow 1.5 when the switch supports it", []uint8{0x01, Version13, 0x05, Version15}, Version15},
+		{"falls back to OpenFlow 1.3", []uint8{0x01, Version13, 0x05}, Version13},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			c := fakeSwitch(t, tc.versions, func(uint8, uint8, uint32, []byte) [][]byte { return nil })
+			assert.Equal(t, tc.expected, c.Version())
+		})
+	}
+}
+
+func TestAggregateStats(t *testing.T) {
+	oxs := func(field uint8, value uint64, length int) []byte {
+		b := make([]byte, 4+length)
+		binary.BigEndian.PutUint16(b[0:], oxsClassBasic)
+		b[2] = field << 1
+		b[3] = uint8(length)
+		if length == 4 {
+			binary.BigEndian.PutUint32(b[4:], uint32(value))
+		} else {
+			binary.BigEndian.PutUint64(b[4:], value)
+		}
+		return b
+	}
+
+	tests := []struct {
+		desc    string
+		version uint8
+		reply   func() []byte
+	}{
+		{
+			desc:    "OpenFlow 1.3",
+			version: Version13,
+			reply: func() []byte {
+				b := make([]byte, 24)
+				binary.BigEndian.PutUint64(b[0:], 100)
+				binary.BigEndian.PutUint64(b[8:], 6400)
+				binary.BigEndian.PutUint32(b[16:], 42)
+				return b
+			},
+		},
+		{
+			desc:    "OpenFlow 1.5",
+			version: Version15,
+			reply: func() []byte {
+				fields := append(append(oxs(oxsFlowCount, 42, 4), oxs(oxsPacketCount, 100, 8)...), oxs(oxsByteCount, 6400, 8)...)
+				b := make([]byte, 4)
+				binary.BigEndian.PutUint16(b[2:], uint16(4+len(fields)))
+				return pad8(append(b, fields...))
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			c := fakeSwitch(t, []uint8{tc.version}, func(version, msgType uint8, xid uint32, body []byte) [][]byte {
+				return [][]byte{multipartReply(version, xid, multipartAggregate, false, tc.reply())}
+			})
+			stats, err := c.AggregateStats(&FlowFilter{Table: TableAll})
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			assert.Equal(t, &AggregateStats{FlowCount: 42, PacketCount: 100, ByteCount: 6400}, stats)
+		})
+	}
+}
+
+func TestAggregateStatsError(t *testing.T) {
+	var request []byte
+	c := fakeSwitch(t, []uint8{Version13}, func(version, msgType uint8, xid uint32, body []byte) [][]byte {
+		request = body
+		reply := header(version, typeError, headerLen, xid)
+		return [][]byte{finishMessage(append(reply, 0, 1, 0, 2))}
+	})
+
+	_, err := c.AggregateStats(&FlowFilter{Table: 9, OutPort: 3})
+	assert.Equal(t, &Error{Type: 1, Code: 2}, err)
+	assert.Equal(t, multipartAggregate, binary.BigEndian.Uint16(request[0:]))
+	assert.Equal(t, uint8(9), request[8])
+	assert.Equal(t, uint32(3), binary.BigEndian.Uint32(request[12:]))
+}
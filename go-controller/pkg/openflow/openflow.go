@@ -0,0 +1,186 @@
+// Package openflow is a minimal OpenFlow 1.3 and 1.5 client, talking to the
+// management socket of an OVS bridge directly instead of running ovs-ofctl
+// and parsing its output.
+//
+// It only reads the aggregate statistics of flows, for the flow counts that
+// the metrics and the readiness checks poll. Adding, deleting and dumping
+// flows and group mods are out of its scope: the gateway, hybrid overlay and
+// CNI flows use the ovs-ofctl syntax with many Nicira extensions, which would
+// all need encoders, so they are still programmed and dumped with ovs-ofctl.
+package openflow
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// OpenFlow versions
+const (
+	Version13 uint8 = 0x04
+	Version15 uint8 = 0x06
+)
+
+// message types, the same in all the supported versions
+const (
+	typeHello            uint8 = 0
+	typeError            uint8 = 1
+	typeEchoRequest      uint8 = 2
+	typeEchoReply        uint8 = 3
+	typeMultipartRequest uint8 = 18
+	typeMultipartReply   uint8 = 19
+)
+
+const (
+	headerLen = 8
+
+	helloElemVersionBitmap = 1
+
+	multipartAggregate uint16 = 2
+	multipartReplyMore uint16 = 1
+
+	matchTypeOXM   uint16 = 1
+	oxsClassBasic  uint16 = 0x8002
+	oxsFlowCount   uint8  = 3
+	oxsPacketCount uint8  = 4
+	oxsByteCount   uint8  = 5
+)
+
+// Reserved ports, tables and groups
+const (
+	PortAny uint32 = 0xffffffff
+
+	TableAll uint8 = 0xff
+
+	GroupAny uint32 = 0xffffffff
+)
+
+// FlowFilter selects the flows to read the statistics of
+type FlowFilter struct {
+	// Table is the table of the flows, or TableAll. The zero value is
+	// table 0.
+	Table uint8
+	// Cookie and CookieMask select the flows whose cookie is Cookie in the
+	// bits of CookieMask
+	Cookie     uint64
+	CookieMask uint64
+	// OutPort selects the flows that output to a port, unless it's 0 or
+	// PortAny
+	OutPort uint32
+}
+
+// AggregateStats are the statistics of a set of flows
+type AggregateStats struct {
+	FlowCount   uint32
+	PacketCount uint64
+	ByteCount   uint64
+}
+
+// Error is an error message received from the switch
+type Error struct {
+	Type uint16
+	Code uint16
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("OpenFlow error type %d code %d", e.Type, e.Code)
+}
+
+func header(version, msgType uint8, length int, xid uint32) []byte {
+	b := make([]byte, headerLen, length)
+	b[0] = version
+	b[1] = msgType
+	binary.BigEndian.PutUint16(b[2:], uint16(length))
+	binary.BigEndian.PutUint32(b[4:], xid)
+	return b
+}
+
+// finishMessage sets the length of a message in its header
+func finishMessage(b []byte) []byte {
+	binary.BigEndian.PutUint16(b[2:], uint16(len(b)))
+	return b
+}
+
+// multipartRequest encodes an aggregate statistics request, which is
+// the same in OpenFlow 1.3 and 1.5, with an empty match
+func multipartRequest(version uint8, xid uint32, multipartType uint16, filter *FlowFilter) []byte {
+	b := header(version, typeMultipartRequest, 56, xid)
+	body := make([]byte, 40)
+	binary.BigEndian.PutUint16(body[0:], multipartType)
+	body[8] = filter.Table
+	outPort := PortAny
+	if filter.OutPort != 0 {
+		outPort = filter.OutPort
+	}
+	binary.BigEndian.PutUint32(body[12:], outPort)
+	binary.BigEndian.PutUint32(body[16:], GroupAny)
+	binary.BigEndian.PutUint64(body[24:], filter.Cookie)
+	binary.BigEndian.PutUint64(body[32:], filter.CookieMask)
+	b = append(b, body...)
+
+	match := make([]byte, 8)
+	binary.BigEndian.PutUint16(match[0:], matchTypeOXM)
+	binary.BigEndian.PutUint16(match[2:], 4)
+	return finishMessage(append(b, match...))
+}
+
+// unmarshalAggregateStats decodes the aggregate statistics of a multipart
+// reply body
+func unmarshalAggregateStats(version uint8, b []byte) (*AggregateStats, error) {
+	if version == Version13 {
+		if len(b) < 24 {
+			return nil, fmt.Errorf("aggregate stats too short: %d bytes", len(b))
+		}
+		return &AggregateStats{
+			PacketCount: binary.BigEndian.Uint64(b[0:]),
+			ByteCount:   binary.BigEndian.Uint64(b[8:]),
+			FlowCount:   binary.BigEndian.Uint32(b[16:]),
+		}, nil
+	}
+	values, _, err := unmarshalStats(b)
+	if err != nil {
+		return nil, err
+	}
+	return &AggregateStats{
+		PacketCount: values[oxsPacketCount],
+		ByteCount:   values[oxsByteCount],
+		FlowCount:   uint32(values[oxsFlowCount]),
+	}, nil
+}
+
+// unmarshalStats decodes the OXS statistics of OpenFlow 1.5, and returns them
+// by field with their padded length
+func unmarshalStats(b []byte) (map[uint8]uint64, int, error) {
+	if len(b) < 4 {
+		return nil, 0, fmt.Errorf("stats too short: %d bytes", len(b))
+	}
+	length := int(binary.BigEndian.Uint16(b[2:]))
+	padded := (length + 7) / 8 * 8
+	if length < 4 || len(b) < length {
+		return nil, 0, fmt.Errorf("invalid stats length %d", length)
+	}
+	values := map[uint8]uint64{}
+	fields := b[4:length]
+	for len(fields) > 0 {
+		if len(fields) < 4 {
+			return nil, 0, fmt.Errorf("OXS field too short: %d bytes", len(fields))
+		}
+		class := binary.BigEndian.Uint16(fields[0:])
+		field := fields[2] >> 1
+		valueLen := int(fields[3])
+		if len(fields) < 4+valueLen {
+			return nil, 0, fmt.Errorf("OXS field %d too short", field)
+		}
+		value := fields[4 : 4+valueLen]
+		fields = fields[4+valueLen:]
+		if class != oxsClassBasic {
+			continue
+		}
+		switch valueLen {
+		case 4:
+			values[field] = uint64(binary.BigEndian.Uint32(value))
+		case 8:
+			values[field] = binary.BigEndian.Uint64(value)
+		}
+	}
+	return values, padded, nil
+}
@@ -0,0 +1,378 @@
+package loadbalancerip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"time"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/ipallocator"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	utilnet "k8s.io/utils/net"
+)
+
+const (
+	// maxRetries is the number of times a service will be retried before it
+	// is dropped out of the queue
+	maxRetries = 15
+
+	controllerName = "ovn-lb-ip-allocator"
+)
+
+// Controller allocates the ingress IPs of LoadBalancer services from the
+// configured pools and writes them to the service status, from where the
+// services controller and the nodes pick them up like any other load
+// balancer IP. Services with a loadBalancerClass, or with ingress IPs set by
+// another controller, are left alone.
+type Controller struct {
+	client        clientset.Interface
+	eventRecorder record.EventRecorder
+
+	serviceLister  corelisters.ServiceLister
+	servicesSynced cache.InformerSynced
+
+	queue workqueue.RateLimitingInterface
+
+	pools []*ipallocator.Range
+	// allocated maps service keys to the IPs allocated to them. It is only
+	// accessed by the single worker, so needs no lock.
+	allocated map[string][]net.IP
+}
+
+// NewController creates a new load balancer IP allocator for the given pools
+func NewController(client clientset.Interface, recorder record.EventRecorder,
+	serviceInformer cache.SharedIndexInformer, pools []*net.IPNet) (*Controller, error) {
+	c := &Controller{
+		client:         client,
+		eventRecorder:  recorder,
+		serviceLister:  corelisters.NewServiceLister(serviceInformer.GetIndexer()),
+		servicesSynced: serviceInformer.HasSynced,
+		queue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
+		allocated:      map[string][]net.IP{},
+	}
+	for _, pool := range pools {
+		r, err := ipallocator.NewCIDRRange(pool)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create load balancer IP pool %s: %v", pool, err)
+		}
+		c.pools = append(c.pools, r)
+	}
+
+	serviceInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueService,
+		UpdateFunc: func(old, new interface{}) {
+			c.enqueueService(new)
+		},
+		DeleteFunc: c.enqueueService,
+	})
+	return c, nil
+}
+
+func (c *Controller) enqueueService(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("couldn't get key for object %+v: %v", obj, err))
+		return
+	}
+	c.queue.Add(key)
+}
+
+// Run will not return until stopCh is closed
+func (c *Controller) Run(stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Infof("Starting controller %s", controllerName)
+	defer klog.Infof("Shutting down controller %s", controllerName)
+
+	if !cache.WaitForNamedCacheSync(controllerName, stopCh, c.servicesSynced) {
+		return
+	}
+	if err := c.restoreAllocations(); err != nil {
+		klog.Errorf("Failed to restore load balancer IP allocations: %v", err)
+		return
+	}
+
+	// a single worker, allocations are serialized
+	go wait.Until(c.worker, time.Second, stopCh)
+
+	<-stopCh
+}
+
+// restoreAllocations marks the IPs of the pools already in the status of
+// services as allocated, so they keep them across restarts. IPs claimed by
+// more than one service are kept by the first, the others get a new one.
+func (c *Controller) restoreAllocations() error {
+	services, err := c.serviceLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, service := range services {
+		if !c.managesService(service) {
+			continue
+		}
+		key, err := cache.MetaNamespaceKeyFunc(service)
+		if err != nil {
+			return err
+		}
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			ip := net.ParseIP(ingress.IP)
+			pool := c.poolFor(ip)
+			if pool == nil {
+				continue
+			}
+			if err := pool.Allocate(ip); err != nil {
+				klog.Warningf("Load balancer IP %s of service %s is already in use: %v", ip, key, err)
+				continue
+			}
+			c.allocated[key] = append(c.allocated[key], ip)
+		}
+	}
+	return nil
+}
+
+func (c *Controller) worker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *Controller) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	err := c.syncService(key.(string))
+	if err == nil {
+		c.queue.Forget(key)
+		return true
+	}
+	if c.queue.NumRequeues(key) < maxRetries {
+		klog.V(2).Infof("Error allocating load balancer IPs of service %s, retrying: %v", key, err)
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	klog.Warningf("Dropping service %q out of the queue: %v", key, err)
+	c.queue.Forget(key)
+	utilruntime.HandleError(err)
+	return true
+}
+
+// syncService allocates the load balancer IPs of a service, or releases them
+// once the service is deleted or no longer a LoadBalancer, and updates the
+// service status accordingly
+func (c *Controller) syncService(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	service, err := c.serviceLister.Services(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		c.release(key)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if !c.managesService(service) {
+		c.release(key)
+		return nil
+	}
+
+	var ips []net.IP
+	if service.Spec.Type == v1.ServiceTypeLoadBalancer {
+		ips, err = c.allocate(key, service)
+		if err != nil {
+			c.eventRecorder.Eventf(service, v1.EventTypeWarning, "LoadBalancerIPAllocationFailed",
+				"Failed to allocate load balancer IPs: %v", err)
+			return err
+		}
+	} else {
+		c.release(key)
+	}
+
+	return c.updateStatus(service, ips)
+}
+
+// managesService returns true if the load balancer IPs of a service are
+// allocated by this controller
+func (c *Controller) managesService(service *v1.Service) bool {
+	if service.Spec.LoadBalancerClass != nil {
+		return false
+	}
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ingress.Hostname != "" || c.poolFor(net.ParseIP(ingress.IP)) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// allocate returns the load balancer IPs of a service, one per IP family of
+// the service with a pool, allocating them if needed. The IP requested by
+// spec.loadBalancerIP is allocated for its family.
+func (c *Controller) allocate(key string, service *v1.Service) ([]net.IP, error) {
+	requested := net.ParseIP(service.Spec.LoadBalancerIP)
+	if service.Spec.LoadBalancerIP != "" && requested == nil {
+		return nil, fmt.Errorf("invalid loadBalancerIP %q", service.Spec.LoadBalancerIP)
+	}
+
+	current := c.allocated[key]
+	ips := []net.IP{}
+	for _, family := range serviceIPFamilies(service) {
+		isIPv6 := family == v1.IPv6Protocol
+		var ip net.IP
+		for _, allocated := range current {
+			if utilnet.IsIPv6(allocated) == isIPv6 {
+				ip = allocated
+			}
+		}
+
+		if requested != nil && utilnet.IsIPv6(requested) == isIPv6 && !requested.Equal(ip) {
+			pool := c.poolFor(requested)
+			if pool == nil {
+				return nil, fmt.Errorf("loadBalancerIP %s is not in any load balancer IP pool", requested)
+			}
+			if err := pool.Allocate(requested); err != nil {
+				return nil, fmt.Errorf("failed to allocate loadBalancerIP %s: %v", requested, err)
+			}
+			c.releaseIP(key, ip)
+			ip = requested
+			c.allocated[key] = append(c.allocated[key], ip)
+		}
+
+		if ip == nil {
+			var err error
+			ip, err = c.allocateNext(isIPv6)
+			if err != nil {
+				return nil, err
+			}
+			if ip == nil {
+				// no pool for this family
+				continue
+			}
+			c.allocated[key] = append(c.allocated[key], ip)
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+// allocateNext allocates the next free IP of the pools of an IP family, or
+// returns nil if there is no pool of that family
+func (c *Controller) allocateNext(isIPv6 bool) (net.IP, error) {
+	hasPool := false
+	for _, pool := range c.pools {
+		cidr := pool.CIDR()
+		if utilnet.IsIPv6CIDR(&cidr) != isIPv6 {
+			continue
+		}
+		hasPool = true
+		ip, err := pool.AllocateNext()
+		if err == ipallocator.ErrFull {
+			continue
+		}
+		return ip, err
+	}
+	if hasPool {
+		return nil, fmt.Errorf("all load balancer IP pools are full")
+	}
+	return nil, nil
+}
+
+// release returns the IPs allocated to a service to their pools
+func (c *Controller) release(key string) {
+	for _, ip := range c.allocated[key] {
+		if pool := c.poolFor(ip); pool != nil {
+			if err := pool.Release(ip); err != nil {
+				klog.Errorf("Failed to release load balancer IP %s of service %s: %v", ip, key, err)
+			}
+		}
+	}
+	delete(c.allocated, key)
+}
+
+// releaseIP returns a single IP allocated to a service to its pool
+func (c *Controller) releaseIP(key string, ip net.IP) {
+	if ip == nil {
+		return
+	}
+	if pool := c.poolFor(ip); pool != nil {
+		if err := pool.Release(ip); err != nil {
+			klog.Errorf("Failed to release load balancer IP %s of service %s: %v", ip, key, err)
+		}
+	}
+	ips := []net.IP{}
+	for _, allocated := range c.allocated[key] {
+		if !allocated.Equal(ip) {
+			ips = append(ips, allocated)
+		}
+	}
+	c.allocated[key] = ips
+}
+
+// poolFor returns the pool an IP belongs to, or nil
+func (c *Controller) poolFor(ip net.IP) *ipallocator.Range {
+	if ip == nil {
+		return nil
+	}
+	for _, pool := range c.pools {
+		cidr := pool.CIDR()
+		if cidr.Contains(ip) {
+			return pool
+		}
+	}
+	return nil
+}
+
+// updateStatus sets the ingress IPs of a service to ips, if they changed
+func (c *Controller) updateStatus(service *v1.Service, ips []net.IP) error {
+	ingress := []v1.LoadBalancerIngress{}
+	for _, ip := range ips {
+		ingress = append(ingress, v1.LoadBalancerIngress{IP: ip.String()})
+	}
+	if len(ingress) == 0 && len(service.Status.LoadBalancer.Ingress) == 0 ||
+		reflect.DeepEqual(ingress, service.Status.LoadBalancer.Ingress) {
+		return nil
+	}
+
+	klog.Infof("Setting load balancer IPs of service %s/%s to %v", service.Namespace, service.Name, ips)
+	updated := service.DeepCopy()
+	updated.Status.LoadBalancer.Ingress = ingress
+	_, err := c.client.CoreV1().Services(service.Namespace).UpdateStatus(context.TODO(), updated, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update status of service %s/%s: %v", service.Namespace, service.Name, err)
+	}
+	return nil
+}
+
+// serviceIPFamilies returns the IP families of a service, from its cluster IPs
+// if the API server didn't set them
+func serviceIPFamilies(service *v1.Service) []v1.IPFamily {
+	if len(service.Spec.IPFamilies) > 0 {
+		return service.Spec.IPFamilies
+	}
+	families := []v1.IPFamily{}
+	for _, ip := range service.Spec.ClusterIPs {
+		if utilnet.IsIPv6String(ip) {
+			families = append(families, v1.IPv6Protocol)
+		} else if net.ParseIP(ip) != nil {
+			families = append(families, v1.IPv4Protocol)
+		}
+	}
+	return families
+}
@@ -0,0 +1,164 @@
+package loadbalancerip
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ovntest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	utilpointer "k8s.io/utils/pointer"
+)
+
+type testController struct {
+	*Controller
+	client       *fake.Clientset
+	serviceStore cache.Store
+}
+
+func newTestController(t *testing.T, pools []*net.IPNet, services ...*v1.Service) *testController {
+	objects := []runtime.Object{}
+	for _, service := range services {
+		objects = append(objects, service)
+	}
+	client := fake.NewSimpleClientset(objects...)
+	informer := informers.NewSharedInformerFactory(client, 0).Core().V1().Services().Informer()
+	for _, service := range services {
+		if err := informer.GetStore().Add(service); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, err := NewController(client, record.NewFakeRecorder(10), informer, pools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testController{c, client, informer.GetStore()}
+}
+
+// sync syncs a service, refreshing the informer store with its status
+func (c *testController) sync(t *testing.T, name string) error {
+	err := c.syncService("testns/" + name)
+	if service, getErr := c.client.CoreV1().Services("testns").Get(context.TODO(), name, metav1.GetOptions{}); getErr == nil {
+		if err := c.serviceStore.Update(service); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return err
+}
+
+func (c *testController) ingressIPs(t *testing.T, name string) []string {
+	service, err := c.client.CoreV1().Services("testns").Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ips := []string{}
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		ips = append(ips, ingress.IP)
+	}
+	return ips
+}
+
+func lbService(name string, families ...v1.IPFamily) *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "testns"},
+		Spec: v1.ServiceSpec{
+			Type:       v1.ServiceTypeLoadBalancer,
+			IPFamilies: families,
+		},
+	}
+}
+
+func TestSyncService(t *testing.T) {
+	pools := []*net.IPNet{
+		ovntest.MustParseIPNet("192.168.100.0/30"),
+		ovntest.MustParseIPNet("fd03::/120"),
+	}
+	requested := lbService("requested", v1.IPv4Protocol)
+	requested.Spec.LoadBalancerIP = "192.168.100.2"
+	classed := lbService("classed", v1.IPv4Protocol)
+	classed.Spec.LoadBalancerClass = utilpointer.StringPtr("example.com/lb")
+	foreign := lbService("foreign", v1.IPv4Protocol)
+	foreign.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "10.1.1.1"}}
+
+	c := newTestController(t, pools,
+		lbService("dualstack", v1.IPv6Protocol, v1.IPv4Protocol),
+		requested,
+		lbService("full", v1.IPv4Protocol),
+		classed,
+		foreign,
+	)
+
+	assert.NoError(t, c.sync(t, "requested"))
+	assert.Equal(t, []string{"192.168.100.2"}, c.ingressIPs(t, "requested"))
+
+	// free IPs are picked at random, the IPv4 pool only has one left
+	assert.NoError(t, c.sync(t, "dualstack"))
+	dualStackIPs := c.ingressIPs(t, "dualstack")
+	assert.Len(t, dualStackIPs, 2)
+	assert.True(t, pools[1].Contains(net.ParseIP(dualStackIPs[0])))
+	assert.Equal(t, "192.168.100.1", dualStackIPs[1])
+	// syncing again keeps the IPs
+	assert.NoError(t, c.sync(t, "dualstack"))
+	assert.Equal(t, dualStackIPs, c.ingressIPs(t, "dualstack"))
+
+	// the /30 pool only has two usable IPs
+	assert.Error(t, c.sync(t, "full"))
+	assert.Equal(t, []string{}, c.ingressIPs(t, "full"))
+
+	// services with a load balancer class or with IPs set by another
+	// controller are left alone
+	assert.NoError(t, c.sync(t, "classed"))
+	assert.Equal(t, []string{}, c.ingressIPs(t, "classed"))
+	assert.NoError(t, c.sync(t, "foreign"))
+	assert.Equal(t, []string{"10.1.1.1"}, c.ingressIPs(t, "foreign"))
+
+	// a service that is no longer a LoadBalancer releases its IPs
+	service, err := c.client.CoreV1().Services("testns").Get(context.TODO(), "dualstack", metav1.GetOptions{})
+	assert.NoError(t, err)
+	service.Spec.Type = v1.ServiceTypeClusterIP
+	assert.NoError(t, c.serviceStore.Update(service))
+	assert.NoError(t, c.sync(t, "dualstack"))
+	assert.Equal(t, []string{}, c.ingressIPs(t, "dualstack"))
+
+	assert.NoError(t, c.sync(t, "full"))
+	assert.Equal(t, []string{"192.168.100.1"}, c.ingressIPs(t, "full"))
+
+	// and so does a deleted one
+	assert.NoError(t, c.serviceStore.Delete(requested))
+	assert.NoError(t, c.syncService("testns/requested"))
+	assert.Empty(t, c.allocated["testns/requested"])
+	assert.Equal(t, 1, c.poolFor(net.ParseIP("192.168.100.2")).Free())
+}
+
+func TestRestoreAllocations(t *testing.T) {
+	pools := []*net.IPNet{ovntest.MustParseIPNet("192.168.100.0/29")}
+	existing := lbService("existing", v1.IPv4Protocol)
+	existing.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "192.168.100.1"}}
+	duplicate := lbService("duplicate", v1.IPv4Protocol)
+	duplicate.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "192.168.100.1"}}
+
+	c := newTestController(t, pools, existing, duplicate, lbService("new", v1.IPv4Protocol))
+	assert.NoError(t, c.restoreAllocations())
+
+	assert.NoError(t, c.sync(t, "new"))
+	assert.Len(t, c.ingressIPs(t, "new"), 1)
+	assert.NotEqual(t, "192.168.100.1", c.ingressIPs(t, "new")[0])
+
+	// only one of the services keeps an IP claimed by both
+	assert.NoError(t, c.sync(t, "existing"))
+	assert.NoError(t, c.sync(t, "duplicate"))
+	ips := append(c.ingressIPs(t, "existing"), c.ingressIPs(t, "duplicate")...)
+	assert.Len(t, ips, 2)
+	assert.Contains(t, ips, "192.168.100.1")
+	assert.NotEqual(t, ips[0], ips[1])
+	assert.NotContains(t, ips, c.ingressIPs(t, "new")[0])
+}
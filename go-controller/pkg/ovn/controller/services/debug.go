@@ -0,0 +1,126 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	ovnlb "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/loadbalancer"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// cachedLB is the json friendly form of an ovnlb.CachedLB
+type cachedLB struct {
+	Name        string            `json:"name"`
+	UUID        string            `json:"uuid"`
+	Protocol    string            `json:"protocol"`
+	ExternalIDs map[string]string `json:"externalIDs"`
+	VIPs        []string          `json:"vips"`
+	Backends    map[string]string `json:"backends"`
+	Options     map[string]string `json:"options"`
+	Switches    []string          `json:"switches"`
+	Routers     []string          `json:"routers"`
+	Groups      []string          `json:"groups"`
+}
+
+// serviceLoadBalancers is what the services controller wants to apply for a
+// service, compared with the load balancers it knows of in nbdb
+type serviceLoadBalancers struct {
+	Service string         `json:"service"`
+	Desired []ovnlb.LB     `json:"desired"`
+	Cached  []cachedLB     `json:"cached"`
+	Diff    []ovnlb.LBDiff `json:"diff"`
+}
+
+// getServiceLoadBalancers builds the load balancers of a service like
+// syncService does, without applying them
+func (c *Controller) getServiceLoadBalancers(key string) (*serviceLoadBalancers, error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	desired := []ovnlb.LB{}
+	service, err := c.serviceLister.Services(namespace).Get(name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if err != nil || !util.ServiceTypeHasClusterIP(service) || !util.IsClusterIPSet(service) {
+		// syncService deletes all the load balancers of the service
+		service = &v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	} else {
+		esLabelSelector := labels.Set(map[string]string{
+			discovery.LabelServiceName: name,
+		}).AsSelectorPreValidated()
+		endpointSlices, err := c.endpointSliceLister.EndpointSlices(namespace).List(esLabelSelector)
+		if err != nil {
+			return nil, err
+		}
+		// the ignored options are only reported by syncService
+		opts, _ := lbOpts(service, c.lbFeatures)
+		desired = c.buildServiceLBs(key, service, opts, endpointSlices)
+	}
+
+	lbCache, err := ovnlb.GetLBCache(c.nbClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed initialize LBcache: %w", err)
+	}
+	existing := lbCache.Find(util.ExternalIDsForObject(service))
+
+	cached := make([]cachedLB, 0, len(existing))
+	for _, lb := range existing {
+		cached = append(cached, cachedLB{
+			Name:        lb.Name,
+			UUID:        lb.UUID,
+			Protocol:    lb.Protocol,
+			ExternalIDs: lb.ExternalIDs,
+			VIPs:        lb.VIPs.List(),
+			Backends:    lb.Backends,
+			Options:     lb.Options,
+			Switches:    lb.Switches.List(),
+			Routers:     lb.Routers.List(),
+			Groups:      lb.Groups.List(),
+		})
+	}
+	sort.Slice(cached, func(i, j int) bool { return cached[i].Name < cached[j].Name })
+
+	return &serviceLoadBalancers{
+		Service: key,
+		Desired: desired,
+		Cached:  cached,
+		Diff:    ovnlb.DiffLBs(existing, desired),
+	}, nil
+}
+
+// serveServiceLoadBalancers serves the desired and cached load balancers of
+// the service given by the "service" namespace/name query parameter, and the
+// changes syncService would make to them
+func (c *Controller) serveServiceLoadBalancers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	key := r.URL.Query().Get("service")
+	if key == "" {
+		http.Error(w, "missing service query parameter", http.StatusBadRequest)
+		return
+	}
+	lbs, err := c.getServiceLoadBalancers(key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(lbs); err != nil {
+		klog.Errorf("Failed to write load balancers of service %s: %v", key, err)
+	}
+}
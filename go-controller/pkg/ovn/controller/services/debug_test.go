@@ -0,0 +1,153 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	globalconfig "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/nbdb"
+	ovnlb "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/loadbalancer"
+	libovsdbtest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing/libovsdb"
+
+	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestServeServiceLoadBalancers(t *testing.T) {
+	oldGateway := globalconfig.Gateway.Mode
+	globalconfig.Gateway.Mode = globalconfig.GatewayModeShared
+	defer func() {
+		globalconfig.Gateway.Mode = oldGateway
+	}()
+
+	ovnlb.TestOnlySetCache(nil)
+	controller, err := newControllerWithDBSetup(libovsdbtest.TestSetup{
+		NBData: []libovsdbtest.TestData{
+			&nbdb.LoadBalancer{
+				UUID:     "Service_testns/foo_TCP_cluster",
+				Name:     "Service_testns/foo_TCP_cluster",
+				Protocol: &nbdb.LoadBalancerProtocolTCP,
+				Vips: map[string]string{
+					"192.168.0.1:6443": "",
+				},
+				Options: map[string]string{
+					"reject":    "true",
+					"event":     "false",
+					"skip_snat": "true",
+				},
+				ExternalIDs: map[string]string{
+					"k8s.ovn.org/kind":  "Service",
+					"k8s.ovn.org/owner": "testns/foo",
+				},
+			},
+			&nbdb.LogicalSwitch{
+				UUID:         "switch-node-a",
+				Name:         "switch-node-a",
+				LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
+			},
+			&nbdb.LogicalRouter{
+				UUID: "gr-node-a",
+				Name: "gr-node-a",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error creating controller: %v", err)
+	}
+	defer controller.close()
+
+	controller.serviceStore.Add(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "testns"},
+		Spec: v1.ServiceSpec{
+			Type:       v1.ServiceTypeClusterIP,
+			ClusterIP:  "192.168.1.1",
+			ClusterIPs: []string{"192.168.1.1"},
+			Selector:   map[string]string{"foo": "bar"},
+			Ports: []v1.ServicePort{{
+				Port:       80,
+				Protocol:   v1.ProtocolTCP,
+				TargetPort: intstr.FromInt(3456),
+			}},
+		},
+	})
+	controller.endpointSliceStore.Add(&discovery.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "fooab23",
+			Namespace: "testns",
+			Labels:    map[string]string{discovery.LabelServiceName: "foo"},
+		},
+		AddressType: discovery.AddressTypeIPv4,
+	})
+	controller.nodeTracker.nodes = map[string]nodeInfo{
+		"node-a": {
+			name:              "node-a",
+			nodeIPs:           []string{"10.0.0.1"},
+			gatewayRouterName: "gr-node-a",
+			switchName:        "switch-node-a",
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/ovnkube/service-load-balancers?service=testns/foo", nil)
+	rec := httptest.NewRecorder()
+	controller.serveServiceLoadBalancers(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	lbs := serviceLoadBalancers{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &lbs))
+	assert.Equal(t, "testns/foo", lbs.Service)
+	assert.Len(t, lbs.Desired, 1)
+	assert.Equal(t, []cachedLB{
+		{
+			Name:     "Service_testns/foo_TCP_cluster",
+			UUID:     lbs.Cached[0].UUID,
+			Protocol: "tcp",
+			ExternalIDs: map[string]string{
+				"k8s.ovn.org/kind":  "Service",
+				"k8s.ovn.org/owner": "testns/foo",
+			},
+			VIPs:     []string{"192.168.0.1:6443"},
+			Backends: map[string]string{"192.168.0.1:6443": ""},
+			Options: map[string]string{
+				"reject":    "true",
+				"event":     "false",
+				"skip_snat": "true",
+			},
+			Switches: []string{"switch-node-a"},
+			Routers:  []string{},
+			Groups:   []string{},
+		},
+	}, lbs.Cached)
+	assert.Equal(t, []ovnlb.LBDiff{
+		{
+			Name:           "Service_testns/foo_TCP_cluster",
+			UUID:           lbs.Cached[0].UUID,
+			Action:         ovnlb.LBDiffUpdate,
+			AddVIPs:        []string{"192.168.1.1:80"},
+			RemoveVIPs:     []string{"192.168.0.1:6443"},
+			SetOptions:     map[string]string{"skip_snat": "false"},
+			RemoveSwitches: []string{"switch-node-a"},
+			AddGroups:      []string{"clusterRouterLBGroup", "clusterSwitchLBGroup"},
+		},
+	}, lbs.Diff)
+
+	// a deleted service has its load balancers deleted
+	req = httptest.NewRequest(http.MethodGet, "/debug/ovnkube/service-load-balancers?service=testns/bar", nil)
+	rec = httptest.NewRecorder()
+	controller.serveServiceLoadBalancers(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	lbs = serviceLoadBalancers{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &lbs))
+	assert.Equal(t, []ovnlb.LB{}, lbs.Desired)
+	assert.Equal(t, []ovnlb.LBDiff{}, lbs.Diff)
+
+	req = httptest.NewRequest(http.MethodGet, "/debug/ovnkube/service-load-balancers", nil)
+	rec = httptest.NewRecorder()
+	controller.serveServiceLoadBalancers(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -32,6 +32,16 @@ type lbConfig struct {
 	// that means, skipSNAT, and remove any non-local endpoints.
 	// (see below)
 	externalTrafficLocal bool
+
+	// if true, then vips are only load balanced to endpoints local to the
+	// node, and traffic is dropped when there are none
+	// (InternalTrafficPolicy=Local)
+	internalTrafficLocal bool
+
+	// if set, then IPv4 vips have OVN probe the pod endpoints,
+	// which are found through their logical switch ports
+	healthCheck  *ovnlb.HealthCheck
+	logicalPorts map[string]string
 }
 
 // just used for consistent ordering
@@ -55,15 +65,39 @@ var protos = []v1.Protocol{
 // - services with NodePort set
 // - services with host-network endpoints (for shared gateway mode)
 // - services with ExternalTrafficPolicy=Local
+// - services with InternalTrafficPolicy=Local, for their ClusterIPs
 func buildServiceLBConfigs(service *v1.Service, endpointSlices []*discovery.EndpointSlice) (perNodeConfigs []lbConfig, clusterConfigs []lbConfig) {
+	healthChecks, err := parseServiceHealthChecks(service)
+	if err != nil {
+		klog.Warningf("Ignoring health checks of service %s/%s: %v", service.Namespace, service.Name, err)
+	}
+	var logicalPorts map[string]string
+	if len(healthChecks) > 0 {
+		logicalPorts = getEndpointLogicalPorts(endpointSlices)
+	}
+
 	// For each svcPort, determine if it will be applied per-node or cluster-wide
 	for _, svcPort := range service.Spec.Ports {
 		eps := util.GetLbEndpoints(endpointSlices, svcPort)
+		// the hints only need per-node load balancers when they give some
+		// zones a different set of endpoints than the others
+		if !svcUsesTopologyAwareHints(service) || !eps.FiltersByZone() {
+			eps.ZoneHints = nil
+		}
+		healthCheck := svcPortHealthCheck(healthChecks, svcPort)
+		var portLogicalPorts map[string]string
+		if healthCheck != nil {
+			portLogicalPorts = logicalPorts
+		}
 
 		// if ExternalTrafficPolicy is local, then we need to do things a bit differently
 		externalTrafficLocal := globalconfig.Gateway.Mode == globalconfig.GatewayModeShared &&
 			service.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal
 
+		// if InternalTrafficPolicy is local, then ClusterIPs only target node-local endpoints
+		internalTrafficLocal := service.Spec.InternalTrafficPolicy != nil &&
+			*service.Spec.InternalTrafficPolicy == v1.ServiceInternalTrafficPolicyLocal
+
 		// NodePort services get a per-node load balancer, but with the node's physical IP as the vip
 		// Thus, the vip "node" will be expanded later.
 		if svcPort.NodePort != 0 {
@@ -73,6 +107,8 @@ func buildServiceLBConfigs(service *v1.Service, endpointSlices []*discovery.Endp
 				vips:                 []string{placeholderNodeIPs}, // shortcut for all-physical-ips
 				eps:                  eps,
 				externalTrafficLocal: externalTrafficLocal,
+				healthCheck:          healthCheck,
+				logicalPorts:         portLogicalPorts,
 			}
 			perNodeConfigs = append(perNodeConfigs, nodePortLBConfig)
 		}
@@ -102,12 +138,28 @@ func buildServiceLBConfigs(service *v1.Service, endpointSlices []*discovery.Endp
 				vips:                 externalVips,
 				eps:                  eps,
 				externalTrafficLocal: true,
+				healthCheck:          healthCheck,
+				logicalPorts:         portLogicalPorts,
 			}
 			perNodeConfigs = append(perNodeConfigs, externalIPConfig)
-		} else {
+		} else if !internalTrafficLocal {
 			vips = append(vips, externalVips...)
 		}
 
+		// InternalTrafficPolicy=Local only applies to ClusterIPs, so the
+		// external vips not handled above get their own config
+		if internalTrafficLocal && !externalTrafficLocal && len(externalVips) > 0 {
+			externalIPConfig := lbConfig{
+				protocol:     svcPort.Protocol,
+				inport:       svcPort.Port,
+				vips:         externalVips,
+				eps:          eps,
+				healthCheck:  healthCheck,
+				logicalPorts: portLogicalPorts,
+			}
+			perNodeConfigs, clusterConfigs = appendClusterIPConfig(perNodeConfigs, clusterConfigs, externalIPConfig)
+		}
+
 		// Build the clusterIP config
 		// This is NEVER influenced by ExternalTrafficPolicy
 		clusterIPConfig := lbConfig{
@@ -116,25 +168,37 @@ func buildServiceLBConfigs(service *v1.Service, endpointSlices []*discovery.Endp
 			vips:                 vips,
 			eps:                  eps,
 			externalTrafficLocal: false, // always false for ClusterIPs
+			internalTrafficLocal: internalTrafficLocal,
+			healthCheck:          healthCheck,
+			logicalPorts:         portLogicalPorts,
 		}
-
-		// Normally, the ClusterIP LB is global (on all node switches and routers),
-		// unless both of the following are true:
-		// - We're in shared gateway mode, and
-		// - Any of the endpoints are host-network
-		//
-		// In that case, we need to create per-node LBs.
-		if globalconfig.Gateway.Mode == globalconfig.GatewayModeShared &&
-			(hasHostEndpoints(eps.V4IPs) || hasHostEndpoints(eps.V6IPs)) {
-			perNodeConfigs = append(perNodeConfigs, clusterIPConfig)
-		} else {
-			clusterConfigs = append(clusterConfigs, clusterIPConfig)
-		}
+		perNodeConfigs, clusterConfigs = appendClusterIPConfig(perNodeConfigs, clusterConfigs, clusterIPConfig)
 	}
 
 	return
 }
 
+// appendClusterIPConfig adds a clusterIP config to either the per-node or the
+// cluster-wide configs.
+//
+// Normally, the ClusterIP LB is global (on all node switches and routers),
+// unless either:
+// - InternalTrafficPolicy is Local, since every node has its own endpoints, or
+// - The endpoints have topology aware hints giving zones different endpoints, or
+// - We're in shared gateway mode and any of the endpoints are host-network
+//
+// In that case, we need to create per-node LBs.
+func appendClusterIPConfig(perNodeConfigs, clusterConfigs []lbConfig, config lbConfig) ([]lbConfig, []lbConfig) {
+	if config.internalTrafficLocal || config.eps.ZoneHints != nil ||
+		(globalconfig.Gateway.Mode == globalconfig.GatewayModeShared &&
+			(hasHostEndpoints(config.eps.V4IPs) || hasHostEndpoints(config.eps.V6IPs))) {
+		perNodeConfigs = append(perNodeConfigs, config)
+	} else {
+		clusterConfigs = append(clusterConfigs, config)
+	}
+	return perNodeConfigs, clusterConfigs
+}
+
 // makeLBName creates the load balancer name - used to minimize churn
 func makeLBName(service *v1.Service, proto v1.Protocol, scope string) string {
 	return fmt.Sprintf("Service_%s/%s_%s_%s",
@@ -148,17 +212,27 @@ func makeLBName(service *v1.Service, proto v1.Protocol, scope string) string {
 //
 // It takes a list of (proto:[vips]:port -> [endpoints]) configs and re-aggregates
 // them to a list of (proto:[vip:port -> [endpoint:port]])
-// This load balancer is attached to all node switches. In shared-GW mode, it is also on all routers
-func buildClusterLBs(service *v1.Service, configs []lbConfig, nodeInfos []nodeInfo) []ovnlb.LB {
-	nodeSwitches := make([]string, 0, len(nodeInfos))
-	nodeRouters := make([]string, 0, len(nodeInfos))
-	for _, node := range nodeInfos {
-		nodeSwitches = append(nodeSwitches, node.switchName)
-		// For shared gateway, add to the node's GWR as well.
-		// The node may not have a gateway router - it might be waiting initialization, or
-		// might have disabled GWR creation via the k8s.ovn.org/l3-gateway-config annotation
-		if globalconfig.Gateway.Mode == globalconfig.GatewayModeShared && node.gatewayRouterName != "" {
-			nodeRouters = append(nodeRouters, node.gatewayRouterName)
+// This load balancer is attached to all node switches. In shared-GW mode, it is also on all routers.
+// If useLBGroups is set, it is attached through the cluster switch and router load balancer groups
+// instead of to each node.
+func buildClusterLBs(service *v1.Service, opts ovnlb.LBOpts, configs []lbConfig, nodeInfos []nodeInfo, useLBGroups bool) []ovnlb.LB {
+	var nodeSwitches, nodeRouters, groups []string
+	if useLBGroups {
+		groups = []string{types.ClusterSwitchLBGroupName}
+		if globalconfig.Gateway.Mode == globalconfig.GatewayModeShared {
+			groups = append(groups, types.ClusterRouterLBGroupName)
+		}
+	} else {
+		nodeSwitches = make([]string, 0, len(nodeInfos))
+		nodeRouters = make([]string, 0, len(nodeInfos))
+		for _, node := range nodeInfos {
+			nodeSwitches = append(nodeSwitches, node.switchName)
+			// For shared gateway, add to the node's GWR as well.
+			// The node may not have a gateway router - it might be waiting initialization, or
+			// might have disabled GWR creation via the k8s.ovn.org/l3-gateway-config annotation
+			if globalconfig.Gateway.Mode == globalconfig.GatewayModeShared && node.gatewayRouterName != "" {
+				nodeRouters = append(nodeRouters, node.gatewayRouterName)
+			}
 		}
 	}
 
@@ -174,10 +248,11 @@ func buildClusterLBs(service *v1.Service, configs []lbConfig, nodeInfos []nodeIn
 			Name:        makeLBName(service, proto, "cluster"),
 			Protocol:    string(proto),
 			ExternalIDs: util.ExternalIDsForObject(service),
-			Opts:        lbOpts(service),
+			Opts:        opts,
 
 			Switches: nodeSwitches,
 			Routers:  nodeRouters,
+			Groups:   groups,
 		}
 
 		for _, config := range cfgs {
@@ -210,8 +285,10 @@ func buildClusterLBs(service *v1.Service, configs []lbConfig, nodeInfos []nodeIn
 					continue
 				}
 				targets := v4targets
+				healthCheck := config.healthCheck
 				if utilnet.IsIPv6String(vip) {
 					targets = v6targets
+					healthCheck = nil
 				}
 
 				rules = append(rules, ovnlb.LBRule{
@@ -219,12 +296,14 @@ func buildClusterLBs(service *v1.Service, configs []lbConfig, nodeInfos []nodeIn
 						IP:   vip,
 						Port: config.inport,
 					},
-					Targets: targets,
+					Targets:     targets,
+					HealthCheck: healthCheck,
 				})
 			}
 			lb.Rules = append(lb.Rules, rules...)
 		}
 
+		setHealthCheckMappings(&lb, logicalPortsOf(cfgs), nodeInfos)
 		out = append(out, lb)
 	}
 	return out
@@ -250,7 +329,15 @@ func buildClusterLBs(service *v1.Service, configs []lbConfig, nodeInfos []nodeIn
 // - targets filtered to only local targets
 // - SkipSNAT enabled
 // This results in the creation of an additional load balancer on the GatewayRouters.
-func buildPerNodeLBs(service *v1.Service, configs []lbConfig, nodes []nodeInfo) []ovnlb.LB {
+//
+// For InternalTrafficPolicy, ClusterIPs have:
+// - targets filtered to only local targets, on both the switch and the router
+// - traffic dropped rather than rejected when there are no local targets
+// This results in the creation of additional load balancers, since the option is LB-wide.
+//
+// For topology aware hints, the targets of vips not subject to a Local traffic policy
+// are filtered to the ones hinted for the node's zone, if any.
+func buildPerNodeLBs(service *v1.Service, opts ovnlb.LBOpts, configs []lbConfig, nodes []nodeInfo) []ovnlb.LB {
 	cbp := configsByProto(configs)
 	eids := util.ExternalIDsForObject(service)
 
@@ -272,19 +359,39 @@ func buildPerNodeLBs(service *v1.Service, configs []lbConfig, nodes []nodeInfo)
 			routerRules := make([]ovnlb.LBRule, 0, len(configs))
 			noSNATRouterRules := make([]ovnlb.LBRule, 0)
 			switchRules := make([]ovnlb.LBRule, 0, len(configs))
+			// rules for InternalTrafficPolicy=Local vips
+			internalLocalRouterRules := make([]ovnlb.LBRule, 0)
+			internalLocalSwitchRules := make([]ovnlb.LBRule, 0)
 
 			for _, config := range configs {
 				vips := config.vips
 
-				routerV4targetips := config.eps.V4IPs
-				routerV6targetips := config.eps.V6IPs
+				eps := config.eps
+				// the endpoints of a Local traffic policy are already local to the node
+				if !config.externalTrafficLocal && !config.internalTrafficLocal {
+					eps = eps.ForZone(node.zone)
+				}
+
+				switchV4targetips := eps.V4IPs
+				switchV6targetips := eps.V6IPs
+				// for InternalTrafficPolicy=Local, then remove non-local endpoints everywhere
+				if config.internalTrafficLocal {
+					localEps := eps.InSubnets(node.nodeSubnets())
+					switchV4targetips = localEps.V4IPs
+					switchV6targetips = localEps.V6IPs
+				}
+
+				routerV4targetips := switchV4targetips
+				routerV6targetips := switchV6targetips
 
 				// shared gateway needs to "massage" some of the targets
 				if globalconfig.Gateway.Mode == "shared" {
 					// for ExternalTrafficPolicy=Local, then remove non-local endpoints from the router targets
+					// falling back to the local endpoints still serving while they terminate
 					if config.externalTrafficLocal {
-						routerV4targetips = util.FilterIPsSlice(routerV4targetips, node.nodeSubnets(), true)
-						routerV6targetips = util.FilterIPsSlice(routerV6targetips, node.nodeSubnets(), true)
+						localEps := eps.InSubnets(node.nodeSubnets())
+						routerV4targetips = localEps.V4IPs
+						routerV6targetips = localEps.V6IPs
 					}
 
 					// at this point, the targets may be empty
@@ -298,8 +405,8 @@ func buildPerNodeLBs(service *v1.Service, configs []lbConfig, nodes []nodeInfo)
 				routerV4targets := ovnlb.JoinHostsPort(routerV4targetips, config.eps.Port)
 				routerV6targets := ovnlb.JoinHostsPort(routerV6targetips, config.eps.Port)
 
-				switchV4Targets := ovnlb.JoinHostsPort(config.eps.V4IPs, config.eps.Port)
-				switchV6Targets := ovnlb.JoinHostsPort(config.eps.V6IPs, config.eps.Port)
+				switchV4Targets := ovnlb.JoinHostsPort(switchV4targetips, config.eps.Port)
+				switchV6Targets := ovnlb.JoinHostsPort(switchV6targetips, config.eps.Port)
 
 				// Substitute the special vip "node" for the node's physical ips
 				// This is used for nodeport
@@ -316,14 +423,22 @@ func buildPerNodeLBs(service *v1.Service, configs []lbConfig, nodes []nodeInfo)
 					isv6 := utilnet.IsIPv6String((vip))
 					// build switch rules
 					targets := switchV4Targets
+					healthCheck := config.healthCheck
 					if isv6 {
 						targets = switchV6Targets
+						healthCheck = nil
 					}
 
-					switchRules = append(switchRules, ovnlb.LBRule{
-						Source:  ovnlb.Addr{IP: vip, Port: config.inport},
-						Targets: targets,
-					})
+					switchRule := ovnlb.LBRule{
+						Source:      ovnlb.Addr{IP: vip, Port: config.inport},
+						Targets:     targets,
+						HealthCheck: healthCheck,
+					}
+					if config.internalTrafficLocal {
+						internalLocalSwitchRules = append(internalLocalSwitchRules, switchRule)
+					} else {
+						switchRules = append(switchRules, switchRule)
+					}
 
 					// For shared gateway, there is also a per-router rule
 					// with targets that *may* be different
@@ -333,8 +448,9 @@ func buildPerNodeLBs(service *v1.Service, configs []lbConfig, nodes []nodeInfo)
 							targets = routerV6targets
 						}
 						rule := ovnlb.LBRule{
-							Source:  ovnlb.Addr{IP: vip, Port: config.inport},
-							Targets: targets,
+							Source:      ovnlb.Addr{IP: vip, Port: config.inport},
+							Targets:     targets,
+							HealthCheck: healthCheck,
 						}
 
 						// in other words, is this ExternalTrafficPolicy=local?
@@ -342,6 +458,8 @@ func buildPerNodeLBs(service *v1.Service, configs []lbConfig, nodes []nodeInfo)
 						// (but there's no need to do this if the list of targets is empty)
 						if config.externalTrafficLocal && len(targets) > 0 {
 							noSNATRouterRules = append(noSNATRouterRules, rule)
+						} else if config.internalTrafficLocal {
+							internalLocalRouterRules = append(internalLocalRouterRules, rule)
 						} else {
 							routerRules = append(routerRules, rule)
 						}
@@ -355,7 +473,7 @@ func buildPerNodeLBs(service *v1.Service, configs []lbConfig, nodes []nodeInfo)
 					Name:        makeLBName(service, proto, "node_router+switch_"+node.name),
 					Protocol:    string(proto),
 					ExternalIDs: eids,
-					Opts:        lbOpts(service),
+					Opts:        opts,
 					Routers:     []string{node.gatewayRouterName},
 					Switches:    []string{node.switchName},
 					Rules:       routerRules,
@@ -366,7 +484,7 @@ func buildPerNodeLBs(service *v1.Service, configs []lbConfig, nodes []nodeInfo)
 						Name:        makeLBName(service, proto, "node_router_"+node.name),
 						Protocol:    string(proto),
 						ExternalIDs: eids,
-						Opts:        lbOpts(service),
+						Opts:        opts,
 						Routers:     []string{node.gatewayRouterName},
 						Rules:       routerRules,
 					})
@@ -376,7 +494,7 @@ func buildPerNodeLBs(service *v1.Service, configs []lbConfig, nodes []nodeInfo)
 						Name:        makeLBName(service, proto, "node_local_router_"+node.name),
 						Protocol:    string(proto),
 						ExternalIDs: eids,
-						Opts:        lbOpts(service),
+						Opts:        opts,
 						Routers:     []string{node.gatewayRouterName},
 						Rules:       noSNATRouterRules,
 					}
@@ -389,15 +507,62 @@ func buildPerNodeLBs(service *v1.Service, configs []lbConfig, nodes []nodeInfo)
 						Name:        makeLBName(service, proto, "node_switch_"+node.name),
 						Protocol:    string(proto),
 						ExternalIDs: eids,
-						Opts:        lbOpts(service),
+						Opts:        opts,
 						Switches:    []string{node.switchName},
 						Rules:       switchRules,
 					})
 				}
 			}
+
+			// InternalTrafficPolicy=Local vips get their own load balancers,
+			// dropping traffic when there are no local endpoints
+			if reflect.DeepEqual(internalLocalSwitchRules, internalLocalRouterRules) &&
+				len(internalLocalSwitchRules) > 0 && node.gatewayRouterName != "" {
+				lb := ovnlb.LB{
+					Name:        makeLBName(service, proto, "node_internal_local_router+switch_"+node.name),
+					Protocol:    string(proto),
+					ExternalIDs: eids,
+					Opts:        opts,
+					Routers:     []string{node.gatewayRouterName},
+					Switches:    []string{node.switchName},
+					Rules:       internalLocalRouterRules,
+				}
+				lb.Opts.DropEmpty = true
+				out = append(out, lb)
+			} else {
+				if len(internalLocalRouterRules) > 0 && node.gatewayRouterName != "" {
+					lb := ovnlb.LB{
+						Name:        makeLBName(service, proto, "node_internal_local_router_"+node.name),
+						Protocol:    string(proto),
+						ExternalIDs: eids,
+						Opts:        opts,
+						Routers:     []string{node.gatewayRouterName},
+						Rules:       internalLocalRouterRules,
+					}
+					lb.Opts.DropEmpty = true
+					out = append(out, lb)
+				}
+				if len(internalLocalSwitchRules) > 0 {
+					lb := ovnlb.LB{
+						Name:        makeLBName(service, proto, "node_internal_local_switch_"+node.name),
+						Protocol:    string(proto),
+						ExternalIDs: eids,
+						Opts:        opts,
+						Switches:    []string{node.switchName},
+						Rules:       internalLocalSwitchRules,
+					}
+					lb.Opts.DropEmpty = true
+					out = append(out, lb)
+				}
+			}
 		}
 	}
 
+	logicalPorts := logicalPortsOf(configs)
+	for i := range out {
+		setHealthCheckMappings(&out[i], logicalPorts, nodes)
+	}
+
 	merged := mergeLBs(out)
 	if len(merged) != len(out) {
 		klog.V(5).Infof("Service %s/%s merged %d LBs to %d",
@@ -408,6 +573,17 @@ func buildPerNodeLBs(service *v1.Service, configs []lbConfig, nodes []nodeInfo)
 	return merged
 }
 
+// logicalPortsOf returns the endpoint logical ports of configs with health checks
+func logicalPortsOf(configs []lbConfig) map[string]string {
+	out := map[string]string{}
+	for _, config := range configs {
+		for ip, logicalPort := range config.logicalPorts {
+			out[ip] = logicalPort
+		}
+	}
+	return out
+}
+
 // configsByProto buckets a list of configs by protocol (tcp, udp, sctp)
 func configsByProto(configs []lbConfig) map[v1.Protocol][]lbConfig {
 	out := map[v1.Protocol][]lbConfig{}
@@ -417,13 +593,80 @@ func configsByProto(configs []lbConfig) map[v1.Protocol][]lbConfig {
 	return out
 }
 
-// lbOpts generates the OVN load balancer options from the kubernetes Service.
-func lbOpts(service *v1.Service) ovnlb.LBOpts {
-	return ovnlb.LBOpts{
-		Unidling: svcNeedsIdling(service.GetAnnotations()),
-		Affinity: service.Spec.SessionAffinity == v1.ServiceAffinityClientIP,
-		SkipSNAT: false, // never service-wide, ExternalTrafficPolicy-specific
+// lbOptsWarning is a load balancer option of a service that had to be
+// ignored, reported as a Warning event on the service
+type lbOptsWarning struct {
+	reason  string
+	message string
+}
+
+// lbOpts generates the OVN load balancer options from the kubernetes Service,
+// along with warnings for the options of the Service that had to be ignored.
+func lbOpts(service *v1.Service, features lbFeatures) (ovnlb.LBOpts, []lbOptsWarning) {
+	warnings := []lbOptsWarning{}
+	affinity := service.Spec.SessionAffinity == v1.ServiceAffinityClientIP
+	var affinityTimeOut int32
+	if affinity {
+		if features.affinityTimeout {
+			affinityTimeOut = svcAffinityTimeout(service)
+		} else {
+			warnings = append(warnings, lbOptsWarning{
+				reason: "SessionAffinityTimeoutUnsupported",
+				message: fmt.Sprintf("ClientIP session affinity timeout of %d seconds is unsupported by this version of OVN, "+
+					"clients are balanced by a hash of their IP instead", svcAffinityTimeout(service)),
+			})
+		}
 	}
+	hashFields, err := parseServiceLBHashFields(service)
+	if err != nil {
+		warnings = append(warnings, lbOptsWarning{
+			reason: "InvalidLoadBalancerHashFields",
+			message: fmt.Sprintf("Ignoring load balancer hash fields of Service %s/%s: %v",
+				service.Namespace, service.Name, err),
+		})
+	}
+	if len(hashFields) > 0 && !features.hashFields {
+		warnings = append(warnings, lbOptsWarning{
+			reason: "LoadBalancerHashFieldsUnsupported",
+			message: fmt.Sprintf("Load balancer hash fields %s are unsupported by this version of OVN, "+
+				"clients are balanced by the default hash instead", strings.Join(hashFields, ",")),
+		})
+		hashFields = nil
+	}
+	hairpinSNATIPs, err := parseServiceHairpinSNATIPs(service)
+	if err != nil {
+		warnings = append(warnings, lbOptsWarning{
+			reason: "InvalidHairpinSNATIP",
+			message: fmt.Sprintf("Ignoring hairpin SNAT IPs of Service %s/%s: %v",
+				service.Namespace, service.Name, err),
+		})
+	}
+	if len(hairpinSNATIPs) > 0 && !features.hairpinSNATIP {
+		warnings = append(warnings, lbOptsWarning{
+			reason: "HairpinSNATIPUnsupported",
+			message: fmt.Sprintf("Hairpin SNAT IPs %s are unsupported by this version of OVN, "+
+				"hairpin traffic is SNATed to the service vip instead", strings.Join(hairpinSNATIPs, ",")),
+		})
+		hairpinSNATIPs = nil
+	}
+	emptyLBAction, err := parseServiceEmptyLBAction(service)
+	if err != nil {
+		warnings = append(warnings, lbOptsWarning{
+			reason: "InvalidEmptyLBAction",
+			message: fmt.Sprintf("Rejecting traffic to Service %s/%s without endpoints: %v",
+				service.Namespace, service.Name, err),
+		})
+	}
+	return ovnlb.LBOpts{
+		Unidling:        svcNeedsIdling(service.GetAnnotations()),
+		Affinity:        affinity,
+		AffinityTimeOut: affinityTimeOut,
+		SkipSNAT:        false, // never service-wide, ExternalTrafficPolicy-specific
+		DropEmpty:       emptyLBAction == EmptyLBActionDrop,
+		EmptyEvent:      emptyLBAction == EmptyLBActionEvent,
+		HashFields:      hashFields,
+		HairpinSNATIPs:  hairpinSNATIPs,
+	}, warnings
 }
 
 // mergeLBs joins two LBs together if it is safe to do so.
@@ -469,6 +712,10 @@ func canMergeLB(a, b ovnlb.LB) bool {
 		return false
 	}
 
+	if !reflect.DeepEqual(a.IPPortMappings, b.IPPortMappings) {
+		return false
+	}
+
 	// While rules are actually a set, we generate all our lbConfigs from a single source
 	// so the ordering will be the same. Thus, we can cheat and just reflect.DeepEqual
 	return reflect.DeepEqual(a.Rules, b.Rules)
@@ -7,6 +7,7 @@ import (
 
 	globalconfig "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
 	ovnlb "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/loadbalancer"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
 	"github.com/stretchr/testify/assert"
 
@@ -14,6 +15,7 @@ import (
 	discovery "k8s.io/api/discovery/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
 	utilpointer "k8s.io/utils/pointer"
 )
 
@@ -41,6 +43,7 @@ func Test_buildServiceLBConfigs(t *testing.T) {
 	emptyEPs := util.LbEndpoints{V4IPs: []string{}, V6IPs: []string{}, Port: 0}
 	tcp := v1.ProtocolTCP
 	udp := v1.ProtocolUDP
+	itpLocal := v1.ServiceInternalTrafficPolicyLocal
 
 	// make slices
 	// nil slice = don't use this family
@@ -120,6 +123,52 @@ func Test_buildServiceLBConfigs(t *testing.T) {
 		return out
 	}
 
+	// makeHintedSlices makes an IPv4 slice with an endpoint per IP, hinted
+	// for the given zone
+	makeHintedSlices := func(zones map[string]string) []*discovery.EndpointSlice {
+		slice := &discovery.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      serviceName + "ab1",
+				Namespace: ns,
+				Labels:    map[string]string{discovery.LabelServiceName: serviceName},
+			},
+			Ports: []discovery.EndpointPort{{
+				Protocol: &tcp,
+				Port:     &outport,
+				Name:     &portName,
+			}},
+			AddressType: discovery.AddressTypeIPv4,
+		}
+		for _, ip := range sets.StringKeySet(zones).List() {
+			slice.Endpoints = append(slice.Endpoints, discovery.Endpoint{
+				Conditions: discovery.EndpointConditions{
+					Ready: utilpointer.BoolPtr(true),
+				},
+				Addresses: []string{ip},
+				Hints:     &discovery.EndpointHints{ForZones: []discovery.ForZone{{Name: zones[ip]}}},
+			})
+		}
+		return []*discovery.EndpointSlice{slice}
+	}
+	hintedService := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        serviceName,
+			Namespace:   ns,
+			Annotations: map[string]string{v1.AnnotationTopologyAwareHints: "auto"},
+		},
+		Spec: v1.ServiceSpec{
+			Type:       v1.ServiceTypeClusterIP,
+			ClusterIP:  "192.168.1.1",
+			ClusterIPs: []string{"192.168.1.1"},
+			Ports: []v1.ServicePort{{
+				Port:       inport,
+				Protocol:   v1.ProtocolTCP,
+				TargetPort: outportstr,
+				Name:       portName,
+			}},
+		},
+	}
+
 	type args struct {
 		service *v1.Service
 		slices  []*discovery.EndpointSlice
@@ -485,6 +534,50 @@ func Test_buildServiceLBConfigs(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "dual-stack clusterip, one port, endpoints, external ips, InternalTrafficPolicy=Local",
+			args: args{
+				slices: makeSlices([]string{"10.128.0.2"}, []string{"fe00::1:1"}, v1.ProtocolTCP),
+				service: &v1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: serviceName, Namespace: ns},
+					Spec: v1.ServiceSpec{
+						Type:                  v1.ServiceTypeClusterIP,
+						ClusterIP:             "192.168.1.1",
+						ClusterIPs:            []string{"192.168.1.1", "2002::1"},
+						InternalTrafficPolicy: &itpLocal,
+						Ports: []v1.ServicePort{{
+							Port:       inport,
+							Protocol:   v1.ProtocolTCP,
+							TargetPort: outportstr,
+						}},
+						ExternalIPs: []string{"4.2.2.2", "42::42"},
+					},
+				},
+			},
+			resultsSame: true,
+			// External IPs are not subject to InternalTrafficPolicy
+			resultSharedGatewayCluster: []lbConfig{{
+				vips:     []string{"4.2.2.2", "42::42"},
+				protocol: v1.ProtocolTCP,
+				inport:   inport,
+				eps: util.LbEndpoints{
+					V4IPs: []string{"10.128.0.2"},
+					V6IPs: []string{"fe00::1:1"},
+					Port:  outport,
+				},
+			}},
+			resultSharedGatewayNode: []lbConfig{{
+				vips:                 []string{"192.168.1.1", "2002::1"},
+				protocol:             v1.ProtocolTCP,
+				inport:               inport,
+				internalTrafficLocal: true,
+				eps: util.LbEndpoints{
+					V4IPs: []string{"10.128.0.2"},
+					V6IPs: []string{"fe00::1:1"},
+					Port:  outport,
+				},
+			}},
+		},
 		{
 			name: "dual-stack clusterip, one port, endpoints, nodePort",
 			args: args{
@@ -711,6 +804,50 @@ func Test_buildServiceLBConfigs(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "clusterip, topology aware hints giving zones different endpoints",
+			args: args{
+				slices:  makeHintedSlices(map[string]string{"10.128.0.2": "zone-a", "10.128.1.2": "zone-b"}),
+				service: hintedService,
+			},
+			resultsSame: true,
+			resultSharedGatewayNode: []lbConfig{
+				{
+					vips:     []string{"192.168.1.1"},
+					protocol: v1.ProtocolTCP,
+					inport:   inport,
+					eps: util.LbEndpoints{
+						V4IPs: []string{"10.128.0.2", "10.128.1.2"},
+						V6IPs: []string{},
+						Port:  outport,
+						ZoneHints: map[string]sets.String{
+							"10.128.0.2": sets.NewString("zone-a"),
+							"10.128.1.2": sets.NewString("zone-b"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "clusterip, topology aware hints giving every zone the same endpoints",
+			args: args{
+				slices:  makeHintedSlices(map[string]string{"10.128.0.2": "zone-a", "10.128.1.2": "zone-a"}),
+				service: hintedService,
+			},
+			resultsSame: true,
+			resultSharedGatewayCluster: []lbConfig{
+				{
+					vips:     []string{"192.168.1.1"},
+					protocol: v1.ProtocolTCP,
+					inport:   inport,
+					eps: util.LbEndpoints{
+						V4IPs: []string{"10.128.0.2", "10.128.1.2"},
+						V6IPs: []string{},
+						Port:  outport,
+					},
+				},
+			},
+		},
 	}
 
 	for i, tt := range tests {
@@ -770,8 +907,7 @@ func Test_buildClusterLBs(t *testing.T) {
 		"k8s.ovn.org/owner": fmt.Sprintf("%s/%s", namespace, name),
 	}
 
-	defaultRouters := []string{"gr-node-a", "gr-node-b"}
-	defaultSwitches := []string{"switch-node-a", "switch-node-b"}
+	defaultGroups := []string{types.ClusterSwitchLBGroupName, types.ClusterRouterLBGroupName}
 
 	tc := []struct {
 		name      string
@@ -820,8 +956,7 @@ func Test_buildClusterLBs(t *testing.T) {
 						},
 					},
 
-					Routers:  defaultRouters,
-					Switches: defaultSwitches,
+					Groups: defaultGroups,
 				},
 			},
 		},
@@ -861,8 +996,7 @@ func Test_buildClusterLBs(t *testing.T) {
 						},
 					},
 
-					Routers:  defaultRouters,
-					Switches: defaultSwitches,
+					Groups: defaultGroups,
 				},
 				{
 					Name:        fmt.Sprintf("Service_%s/%s_UDP_cluster", namespace, name),
@@ -875,8 +1009,7 @@ func Test_buildClusterLBs(t *testing.T) {
 						},
 					},
 
-					Routers:  defaultRouters,
-					Switches: defaultSwitches,
+					Groups: defaultGroups,
 				},
 			},
 		},
@@ -930,8 +1063,69 @@ func Test_buildClusterLBs(t *testing.T) {
 						},
 					},
 
-					Routers:  defaultRouters,
-					Switches: defaultSwitches,
+					Groups: defaultGroups,
+				},
+			},
+		},
+		{
+			name:    "dual stack, health check",
+			service: defaultService,
+			configs: []lbConfig{
+				{
+					vips:     []string{"1.2.3.4", "fe80::1"},
+					protocol: v1.ProtocolTCP,
+					inport:   80,
+					eps: util.LbEndpoints{
+						V4IPs: []string{"10.128.0.2", "10.128.1.2", "192.168.0.1"},
+						V6IPs: []string{"fe90::1"},
+						Port:  8080,
+					},
+					healthCheck: &ovnlb.HealthCheck{Interval: 5, FailureCount: 2},
+					logicalPorts: map[string]string{
+						"10.128.0.2": "testns_pod-a",
+						"10.128.1.2": "testns_pod-b",
+						"fe90::1":    "testns_pod-a",
+					},
+				},
+			},
+			nodeInfos: []nodeInfo{
+				{
+					name:              "node-a",
+					nodeIPs:           []string{"10.0.0.1"},
+					gatewayRouterName: "gr-node-a",
+					switchName:        "switch-node-a",
+					podSubnets:        []net.IPNet{{IP: net.ParseIP("10.128.0.0"), Mask: net.CIDRMask(24, 32)}},
+				},
+				{
+					name:              "node-b",
+					nodeIPs:           []string{"10.0.0.2"},
+					gatewayRouterName: "gr-node-b",
+					switchName:        "switch-node-b",
+					podSubnets:        []net.IPNet{{IP: net.ParseIP("10.128.1.0"), Mask: net.CIDRMask(24, 32)}},
+				},
+			},
+			expected: []ovnlb.LB{
+				{
+					Name:        fmt.Sprintf("Service_%s/%s_TCP_cluster", namespace, name),
+					Protocol:    "TCP",
+					ExternalIDs: defaultExternalIDs,
+					Rules: []ovnlb.LBRule{
+						{
+							Source:      ovnlb.Addr{"1.2.3.4", 80},
+							Targets:     []ovnlb.Addr{{"10.128.0.2", 8080}, {"10.128.1.2", 8080}, {"192.168.0.1", 8080}},
+							HealthCheck: &ovnlb.HealthCheck{Interval: 5, FailureCount: 2},
+						},
+						{
+							Source:  ovnlb.Addr{"fe80::1", 80},
+							Targets: []ovnlb.Addr{{"fe90::1", 8080}},
+						},
+					},
+					IPPortMappings: map[string]string{
+						"10.128.0.2": "testns_pod-a:10.128.0.254",
+						"10.128.1.2": "testns_pod-b:10.128.1.254",
+					},
+
+					Groups: defaultGroups,
 				},
 			},
 		},
@@ -939,12 +1133,84 @@ func Test_buildClusterLBs(t *testing.T) {
 
 	for i, tt := range tc {
 		t.Run(fmt.Sprintf("%d_%s", i, tt.name), func(t *testing.T) {
-			actual := buildClusterLBs(tt.service, tt.configs, tt.nodeInfos)
+			actual := buildClusterLBs(tt.service, ovnlb.LBOpts{}, tt.configs, tt.nodeInfos, true)
 			assert.Equal(t, tt.expected, actual)
 		})
 	}
 }
 
+func Test_buildClusterLBsGroups(t *testing.T) {
+	oldGwMode := globalconfig.Gateway.Mode
+	defer func() {
+		globalconfig.Gateway.Mode = oldGwMode
+	}()
+
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "testns"},
+		Spec: v1.ServiceSpec{
+			Type: v1.ServiceTypeClusterIP,
+		},
+	}
+	configs := []lbConfig{
+		{
+			vips:     []string{"1.2.3.4"},
+			protocol: v1.ProtocolTCP,
+			inport:   80,
+			eps: util.LbEndpoints{
+				V4IPs: []string{"192.168.0.1"},
+				Port:  8080,
+			},
+		},
+	}
+	nodeInfos := []nodeInfo{
+		{
+			name:              "node-a",
+			nodeIPs:           []string{"10.0.0.1"},
+			gatewayRouterName: "gr-node-a",
+			switchName:        "switch-node-a",
+		},
+	}
+
+	tc := []struct {
+		gwMode           globalconfig.GatewayMode
+		useLBGroups      bool
+		expectedGroups   []string
+		expectedSwitches []string
+		expectedRouters  []string
+	}{
+		{
+			gwMode:         globalconfig.GatewayModeShared,
+			useLBGroups:    true,
+			expectedGroups: []string{types.ClusterSwitchLBGroupName, types.ClusterRouterLBGroupName},
+		},
+		{
+			gwMode:         globalconfig.GatewayModeLocal,
+			useLBGroups:    true,
+			expectedGroups: []string{types.ClusterSwitchLBGroupName},
+		},
+		{
+			gwMode:           globalconfig.GatewayModeShared,
+			expectedSwitches: []string{"switch-node-a"},
+			expectedRouters:  []string{"gr-node-a"},
+		},
+		{
+			gwMode:           globalconfig.GatewayModeLocal,
+			expectedSwitches: []string{"switch-node-a"},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(fmt.Sprintf("%s_groups_%t", tt.gwMode, tt.useLBGroups), func(t *testing.T) {
+			globalconfig.Gateway.Mode = tt.gwMode
+			actual := buildClusterLBs(service, ovnlb.LBOpts{}, configs, nodeInfos, tt.useLBGroups)
+			assert.Len(t, actual, 1)
+			assert.Equal(t, tt.expectedGroups, actual[0].Groups)
+			assert.ElementsMatch(t, tt.expectedSwitches, actual[0].Switches)
+			assert.ElementsMatch(t, tt.expectedRouters, actual[0].Routers)
+		})
+	}
+}
+
 func Test_buildPerNodeLBs(t *testing.T) {
 	oldClusterSubnet := globalconfig.Default.ClusterSubnets
 	oldGwMode := globalconfig.Gateway.Mode
@@ -973,6 +1239,7 @@ func Test_buildPerNodeLBs(t *testing.T) {
 			gatewayRouterName: "gr-node-a",
 			switchName:        "switch-node-a",
 			podSubnets:        []net.IPNet{{IP: net.ParseIP("10.128.0.0"), Mask: net.CIDRMask(24, 32)}},
+			zone:              "zone-a",
 		},
 		{
 			name:              "node-b",
@@ -980,6 +1247,7 @@ func Test_buildPerNodeLBs(t *testing.T) {
 			gatewayRouterName: "gr-node-b",
 			switchName:        "switch-node-b",
 			podSubnets:        []net.IPNet{{IP: net.ParseIP("10.128.1.0"), Mask: net.CIDRMask(24, 32)}},
+			zone:              "zone-b",
 		},
 	}
 
@@ -1109,6 +1377,228 @@ func Test_buildPerNodeLBs(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:    "clusterip service, topology aware hints",
+			service: defaultService,
+			configs: []lbConfig{
+				{
+					vips:     []string{"1.2.3.4"},
+					protocol: v1.ProtocolTCP,
+					inport:   80,
+					eps: util.LbEndpoints{
+						V4IPs: []string{"10.128.0.2", "10.128.1.2"},
+						Port:  8080,
+						ZoneHints: map[string]sets.String{
+							"10.128.0.2": sets.NewString("zone-a"),
+							"10.128.1.2": sets.NewString("zone-b"),
+						},
+					},
+				},
+			},
+			expectedShared: []ovnlb.LB{
+				{
+					Name:        "Service_testns/foo_TCP_node_router+switch_node-a",
+					ExternalIDs: defaultExternalIDs,
+					Routers:     []string{"gr-node-a"},
+					Switches:    []string{"switch-node-a"},
+					Protocol:    "TCP",
+					Rules: []ovnlb.LBRule{
+						{
+							Source:  ovnlb.Addr{"1.2.3.4", 80},
+							Targets: []ovnlb.Addr{{"10.128.0.2", 8080}},
+						},
+					},
+				},
+				{
+					Name:        "Service_testns/foo_TCP_node_router+switch_node-b",
+					ExternalIDs: defaultExternalIDs,
+					Routers:     []string{"gr-node-b"},
+					Switches:    []string{"switch-node-b"},
+					Protocol:    "TCP",
+					Rules: []ovnlb.LBRule{
+						{
+							Source:  ovnlb.Addr{"1.2.3.4", 80},
+							Targets: []ovnlb.Addr{{"10.128.1.2", 8080}},
+						},
+					},
+				},
+			},
+			expectedLocal: []ovnlb.LB{
+				{
+					Name:        "Service_testns/foo_TCP_node_switch_node-a",
+					ExternalIDs: defaultExternalIDs,
+					Switches:    []string{"switch-node-a"},
+					Protocol:    "TCP",
+					Rules: []ovnlb.LBRule{
+						{
+							Source:  ovnlb.Addr{"1.2.3.4", 80},
+							Targets: []ovnlb.Addr{{"10.128.0.2", 8080}},
+						},
+					},
+				},
+				{
+					Name:        "Service_testns/foo_TCP_node_switch_node-b",
+					ExternalIDs: defaultExternalIDs,
+					Switches:    []string{"switch-node-b"},
+					Protocol:    "TCP",
+					Rules: []ovnlb.LBRule{
+						{
+							Source:  ovnlb.Addr{"1.2.3.4", 80},
+							Targets: []ovnlb.Addr{{"10.128.1.2", 8080}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:    "clusterip service, InternalTrafficPolicy=Local",
+			service: defaultService,
+			configs: []lbConfig{
+				{
+					vips:                 []string{"1.2.3.4"},
+					protocol:             v1.ProtocolTCP,
+					inport:               80,
+					internalTrafficLocal: true,
+					eps: util.LbEndpoints{
+						V4IPs: []string{"10.128.0.2"},
+						Port:  8080,
+					},
+				},
+			},
+			expectedShared: []ovnlb.LB{
+				{
+					Name:        "Service_testns/foo_TCP_node_internal_local_router+switch_node-a",
+					ExternalIDs: defaultExternalIDs,
+					Opts:        ovnlb.LBOpts{DropEmpty: true},
+					Routers:     []string{"gr-node-a"},
+					Switches:    []string{"switch-node-a"},
+					Protocol:    "TCP",
+					Rules: []ovnlb.LBRule{
+						{
+							Source:  ovnlb.Addr{"1.2.3.4", 80},
+							Targets: []ovnlb.Addr{{"10.128.0.2", 8080}},
+						},
+					},
+				},
+				{
+					Name:        "Service_testns/foo_TCP_node_internal_local_router+switch_node-b",
+					ExternalIDs: defaultExternalIDs,
+					Opts:        ovnlb.LBOpts{DropEmpty: true},
+					Routers:     []string{"gr-node-b"},
+					Switches:    []string{"switch-node-b"},
+					Protocol:    "TCP",
+					Rules: []ovnlb.LBRule{
+						{
+							Source:  ovnlb.Addr{"1.2.3.4", 80},
+							Targets: []ovnlb.Addr{},
+						},
+					},
+				},
+			},
+			expectedLocal: []ovnlb.LB{
+				{
+					Name:        "Service_testns/foo_TCP_node_internal_local_switch_node-a",
+					ExternalIDs: defaultExternalIDs,
+					Opts:        ovnlb.LBOpts{DropEmpty: true},
+					Switches:    []string{"switch-node-a"},
+					Protocol:    "TCP",
+					Rules: []ovnlb.LBRule{
+						{
+							Source:  ovnlb.Addr{"1.2.3.4", 80},
+							Targets: []ovnlb.Addr{{"10.128.0.2", 8080}},
+						},
+					},
+				},
+				{
+					Name:        "Service_testns/foo_TCP_node_internal_local_switch_node-b",
+					ExternalIDs: defaultExternalIDs,
+					Opts:        ovnlb.LBOpts{DropEmpty: true},
+					Switches:    []string{"switch-node-b"},
+					Protocol:    "TCP",
+					Rules: []ovnlb.LBRule{
+						{
+							Source:  ovnlb.Addr{"1.2.3.4", 80},
+							Targets: []ovnlb.Addr{},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:    "clusterip service, InternalTrafficPolicy=Local, terminating endpoints",
+			service: defaultService,
+			configs: []lbConfig{
+				{
+					vips:                 []string{"1.2.3.4"},
+					protocol:             v1.ProtocolTCP,
+					inport:               80,
+					internalTrafficLocal: true,
+					eps: util.LbEndpoints{
+						V4IPs:            []string{"10.128.0.2"},
+						Port:             8080,
+						TerminatingV4IPs: []string{"10.128.0.3", "10.128.1.3"},
+					},
+				},
+			},
+			expectedShared: []ovnlb.LB{
+				{
+					Name:        "Service_testns/foo_TCP_node_internal_local_router+switch_node-a",
+					ExternalIDs: defaultExternalIDs,
+					Opts:        ovnlb.LBOpts{DropEmpty: true},
+					Routers:     []string{"gr-node-a"},
+					Switches:    []string{"switch-node-a"},
+					Protocol:    "TCP",
+					Rules: []ovnlb.LBRule{
+						{
+							Source:  ovnlb.Addr{"1.2.3.4", 80},
+							Targets: []ovnlb.Addr{{"10.128.0.2", 8080}},
+						},
+					},
+				},
+				{
+					Name:        "Service_testns/foo_TCP_node_internal_local_router+switch_node-b",
+					ExternalIDs: defaultExternalIDs,
+					Opts:        ovnlb.LBOpts{DropEmpty: true},
+					Routers:     []string{"gr-node-b"},
+					Switches:    []string{"switch-node-b"},
+					Protocol:    "TCP",
+					Rules: []ovnlb.LBRule{
+						{
+							Source:  ovnlb.Addr{"1.2.3.4", 80},
+							Targets: []ovnlb.Addr{{"10.128.1.3", 8080}},
+						},
+					},
+				},
+			},
+			expectedLocal: []ovnlb.LB{
+				{
+					Name:        "Service_testns/foo_TCP_node_internal_local_switch_node-a",
+					ExternalIDs: defaultExternalIDs,
+					Opts:        ovnlb.LBOpts{DropEmpty: true},
+					Switches:    []string{"switch-node-a"},
+					Protocol:    "TCP",
+					Rules: []ovnlb.LBRule{
+						{
+							Source:  ovnlb.Addr{"1.2.3.4", 80},
+							Targets: []ovnlb.Addr{{"10.128.0.2", 8080}},
+						},
+					},
+				},
+				{
+					Name:        "Service_testns/foo_TCP_node_internal_local_switch_node-b",
+					ExternalIDs: defaultExternalIDs,
+					Opts:        ovnlb.LBOpts{DropEmpty: true},
+					Switches:    []string{"switch-node-b"},
+					Protocol:    "TCP",
+					Rules: []ovnlb.LBRule{
+						{
+							Source:  ovnlb.Addr{"1.2.3.4", 80},
+							Targets: []ovnlb.Addr{{"10.128.1.3", 8080}},
+						},
+					},
+				},
+			},
+		},
 		{
 			name:    "nodeport service, host-network pod",
 			service: defaultService,
@@ -1335,13 +1825,13 @@ func Test_buildPerNodeLBs(t *testing.T) {
 
 			if tt.expectedShared != nil {
 				globalconfig.Gateway.Mode = globalconfig.GatewayModeShared
-				actual := buildPerNodeLBs(tt.service, tt.configs, defaultNodes)
+				actual := buildPerNodeLBs(tt.service, ovnlb.LBOpts{}, tt.configs, defaultNodes)
 				assert.Equal(t, tt.expectedShared, actual, "shared gateway mode not as expected")
 			}
 
 			if tt.expectedLocal != nil {
 				globalconfig.Gateway.Mode = globalconfig.GatewayModeLocal
-				actual := buildPerNodeLBs(tt.service, tt.configs, defaultNodes)
+				actual := buildPerNodeLBs(tt.service, ovnlb.LBOpts{}, tt.configs, defaultNodes)
 				assert.Equal(t, tt.expectedLocal, actual, "local gateway mode not as expected")
 			}
 
@@ -40,6 +40,8 @@ type nodeInfo struct {
 	gatewayRouterName string
 	// The name of the node's switch - never empty
 	switchName string
+	// The node's topology.kubernetes.io/zone label, or "" if not set
+	zone string
 }
 
 // returns a list of all ip blocks "assigned" to this node
@@ -93,8 +95,10 @@ func newNodeTracker(nodeInformer coreinformers.NodeInformer) *nodeTracker {
 
 			// updateNode needs to be called only when hostSubnet annotation has changed or
 			// if L3Gateway annotation's ip addresses have changed or the name of the node (very rare)
-			// has changed. No need to trigger update for any other field change.
-			if util.NodeSubnetAnnotationChanged(oldObj, newObj) || util.NodeL3GatewayAnnotationChanged(oldObj, newObj) || oldObj.Name != newObj.Name {
+			// has changed or the zone of the node has changed. No need to trigger update for any
+			// other field change.
+			if util.NodeSubnetAnnotationChanged(oldObj, newObj) || util.NodeL3GatewayAnnotationChanged(oldObj, newObj) ||
+				oldObj.Name != newObj.Name || oldObj.Labels[v1.LabelTopologyZone] != newObj.Labels[v1.LabelTopologyZone] {
 				nt.updateNode(newObj)
 			}
 		},
@@ -122,13 +126,14 @@ func newNodeTracker(nodeInformer coreinformers.NodeInformer) *nodeTracker {
 
 // updateNodeInfo updates the node info cache, and syncs all services
 // if it changed.
-func (nt *nodeTracker) updateNodeInfo(nodeName, switchName, routerName string, nodeIPs []string, podSubnets []*net.IPNet) {
+func (nt *nodeTracker) updateNodeInfo(nodeName, switchName, routerName, zone string, nodeIPs []string, podSubnets []*net.IPNet) {
 	ni := nodeInfo{
 		name:              nodeName,
 		nodeIPs:           nodeIPs,
 		podSubnets:        make([]net.IPNet, 0, len(podSubnets)),
 		gatewayRouterName: routerName,
 		switchName:        switchName,
+		zone:              zone,
 	}
 	for i := range podSubnets {
 		ni.podSubnets = append(ni.podSubnets, *podSubnets[i]) // de-pointer
@@ -195,6 +200,7 @@ func (nt *nodeTracker) updateNode(node *v1.Node) {
 		node.Name,
 		switchName,
 		grName,
+		node.Labels[v1.LabelTopologyZone],
 		ips,
 		hsn,
 	)
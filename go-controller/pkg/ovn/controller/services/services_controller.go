@@ -3,11 +3,13 @@ package services
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"sync"
 	"time"
 
 	libovsdbclient "github.com/ovn-org/libovsdb/client"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/metrics"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/libovsdbops"
 	ovnlb "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/loadbalancer"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
 	"golang.org/x/time/rate"
@@ -66,8 +68,18 @@ func NewController(client clientset.Interface,
 		queue:            workqueue.NewNamedRateLimitingQueue(newRatelimiter(100), controllerName),
 		workerLoopPeriod: time.Second,
 		alreadyApplied:   map[string][]ovnlb.LB{},
+		reportedLBOpts:   map[string]string{},
+
+		serviceMonitorIPConflicts: newServiceMonitorIPConflicts(),
 	}
 
+	// Determine the load balancer options supported by OVN
+	c.lbFeatures = detectLBFeatures(nbClient)
+
+	// The cluster-wide load balancers are attached through the cluster load
+	// balancer groups if OVN supports them, and to every node otherwise
+	c.useLBGroups = libovsdbops.LoadBalancerGroupsSupported(nbClient)
+
 	// services
 	klog.Info("Setting up event handlers for services")
 	serviceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -148,6 +160,23 @@ type Controller struct {
 	// if a service's config hasn't changed
 	alreadyApplied     map[string][]ovnlb.LB
 	alreadyAppliedLock sync.Mutex
+
+	// lbFeatures are the optional load balancer options supported by OVN
+	lbFeatures lbFeatures
+
+	// useLBGroups is whether the cluster-wide load balancers are attached
+	// through the cluster load balancer groups rather than to every node
+	useLBGroups bool
+
+	// reportedLBOpts is a map of service key -> resource version of the service
+	// whose ignored load balancer options were last reported, so they are
+	// reported once per change of the service rather than on every sync
+	reportedLBOpts     map[string]string
+	reportedLBOptsLock sync.Mutex
+
+	// serviceMonitorIPConflicts indexes the logical switch ports holding
+	// service monitor addresses
+	serviceMonitorIPConflicts *serviceMonitorIPConflicts
 }
 
 // Run will not return until stopCh is closed. workers determines how many
@@ -171,6 +200,8 @@ func (c *Controller) Run(workers int, stopCh <-chan struct{}, runRepair bool) er
 		// and handles removal of stale data on upgrades
 		c.repair.runBeforeSync()
 	}
+	metrics.RegisterDebugHandler("service-load-balancers", http.HandlerFunc(c.serveServiceLoadBalancers))
+
 	// Start the workers after the repair loop to avoid races
 	klog.Info("Starting workers")
 	for i := 0; i < workers; i++ {
@@ -270,6 +301,10 @@ func (c *Controller) syncService(key string) error {
 				namespace, name, err)
 		}
 
+		c.reportedLBOptsLock.Lock()
+		delete(c.reportedLBOpts, key)
+		c.reportedLBOptsLock.Unlock()
+
 		c.repair.serviceSynced(key)
 		return nil
 	}
@@ -289,20 +324,7 @@ func (c *Controller) syncService(key string) error {
 		return err
 	}
 
-	// Build the abstract LB configs for this service
-	perNodeConfigs, clusterConfigs := buildServiceLBConfigs(service, endpointSlices)
-	klog.V(5).Infof("Built service %s LB cluster-wide configs %#v", key, clusterConfigs)
-	klog.V(5).Infof("Built service %s LB per-node configs %#v", key, perNodeConfigs)
-
-	// Convert the LB configs in to load-balancer objects
-	nodeInfos := c.nodeTracker.allNodes()
-	clusterLBs := buildClusterLBs(service, clusterConfigs, nodeInfos)
-	perNodeLBs := buildPerNodeLBs(service, perNodeConfigs, nodeInfos)
-	klog.V(5).Infof("Built service %s cluster-wide LB %#v", key, clusterLBs)
-	klog.V(5).Infof("Built service %s per-node LB %#v", key, perNodeLBs)
-	klog.V(3).Infof("Service %s has %d cluster-wide and %d per-node configs, making %d and %d load balancers",
-		key, len(clusterConfigs), len(perNodeConfigs), len(clusterLBs), len(perNodeLBs))
-	lbs := append(clusterLBs, perNodeLBs...)
+	lbs := c.buildServiceLBs(key, service, c.serviceLBOpts(key, service), endpointSlices)
 
 	// Short-circuit if nothing has changed
 	c.alreadyAppliedLock.Lock()
@@ -337,6 +359,48 @@ func (c *Controller) syncService(key string) error {
 	return nil
 }
 
+// serviceLBOpts returns the load balancer options of a service, reporting the
+// options it had to ignore once per change of the service
+func (c *Controller) serviceLBOpts(key string, service *v1.Service) ovnlb.LBOpts {
+	opts, warnings := lbOpts(service, c.lbFeatures)
+
+	c.reportedLBOptsLock.Lock()
+	resourceVersion, reported := c.reportedLBOpts[key]
+	reported = reported && resourceVersion == service.ResourceVersion
+	c.reportedLBOpts[key] = service.ResourceVersion
+	c.reportedLBOptsLock.Unlock()
+
+	if !reported {
+		for _, warning := range warnings {
+			c.eventRecorder.Event(service, v1.EventTypeWarning, warning.reason, warning.message)
+		}
+	}
+	return opts
+}
+
+// buildServiceLBs builds the load balancers of a service from its options,
+// its endpoint slices and the current nodes
+func (c *Controller) buildServiceLBs(key string, service *v1.Service, opts ovnlb.LBOpts, endpointSlices []*discovery.EndpointSlice) []ovnlb.LB {
+	// Build the abstract LB configs for this service
+	perNodeConfigs, clusterConfigs := buildServiceLBConfigs(service, endpointSlices)
+	klog.V(5).Infof("Built service %s LB cluster-wide configs %#v", key, clusterConfigs)
+	klog.V(5).Infof("Built service %s LB per-node configs %#v", key, perNodeConfigs)
+
+	// Convert the LB configs in to load-balancer objects
+	nodeInfos := c.nodeTracker.allNodes()
+	clusterLBs := buildClusterLBs(service, opts, clusterConfigs, nodeInfos, c.useLBGroups)
+	perNodeLBs := buildPerNodeLBs(service, opts, perNodeConfigs, nodeInfos)
+	klog.V(5).Infof("Built service %s cluster-wide LB %#v", key, clusterLBs)
+	klog.V(5).Infof("Built service %s per-node LB %#v", key, perNodeLBs)
+	klog.V(3).Infof("Service %s has %d cluster-wide and %d per-node configs, making %d and %d load balancers",
+		key, len(clusterConfigs), len(perNodeConfigs), len(clusterLBs), len(perNodeLBs))
+	lbs := append(clusterLBs, perNodeLBs...)
+	if err := c.dropServiceMonitorIPConflicts(key, lbs); err != nil {
+		klog.Warningf("Failed to check the service monitor addresses of service %s: %v", key, err)
+	}
+	return lbs
+}
+
 // RequestFullSync re-syncs every service that currently exists
 func (c *Controller) RequestFullSync() {
 	klog.Info("Full service sync requested")
@@ -10,6 +10,7 @@ import (
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/nbdb"
 	ovnlb "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/loadbalancer"
 	libovsdbtest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing/libovsdb"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
 
 	v1 "k8s.io/api/core/v1"
 	discovery "k8s.io/api/discovery/v1beta1"
@@ -63,6 +64,21 @@ func (c *serviceController) close() {
 	close(c.stopChan)
 }
 
+// clusterLBGroups returns the empty load balancer groups the cluster load
+// balancers are attached to
+func clusterLBGroups() []libovsdbtest.TestData {
+	return []libovsdbtest.TestData{
+		&nbdb.LoadBalancerGroup{
+			UUID: types.ClusterSwitchLBGroupName,
+			Name: types.ClusterSwitchLBGroupName,
+		},
+		&nbdb.LoadBalancerGroup{
+			UUID: types.ClusterRouterLBGroupName,
+			Name: types.ClusterRouterLBGroupName,
+		},
+	}
+}
+
 // TestSyncServices - an end-to-end test for the services controller.
 func TestSyncServices(t *testing.T) {
 	ns := "testns"
@@ -172,23 +188,29 @@ func TestSyncServices(t *testing.T) {
 					},
 				},
 				&nbdb.LogicalSwitch{
-					UUID:         "switch-node-a",
-					Name:         "switch-node-a",
-					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
+					UUID: "switch-node-a",
+					Name: "switch-node-a",
 				},
 				&nbdb.LogicalSwitch{
-					UUID:         "switch-node-b",
-					Name:         "switch-node-b",
-					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
+					UUID: "switch-node-b",
+					Name: "switch-node-b",
 				},
 				&nbdb.LogicalRouter{
-					UUID:         "gr-node-a",
-					Name:         "gr-node-a",
-					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
+					UUID: "gr-node-a",
+					Name: "gr-node-a",
 				},
 				&nbdb.LogicalRouter{
-					UUID:         "gr-node-b",
-					Name:         "gr-node-b",
+					UUID: "gr-node-b",
+					Name: "gr-node-b",
+				},
+				&nbdb.LoadBalancerGroup{
+					UUID:         types.ClusterSwitchLBGroupName,
+					Name:         types.ClusterSwitchLBGroupName,
+					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
+				},
+				&nbdb.LoadBalancerGroup{
+					UUID:         types.ClusterRouterLBGroupName,
+					Name:         types.ClusterRouterLBGroupName,
 					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
 				},
 			},
@@ -284,33 +306,39 @@ func TestSyncServices(t *testing.T) {
 					},
 				},
 				&nbdb.LogicalSwitch{
-					UUID:         "switch-node-a",
-					Name:         "switch-node-a",
-					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
+					UUID: "switch-node-a",
+					Name: "switch-node-a",
 				},
 				&nbdb.LogicalSwitch{
-					UUID:         "switch-node-b",
-					Name:         "switch-node-b",
-					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
+					UUID: "switch-node-b",
+					Name: "switch-node-b",
 				},
 				&nbdb.LogicalSwitch{
 					UUID: "wrong-switch",
 					Name: "wrong-switch",
 				},
 				&nbdb.LogicalRouter{
-					UUID:         "gr-node-a",
-					Name:         "gr-node-a",
-					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
+					UUID: "gr-node-a",
+					Name: "gr-node-a",
 				},
 				&nbdb.LogicalRouter{
-					UUID:         "gr-node-b",
-					Name:         "gr-node-b",
-					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
+					UUID: "gr-node-b",
+					Name: "gr-node-b",
 				},
 				&nbdb.LogicalRouter{
 					UUID: "gr-node-c",
 					Name: "gr-node-c",
 				},
+				&nbdb.LoadBalancerGroup{
+					UUID:         types.ClusterSwitchLBGroupName,
+					Name:         types.ClusterSwitchLBGroupName,
+					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
+				},
+				&nbdb.LoadBalancerGroup{
+					UUID:         types.ClusterRouterLBGroupName,
+					Name:         types.ClusterRouterLBGroupName,
+					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
+				},
 			},
 		},
 		{
@@ -414,23 +442,29 @@ func TestSyncServices(t *testing.T) {
 					},
 				},
 				&nbdb.LogicalSwitch{
-					UUID:         "switch-node-a",
-					Name:         "switch-node-a",
-					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
+					UUID: "switch-node-a",
+					Name: "switch-node-a",
 				},
 				&nbdb.LogicalSwitch{
-					UUID:         "switch-node-b",
-					Name:         "switch-node-b",
-					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
+					UUID: "switch-node-b",
+					Name: "switch-node-b",
 				},
 				&nbdb.LogicalRouter{
-					UUID:         "gr-node-a",
-					Name:         "gr-node-a",
-					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
+					UUID: "gr-node-a",
+					Name: "gr-node-a",
 				},
 				&nbdb.LogicalRouter{
-					UUID:         "gr-node-b",
-					Name:         "gr-node-b",
+					UUID: "gr-node-b",
+					Name: "gr-node-b",
+				},
+				&nbdb.LoadBalancerGroup{
+					UUID:         types.ClusterSwitchLBGroupName,
+					Name:         types.ClusterSwitchLBGroupName,
+					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
+				},
+				&nbdb.LoadBalancerGroup{
+					UUID:         types.ClusterRouterLBGroupName,
+					Name:         types.ClusterRouterLBGroupName,
 					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
 				},
 			},
@@ -569,7 +603,6 @@ func TestSyncServices(t *testing.T) {
 					UUID: "switch-node-a",
 					Name: "switch-node-a",
 					LoadBalancer: []string{
-						"Service_testns/foo_TCP_cluster",
 						"Service_testns/foo_TCP_node_router+switch_node-a",
 					},
 				},
@@ -577,7 +610,6 @@ func TestSyncServices(t *testing.T) {
 					UUID: "switch-node-b",
 					Name: "switch-node-b",
 					LoadBalancer: []string{
-						"Service_testns/foo_TCP_cluster",
 						"Service_testns/foo_TCP_node_router+switch_node-b",
 					},
 				},
@@ -585,7 +617,6 @@ func TestSyncServices(t *testing.T) {
 					UUID: "gr-node-a",
 					Name: "gr-node-a",
 					LoadBalancer: []string{
-						"Service_testns/foo_TCP_cluster",
 						"Service_testns/foo_TCP_node_router+switch_node-a",
 					},
 				},
@@ -593,10 +624,19 @@ func TestSyncServices(t *testing.T) {
 					UUID: "gr-node-b",
 					Name: "gr-node-b",
 					LoadBalancer: []string{
-						"Service_testns/foo_TCP_cluster",
 						"Service_testns/foo_TCP_node_router+switch_node-b",
 					},
 				},
+				&nbdb.LoadBalancerGroup{
+					UUID:         types.ClusterSwitchLBGroupName,
+					Name:         types.ClusterSwitchLBGroupName,
+					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
+				},
+				&nbdb.LoadBalancerGroup{
+					UUID:         types.ClusterRouterLBGroupName,
+					Name:         types.ClusterRouterLBGroupName,
+					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
+				},
 			},
 		},
 	}
@@ -612,7 +652,7 @@ func TestSyncServices(t *testing.T) {
 			}
 
 			ovnlb.TestOnlySetCache(nil)
-			controller, err := newControllerWithDBSetup(libovsdbtest.TestSetup{NBData: tt.initialDb})
+			controller, err := newControllerWithDBSetup(libovsdbtest.TestSetup{NBData: append(clusterLBGroups(), tt.initialDb...)})
 			if err != nil {
 				t.Fatalf("Error creating controller: %v", err)
 			}
@@ -632,3 +672,159 @@ func TestSyncServices(t *testing.T) {
 		})
 	}
 }
+
+// TestSyncServiceHealthChecks checks that services with health checks get
+// health checks and ip_port_mappings on their load balancers
+func TestSyncServiceHealthChecks(t *testing.T) {
+	ns := "testns"
+	serviceName := "foo"
+
+	oldGateway := globalconfig.Gateway.Mode
+	oldClusterSubnet := globalconfig.Default.ClusterSubnets
+	globalconfig.Gateway.Mode = globalconfig.GatewayModeLocal
+	globalconfig.OVNKubernetesFeature.EnableServiceHealthChecks = true
+	globalconfig.IPv4Mode = true
+	defer func() {
+		globalconfig.OVNKubernetesFeature.EnableServiceHealthChecks = false
+		globalconfig.IPv4Mode = false
+		globalconfig.Gateway.Mode = oldGateway
+		globalconfig.Default.ClusterSubnets = oldClusterSubnet
+	}()
+	_, cidr4, _ := net.ParseCIDR("10.128.0.0/16")
+	globalconfig.Default.ClusterSubnets = []globalconfig.CIDRNetworkEntry{{cidr4, 24}}
+	tcp := v1.ProtocolTCP
+
+	tests := []struct {
+		name             string
+		nbData           []libovsdbtest.TestData
+		expectedMappings map[string]string
+	}{
+		{
+			name: "maps the endpoints to their port and the service monitor address",
+			expectedMappings: map[string]string{
+				"10.128.0.2": "testns_pod-a:10.128.0.254",
+			},
+		},
+		{
+			name: "skips the nodes whose service monitor address is held by a pod",
+			nbData: []libovsdbtest.TestData{
+				&nbdb.LogicalSwitchPort{
+					UUID:      "pod-b",
+					Name:      "testns_pod-b",
+					Addresses: []string{"0a:58:0a:80:00:fe 10.128.0.254"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			ovnlb.TestOnlySetCache(nil)
+			controller, err := newControllerWithDBSetup(libovsdbtest.TestSetup{
+				NBData: append(append(clusterLBGroups(),
+					&nbdb.LogicalSwitch{UUID: "switch-node-a", Name: "switch-node-a"},
+				), tt.nbData...),
+			})
+			if err != nil {
+				t.Fatalf("Error creating controller: %v", err)
+			}
+			defer controller.close()
+
+			controller.endpointSliceStore.Add(&discovery.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      serviceName + "ab23",
+					Namespace: ns,
+					Labels:    map[string]string{discovery.LabelServiceName: serviceName},
+				},
+				Ports: []discovery.EndpointPort{{
+					Name:     utilpointer.StringPtr("http"),
+					Protocol: &tcp,
+					Port:     utilpointer.Int32Ptr(3456),
+				}},
+				AddressType: discovery.AddressTypeIPv4,
+				Endpoints: []discovery.Endpoint{{
+					Conditions: discovery.EndpointConditions{Ready: utilpointer.BoolPtr(true)},
+					Addresses:  []string{"10.128.0.2"},
+					TargetRef:  &v1.ObjectReference{Kind: "Pod", Namespace: ns, Name: "pod-a"},
+				}},
+			})
+			controller.serviceStore.Add(&v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      serviceName,
+					Namespace: ns,
+					Annotations: map[string]string{
+						ServiceHealthCheckAnnotation: `{"http": {"interval": 5, "failureCount": 2}}`,
+					},
+				},
+				Spec: v1.ServiceSpec{
+					Type:       v1.ServiceTypeClusterIP,
+					ClusterIP:  "192.168.1.1",
+					ClusterIPs: []string{"192.168.1.1"},
+					Selector:   map[string]string{"foo": "bar"},
+					Ports: []v1.ServicePort{{
+						Name:       "http",
+						Port:       80,
+						Protocol:   v1.ProtocolTCP,
+						TargetPort: intstr.FromInt(3456),
+					}},
+				},
+			})
+			controller.nodeTracker.nodes = map[string]nodeInfo{
+				"node-a": {
+					name:       "node-a",
+					nodeIPs:    []string{"10.0.0.1"},
+					switchName: "switch-node-a",
+					podSubnets: []net.IPNet{{IP: net.ParseIP("10.128.0.0"), Mask: net.CIDRMask(24, 32)}},
+				},
+			}
+
+			err = controller.syncService(ns + "/" + serviceName)
+			if err != nil {
+				t.Errorf("syncServices error: %v", err)
+			}
+
+			g.Eventually(controller.nbClient).Should(libovsdbtest.HaveData(append([]libovsdbtest.TestData{
+				&nbdb.LoadBalancerHealthCheck{
+					UUID: "health-check",
+					Vip:  "192.168.1.1:80",
+					Options: map[string]string{
+						"interval":      "5",
+						"failure_count": "2",
+					},
+				},
+				&nbdb.LoadBalancer{
+					UUID: "Service_testns/foo_TCP_cluster",
+					Name: "Service_testns/foo_TCP_cluster",
+					Options: map[string]string{
+						"event":     "false",
+						"reject":    "true",
+						"skip_snat": "false",
+					},
+					Protocol: &nbdb.LoadBalancerProtocolTCP,
+					Vips: map[string]string{
+						"192.168.1.1:80": "10.128.0.2:3456",
+					},
+					HealthCheck:    []string{"health-check"},
+					IPPortMappings: tt.expectedMappings,
+					ExternalIDs: map[string]string{
+						"k8s.ovn.org/kind":  "Service",
+						"k8s.ovn.org/owner": "testns/foo",
+					},
+				},
+				&nbdb.LogicalSwitch{
+					UUID: "switch-node-a",
+					Name: "switch-node-a",
+				},
+				&nbdb.LoadBalancerGroup{
+					UUID:         types.ClusterSwitchLBGroupName,
+					Name:         types.ClusterSwitchLBGroupName,
+					LoadBalancer: []string{"Service_testns/foo_TCP_cluster"},
+				},
+				&nbdb.LoadBalancerGroup{
+					UUID: types.ClusterRouterLBGroupName,
+					Name: types.ClusterRouterLBGroupName,
+				},
+			}, tt.nbData...)))
+		})
+	}
+}
@@ -1,18 +1,26 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 
 	libovsdbclient "github.com/ovn-org/libovsdb/client"
 	globalconfig "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/nbdb"
 	ovnlb "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/loadbalancer"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
 
 	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
 )
 
 // deleteServiceFrom LegacyLBs removes any of a service's vips from
@@ -134,7 +142,11 @@ func svcNeedsIdling(annotations map[string]string) bool {
 	if !globalconfig.Kubernetes.OVNEmptyLbEvents {
 		return false
 	}
+	return HasIdledAnnotation(annotations)
+}
 
+// HasIdledAnnotation returns true if the annotations mark a service as idled
+func HasIdledAnnotation(annotations map[string]string) bool {
 	for annotationKey := range annotations {
 		if strings.HasSuffix(annotationKey, OvnServiceIdledSuffix) {
 			return true
@@ -142,3 +154,422 @@ func svcNeedsIdling(annotations map[string]string) bool {
 	}
 	return false
 }
+
+// ServiceEmptyLBActionAnnotation chooses what happens to the traffic to a
+// service without endpoints, one of the EmptyLBAction values
+const ServiceEmptyLBActionAnnotation = "k8s.ovn.org/service-empty-lb-action"
+
+const (
+	// EmptyLBActionReject rejects the traffic with a TCP RST or an ICMP
+	// port unreachable. This is the default.
+	EmptyLBActionReject = "reject"
+	// EmptyLBActionDrop silently drops the traffic
+	EmptyLBActionDrop = "drop"
+	// EmptyLBActionEvent drops the traffic and raises a warning event on the
+	// service. It requires empty LB events to be enabled.
+	EmptyLBActionEvent = "event"
+)
+
+// parseServiceEmptyLBAction parses and validates the ServiceEmptyLBActionAnnotation
+// of a service. It returns EmptyLBActionReject if the service has none.
+func parseServiceEmptyLBAction(service *v1.Service) (string, error) {
+	action, ok := service.Annotations[ServiceEmptyLBActionAnnotation]
+	if !ok {
+		return EmptyLBActionReject, nil
+	}
+	switch action {
+	case EmptyLBActionReject, EmptyLBActionDrop:
+		return action, nil
+	case EmptyLBActionEvent:
+		if !globalconfig.Kubernetes.OVNEmptyLbEvents {
+			return EmptyLBActionReject, fmt.Errorf("%s annotation %q requires empty LB events to be enabled",
+				ServiceEmptyLBActionAnnotation, action)
+		}
+		return action, nil
+	}
+	return EmptyLBActionReject, fmt.Errorf("invalid %s annotation %q, must be one of %s, %s or %s",
+		ServiceEmptyLBActionAnnotation, action, EmptyLBActionReject, EmptyLBActionDrop, EmptyLBActionEvent)
+}
+
+// The OVN_Northbound schema versions of the first OVN releases supporting
+// the optional load balancer options
+const (
+	// OVN 20.06 added the selection_fields column
+	hashFieldsSchemaVersion = "5.23.0"
+	// OVN 21.03 added the hairpin_snat_ip option
+	hairpinSNATIPSchemaVersion = "5.31.0"
+	// OVN 22.09 added the affinity_timeout option
+	affinityTimeoutSchemaVersion = "6.3.0"
+)
+
+// lbFeatures are the optional load balancer options supported by OVN
+type lbFeatures struct {
+	hashFields      bool
+	hairpinSNATIP   bool
+	affinityTimeout bool
+}
+
+// detectLBFeatures probes the OVN_Northbound schema for the optional load
+// balancer options
+func detectLBFeatures(nbClient libovsdbclient.Client) lbFeatures {
+	detect := func(name, version, unsupported string) bool {
+		supported, err := nbSchemaVersionAtLeast(nbClient, version)
+		if err != nil {
+			klog.Warningf("Unable to detect %s support in OVN: %v", name, err)
+		}
+		if !supported {
+			klog.Warningf("%s unsupported by this version of OVN. %s", name, unsupported)
+		} else {
+			klog.Infof("%s support detected in OVN", name)
+		}
+		return supported
+	}
+	return lbFeatures{
+		hashFields: detect("Load balancer hash fields", hashFieldsSchemaVersion,
+			"The "+ServiceLBHashFieldsAnnotation+" annotation of Kubernetes services will be ignored"),
+		hairpinSNATIP: detect("Load balancer hairpin SNAT IP", hairpinSNATIPSchemaVersion,
+			"The "+ServiceHairpinSNATIPAnnotation+" annotation of Kubernetes services will be ignored"),
+		affinityTimeout: detect("Session affinity timeout", affinityTimeoutSchemaVersion,
+			"Kubernetes services with ClientIP session affinity will not time out"),
+	}
+}
+
+// nbSchemaVersionAtLeast returns true if the OVN_Northbound schema served to
+// the client is at least the given major.minor.patch version
+func nbSchemaVersionAtLeast(nbClient libovsdbclient.Client, version string) (bool, error) {
+	parse := func(version string) ([3]int, error) {
+		var parsed [3]int
+		parts := strings.Split(version, ".")
+		if len(parts) != len(parsed) {
+			return parsed, fmt.Errorf("invalid schema version %q", version)
+		}
+		for i, part := range parts {
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return parsed, fmt.Errorf("invalid schema version %q: %v", version, err)
+			}
+			parsed[i] = n
+		}
+		return parsed, nil
+	}
+	have, err := parse(nbClient.Schema().Version)
+	if err != nil {
+		return false, err
+	}
+	want, err := parse(version)
+	if err != nil {
+		return false, err
+	}
+	for i := range have {
+		if have[i] != want[i] {
+			return have[i] > want[i], nil
+		}
+	}
+	return true, nil
+}
+
+const (
+	// ServiceLBHashFieldsAnnotation selects the fields OVN hashes to pick the
+	// endpoint of a connection to the service, e.g. "ip_src,ip_dst,tp_src,tp_dst".
+	// It can't be combined with ClientIP session affinity.
+	ServiceLBHashFieldsAnnotation = "k8s.ovn.org/service-lb-hash-fields"
+
+	// ServiceHairpinSNATIPAnnotation sets the IPs, at most one per IP family,
+	// that hairpin traffic of the service is SNATed to instead of its vip
+	ServiceHairpinSNATIPAnnotation = "k8s.ovn.org/service-hairpin-snat-ip"
+)
+
+// lbHashFields are the fields ServiceLBHashFieldsAnnotation can select
+var lbHashFields = sets.NewString(
+	nbdb.LoadBalancerSelectionFieldsIPSrc,
+	nbdb.LoadBalancerSelectionFieldsIPDst,
+	nbdb.LoadBalancerSelectionFieldsTpSrc,
+	nbdb.LoadBalancerSelectionFieldsTpDst,
+)
+
+// parseServiceLBHashFields parses and validates the ServiceLBHashFieldsAnnotation
+// of a service. It returns nil if the service has none.
+func parseServiceLBHashFields(service *v1.Service) ([]string, error) {
+	annotation, ok := service.Annotations[ServiceLBHashFieldsAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	if service.Spec.SessionAffinity == v1.ServiceAffinityClientIP {
+		return nil, fmt.Errorf("%s annotation can't be combined with ClientIP session affinity",
+			ServiceLBHashFieldsAnnotation)
+	}
+	fields := sets.NewString()
+	for _, field := range strings.Split(annotation, ",") {
+		field = strings.TrimSpace(field)
+		if !lbHashFields.Has(field) {
+			return nil, fmt.Errorf("invalid %s annotation: unsupported hash field %q, must be one of %v",
+				ServiceLBHashFieldsAnnotation, field, lbHashFields.List())
+		}
+		fields.Insert(field)
+	}
+	return fields.List(), nil
+}
+
+// parseServiceHairpinSNATIPs parses and validates the ServiceHairpinSNATIPAnnotation
+// of a service. It returns nil if the service has none.
+func parseServiceHairpinSNATIPs(service *v1.Service) ([]string, error) {
+	annotation, ok := service.Annotations[ServiceHairpinSNATIPAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var v4IP, v6IP string
+	for _, ipStr := range strings.Split(annotation, ",") {
+		ip := net.ParseIP(strings.TrimSpace(ipStr))
+		if ip == nil {
+			return nil, fmt.Errorf("invalid %s annotation: %q is not an IP address",
+				ServiceHairpinSNATIPAnnotation, ipStr)
+		}
+		family := &v4IP
+		if ip.To4() == nil {
+			family = &v6IP
+		}
+		if *family != "" {
+			return nil, fmt.Errorf("invalid %s annotation: more than one IP of the family of %s",
+				ServiceHairpinSNATIPAnnotation, ip)
+		}
+		*family = ip.String()
+	}
+	ips := []string{}
+	for _, ip := range []string{v4IP, v6IP} {
+		if ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// svcAffinityTimeout returns the ClientIP session affinity timeout of a service
+func svcAffinityTimeout(service *v1.Service) int32 {
+	if service.Spec.SessionAffinityConfig != nil &&
+		service.Spec.SessionAffinityConfig.ClientIP != nil &&
+		service.Spec.SessionAffinityConfig.ClientIP.TimeoutSeconds != nil {
+		return *service.Spec.SessionAffinityConfig.ClientIP.TimeoutSeconds
+	}
+	return v1.DefaultClientIPServiceAffinitySeconds
+}
+
+// svcUsesTopologyAwareHints returns true if the service opted in to topology
+// aware hints, in which case endpoints are filtered by the zone hints the
+// EndpointSlice controller sets, as kube-proxy does
+func svcUsesTopologyAwareHints(service *v1.Service) bool {
+	return strings.ToLower(service.Annotations[v1.AnnotationTopologyAwareHints]) == "auto"
+}
+
+// ServiceHealthCheckAnnotation configures OVN load balancer health checks for
+// the ports of a service, keyed by port name or number. For example:
+// {"http": {"interval": 5, "timeout": 20, "successCount": 3, "failureCount": 3}}
+const ServiceHealthCheckAnnotation = "k8s.ovn.org/service-health-check"
+
+// serviceHealthCheck is the health check of a service port, as set in the
+// ServiceHealthCheckAnnotation. Zero values leave the OVN default in place.
+type serviceHealthCheck struct {
+	Interval     int32 `json:"interval,omitempty"`
+	Timeout      int32 `json:"timeout,omitempty"`
+	SuccessCount int32 `json:"successCount,omitempty"`
+	FailureCount int32 `json:"failureCount,omitempty"`
+}
+
+// parseServiceHealthChecks parses the ServiceHealthCheckAnnotation of a service.
+// It returns nil if the service has no health checks.
+func parseServiceHealthChecks(service *v1.Service) (map[string]serviceHealthCheck, error) {
+	annotation, ok := service.Annotations[ServiceHealthCheckAnnotation]
+	if !ok || !globalconfig.OVNKubernetesFeature.EnableServiceHealthChecks {
+		return nil, nil
+	}
+	healthChecks := map[string]serviceHealthCheck{}
+	if err := json.Unmarshal([]byte(annotation), &healthChecks); err != nil {
+		return nil, fmt.Errorf("failed to parse %s annotation: %v", ServiceHealthCheckAnnotation, err)
+	}
+	for port, hc := range healthChecks {
+		if hc.Interval < 0 || hc.Timeout < 0 || hc.SuccessCount < 0 || hc.FailureCount < 0 {
+			return nil, fmt.Errorf("invalid %s annotation: negative value for port %s", ServiceHealthCheckAnnotation, port)
+		}
+	}
+	return healthChecks, nil
+}
+
+// svcPortHealthCheck returns the health check of a service port, looked up by
+// name and then by number, or nil if it has none. OVN can't probe SCTP.
+func svcPortHealthCheck(healthChecks map[string]serviceHealthCheck, svcPort v1.ServicePort) *ovnlb.HealthCheck {
+	if svcPort.Protocol == v1.ProtocolSCTP {
+		return nil
+	}
+	hc, ok := healthChecks[svcPort.Name]
+	if !ok || svcPort.Name == "" {
+		hc, ok = healthChecks[strconv.Itoa(int(svcPort.Port))]
+	}
+	if !ok {
+		return nil
+	}
+	return &ovnlb.HealthCheck{
+		Interval:     hc.Interval,
+		Timeout:      hc.Timeout,
+		SuccessCount: hc.SuccessCount,
+		FailureCount: hc.FailureCount,
+	}
+}
+
+// getEndpointLogicalPorts returns the logical switch port of each pod endpoint
+// address. Host network endpoints are included, but aren't probed, since their
+// addresses aren't in the node's subnet.
+func getEndpointLogicalPorts(endpointSlices []*discovery.EndpointSlice) map[string]string {
+	out := map[string]string{}
+	for _, slice := range endpointSlices {
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.TargetRef == nil || endpoint.TargetRef.Kind != "Pod" {
+				continue
+			}
+			for _, ip := range endpoint.Addresses {
+				out[ip] = util.GetLogicalPortName(endpoint.TargetRef.Namespace, endpoint.TargetRef.Name)
+			}
+		}
+	}
+	return out
+}
+
+// setHealthCheckMappings sets the ip_port_mappings OVN needs to probe the
+// targets of the rules with health checks. Probes are sent from the service
+// monitor address of the node subnet the target belongs to; targets outside
+// of any node subnet are not probed.
+func setHealthCheckMappings(lb *ovnlb.LB, logicalPorts map[string]string, nodes []nodeInfo) {
+	for _, rule := range lb.Rules {
+		if rule.HealthCheck == nil {
+			continue
+		}
+		for _, target := range rule.Targets {
+			logicalPort, ok := logicalPorts[target.IP]
+			if !ok {
+				continue
+			}
+			ip := net.ParseIP(target.IP)
+		nodes:
+			for _, node := range nodes {
+				for i := range node.podSubnets {
+					subnet := &node.podSubnets[i]
+					if subnet.Contains(ip) {
+						if lb.IPPortMappings == nil {
+							lb.IPPortMappings = map[string]string{}
+						}
+						lb.IPPortMappings[target.IP] = logicalPort + ":" + util.GetNodeServiceMonitorIfAddr(subnet).IP.String()
+						break nodes
+					}
+				}
+			}
+		}
+	}
+}
+
+// serviceMonitorIPConflicts indexes the logical switch ports holding service
+// monitor addresses. The service monitor address of a node is reserved, but a
+// pod started before health checks were enabled may already hold it. As no
+// other port can take the address afterwards, the logical switch ports are
+// scanned once per address, and only the ports found are checked again.
+type serviceMonitorIPConflicts struct {
+	sync.Mutex
+	// scanned are the addresses the logical switch ports were scanned for
+	scanned sets.String
+	// ports maps the addresses held by logical switch ports to the port names
+	ports map[string]string
+}
+
+func newServiceMonitorIPConflicts() *serviceMonitorIPConflicts {
+	return &serviceMonitorIPConflicts{
+		scanned: sets.NewString(),
+		ports:   map[string]string{},
+	}
+}
+
+// heldSources returns the given service monitor addresses that are held by a
+// logical switch port, mapped to the port names
+func (conflicts *serviceMonitorIPConflicts) heldSources(nbClient libovsdbclient.Client, sources sets.String) (map[string]string, error) {
+	conflicts.Lock()
+	defer conflicts.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), types.OVSDBTimeout)
+	defer cancel()
+	if unscanned := sources.Difference(conflicts.scanned); unscanned.Len() > 0 {
+		lsps := []nbdb.LogicalSwitchPort{}
+		err := nbClient.WhereCache(func(lsp *nbdb.LogicalSwitchPort) bool {
+			return len(lspSources(lsp, unscanned)) > 0
+		}).List(ctx, &lsps)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up the logical switch ports holding service monitor addresses: %w", err)
+		}
+		for i := range lsps {
+			for _, source := range lspSources(&lsps[i], unscanned) {
+				conflicts.ports[source] = lsps[i].Name
+			}
+		}
+		conflicts.scanned = conflicts.scanned.Union(unscanned)
+	}
+
+	held := map[string]string{}
+	for source := range sources {
+		name, ok := conflicts.ports[source]
+		if !ok {
+			continue
+		}
+		lsp := &nbdb.LogicalSwitchPort{Name: name}
+		err := nbClient.Get(ctx, lsp)
+		if err != nil && err != libovsdbclient.ErrNotFound {
+			return nil, fmt.Errorf("failed to look up logical switch port %s: %w", name, err)
+		}
+		if err == libovsdbclient.ErrNotFound || len(lspSources(lsp, sets.NewString(source))) == 0 {
+			delete(conflicts.ports, source)
+			continue
+		}
+		held[source] = name
+	}
+	return held, nil
+}
+
+// lspSources returns the given addresses held by a logical switch port
+func lspSources(lsp *nbdb.LogicalSwitchPort, sources sets.String) []string {
+	held := []string{}
+	for _, addresses := range lsp.Addresses {
+		for _, address := range strings.Fields(addresses) {
+			if sources.Has(address) {
+				held = append(held, address)
+			}
+		}
+	}
+	return held
+}
+
+// dropServiceMonitorIPConflicts removes the ip_port_mappings whose source
+// address is held by a logical switch port, as OVN would then probe from the
+// address of that port. The endpoints of such nodes are not probed until the
+// port is gone. Only load balancers with health checks have ip_port_mappings.
+func (c *Controller) dropServiceMonitorIPConflicts(key string, lbs []ovnlb.LB) error {
+	sources := sets.NewString()
+	for _, lb := range lbs {
+		for _, mapping := range lb.IPPortMappings {
+			sources.Insert(mapping[strings.LastIndex(mapping, ":")+1:])
+		}
+	}
+	if sources.Len() == 0 {
+		return nil
+	}
+
+	held, err := c.serviceMonitorIPConflicts.heldSources(c.nbClient, sources)
+	if err != nil {
+		return err
+	}
+	for source, name := range held {
+		klog.Warningf("Logical switch port %s holds service monitor address %s, not health checking the endpoints "+
+			"of service %s on its node", name, source, key)
+	}
+	for _, lb := range lbs {
+		for ip, mapping := range lb.IPPortMappings {
+			if _, ok := held[mapping[strings.LastIndex(mapping, ":")+1:]]; ok {
+				delete(lb.IPPortMappings, ip)
+			}
+		}
+	}
+	return nil
+}
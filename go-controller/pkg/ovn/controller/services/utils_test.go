@@ -4,6 +4,16 @@ import (
 	"testing"
 
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/nbdb"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/libovsdbops"
+	ovnlb "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/loadbalancer"
+	libovsdbtest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing/libovsdb"
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
 )
 
 func TestServiceNeedsIdling(t *testing.T) {
@@ -49,3 +59,351 @@ func TestServiceNeedsIdling(t *testing.T) {
 	}
 
 }
+
+func TestServiceHealthChecks(t *testing.T) {
+	config.OVNKubernetesFeature.EnableServiceHealthChecks = true
+	defer func() {
+		config.OVNKubernetesFeature.EnableServiceHealthChecks = false
+	}()
+
+	httpPort := v1.ServicePort{Name: "http", Port: 80}
+	unnamedPort := v1.ServicePort{Port: 443}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expectErr   bool
+		expected    map[string]*ovnlb.HealthCheck
+	}{
+		{
+			name: "no annotation",
+			expected: map[string]*ovnlb.HealthCheck{
+				"http": nil,
+				"443":  nil,
+			},
+		},
+		{
+			name: "by port name and number",
+			annotations: map[string]string{
+				ServiceHealthCheckAnnotation: `{"http": {"interval": 5, "timeout": 20}, "443": {"successCount": 3, "failureCount": 2}}`,
+			},
+			expected: map[string]*ovnlb.HealthCheck{
+				"http": {Interval: 5, Timeout: 20},
+				"443":  {SuccessCount: 3, FailureCount: 2},
+			},
+		},
+		{
+			name: "by port number only",
+			annotations: map[string]string{
+				ServiceHealthCheckAnnotation: `{"80": {}}`,
+			},
+			expected: map[string]*ovnlb.HealthCheck{
+				"http": {},
+				"443":  nil,
+			},
+		},
+		{
+			name: "malformed",
+			annotations: map[string]string{
+				ServiceHealthCheckAnnotation: `{"http": 5}`,
+			},
+			expectErr: true,
+		},
+		{
+			name: "negative value",
+			annotations: map[string]string{
+				ServiceHealthCheckAnnotation: `{"http": {"timeout": -1}}`,
+			},
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &v1.Service{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			healthChecks, err := parseServiceHealthChecks(service)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected["http"], svcPortHealthCheck(healthChecks, httpPort))
+			assert.Equal(t, tt.expected["443"], svcPortHealthCheck(healthChecks, unnamedPort))
+		})
+	}
+}
+
+func TestLBOptsSessionAffinity(t *testing.T) {
+	timeout := int32(600)
+	tests := []struct {
+		name      string
+		supported bool
+		spec      v1.ServiceSpec
+		expected  ovnlb.LBOpts
+		warned    bool
+	}{
+		{
+			name:      "no affinity",
+			supported: true,
+			spec:      v1.ServiceSpec{SessionAffinity: v1.ServiceAffinityNone},
+			expected:  ovnlb.LBOpts{},
+		},
+		{
+			name:      "ClientIP affinity, default timeout",
+			supported: true,
+			spec:      v1.ServiceSpec{SessionAffinity: v1.ServiceAffinityClientIP},
+			expected:  ovnlb.LBOpts{Affinity: true, AffinityTimeOut: v1.DefaultClientIPServiceAffinitySeconds},
+		},
+		{
+			name:      "ClientIP affinity, timeout",
+			supported: true,
+			spec: v1.ServiceSpec{
+				SessionAffinity: v1.ServiceAffinityClientIP,
+				SessionAffinityConfig: &v1.SessionAffinityConfig{
+					ClientIP: &v1.ClientIPConfig{TimeoutSeconds: &timeout},
+				},
+			},
+			expected: ovnlb.LBOpts{Affinity: true, AffinityTimeOut: 600},
+		},
+		{
+			name:      "ClientIP affinity, timeout unsupported",
+			supported: false,
+			spec: v1.ServiceSpec{
+				SessionAffinity: v1.ServiceAffinityClientIP,
+				SessionAffinityConfig: &v1.SessionAffinityConfig{
+					ClientIP: &v1.ClientIPConfig{TimeoutSeconds: &timeout},
+				},
+			},
+			expected: ovnlb.LBOpts{Affinity: true},
+			warned:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, warnings := lbOpts(&v1.Service{Spec: tt.spec}, lbFeatures{affinityTimeout: tt.supported})
+			assert.Equal(t, tt.expected, opts)
+			assert.Equal(t, tt.warned, len(warnings) > 0)
+		})
+	}
+}
+
+func TestServiceLBOptsReportedOnce(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	c := &Controller{
+		eventRecorder:  recorder,
+		reportedLBOpts: map[string]string{},
+	}
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "testns", ResourceVersion: "1"},
+		Spec:       v1.ServiceSpec{SessionAffinity: v1.ServiceAffinityClientIP},
+	}
+
+	c.serviceLBOpts("testns/foo", service)
+	c.serviceLBOpts("testns/foo", service)
+	assert.Len(t, recorder.Events, 1)
+
+	// a change of the service reports again
+	service.ResourceVersion = "2"
+	c.serviceLBOpts("testns/foo", service)
+	assert.Len(t, recorder.Events, 2)
+}
+
+func TestLBOptsAlgorithm(t *testing.T) {
+	allFeatures := lbFeatures{hashFields: true, hairpinSNATIP: true, affinityTimeout: true}
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		affinity    v1.ServiceAffinity
+		features    *lbFeatures
+		expected    ovnlb.LBOpts
+		expectedErr bool
+	}{
+		{
+			name:     "no annotations",
+			expected: ovnlb.LBOpts{},
+		},
+		{
+			name:        "hash fields unsupported",
+			annotations: map[string]string{ServiceLBHashFieldsAnnotation: "ip_src,ip_dst"},
+			features:    &lbFeatures{hairpinSNATIP: true, affinityTimeout: true},
+			expected:    ovnlb.LBOpts{},
+			expectedErr: true,
+		},
+		{
+			name:        "hairpin SNAT IPs unsupported",
+			annotations: map[string]string{ServiceHairpinSNATIPAnnotation: "169.254.169.5"},
+			features:    &lbFeatures{hashFields: true, affinityTimeout: true},
+			expected:    ovnlb.LBOpts{},
+			expectedErr: true,
+		},
+		{
+			name:        "hash fields",
+			annotations: map[string]string{ServiceLBHashFieldsAnnotation: "tp_dst, ip_src,ip_dst,ip_src"},
+			expected:    ovnlb.LBOpts{HashFields: []string{"ip_dst", "ip_src", "tp_dst"}},
+		},
+		{
+			name:        "invalid hash field",
+			annotations: map[string]string{ServiceLBHashFieldsAnnotation: "ip_src,eth_src"},
+			expected:    ovnlb.LBOpts{},
+			expectedErr: true,
+		},
+		{
+			name:        "hash fields with ClientIP affinity",
+			annotations: map[string]string{ServiceLBHashFieldsAnnotation: "ip_src"},
+			affinity:    v1.ServiceAffinityClientIP,
+			expected:    ovnlb.LBOpts{Affinity: true, AffinityTimeOut: v1.DefaultClientIPServiceAffinitySeconds},
+			expectedErr: true,
+		},
+		{
+			name:        "hairpin SNAT IPs",
+			annotations: map[string]string{ServiceHairpinSNATIPAnnotation: "fd69::5,169.254.169.5"},
+			expected:    ovnlb.LBOpts{HairpinSNATIPs: []string{"169.254.169.5", "fd69::5"}},
+		},
+		{
+			name:        "two hairpin SNAT IPs of a family",
+			annotations: map[string]string{ServiceHairpinSNATIPAnnotation: "169.254.169.5,169.254.169.6"},
+			expected:    ovnlb.LBOpts{},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid hairpin SNAT IP",
+			annotations: map[string]string{ServiceHairpinSNATIPAnnotation: "foo"},
+			expected:    ovnlb.LBOpts{},
+			expectedErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations},
+				Spec:       v1.ServiceSpec{SessionAffinity: tt.affinity},
+			}
+			features := allFeatures
+			if tt.features != nil {
+				features = *tt.features
+			}
+			opts, warnings := lbOpts(service, features)
+			assert.Equal(t, tt.expected, opts)
+			assert.Equal(t, tt.expectedErr, len(warnings) > 0)
+		})
+	}
+}
+
+func TestLBOptsEmptyLBAction(t *testing.T) {
+	oldEmptyLbEvents := config.Kubernetes.OVNEmptyLbEvents
+	defer func() {
+		config.Kubernetes.OVNEmptyLbEvents = oldEmptyLbEvents
+	}()
+
+	tests := []struct {
+		name        string
+		action      string
+		emptyEvents bool
+		expected    ovnlb.LBOpts
+		expectedErr bool
+	}{
+		{
+			name:     "reject",
+			action:   EmptyLBActionReject,
+			expected: ovnlb.LBOpts{},
+		},
+		{
+			name:     "drop",
+			action:   EmptyLBActionDrop,
+			expected: ovnlb.LBOpts{DropEmpty: true},
+		},
+		{
+			name:        "event",
+			action:      EmptyLBActionEvent,
+			emptyEvents: true,
+			expected:    ovnlb.LBOpts{EmptyEvent: true},
+		},
+		{
+			name:        "event without empty LB events",
+			action:      EmptyLBActionEvent,
+			expected:    ovnlb.LBOpts{},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid action",
+			action:      "icmp",
+			expected:    ovnlb.LBOpts{},
+			expectedErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Kubernetes.OVNEmptyLbEvents = tt.emptyEvents
+			service := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{ServiceEmptyLBActionAnnotation: tt.action},
+				},
+			}
+			opts, _ := lbOpts(service, lbFeatures{})
+			assert.Equal(t, tt.expected, opts)
+			_, err := parseServiceEmptyLBAction(service)
+			assert.Equal(t, tt.expectedErr, err != nil)
+		})
+	}
+}
+
+func TestNBSchemaVersionAtLeast(t *testing.T) {
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+	nbClient, err := libovsdbtest.NewNBTestHarness(libovsdbtest.TestSetup{}, stopChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := nbClient.Schema().Version
+
+	for _, tt := range []struct {
+		version  string
+		expected bool
+	}{
+		{version: version, expected: true},
+		{version: "5.4.0", expected: true},
+		{version: "5.32.2", expected: false},
+		{version: affinityTimeoutSchemaVersion, expected: false},
+	} {
+		atLeast, err := nbSchemaVersionAtLeast(nbClient, tt.version)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, atLeast, "schema %s at least %s", version, tt.version)
+	}
+	_, err = nbSchemaVersionAtLeast(nbClient, "6.3")
+	assert.Error(t, err)
+
+	// the test NB schema is older than affinity_timeout only
+	assert.Equal(t, lbFeatures{hashFields: true, hairpinSNATIP: true}, detectLBFeatures(nbClient))
+}
+
+func TestServiceMonitorIPConflicts(t *testing.T) {
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+	lsp := &nbdb.LogicalSwitchPort{
+		UUID:      "pod-b",
+		Name:      "testns_pod-b",
+		Addresses: []string{"0a:58:0a:80:00:fe 10.128.0.254"},
+	}
+	nbClient, err := libovsdbtest.NewNBTestHarness(libovsdbtest.TestSetup{
+		NBData: []libovsdbtest.TestData{lsp},
+	}, stopChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conflicts := newServiceMonitorIPConflicts()
+	sources := sets.NewString("10.128.0.254", "10.128.1.254")
+	held, err := conflicts.heldSources(nbClient, sources)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"10.128.0.254": "testns_pod-b"}, held)
+	assert.Equal(t, sources, conflicts.scanned)
+
+	// once the port is gone, the address is free for good
+	ops, err := nbClient.Where(lsp).Delete()
+	assert.NoError(t, err)
+	_, err = libovsdbops.TransactAndCheck(nbClient, ops)
+	assert.NoError(t, err)
+	held, err = conflicts.heldSources(nbClient, sources)
+	assert.NoError(t, err)
+	assert.Empty(t, held)
+	assert.Empty(t, conflicts.ports)
+}
@@ -6,10 +6,12 @@ import (
 	"time"
 
 	libovsdbclient "github.com/ovn-org/libovsdb/client"
+	svccontroller "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/controller/services"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 
@@ -18,9 +20,11 @@ import (
 
 // unidlingController checks periodically the OVN events db
 // and generates a Kubernetes NeedPods events with the Service
-// associated to the VIP
+// associated to the VIP. Services that are not idled, but opted in
+// to empty LB events, get a warning event instead.
 type unidlingController struct {
 	eventRecorder record.EventRecorder
+	serviceLister corelisters.ServiceLister
 	// Map of load balancers to service namespace
 	serviceVIPToName     map[ServiceVIPKey]types.NamespacedName
 	serviceVIPToNameLock sync.Mutex
@@ -31,6 +35,7 @@ type unidlingController struct {
 func NewController(recorder record.EventRecorder, serviceInformer cache.SharedIndexInformer, sbClient libovsdbclient.Client) *unidlingController {
 	uc := &unidlingController{
 		eventRecorder:    recorder,
+		serviceLister:    corelisters.NewServiceLister(serviceInformer.GetIndexer()),
 		serviceVIPToName: map[ServiceVIPKey]types.NamespacedName{},
 		sbClient:         sbClient,
 	}
@@ -50,13 +55,8 @@ func NewController(recorder record.EventRecorder, serviceInformer cache.SharedIn
 
 func (uc *unidlingController) onServiceAdd(obj interface{}) {
 	svc := obj.(*v1.Service)
-	if util.ServiceTypeHasClusterIP(svc) && util.IsClusterIPSet(svc) {
-		for _, ip := range util.GetClusterIPs(svc) {
-			for _, svcPort := range svc.Spec.Ports {
-				vip := util.JoinHostPortInt32(ip, svcPort.Port)
-				uc.AddServiceVIPToName(vip, svcPort.Protocol, svc.Namespace, svc.Name)
-			}
-		}
+	for _, key := range serviceVIPKeys(svc) {
+		uc.AddServiceVIPToName(key.vip, key.protocol, svc.Namespace, svc.Name)
 	}
 }
 
@@ -75,20 +75,42 @@ func (uc *unidlingController) onServiceDelete(obj interface{}) {
 		}
 	}
 
+	for _, key := range serviceVIPKeys(svc) {
+		uc.DeleteServiceVIPToName(key.vip, key.protocol)
+	}
+}
+
+// serviceVIPKeys returns the keys of all the load balancer VIPs of a service:
+// its cluster IPs, external IPs, load balancer ingress IPs and node ports
+func serviceVIPKeys(svc *v1.Service) []ServiceVIPKey {
+	ips := []string{}
 	if util.ServiceTypeHasClusterIP(svc) && util.IsClusterIPSet(svc) {
-		for _, ip := range util.GetClusterIPs(svc) {
-			for _, svcPort := range svc.Spec.Ports {
-				vip := util.JoinHostPortInt32(ip, svcPort.Port)
-				uc.DeleteServiceVIPToName(vip, svcPort.Protocol)
-			}
+		ips = append(ips, util.GetClusterIPs(svc)...)
+	}
+	ips = append(ips, svc.Spec.ExternalIPs...)
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			ips = append(ips, ingress.IP)
+		}
+	}
+
+	keys := []ServiceVIPKey{}
+	for _, svcPort := range svc.Spec.Ports {
+		for _, ip := range ips {
+			keys = append(keys, ServiceVIPKey{util.JoinHostPortInt32(ip, svcPort.Port), svcPort.Protocol})
+		}
+		// the node port is on every node IP, and is unique in the cluster
+		if util.ServiceTypeHasNodePort(svc) && svcPort.NodePort != 0 {
+			keys = append(keys, ServiceVIPKey{util.JoinHostPortInt32("", svcPort.NodePort), svcPort.Protocol})
 		}
 	}
+	return keys
 }
 
 // ServiceVIPKey is used for looking up service namespace information for a
 // particular load balancer
 type ServiceVIPKey struct {
-	// Load balancer VIP in the form "ip:port"
+	// Load balancer VIP in the form "ip:port", or ":port" for a node port
 	vip string
 	// Protocol used by the load balancer
 	protocol v1.Protocol
@@ -101,11 +123,20 @@ func (uc *unidlingController) AddServiceVIPToName(vip string, protocol v1.Protoc
 	uc.serviceVIPToName[ServiceVIPKey{vip, protocol}] = types.NamespacedName{Namespace: namespace, Name: name}
 }
 
-// GetServiceVIPToName retrieves the associated k8s service name for a load balancer VIP
+// GetServiceVIPToName retrieves the associated k8s service name for a load balancer VIP.
+// A VIP of no other service is looked up as a node port of any node IP.
 func (uc *unidlingController) GetServiceVIPToName(vip string, protocol v1.Protocol) (types.NamespacedName, bool) {
 	uc.serviceVIPToNameLock.Lock()
 	defer uc.serviceVIPToNameLock.Unlock()
 	namespace, ok := uc.serviceVIPToName[ServiceVIPKey{vip, protocol}]
+	if ok {
+		return namespace, ok
+	}
+	_, port, err := util.SplitHostPortInt32(vip)
+	if err != nil {
+		return namespace, false
+	}
+	namespace, ok = uc.serviceVIPToName[ServiceVIPKey{util.JoinHostPortInt32("", port), protocol}]
 	return namespace, ok
 }
 
@@ -140,13 +171,7 @@ func (uc *unidlingController) Run(stopCh <-chan struct{}) {
 					continue
 				}
 				if serviceName, ok := uc.GetServiceVIPToName(event.vip, event.protocol); ok {
-					serviceRef := v1.ObjectReference{
-						Kind:      "Service",
-						Namespace: serviceName.Namespace,
-						Name:      serviceName.Name,
-					}
-					klog.V(5).Infof("Sending a NeedPods event for service %s in namespace %s.", serviceName.Name, serviceName.Namespace)
-					uc.eventRecorder.Eventf(&serviceRef, v1.EventTypeNormal, "NeedPods", "The service %s needs pods", serviceName.Name)
+					uc.sendServiceEvent(serviceName, event.vip)
 				}
 			}
 		case <-stopCh:
@@ -154,3 +179,22 @@ func (uc *unidlingController) Run(stopCh <-chan struct{}) {
 		}
 	}
 }
+
+// sendServiceEvent raises the event for traffic to a service without endpoints.
+// Idled services need pods, traffic to any other service was dropped.
+func (uc *unidlingController) sendServiceEvent(serviceName types.NamespacedName, vip string) {
+	serviceRef := v1.ObjectReference{
+		Kind:      "Service",
+		Namespace: serviceName.Namespace,
+		Name:      serviceName.Name,
+	}
+	service, err := uc.serviceLister.Services(serviceName.Namespace).Get(serviceName.Name)
+	if err == nil && !svccontroller.HasIdledAnnotation(service.Annotations) {
+		klog.V(5).Infof("Sending a NoEndpoints event for service %s in namespace %s.", serviceName.Name, serviceName.Namespace)
+		uc.eventRecorder.Eventf(&serviceRef, v1.EventTypeWarning, "NoEndpoints",
+			"Traffic to %s of service %s was dropped, the service has no endpoints", vip, serviceName.Name)
+		return
+	}
+	klog.V(5).Infof("Sending a NeedPods event for service %s in namespace %s.", serviceName.Name, serviceName.Namespace)
+	uc.eventRecorder.Eventf(&serviceRef, v1.EventTypeNormal, "NeedPods", "The service %s needs pods", serviceName.Name)
+}
@@ -0,0 +1,102 @@
+package unidling
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestSendServiceEvent(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	_ = indexer.Add(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "idled",
+			Namespace:   "testns",
+			Annotations: map[string]string{"idling.alpha.openshift.io/idled-at": "2021-01-01T00:00:00Z"},
+		},
+	})
+	_ = indexer.Add(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "empty",
+			Namespace:   "testns",
+			Annotations: map[string]string{"k8s.ovn.org/service-empty-lb-action": "event"},
+		},
+	})
+
+	tests := []struct {
+		name     string
+		service  string
+		expected string
+	}{
+		{
+			name:     "idled service needs pods",
+			service:  "idled",
+			expected: "Normal NeedPods The service idled needs pods",
+		},
+		{
+			name:     "service without endpoints",
+			service:  "empty",
+			expected: "Warning NoEndpoints Traffic to 172.30.72.79:80 of service empty was dropped, the service has no endpoints",
+		},
+		{
+			name:     "unknown service needs pods",
+			service:  "deleted",
+			expected: "Normal NeedPods The service deleted needs pods",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(1)
+			uc := &unidlingController{
+				eventRecorder: recorder,
+				serviceLister: corelisters.NewServiceLister(indexer),
+			}
+			uc.sendServiceEvent(types.NamespacedName{Namespace: "testns", Name: tt.service}, "172.30.72.79:80")
+			assert.Equal(t, tt.expected, <-recorder.Events)
+		})
+	}
+}
+
+func TestServiceVIPToName(t *testing.T) {
+	uc := &unidlingController{
+		serviceVIPToName: map[ServiceVIPKey]types.NamespacedName{},
+	}
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "testns"},
+		Spec: v1.ServiceSpec{
+			Type:        v1.ServiceTypeLoadBalancer,
+			ClusterIP:   "172.30.72.79",
+			ClusterIPs:  []string{"172.30.72.79", "fd00:10:96::1"},
+			ExternalIPs: []string{"10.1.1.1"},
+			Ports: []v1.ServicePort{
+				{Protocol: v1.ProtocolTCP, Port: 80, NodePort: 30080},
+			},
+		},
+		Status: v1.ServiceStatus{
+			LoadBalancer: v1.LoadBalancerStatus{
+				Ingress: []v1.LoadBalancerIngress{{IP: "192.168.10.1"}, {Hostname: "lb.example.com"}},
+			},
+		},
+	}
+	name := types.NamespacedName{Namespace: "testns", Name: "foo"}
+
+	uc.onServiceAdd(svc)
+	for _, vip := range []string{"172.30.72.79:80", "[fd00:10:96::1]:80", "10.1.1.1:80", "192.168.10.1:80", "172.18.0.2:30080"} {
+		actual, ok := uc.GetServiceVIPToName(vip, v1.ProtocolTCP)
+		assert.True(t, ok, vip)
+		assert.Equal(t, name, actual, vip)
+	}
+	_, ok := uc.GetServiceVIPToName("172.30.72.79:80", v1.ProtocolUDP)
+	assert.False(t, ok)
+	_, ok = uc.GetServiceVIPToName("172.18.0.2:30081", v1.ProtocolTCP)
+	assert.False(t, ok)
+
+	uc.onServiceDelete(svc)
+	assert.Empty(t, uc.serviceVIPToName)
+}
@@ -0,0 +1,420 @@
+package ovn
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/nbdb"
+	libovsdbops "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/libovsdbops"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+
+	kapi "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	utilnet "k8s.io/utils/net"
+)
+
+// egressInterfaceExternalID is the external ID of the SNATs to the IP of an
+// egress interface, set to the name of the interface
+const egressInterfaceExternalID = "egress-interface"
+
+// egressInterfaceSwitchPrefix returns the prefix of the external switch, and of
+// its ports, of the named egress interface of a node
+func egressInterfaceSwitchPrefix(name string) string {
+	return types.EgressInterfaceSwitchPrefix + name + "-"
+}
+
+// activeEgressInterface returns the name of the egress interface the pods of
+// the namespace egress through, or "" if there is none. External gateways of
+// the namespace take precedence over its egress interface.
+// must be called with nsInfo lock
+func (nsInfo *namespaceInfo) activeEgressInterface() string {
+	if len(nsInfo.routingExternalGWs.gws) > 0 {
+		return ""
+	}
+	return nsInfo.egressInterface
+}
+
+// getNodeL3GatewayConfig returns the L3 gateway config of a node
+func (oc *Controller) getNodeL3GatewayConfig(nodeName string) (*util.L3GatewayConfig, error) {
+	node, err := oc.watchFactory.GetNode(nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node %s: %v", nodeName, err)
+	}
+	l3GatewayConfig, err := util.ParseNodeL3GatewayAnnotation(node)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse node L3 gw annotation: %v", err)
+	}
+	return l3GatewayConfig, nil
+}
+
+// egressInterfacesChanged returns true if the named egress interfaces of a
+// node changed
+func egressInterfacesChanged(oldNode, newNode *kapi.Node) bool {
+	var oldEgressInterfaces, egressInterfaces map[string]*util.L3GatewayEgressInterface
+	if oldL3GatewayConfig, err := util.ParseNodeL3GatewayAnnotation(oldNode); err == nil {
+		oldEgressInterfaces = oldL3GatewayConfig.EgressInterfaces
+	}
+	if l3GatewayConfig, err := util.ParseNodeL3GatewayAnnotation(newNode); err == nil {
+		egressInterfaces = l3GatewayConfig.EgressInterfaces
+	}
+	if len(oldEgressInterfaces) == 0 && len(egressInterfaces) == 0 {
+		return false
+	}
+	return !reflect.DeepEqual(oldEgressInterfaces, egressInterfaces)
+}
+
+// addEgressInterfaceRoutesForPod routes the egress traffic of a pod out of the
+// named egress interface of its node, SNATed to the IP of that interface. It
+// returns false if the node does not have the egress interface, in which case
+// the pod egresses through the default gateway interface.
+func (oc *Controller) addEgressInterfaceRoutesForPod(pod *kapi.Pod, podIfAddrs []*net.IPNet, name string) (bool, error) {
+	nodeName := pod.Spec.NodeName
+	l3GatewayConfig, err := oc.getNodeL3GatewayConfig(nodeName)
+	if err != nil {
+		return false, err
+	}
+	egressIntf := l3GatewayConfig.EgressInterfaces[name]
+	if egressIntf == nil {
+		klog.Warningf("Node %s has no egress interface %s, pod %s/%s egresses through the gateway interface",
+			nodeName, name, pod.Namespace, pod.Name)
+		return false, nil
+	}
+
+	gr := util.GetGatewayRouterFromNode(nodeName)
+	port := egressInterfaceSwitchPrefix(name) + types.GWRouterToExtSwitchPrefix + gr
+	// drop the routes the pod may have out of another egress interface first
+	oc.deleteEgressInterfaceRoutesForPod(nodeName, podIfAddrs, name)
+
+	nats := make([]*nbdb.NAT, 0, len(podIfAddrs))
+	egressIPs := make(map[string]string, len(podIfAddrs))
+	for _, podIPNet := range podIfAddrs {
+		isIPv6 := utilnet.IsIPv6(podIPNet.IP)
+		nextHops, err := util.MatchIPFamily(isIPv6, egressIntf.NextHops)
+		if err != nil {
+			klog.Warningf("Egress interface %s of node %s has no next hop for pod IP %s", name, nodeName, podIPNet.IP)
+			continue
+		}
+		egressIP, err := util.MatchIPNetFamily(isIPv6, egressIntf.IPAddresses)
+		if err != nil {
+			klog.Warningf("Egress interface %s of node %s has no IP for pod IP %s", name, nodeName, podIPNet.IP)
+			continue
+		}
+		podIP := podIPNet.IP.String()
+		mask := GetIPFullMask(podIP)
+		if err := oc.addEgressInterfaceStaticRoute(gr, port, podIP+mask, nextHops[0].String()); err != nil {
+			return false, err
+		}
+		_, fullMaskPodNet, err := net.ParseCIDR(podIP + mask)
+		if err != nil {
+			return false, fmt.Errorf("invalid IP: %s and mask: %s combination, error: %v", podIP, mask, err)
+		}
+		nats = append(nats, libovsdbops.BuildRouterSNAT(&egressIP.IP, fullMaskPodNet, "",
+			map[string]string{egressInterfaceExternalID: name}))
+		egressIPs[podIP] = egressIP.IP.String()
+	}
+
+	// a pod can only have one SNAT per IP, replace the per pod SNAT to the
+	// node IP, if any
+	gatewayIPs := make(map[string]bool, len(l3GatewayConfig.IPAddresses))
+	for _, gatewayIP := range l3GatewayConfig.IPAddresses {
+		gatewayIPs[gatewayIP.IP.String()] = true
+	}
+	staleNATs := []nbdb.NAT{}
+	ctx, cancel := context.WithTimeout(context.Background(), types.OVSDBTimeout)
+	defer cancel()
+	if err := oc.nbClient.WhereCache(func(nat *nbdb.NAT) bool {
+		_, ok := egressIPs[nat.LogicalIP]
+		return ok && nat.Type == nbdb.NATTypeSNAT && len(nat.ExternalIDs) == 0 && gatewayIPs[nat.ExternalIP]
+	}).List(ctx, &staleNATs); err != nil {
+		return false, fmt.Errorf("failed to list the SNATs of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+	}
+	if len(staleNATs) > 0 {
+		staleNATRefs := make([]*nbdb.NAT, 0, len(staleNATs))
+		for i := range staleNATs {
+			staleNATRefs = append(staleNATRefs, &staleNATs[i])
+		}
+		if err := libovsdbops.DeleteNatsFromRouter(oc.nbClient, gr, staleNATRefs...); err != nil {
+			return false, fmt.Errorf("failed to delete SNAT for pod %s/%s of router: %s, error: %v", pod.Namespace, pod.Name, gr, err)
+		}
+	}
+	if err := libovsdbops.AddOrUpdateNatsToRouter(oc.nbClient, gr, nats...); err != nil {
+		return false, fmt.Errorf("failed to update SNAT for pods of router: %s, error: %v", gr, err)
+	}
+	return true, nil
+}
+
+func (oc *Controller) addEgressInterfaceStaticRoute(gr, port, ipPrefix, nextHop string) error {
+	logicalRouter := nbdb.LogicalRouter{}
+	logicalRouterStaticRoute := nbdb.LogicalRouterStaticRoute{
+		Policy:     &nbdb.LogicalRouterStaticRoutePolicySrcIP,
+		Nexthop:    nextHop,
+		IPPrefix:   ipPrefix,
+		OutputPort: &port,
+	}
+	opModels := []libovsdbops.OperationModel{
+		{
+			Model: &logicalRouterStaticRoute,
+			ModelPredicate: func(lrsr *nbdb.LogicalRouterStaticRoute) bool {
+				return lrsr.IPPrefix == ipPrefix &&
+					lrsr.Nexthop == nextHop &&
+					lrsr.OutputPort != nil && *lrsr.OutputPort == port
+			},
+			DoAfter: func() {
+				if logicalRouterStaticRoute.UUID != "" {
+					logicalRouter.StaticRoutes = []string{logicalRouterStaticRoute.UUID}
+				}
+			},
+		},
+		{
+			Model: &logicalRouter,
+			ModelPredicate: func(lr *nbdb.LogicalRouter) bool {
+				return lr.Name == gr
+			},
+			OnModelMutations: []interface{}{
+				&logicalRouter.StaticRoutes,
+			},
+			ErrNotFound: true,
+		},
+	}
+	if _, err := oc.modelClient.CreateOrUpdate(opModels...); err != nil {
+		return fmt.Errorf("unable to add egress interface src-ip route to GR router, err: %v", err)
+	}
+	return nil
+}
+
+// deleteEgressInterfaceRoutesForPod removes the routes of a pod out of any
+// egress interface of its node but keepName, and the SNATs to the IP of those
+// interfaces
+func (oc *Controller) deleteEgressInterfaceRoutesForPod(nodeName string, podIPNets []*net.IPNet, keepName string) {
+	gr := util.GetGatewayRouterFromNode(nodeName)
+	keepPort := ""
+	if keepName != "" {
+		keepPort = egressInterfaceSwitchPrefix(keepName) + types.GWRouterToExtSwitchPrefix + gr
+	}
+	prefixes := make(map[string]bool, len(podIPNets))
+	podIPs := make(map[string]bool, len(podIPNets))
+	for _, podIPNet := range podIPNets {
+		podIP := podIPNet.IP.String()
+		prefixes[podIP+GetIPFullMask(podIP)] = true
+		podIPs[podIP] = true
+	}
+
+	logicalRouter := nbdb.LogicalRouter{}
+	logicalRouterStaticRouteRes := []nbdb.LogicalRouterStaticRoute{}
+	opModels := []libovsdbops.OperationModel{
+		{
+			ModelPredicate: func(lrsr *nbdb.LogicalRouterStaticRoute) bool {
+				return lrsr.Policy != nil && *lrsr.Policy == nbdb.LogicalRouterStaticRoutePolicySrcIP &&
+					prefixes[lrsr.IPPrefix] && lrsr.OutputPort != nil && *lrsr.OutputPort != keepPort &&
+					strings.HasPrefix(*lrsr.OutputPort, types.EgressInterfaceSwitchPrefix) &&
+					strings.HasSuffix(*lrsr.OutputPort, types.GWRouterToExtSwitchPrefix+gr)
+			},
+			ExistingResult: &logicalRouterStaticRouteRes,
+			DoAfter: func() {
+				logicalRouter.StaticRoutes = libovsdbops.ExtractUUIDsFromModels(&logicalRouterStaticRouteRes)
+			},
+			BulkOp: true,
+		},
+		{
+			Model: &logicalRouter,
+			ModelPredicate: func(lr *nbdb.LogicalRouter) bool {
+				return lr.Name == gr
+			},
+			OnModelMutations: []interface{}{
+				&logicalRouter.StaticRoutes,
+			},
+		},
+	}
+	if err := oc.modelClient.Delete(opModels...); err != nil {
+		klog.Errorf("Unable to delete egress interface src-ip routes from GR router %s, err: %v", gr, err)
+		return
+	}
+
+	staleNATs := []nbdb.NAT{}
+	ctx, cancel := context.WithTimeout(context.Background(), types.OVSDBTimeout)
+	defer cancel()
+	if err := oc.nbClient.WhereCache(func(nat *nbdb.NAT) bool {
+		name, ok := nat.ExternalIDs[egressInterfaceExternalID]
+		return ok && name != keepName && nat.Type == nbdb.NATTypeSNAT && podIPs[nat.LogicalIP]
+	}).List(ctx, &staleNATs); err != nil {
+		klog.Errorf("Unable to list egress interface SNATs of GR router %s, err: %v", gr, err)
+		return
+	}
+	if len(staleNATs) > 0 {
+		staleNATRefs := make([]*nbdb.NAT, 0, len(staleNATs))
+		for i := range staleNATs {
+			staleNATRefs = append(staleNATRefs, &staleNATs[i])
+		}
+		if err := libovsdbops.DeleteNatsFromRouter(oc.nbClient, gr, staleNATRefs...); err != nil {
+			klog.Errorf("Unable to delete egress interface SNATs from GR router %s, err: %v", gr, err)
+		}
+	}
+}
+
+// addEgressInterfaceRoutesForNamespace routes the egress traffic of all the
+// existing pods of a namespace out of the named egress interface of their nodes
+func (oc *Controller) addEgressInterfaceRoutesForNamespace(namespace, name string) error {
+	existingPods, err := oc.watchFactory.GetPods(namespace)
+	if err != nil {
+		return fmt.Errorf("failed to get all the pods (%v)", err)
+	}
+	for _, pod := range existingPods {
+		if pod.Spec.HostNetwork || !util.PodScheduled(pod) {
+			continue
+		}
+		podAnnotation, err := util.UnmarshalPodAnnotation(pod.Annotations)
+		if err != nil {
+			continue
+		}
+		if _, err := oc.addEgressInterfaceRoutesForPod(pod, podAnnotation.IPs, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteEgressInterfaceRoutesForNamespace removes the routes of all the
+// existing pods of a namespace out of egress interfaces. The pods egress
+// through the gateway interface again, with a per pod SNAT if
+// restorePerPodSNAT is true.
+func (oc *Controller) deleteEgressInterfaceRoutesForNamespace(namespace string, restorePerPodSNAT bool) {
+	existingPods, err := oc.watchFactory.GetPods(namespace)
+	if err != nil {
+		klog.Errorf("Failed to get all the pods (%v)", err)
+		return
+	}
+	for _, pod := range existingPods {
+		if pod.Spec.HostNetwork || !util.PodScheduled(pod) {
+			continue
+		}
+		podAnnotation, err := util.UnmarshalPodAnnotation(pod.Annotations)
+		if err != nil {
+			continue
+		}
+		oc.deleteEgressInterfaceRoutesForPod(pod.Spec.NodeName, podAnnotation.IPs, "")
+		if restorePerPodSNAT {
+			if err := oc.addPerPodGRSNAT(pod, podAnnotation.IPs); err != nil {
+				klog.Error(err.Error())
+			}
+		}
+	}
+}
+
+// syncEgressInterfacePodsOnNode routes the pods of a node, in the namespaces
+// with an egress interface, out of that interface if the node has it, or back
+// out of the gateway interface if it does not
+func (oc *Controller) syncEgressInterfacePodsOnNode(nodeName string) {
+	namespaces, err := oc.watchFactory.GetNamespaces()
+	if err != nil {
+		klog.Errorf("Failed to get all the namespaces (%v)", err)
+		return
+	}
+	for _, ns := range namespaces {
+		if ns.Annotations[egressInterfaceAnnotation] == "" {
+			continue
+		}
+		if err := oc.syncNamespaceEgressInterfacePodsOnNode(ns.Name, nodeName); err != nil {
+			klog.Errorf("Failed to route the pods of namespace %s on node %s out of their egress interface: %v",
+				ns.Name, nodeName, err)
+		}
+	}
+}
+
+func (oc *Controller) syncNamespaceEgressInterfacePodsOnNode(namespace, nodeName string) error {
+	nsInfo, nsUnlock := oc.getNamespaceLocked(namespace, true)
+	if nsInfo == nil {
+		return nil
+	}
+	defer nsUnlock()
+	name := nsInfo.activeEgressInterface()
+	if name == "" {
+		return nil
+	}
+	existingPods, err := oc.watchFactory.GetPods(namespace)
+	if err != nil {
+		return fmt.Errorf("failed to get all the pods (%v)", err)
+	}
+	for _, pod := range existingPods {
+		if pod.Spec.HostNetwork || pod.Spec.NodeName != nodeName {
+			continue
+		}
+		podAnnotation, err := util.UnmarshalPodAnnotation(pod.Annotations)
+		if err != nil {
+			continue
+		}
+		routed, err := oc.addEgressInterfaceRoutesForPod(pod, podAnnotation.IPs, name)
+		if err != nil {
+			return err
+		}
+		if routed {
+			continue
+		}
+		oc.deleteEgressInterfaceRoutesForPod(nodeName, podAnnotation.IPs, "")
+		if config.Gateway.DisableSNATMultipleGWs && len(nsInfo.routingExternalPodGWs) == 0 && !nsInfo.routedEgress {
+			if err := oc.addPerPodGRSNAT(pod, podAnnotation.IPs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deleteStaleEgressInterfaceSwitches removes the external switches, and their
+// gateway router ports, of the egress interfaces a node no longer has
+func (oc *Controller) deleteStaleEgressInterfaceSwitches(nodeName string, egressInterfaces map[string]*util.L3GatewayEgressInterface) error {
+	gatewayRouter := types.GWRouterPrefix + nodeName
+	// isStale returns true if name is the name of the object with suffix of
+	// an egress interface the node does not have
+	isStale := func(name, suffix string) bool {
+		suffix = "-" + suffix
+		if !strings.HasPrefix(name, types.EgressInterfaceSwitchPrefix) || !strings.HasSuffix(name, suffix) {
+			return false
+		}
+		_, ok := egressInterfaces[strings.TrimSuffix(strings.TrimPrefix(name, types.EgressInterfaceSwitchPrefix), suffix)]
+		return !ok
+	}
+
+	logicalRouter := nbdb.LogicalRouter{}
+	logicalRouterPortRes := []nbdb.LogicalRouterPort{}
+	opModels := []libovsdbops.OperationModel{
+		{
+			ModelPredicate: func(lrp *nbdb.LogicalRouterPort) bool {
+				return isStale(lrp.Name, types.GWRouterToExtSwitchPrefix+gatewayRouter)
+			},
+			ExistingResult: &logicalRouterPortRes,
+			DoAfter: func() {
+				logicalRouter.Ports = libovsdbops.ExtractUUIDsFromModels(&logicalRouterPortRes)
+			},
+			BulkOp: true,
+		},
+		{
+			Model: &logicalRouter,
+			ModelPredicate: func(lr *nbdb.LogicalRouter) bool {
+				return lr.Name == gatewayRouter
+			},
+			OnModelMutations: []interface{}{
+				&logicalRouter.Ports,
+			},
+		},
+	}
+	if err := oc.modelClient.Delete(opModels...); err != nil {
+		return fmt.Errorf("failed to delete the stale egress interface ports of router %s, error: %v", gatewayRouter, err)
+	}
+
+	opModel := libovsdbops.OperationModel{
+		ModelPredicate: func(ls *nbdb.LogicalSwitch) bool {
+			return isStale(ls.Name, types.ExternalSwitchPrefix+nodeName)
+		},
+		ExistingResult: &[]nbdb.LogicalSwitch{},
+		BulkOp:         true,
+	}
+	if err := oc.modelClient.Delete(opModel); err != nil {
+		return fmt.Errorf("failed to delete the stale egress interface external switches of node %s, error: %v", nodeName, err)
+	}
+	return nil
+}
@@ -0,0 +1,354 @@
+package ovn
+
+import (
+	"context"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/nbdb"
+	libovsdbops "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/libovsdbops"
+	libovsdbtest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing/libovsdb"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+
+	"github.com/urfave/cli/v2"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("OVN Egress Interface Operations", func() {
+	var (
+		app     *cli.App
+		fakeOvn *FakeOVN
+
+		egressRouterPort = "egress-compliance-rtoe-GR_node1"
+	)
+
+	const l3GatewayConfig = `{"default":{"mode":"shared","mac-address":"7e:57:f8:f0:3c:49","ip-address":"169.254.33.2/24","next-hop":"169.254.33.1",` +
+		`"egress-interfaces":{"compliance":{"interface-id":"breth2_node1","mac-address":"7e:57:f8:f0:3c:50","ip-addresses":["192.168.20.10/24"],"next-hops":["192.168.20.1"]}}}}`
+
+	ginkgo.BeforeEach(func() {
+		// Restore global default values before each testcase
+		config.PrepareTestConfig()
+
+		app = cli.NewApp()
+		app.Name = "test"
+		app.Flags = config.Flags
+
+		fakeOvn = NewFakeOVN(nil)
+	})
+
+	ginkgo.AfterEach(func() {
+		fakeOvn.shutdown()
+	})
+
+	podLSP := func() *nbdb.LogicalSwitchPort {
+		return &nbdb.LogicalSwitchPort{
+			UUID:      "lsp1",
+			Addresses: []string{"0a:58:0a:80:01:03 10.128.1.3"},
+			ExternalIDs: map[string]string{
+				"pod":       "true",
+				"namespace": "namespace1",
+			},
+			Name: "namespace1_myPod",
+			Options: map[string]string{
+				"iface-id-ver":      "myPod",
+				"requested-chassis": "node1",
+			},
+			PortSecurity: []string{"0a:58:0a:80:01:03 10.128.1.3"},
+		}
+	}
+
+	// startWithPod starts the controller with a pod in a namespace annotated
+	// with egressInterface, on a node with l3GatewayAnnotation
+	startWithPod := func(ctx *cli.Context, egressInterface, l3GatewayAnnotation string) (v1.Namespace, testPod) {
+		namespaceT := *newNamespace("namespace1")
+		namespaceT.Annotations = map[string]string{"k8s.ovn.org/egress-interface": egressInterface}
+		t := newTPod(
+			"node1",
+			"10.128.1.0/24",
+			"10.128.1.2",
+			"10.128.1.1",
+			"myPod",
+			"10.128.1.3",
+			"0a:58:0a:80:01:03",
+			namespaceT.Name,
+		)
+
+		fakeOvn.startWithDBSetup(ctx,
+			libovsdbtest.TestSetup{
+				NBData: []libovsdbtest.TestData{
+					&nbdb.LogicalSwitch{
+						UUID: "node1",
+						Name: "node1",
+					},
+					&nbdb.LogicalRouter{
+						UUID: "GR_node1-UUID",
+						Name: "GR_node1",
+					},
+				},
+			},
+			&v1.NamespaceList{
+				Items: []v1.Namespace{
+					namespaceT,
+				},
+			},
+			&v1.PodList{
+				Items: []v1.Pod{
+					*newPod(t.namespace, t.podName, t.nodeName, t.podIP),
+				},
+			},
+		)
+		t.populateLogicalSwitchCache(fakeOvn)
+
+		node := &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "node1",
+				Annotations: map[string]string{
+					"k8s.ovn.org/l3-gateway-config": l3GatewayAnnotation,
+					"k8s.ovn.org/node-chassis-id":   "79fdcfc4-6fe6-4cd3-8242-c0f85a4668ec",
+				},
+			},
+		}
+		err := fakeOvn.controller.watchFactory.NodeInformer().GetStore().Add(node)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		fakeOvn.controller.WatchNamespaces()
+		fakeOvn.controller.WatchPods()
+
+		gomega.Eventually(func() string { return getPodAnnotations(fakeOvn.fakeClient.KubeClient, t.namespace, t.podName) }, 2).Should(gomega.MatchJSON(`{"default": {"ip_addresses":["` + t.podIP + `/24"], "mac_address":"` + t.podMAC + `", "gateway_ips": ["` + t.nodeGWIP + `"], "ip_address":"` + t.podIP + `/24", "gateway_ip": "` + t.nodeGWIP + `"}}`))
+		return namespaceT, t
+	}
+
+	egressInterfaceNB := []libovsdbtest.TestData{
+		podLSP(),
+		&nbdb.LogicalSwitch{
+			UUID:  "node1",
+			Name:  "node1",
+			Ports: []string{"lsp1"},
+		},
+		&nbdb.LogicalRouterStaticRoute{
+			UUID:       "static-route-1-UUID",
+			IPPrefix:   "10.128.1.3/32",
+			Nexthop:    "192.168.20.1",
+			Policy:     &nbdb.LogicalRouterStaticRoutePolicySrcIP,
+			OutputPort: &egressRouterPort,
+		},
+		&nbdb.NAT{
+			UUID:        "nat-1-UUID",
+			Type:        nbdb.NATTypeSNAT,
+			ExternalIP:  "192.168.20.10",
+			LogicalIP:   "10.128.1.3",
+			Options:     map[string]string{"stateless": "false"},
+			ExternalIDs: map[string]string{"egress-interface": "compliance"},
+		},
+		&nbdb.LogicalRouter{
+			UUID:         "GR_node1-UUID",
+			Name:         "GR_node1",
+			StaticRoutes: []string{"static-route-1-UUID"},
+			Nat:          []string{"nat-1-UUID"},
+		},
+	}
+
+	// updateNode replaces the L3 gateway annotation of the node and syncs its
+	// pods, as the node handler does once the gateway of the node is synced
+	updateNode := func(l3GatewayAnnotation string) {
+		node, err := fakeOvn.controller.watchFactory.GetNode("node1")
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		node = node.DeepCopy()
+		node.Annotations["k8s.ovn.org/l3-gateway-config"] = l3GatewayAnnotation
+		err = fakeOvn.controller.watchFactory.NodeInformer().GetStore().Update(node)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		fakeOvn.controller.syncEgressInterfacePodsOnNode("node1")
+	}
+
+	gatewayInterfaceNB := []libovsdbtest.TestData{
+		podLSP(),
+		&nbdb.LogicalSwitch{
+			UUID:  "node1",
+			Name:  "node1",
+			Ports: []string{"lsp1"},
+		},
+		&nbdb.LogicalRouter{
+			UUID: "GR_node1-UUID",
+			Name: "GR_node1",
+		},
+	}
+
+	ginkgo.Context("on setting the namespace egress interface annotation", func() {
+
+		ginkgo.It("routes a new pod out of the egress interface of its node", func() {
+			app.Action = func(ctx *cli.Context) error {
+				startWithPod(ctx, "compliance", l3GatewayConfig)
+				gomega.Eventually(fakeOvn.nbClient).Should(libovsdbtest.HaveData(egressInterfaceNB))
+				return nil
+			}
+
+			err := app.Run([]string{app.Name})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		})
+
+		ginkgo.It("keeps the pod on the gateway interface of a node without the egress interface", func() {
+			app.Action = func(ctx *cli.Context) error {
+				startWithPod(ctx, "compliance", `{"default":{"mode":"shared","mac-address":"7e:57:f8:f0:3c:49","ip-address":"169.254.33.2/24","next-hop":"169.254.33.1"}}`)
+				gomega.Eventually(fakeOvn.nbClient).Should(libovsdbtest.HaveData(gatewayInterfaceNB))
+				return nil
+			}
+
+			err := app.Run([]string{app.Name})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		})
+
+		ginkgo.It("routes the existing pods back out of the gateway interface when the annotation is removed", func() {
+			app.Action = func(ctx *cli.Context) error {
+				namespaceT, _ := startWithPod(ctx, "compliance", l3GatewayConfig)
+				gomega.Eventually(fakeOvn.nbClient).Should(libovsdbtest.HaveData(egressInterfaceNB))
+
+				namespaceT.Annotations = nil
+				_, err := fakeOvn.fakeClient.KubeClient.CoreV1().Namespaces().Update(context.Background(), &namespaceT, metav1.UpdateOptions{})
+				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+				gomega.Eventually(fakeOvn.nbClient).Should(libovsdbtest.HaveData(gatewayInterfaceNB))
+				return nil
+			}
+
+			err := app.Run([]string{app.Name})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		})
+	})
+
+	ginkgo.Context("on updating the egress interfaces of a node", func() {
+
+		ginkgo.It("routes the existing pods out of an egress interface added to their node", func() {
+			app.Action = func(ctx *cli.Context) error {
+				startWithPod(ctx, "compliance", `{"default":{"mode":"shared","mac-address":"7e:57:f8:f0:3c:49","ip-address":"169.254.33.2/24","next-hop":"169.254.33.1"}}`)
+				gomega.Eventually(fakeOvn.nbClient).Should(libovsdbtest.HaveData(gatewayInterfaceNB))
+
+				updateNode(l3GatewayConfig)
+				gomega.Eventually(fakeOvn.nbClient).Should(libovsdbtest.HaveData(egressInterfaceNB))
+				return nil
+			}
+
+			err := app.Run([]string{app.Name})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		})
+
+		ginkgo.It("routes the existing pods back out of the gateway interface when their node loses the egress interface", func() {
+			app.Action = func(ctx *cli.Context) error {
+				config.Gateway.DisableSNATMultipleGWs = true
+				startWithPod(ctx, "compliance", l3GatewayConfig)
+				gomega.Eventually(fakeOvn.nbClient).Should(libovsdbtest.HaveData(egressInterfaceNB))
+
+				updateNode(`{"default":{"mode":"shared","mac-address":"7e:57:f8:f0:3c:49","ip-address":"169.254.33.2/24","next-hop":"169.254.33.1"}}`)
+				gomega.Eventually(fakeOvn.nbClient).Should(libovsdbtest.HaveData([]libovsdbtest.TestData{
+					podLSP(),
+					&nbdb.LogicalSwitch{
+						UUID:  "node1",
+						Name:  "node1",
+						Ports: []string{"lsp1"},
+					},
+					&nbdb.NAT{
+						UUID:       "nat-1-UUID",
+						Type:       nbdb.NATTypeSNAT,
+						ExternalIP: "169.254.33.2",
+						LogicalIP:  "10.128.1.3",
+						Options:    map[string]string{"stateless": "false"},
+					},
+					&nbdb.LogicalRouter{
+						UUID: "GR_node1-UUID",
+						Name: "GR_node1",
+						Nat:  []string{"nat-1-UUID"},
+					},
+				}))
+				return nil
+			}
+
+			err := app.Run([]string{app.Name})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		})
+
+		ginkgo.It("only replaces the per pod SNAT to the node IP", func() {
+			app.Action = func(ctx *cli.Context) error {
+				config.Gateway.DisableSNATMultipleGWs = true
+				startWithPod(ctx, "compliance", `{"default":{"mode":"shared","mac-address":"7e:57:f8:f0:3c:49","ip-address":"169.254.33.2/24","next-hop":"169.254.33.1"}}`)
+				egressIPNAT := &nbdb.NAT{
+					UUID:        "egressip-nat-UUID",
+					Type:        nbdb.NATTypeSNAT,
+					ExternalIP:  "192.168.126.101",
+					LogicalIP:   "10.128.1.3",
+					LogicalPort: &[]string{"k8s-node2"}[0],
+					ExternalIDs: map[string]string{"name": "egressip"},
+				}
+				gomega.Expect(libovsdbops.AddOrUpdateNatsToRouter(fakeOvn.controller.nbClient, "GR_node1", egressIPNAT)).To(gomega.Succeed())
+
+				updateNode(l3GatewayConfig)
+				gomega.Eventually(func() []string {
+					nats := []nbdb.NAT{}
+					err := fakeOvn.controller.nbClient.List(context.Background(), &nats)
+					gomega.Expect(err).NotTo(gomega.HaveOccurred())
+					externalIPs := []string{}
+					for _, nat := range nats {
+						externalIPs = append(externalIPs, nat.ExternalIP)
+					}
+					return externalIPs
+				}).Should(gomega.ConsistOf("192.168.126.101", "192.168.20.10"))
+				return nil
+			}
+
+			err := app.Run([]string{app.Name})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		})
+
+		ginkgo.It("removes the external switches of the egress interfaces a node no longer has", func() {
+			app.Action = func(ctx *cli.Context) error {
+				complianceRouterPort := &nbdb.LogicalRouterPort{
+					UUID: "compliance-lrp-UUID",
+					Name: "egress-compliance-rtoe-GR_node1",
+				}
+				complianceSwitch := &nbdb.LogicalSwitch{
+					UUID: "compliance-ls-UUID",
+					Name: "egress-compliance-ext_node1",
+				}
+				fakeOvn.startWithDBSetup(ctx,
+					libovsdbtest.TestSetup{
+						NBData: []libovsdbtest.TestData{
+							complianceRouterPort,
+							&nbdb.LogicalRouterPort{
+								UUID: "old-lrp-UUID",
+								Name: "egress-old-rtoe-GR_node1",
+							},
+							&nbdb.LogicalRouter{
+								UUID:  "GR_node1-UUID",
+								Name:  "GR_node1",
+								Ports: []string{"compliance-lrp-UUID", "old-lrp-UUID"},
+							},
+							complianceSwitch,
+							&nbdb.LogicalSwitch{
+								UUID: "old-ls-UUID",
+								Name: "egress-old-ext_node1",
+							},
+						},
+					},
+				)
+
+				err := fakeOvn.controller.deleteStaleEgressInterfaceSwitches("node1", map[string]*util.L3GatewayEgressInterface{
+					"compliance": {},
+				})
+				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+				gomega.Eventually(fakeOvn.nbClient).Should(libovsdbtest.HaveData([]libovsdbtest.TestData{
+					complianceRouterPort,
+					&nbdb.LogicalRouter{
+						UUID:  "GR_node1-UUID",
+						Name:  "GR_node1",
+						Ports: []string{"compliance-lrp-UUID"},
+					},
+					complianceSwitch,
+				}))
+				return nil
+			}
+
+			err := app.Run([]string{app.Name})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		})
+	})
+})
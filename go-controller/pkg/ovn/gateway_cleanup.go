@@ -94,6 +94,19 @@ func (oc *Controller) gatewayCleanup(nodeName string) error {
 		return fmt.Errorf("failed to delete external switch %s, error: %v", exGWexternalSwitch, err)
 	}
 
+	// Remove the external switches of the named egress interfaces
+	opModel = libovsdbops.OperationModel{
+		ModelPredicate: func(ls *nbdb.LogicalSwitch) bool {
+			return strings.HasPrefix(ls.Name, types.EgressInterfaceSwitchPrefix) &&
+				strings.HasSuffix(ls.Name, "-"+types.ExternalSwitchPrefix+nodeName)
+		},
+		ExistingResult: &[]nbdb.LogicalSwitch{},
+		BulkOp:         true,
+	}
+	if err := oc.modelClient.Delete(opModel); err != nil {
+		return fmt.Errorf("failed to delete the egress interface external switches of node %s, error: %v", nodeName, err)
+	}
+
 	// We don't know the gateway mode as this is running in the master, try to delete the additional local
 	// gateway for the shared gateway mode. it will be no op if this is done for other gateway modes.
 	oc.delPbrAndNatRules(nodeName, nil)
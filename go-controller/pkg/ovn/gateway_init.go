@@ -61,14 +61,20 @@ func (oc *Controller) gatewayInit(nodeName string, clusterIPSubnet []*net.IPNet,
 		Options:     logicalRouterOptions,
 		ExternalIDs: logicalRouterExternalIDs,
 	}
+	logicalRouterUpdates := []interface{}{&logicalRouter.Options, &logicalRouter.ExternalIDs}
+	// In shared gateway mode the cluster-wide service load balancers are on the gateway router too
+	if oc.clusterRouterLBGroupUUID != "" {
+		logicalRouter.LoadBalancerGroup = []string{}
+		if config.Gateway.Mode == config.GatewayModeShared {
+			logicalRouter.LoadBalancerGroup = []string{oc.clusterRouterLBGroupUUID}
+		}
+		logicalRouterUpdates = append(logicalRouterUpdates, &logicalRouter.LoadBalancerGroup)
+	}
 	opModels := []libovsdbops.OperationModel{
 		{
 			Model:          &logicalRouter,
 			ModelPredicate: func(lr *nbdb.LogicalRouter) bool { return lr.Name == gatewayRouter },
-			OnModelUpdates: []interface{}{
-				&logicalRouter.Options,
-				&logicalRouter.ExternalIDs,
-			},
+			OnModelUpdates: logicalRouterUpdates,
 		},
 	}
 
@@ -224,6 +230,22 @@ func (oc *Controller) gatewayInit(nodeName string, clusterIPSubnet []*net.IPNet,
 		}
 	}
 
+	for name, egressIntf := range l3GatewayConfig.EgressInterfaces {
+		if err := oc.addExternalSwitch(egressInterfaceSwitchPrefix(name),
+			egressIntf.InterfaceID,
+			nodeName,
+			gatewayRouter,
+			egressIntf.MACAddress.String(),
+			types.PhysicalNetworkEgressPrefix+name,
+			egressIntf.IPAddresses,
+			nil); err != nil {
+			return err
+		}
+	}
+	if err := oc.deleteStaleEgressInterfaceSwitches(nodeName, l3GatewayConfig.EgressInterfaces); err != nil {
+		return err
+	}
+
 	externalRouterPort := types.GWRouterToExtSwitchPrefix + gatewayRouter
 
 	// Add static routes in GR with gateway router as the default next hop.
@@ -656,7 +678,7 @@ func (oc *Controller) addExternalSwitch(prefix, interfaceID, nodeName, gatewayRo
 	// Connect GR to external_switch with mac address of external interface
 	// and that IP address. In the case of `local` gateway mode, whenever ovnkube-node container
 	// restarts a new br-local bridge will be created with a new `nicMacAddress`.
-	externalRouterPort := types.GWRouterToExtSwitchPrefix + gatewayRouter
+	externalRouterPort := prefix + types.GWRouterToExtSwitchPrefix + gatewayRouter
 
 	externalRouterPortNetworks := []string{}
 	for _, ip := range ipAddresses {
@@ -697,7 +719,7 @@ func (oc *Controller) addExternalSwitch(prefix, interfaceID, nodeName, gatewayRo
 	}
 
 	// Connect the external_switch to the router.
-	externalSwitchPortToRouter := types.EXTSwitchToGWRouterPrefix + gatewayRouter
+	externalSwitchPortToRouter := prefix + types.EXTSwitchToGWRouterPrefix + gatewayRouter
 
 	externalLogicalSwitchPortToRouter := nbdb.LogicalSwitchPort{
 		Name: externalSwitchPortToRouter,
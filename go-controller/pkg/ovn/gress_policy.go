@@ -32,6 +32,16 @@ type gressPolicy struct {
 	// IP addresess.
 	peerAddressSet addressset.AddressSet
 
+	// sharedPeerAddressSets holds the shared address sets referenced by this
+	// gress policy when EnableSharedPeerAddressSets is set, in place of
+	// peerAddressSet.
+	sharedPeerAddressSets []*sharedPeerAddressSet
+
+	// hostNetworkPeerPods holds, for each node, the host network pods matched
+	// by the peer selectors when EnableHostNetworkPolicy is set. The host
+	// network address set of a node is referenced in place of the pods' IPs.
+	hostNetworkPeerPods map[string]sets.String
+
 	// peerV4AddressSets has Address sets for all namespaces and pod selectors for IPv4
 	peerV4AddressSets sets.String
 	// peerV6AddressSets has Address sets for all namespaces and pod selectors for IPv6
@@ -71,6 +81,21 @@ func (pp *portPolicy) getL4Match() (string, error) {
 	return foundProtocol, nil
 }
 
+// matchesPort returns whether traffic of the given protocol to the given
+// destination port is matched by the port policy, following the same rules
+// getL4Match uses to build the L4 match of the ACL
+func (pp *portPolicy) matchesPort(protocol string, port int32) bool {
+	if _, err := pp.getL4Match(); err != nil || pp.protocol != protocol {
+		return false
+	}
+	if pp.endPort != 0 && pp.endPort != pp.port {
+		return pp.port <= port && port <= pp.endPort
+	} else if pp.port != 0 {
+		return pp.port == port
+	}
+	return true
+}
+
 func newGressPolicy(policyType knet.PolicyType, idx int, namespace, name string) *gressPolicy {
 	return &gressPolicy{
 		policyNamespace:   namespace,
@@ -418,6 +443,29 @@ func constructIPBlockStringsForACL(direction string, ipBlocks []*knet.IPBlock, l
 	return matchStrings
 }
 
+// ipBlockMatches returns whether the IP is matched by one of the gress policy's
+// IP blocks, following the same rules as constructIPBlockStringsForACL
+func (gp *gressPolicy) ipBlockMatches(ip net.IP) bool {
+	for _, ipBlock := range gp.ipBlock {
+		_, cidr, err := net.ParseCIDR(ipBlock.CIDR)
+		if err != nil || !cidr.Contains(ip) {
+			continue
+		}
+		excluded := false
+		for _, except := range ipBlock.Except {
+			_, exceptCIDR, err := net.ParseCIDR(except)
+			if err == nil && exceptCIDR.Contains(ip) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			return true
+		}
+	}
+	return false
+}
+
 func (gp *gressPolicy) destroy() error {
 	if gp.peerAddressSet != nil {
 		if err := gp.peerAddressSet.Destroy(); err != nil {
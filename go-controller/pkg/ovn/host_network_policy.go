@@ -0,0 +1,202 @@
+package ovn
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	goovn "github.com/ebay/go-ovn"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/libovsdbops"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+
+	kapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	utilnet "k8s.io/utils/net"
+)
+
+// hostNetworkAddressSetPrefix is the first component of the name of the host
+// network address set of every node. It contains an underscore so that it can
+// never clash with a namespace name when address sets are processed by
+// ProcessEachAddressSet.
+const hostNetworkAddressSetPrefix = "_host"
+
+// getHostNetworkAddressSetName returns the name of the address set holding the
+// host network addresses of a node
+func getHostNetworkAddressSetName(nodeName string) string {
+	return fmt.Sprintf("%s.%s", hostNetworkAddressSetPrefix, nodeName)
+}
+
+// getNodeHostNetworkIPs returns all the addresses traffic from the host network
+// of a node may be sourced from when it reaches a pod: the management port IPs,
+// the join switch IPs of the gateway router, which SNATs host network traffic
+// in shared gateway mode, and the host addresses published by ovnkube-node.
+func (oc *Controller) getNodeHostNetworkIPs(node *kapi.Node, hostSubnets []*net.IPNet) ([]net.IP, error) {
+	ips := make([]net.IP, 0, len(hostSubnets))
+	for _, hostSubnet := range hostSubnets {
+		ips = append(ips, util.GetNodeManagementIfAddr(hostSubnet).IP)
+	}
+
+	lrpIPs, err := oc.joinSwIPManager.EnsureJoinLRPIPs(node.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get join switch port IP address for node %s: %v", node.Name, err)
+	}
+	for _, lrpIP := range lrpIPs {
+		ips = append(ips, lrpIP.IP)
+	}
+
+	hostAddrs, err := util.ParseNodeHostAddresses(node)
+	if err != nil && !util.IsAnnotationNotSetError(err) {
+		return nil, fmt.Errorf("failed to get host addresses for node %s: %v", node.Name, err)
+	}
+	for _, hostAddr := range hostAddrs.List() {
+		ip := net.ParseIP(hostAddr)
+		if ip == nil {
+			klog.Warningf("Failed to parse host address %q of node %s", hostAddr, node.Name)
+			continue
+		}
+		if (utilnet.IsIPv6(ip) && !config.IPv6Mode) || (!utilnet.IsIPv6(ip) && !config.IPv4Mode) {
+			continue
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+// syncNodeHostNetworkAddressSet ensures the host network address set of a node
+// holds the node's current host network addresses
+func (oc *Controller) syncNodeHostNetworkAddressSet(node *kapi.Node, hostSubnets []*net.IPNet) error {
+	if hostSubnets == nil {
+		var err error
+		hostSubnets, err = util.ParseNodeHostSubnetAnnotation(node)
+		if err != nil {
+			return err
+		}
+	}
+
+	ips, err := oc.getNodeHostNetworkIPs(node, hostSubnets)
+	if err != nil {
+		return err
+	}
+	as, err := oc.addressSetFactory.EnsureAddressSet(getHostNetworkAddressSetName(node.Name))
+	if err != nil {
+		return fmt.Errorf("failed to ensure host network address set for node %s: %v", node.Name, err)
+	}
+	if err := as.SetIPs(ips); err != nil {
+		return fmt.Errorf("failed to set host network addresses for node %s: %v", node.Name, err)
+	}
+	return nil
+}
+
+// deleteNodeHostNetworkAddressSet removes the host network address set of a node
+func (oc *Controller) deleteNodeHostNetworkAddressSet(nodeName string) error {
+	return oc.addressSetFactory.DestroyAddressSetInBackingStore(getHostNetworkAddressSetName(nodeName))
+}
+
+// syncHostNetworkAddressSets removes the host network address sets of nodes
+// that no longer exist, or of all nodes when the feature is disabled
+func (oc *Controller) syncHostNetworkAddressSets(foundNodes map[string]*kapi.Node) {
+	stale := []string{}
+	err := oc.addressSetFactory.ProcessEachAddressSet(func(addrSetName, namespaceName, nameSuffix string) {
+		if namespaceName != hostNetworkAddressSetPrefix {
+			return
+		}
+		// node names may contain dots, so don't rely on nameSuffix
+		nodeName := strings.TrimPrefix(addrSetName, hostNetworkAddressSetPrefix+".")
+		if _, ok := foundNodes[nodeName]; !ok || !config.OVNKubernetesFeature.EnableHostNetworkPolicy {
+			stale = append(stale, addrSetName)
+		}
+	})
+	if err != nil {
+		klog.Errorf("Error in syncing host network address sets: %v", err)
+		return
+	}
+	for _, addrSetName := range stale {
+		if err := oc.addressSetFactory.DestroyAddressSetInBackingStore(addrSetName); err != nil {
+			klog.Errorf(err.Error())
+		}
+	}
+}
+
+// removeAllowACLFromNode removes the ACL added by addAllowACLFromNode
+func removeAllowACLFromNode(logicalSwitch string, mgmtPortIP net.IP, ovnNBClient goovn.Client) error {
+	ipFamily := "ip4"
+	if utilnet.IsIPv6(mgmtPortIP) {
+		ipFamily = "ip6"
+	}
+	match := fmt.Sprintf("%s.src==%s", ipFamily, mgmtPortIP.String())
+
+	// TODO use libovsdb client once logical switch libovsdb work is done
+	aclcmd, err := ovnNBClient.ACLDel(logicalSwitch, types.DirectionToLPort, match, types.DefaultAllowPriority, nil)
+	if err == nil {
+		if err = ovnNBClient.Execute(aclcmd); err != nil {
+			return fmt.Errorf("failed to remove the node acl for logical_switch: %s, %v", logicalSwitch, err)
+		}
+	} else if err != goovn.ErrorNotFound {
+		return fmt.Errorf("ACLDel() error when removing node acl for logical switch: %s, %v", logicalSwitch, err)
+	}
+
+	return nil
+}
+
+// addHostNetworkPeerPod makes the gress policy reference the host network
+// address set of the node of a host network peer pod, and returns whether the
+// ACLs of the gress policy need to be updated
+func (gp *gressPolicy) addHostNetworkPeerPod(pod *kapi.Pod) bool {
+	if gp.hostNetworkPeerPods == nil {
+		gp.hostNetworkPeerPods = make(map[string]sets.String)
+	}
+	pods, ok := gp.hostNetworkPeerPods[pod.Spec.NodeName]
+	if !ok {
+		pods = sets.NewString()
+		gp.hostNetworkPeerPods[pod.Spec.NodeName] = pods
+	}
+	pods.Insert(getPodKey(pod))
+	return gp.addNamespaceAddressSet(getHostNetworkAddressSetName(pod.Spec.NodeName))
+}
+
+// delHostNetworkPeerPod stops referencing the host network address set of the
+// node of a host network peer pod once no other peer pod runs on that node, and
+// returns whether the ACLs of the gress policy need to be updated
+func (gp *gressPolicy) delHostNetworkPeerPod(pod *kapi.Pod) bool {
+	pods, ok := gp.hostNetworkPeerPods[pod.Spec.NodeName]
+	if !ok {
+		return false
+	}
+	pods.Delete(getPodKey(pod))
+	if pods.Len() > 0 {
+		return false
+	}
+	delete(gp.hostNetworkPeerPods, pod.Spec.NodeName)
+	return gp.delNamespaceAddressSet(getHostNetworkAddressSetName(pod.Spec.NodeName))
+}
+
+// handlePeerHostNetworkPods updates the gress policy for host network peer
+// pods. The ACLs are only updated once the policy has been created, since
+// createNetworkPolicy builds them from the gress policy in the first place.
+func (oc *Controller) handlePeerHostNetworkPods(np *networkPolicy, gp *gressPolicy, doUpdate func() bool) {
+	aclLoggingLevels := oc.GetNetworkPolicyACLLogging(np.namespace)
+	np.Lock()
+	defer np.Unlock()
+	// This needs to be a write lock because there's no locking around 'gress policies
+	if np.deleted || !doUpdate() || !np.created {
+		return
+	}
+	acls := gp.buildLocalPodACLs(np.portGroupName, aclLoggingLevels.Allow)
+	ops, err := libovsdbops.CreateOrUpdateACLsOps(oc.nbClient, nil, acls...)
+	if err != nil {
+		klog.Errorf(err.Error())
+		return
+	}
+	ops, err = libovsdbops.AddACLsToPortGroupOps(oc.nbClient, ops, np.portGroupName, acls...)
+	if err != nil {
+		klog.Errorf(err.Error())
+		return
+	}
+	if _, err = libovsdbops.TransactAndCheck(oc.nbClient, ops); err != nil {
+		klog.Errorf("Failed to update host network peers of network policy %s/%s: %v",
+			np.namespace, np.name, err)
+	}
+}
@@ -3,6 +3,8 @@ package libovsdbops
 import (
 	"context"
 	"fmt"
+	"reflect"
+
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
 
 	libovsdbclient "github.com/ovn-org/libovsdb/client"
@@ -87,6 +89,83 @@ func BuildLoadBalancer(name string, protocol nbdb.LoadBalancerProtocol, selectio
 	}
 }
 
+// BuildLoadBalancerHealthCheck returns a health check for a vip
+func BuildLoadBalancerHealthCheck(vip string, options map[string]string) *nbdb.LoadBalancerHealthCheck {
+	return &nbdb.LoadBalancerHealthCheck{
+		Vip:     vip,
+		Options: options,
+	}
+}
+
+// SetLoadBalancerHealthChecksOps returns the ops to make a load balancer
+// reference the given health checks. The health checks the existing load
+// balancer already references are reused by vip and updated in place, the
+// others are created. The health checks and ip port mappings of the load
+// balancer are only cleared if the existing load balancer has some and none
+// are wanted anymore, so that load balancers without health checks are left
+// untouched. Health checks are not root rows, the ones no longer referenced
+// are garbage collected by ovsdb-server.
+func SetLoadBalancerHealthChecksOps(nbClient libovsdbclient.Client, ops []libovsdb.Operation, lb *nbdb.LoadBalancer, healthChecks ...*nbdb.LoadBalancerHealthCheck) ([]libovsdb.Operation, error) {
+	if ops == nil {
+		ops = []libovsdb.Operation{}
+	}
+
+	existing := &nbdb.LoadBalancer{UUID: lb.UUID, Name: lb.Name}
+	err := findLoadBalancer(nbClient, existing)
+	if err != nil && err != libovsdbclient.ErrNotFound {
+		return nil, err
+	}
+	existingByVip := map[string]*nbdb.LoadBalancerHealthCheck{}
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), types.OVSDBTimeout)
+		defer cancel()
+		if err := nbClient.Get(ctx, existing); err != nil {
+			return nil, fmt.Errorf("can't get load balancer %s: %v", existing.Name, err)
+		}
+		for _, uuid := range existing.HealthCheck {
+			hc := &nbdb.LoadBalancerHealthCheck{UUID: uuid}
+			if err := nbClient.Get(ctx, hc); err != nil {
+				if err == libovsdbclient.ErrNotFound {
+					continue
+				}
+				return nil, fmt.Errorf("can't get health check %s of load balancer %s: %v", uuid, existing.Name, err)
+			}
+			existingByVip[hc.Vip] = hc
+		}
+	}
+
+	if len(healthChecks) == 0 {
+		if len(existing.HealthCheck) > 0 || len(existing.IPPortMappings) > 0 {
+			lb.HealthCheck = []string{}
+			lb.IPPortMappings = map[string]string{}
+		}
+		return ops, nil
+	}
+
+	lb.HealthCheck = make([]string, 0, len(healthChecks))
+	for _, hc := range healthChecks {
+		if existingHC, ok := existingByVip[hc.Vip]; ok {
+			hc.UUID = existingHC.UUID
+			if !reflect.DeepEqual(hc.Options, existingHC.Options) {
+				op, err := nbClient.Where(hc).Update(hc, &hc.Options)
+				if err != nil {
+					return nil, err
+				}
+				ops = append(ops, op...)
+			}
+		} else {
+			hc.UUID = BuildNamedUUID()
+			op, err := nbClient.Create(hc)
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, op...)
+		}
+		lb.HealthCheck = append(lb.HealthCheck, hc.UUID)
+	}
+	return ops, nil
+}
+
 func ensureLoadBalancerUUID(lb *nbdb.LoadBalancer) {
 	if lb.UUID == "" {
 		lb.UUID = BuildNamedUUID()
@@ -0,0 +1,119 @@
+package libovsdbops
+
+import (
+	"context"
+	"fmt"
+
+	libovsdbclient "github.com/ovn-org/libovsdb/client"
+	"github.com/ovn-org/libovsdb/model"
+	libovsdb "github.com/ovn-org/libovsdb/ovsdb"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/nbdb"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
+)
+
+// LoadBalancerGroupsSupported returns true if the OVN_Northbound schema served
+// to the client has the Load_Balancer_Group table, added in OVN 21.09
+func LoadBalancerGroupsSupported(nbClient libovsdbclient.Client) bool {
+	return nbClient.Schema().Table("Load_Balancer_Group") != nil
+}
+
+// findLoadBalancerGroup looks up the load balancer group in the cache and
+// sets the UUID
+func findLoadBalancerGroup(nbClient libovsdbclient.Client, group *nbdb.LoadBalancerGroup) error {
+	if group.UUID != "" && !IsNamedUUID(group.UUID) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), types.OVSDBTimeout)
+	defer cancel()
+	groups := []nbdb.LoadBalancerGroup{}
+	err := nbClient.WhereCache(func(item *nbdb.LoadBalancerGroup) bool {
+		return item.Name == group.Name
+	}).List(ctx, &groups)
+	if err != nil {
+		return fmt.Errorf("can't find load balancer group %+v: %v", *group, err)
+	}
+
+	if len(groups) > 1 {
+		return fmt.Errorf("unexpectedly found multiple load balancer groups: %+v", groups)
+	}
+
+	if len(groups) == 0 {
+		return libovsdbclient.ErrNotFound
+	}
+
+	group.UUID = groups[0].UUID
+	return nil
+}
+
+// CreateLoadBalancerGroup creates the load balancer group if it doesn't
+// exist yet, and sets its UUID
+func CreateLoadBalancerGroup(nbClient libovsdbclient.Client, group *nbdb.LoadBalancerGroup) error {
+	err := findLoadBalancerGroup(nbClient, group)
+	if err != libovsdbclient.ErrNotFound {
+		return err
+	}
+
+	group.UUID = BuildNamedUUID()
+	ops, err := nbClient.Create(group)
+	if err != nil {
+		return err
+	}
+	_, err = TransactAndCheckAndSetUUIDs(nbClient, group, ops)
+	return err
+}
+
+// ListLoadBalancerGroups lists all load balancer groups in nbdb
+func ListLoadBalancerGroups(nbClient libovsdbclient.Client) ([]nbdb.LoadBalancerGroup, error) {
+	groups := []nbdb.LoadBalancerGroup{}
+	ctx, cancel := context.WithTimeout(context.Background(), types.OVSDBTimeout)
+	defer cancel()
+	err := nbClient.List(ctx, &groups)
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// AddLoadBalancersToGroupOps returns the ops to add load balancers to a load
+// balancer group
+func AddLoadBalancersToGroupOps(nbClient libovsdbclient.Client, ops []libovsdb.Operation, group *nbdb.LoadBalancerGroup, lbs ...*nbdb.LoadBalancer) ([]libovsdb.Operation, error) {
+	return mutateLoadBalancerGroupOps(nbClient, ops, group, libovsdb.MutateOperationInsert, lbs...)
+}
+
+// RemoveLoadBalancersFromGroupOps returns the ops to remove load balancers
+// from a load balancer group
+func RemoveLoadBalancersFromGroupOps(nbClient libovsdbclient.Client, ops []libovsdb.Operation, group *nbdb.LoadBalancerGroup, lbs ...*nbdb.LoadBalancer) ([]libovsdb.Operation, error) {
+	return mutateLoadBalancerGroupOps(nbClient, ops, group, libovsdb.MutateOperationDelete, lbs...)
+}
+
+func mutateLoadBalancerGroupOps(nbClient libovsdbclient.Client, ops []libovsdb.Operation, group *nbdb.LoadBalancerGroup, mutator libovsdb.Mutator, lbs ...*nbdb.LoadBalancer) ([]libovsdb.Operation, error) {
+	if ops == nil {
+		ops = []libovsdb.Operation{}
+	}
+	if len(lbs) == 0 {
+		return ops, nil
+	}
+
+	err := findLoadBalancerGroup(nbClient, group)
+	if err != nil {
+		return nil, err
+	}
+
+	lbUUIDs := make([]string, 0, len(lbs))
+	for _, lb := range lbs {
+		lbUUIDs = append(lbUUIDs, lb.UUID)
+	}
+
+	op, err := nbClient.Where(group).Mutate(group, model.Mutation{
+		Field:   &group.LoadBalancer,
+		Mutator: mutator,
+		Value:   lbUUIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ops = append(ops, op...)
+	return ops, nil
+}
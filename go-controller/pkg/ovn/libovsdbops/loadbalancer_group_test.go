@@ -0,0 +1,163 @@
+package libovsdbops
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/nbdb"
+	libovsdbtest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing/libovsdb"
+)
+
+func TestLoadBalancerGroupOps(t *testing.T) {
+	fakeLB1 := &nbdb.LoadBalancer{
+		Name: "lb1",
+		UUID: BuildNamedUUID(),
+	}
+
+	fakeLB2 := &nbdb.LoadBalancer{
+		Name: "lb2",
+		UUID: BuildNamedUUID(),
+	}
+
+	fakeGroup := &nbdb.LoadBalancerGroup{
+		Name:         "clusterSwitchLBGroup",
+		UUID:         BuildNamedUUID(),
+		LoadBalancer: []string{fakeLB1.UUID},
+	}
+
+	tests := []struct {
+		desc         string
+		add          []*nbdb.LoadBalancer
+		remove       []*nbdb.LoadBalancer
+		expectedNbdb libovsdbtest.TestSetup
+	}{
+		{
+			desc: "add a load balancer to the group",
+			add:  []*nbdb.LoadBalancer{fakeLB2},
+			expectedNbdb: libovsdbtest.TestSetup{
+				NBData: []libovsdbtest.TestData{
+					fakeLB1,
+					fakeLB2,
+					&nbdb.LoadBalancerGroup{
+						Name:         fakeGroup.Name,
+						UUID:         fakeGroup.UUID,
+						LoadBalancer: []string{fakeLB1.UUID, fakeLB2.UUID},
+					},
+				},
+			},
+		},
+		{
+			desc:   "remove a load balancer from the group",
+			remove: []*nbdb.LoadBalancer{fakeLB1},
+			expectedNbdb: libovsdbtest.TestSetup{
+				NBData: []libovsdbtest.TestData{
+					fakeLB1,
+					fakeLB2,
+					&nbdb.LoadBalancerGroup{
+						Name: fakeGroup.Name,
+						UUID: fakeGroup.UUID,
+					},
+				},
+			},
+		},
+		{
+			desc: "nothing to add or remove is a noop",
+			expectedNbdb: libovsdbtest.TestSetup{
+				NBData: []libovsdbtest.TestData{
+					fakeLB1,
+					fakeLB2,
+					fakeGroup,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			stopChan := make(chan struct{})
+			defer close(stopChan)
+
+			nbClient, err := libovsdbtest.NewNBTestHarness(libovsdbtest.TestSetup{
+				NBData: []libovsdbtest.TestData{
+					fakeLB1,
+					fakeLB2,
+					fakeGroup,
+				},
+			}, stopChan)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			lbs, err := ListLoadBalancers(nbClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			byName := map[string]*nbdb.LoadBalancer{}
+			for i := range lbs {
+				byName[lbs[i].Name] = &lbs[i]
+			}
+			lookup := func(lbs []*nbdb.LoadBalancer) []*nbdb.LoadBalancer {
+				out := []*nbdb.LoadBalancer{}
+				for _, lb := range lbs {
+					out = append(out, byName[lb.Name])
+				}
+				return out
+			}
+
+			group := &nbdb.LoadBalancerGroup{Name: fakeGroup.Name}
+			ops, err := AddLoadBalancersToGroupOps(nbClient, nil, group, lookup(tt.add)...)
+			if err != nil {
+				t.Fatal(fmt.Errorf("AddLoadBalancersToGroupOps() error = %v", err))
+			}
+			ops, err = RemoveLoadBalancersFromGroupOps(nbClient, ops, group, lookup(tt.remove)...)
+			if err != nil {
+				t.Fatal(fmt.Errorf("RemoveLoadBalancersFromGroupOps() error = %v", err))
+			}
+			if _, err = TransactAndCheck(nbClient, ops); err != nil {
+				t.Fatal(err)
+			}
+
+			matcher := libovsdbtest.HaveData(tt.expectedNbdb.NBData)
+			success, err := matcher.Match(nbClient)
+			if !success {
+				t.Fatal(fmt.Errorf("test: \"%s\" didn't match expected with actual, err: %v", tt.desc, matcher.FailureMessage(nbClient)))
+			}
+			if err != nil {
+				t.Fatal(fmt.Errorf("test: \"%s\" encountered error: %v", tt.desc, err))
+			}
+		})
+	}
+}
+
+func TestCreateLoadBalancerGroup(t *testing.T) {
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+
+	nbClient, err := libovsdbtest.NewNBTestHarness(libovsdbtest.TestSetup{}, stopChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	group := &nbdb.LoadBalancerGroup{Name: "clusterSwitchLBGroup"}
+	if err := CreateLoadBalancerGroup(nbClient, group); err != nil {
+		t.Fatal(err)
+	}
+	if group.UUID == "" || IsNamedUUID(group.UUID) {
+		t.Fatalf("expected the UUID of the created group, got %q", group.UUID)
+	}
+
+	// creating it again finds the existing group
+	existing := &nbdb.LoadBalancerGroup{Name: "clusterSwitchLBGroup"}
+	if err := CreateLoadBalancerGroup(nbClient, existing); err != nil {
+		t.Fatal(err)
+	}
+	if existing.UUID != group.UUID {
+		t.Fatalf("expected the UUID of the existing group %s, got %s", group.UUID, existing.UUID)
+	}
+	groups, err := ListLoadBalancerGroups(nbClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 {
+		t.Fatalf("expected a single group, got %+v", groups)
+	}
+}
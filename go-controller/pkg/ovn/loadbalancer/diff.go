@@ -0,0 +1,136 @@
+package loadbalancer
+
+import (
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	LBDiffCreate = "create"
+	LBDiffUpdate = "update"
+	LBDiffDelete = "delete"
+)
+
+// LBDiff describes how EnsureLBs would change a cached load balancer to
+// match the desired one.
+type LBDiff struct {
+	Name   string `json:"name"`
+	UUID   string `json:"uuid,omitempty"`
+	Action string `json:"action"`
+
+	// Protocol is the desired protocol, if it differs
+	Protocol string `json:"protocol,omitempty"`
+
+	AddVIPs    []string `json:"addVIPs,omitempty"`
+	RemoveVIPs []string `json:"removeVIPs,omitempty"`
+
+	// UpdateBackends maps the kept vips whose backends change to their
+	// desired backends
+	UpdateBackends map[string]string `json:"updateBackends,omitempty"`
+
+	// SetOptions are the options added or changed, with their desired value
+	SetOptions    map[string]string `json:"setOptions,omitempty"`
+	RemoveOptions []string          `json:"removeOptions,omitempty"`
+
+	AddSwitches    []string `json:"addSwitches,omitempty"`
+	RemoveSwitches []string `json:"removeSwitches,omitempty"`
+	AddRouters     []string `json:"addRouters,omitempty"`
+	RemoveRouters  []string `json:"removeRouters,omitempty"`
+	AddGroups      []string `json:"addGroups,omitempty"`
+	RemoveGroups   []string `json:"removeGroups,omitempty"`
+}
+
+// DiffLBs compares the desired load balancers with the existing ones, as
+// returned by LBCache.Find, and returns the differences sorted by name.
+// Load balancers that are up to date are omitted.
+func DiffLBs(existing map[string]*CachedLB, LBs []LB) []LBDiff {
+	existingByName := make(map[string]*CachedLB, len(existing))
+	for _, lb := range existing {
+		existingByName[lb.Name] = lb
+	}
+
+	diffs := []LBDiff{}
+	for i := range LBs {
+		lb := &LBs[i]
+		cached := existingByName[lb.Name]
+		delete(existingByName, lb.Name)
+
+		diff := LBDiff{Name: lb.Name, Action: LBDiffUpdate}
+		if cached == nil {
+			cached = &CachedLB{}
+			diff.Action = LBDiffCreate
+		} else {
+			diff.UUID = cached.UUID
+		}
+		if !strings.EqualFold(lb.Protocol, cached.Protocol) {
+			diff.Protocol = lb.Protocol
+		}
+		blb, _ := buildLB(lb)
+		diff.AddVIPs, diff.RemoveVIPs = diffSets(getVips(lb), cached.VIPs)
+		for vip, backends := range blb.Vips {
+			if cachedBackends, ok := cached.Backends[vip]; ok && cachedBackends != backends {
+				if diff.UpdateBackends == nil {
+					diff.UpdateBackends = map[string]string{}
+				}
+				diff.UpdateBackends[vip] = backends
+			}
+		}
+		diff.SetOptions, diff.RemoveOptions = diffMaps(blb.Options, cached.Options)
+		diff.AddSwitches, diff.RemoveSwitches = diffSets(sets.NewString(lb.Switches...), cached.Switches)
+		diff.AddRouters, diff.RemoveRouters = diffSets(sets.NewString(lb.Routers...), cached.Routers)
+		diff.AddGroups, diff.RemoveGroups = diffSets(sets.NewString(lb.Groups...), cached.Groups)
+
+		if diff.Action == LBDiffCreate || diff.Protocol != "" ||
+			len(diff.AddVIPs)+len(diff.RemoveVIPs)+len(diff.UpdateBackends)+
+				len(diff.SetOptions)+len(diff.RemoveOptions)+len(diff.AddSwitches)+len(diff.RemoveSwitches)+
+				len(diff.AddRouters)+len(diff.RemoveRouters)+len(diff.AddGroups)+len(diff.RemoveGroups) > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+
+	for name, cached := range existingByName {
+		diffs = append(diffs, LBDiff{Name: name, UUID: cached.UUID, Action: LBDiffDelete})
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Name < diffs[j].Name })
+	return diffs
+}
+
+// diffSets returns the items only in want, and the items only in have, or
+// nil if there are none
+func diffSets(want, have sets.String) ([]string, []string) {
+	if have == nil {
+		have = sets.String{}
+	}
+	return listOrNil(want.Difference(have)), listOrNil(have.Difference(want))
+}
+
+// diffMaps returns the entries of want missing or different in have, and the
+// keys only in have, or nil if there are none
+func diffMaps(want, have map[string]string) (map[string]string, []string) {
+	var set map[string]string
+	for key, value := range want {
+		if haveValue, ok := have[key]; !ok || haveValue != value {
+			if set == nil {
+				set = map[string]string{}
+			}
+			set[key] = value
+		}
+	}
+	removed := sets.NewString()
+	for key := range have {
+		if _, ok := want[key]; !ok {
+			removed.Insert(key)
+		}
+	}
+	return set, listOrNil(removed)
+}
+
+func listOrNil(s sets.String) []string {
+	if s.Len() == 0 {
+		return nil
+	}
+	return s.List()
+}
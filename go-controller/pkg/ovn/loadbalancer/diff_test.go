@@ -0,0 +1,187 @@
+package loadbalancer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+var defaultLBOptions = map[string]string{"reject": "true", "event": "false", "skip_snat": "false"}
+
+func TestDiffLBs(t *testing.T) {
+	existing := map[string]*CachedLB{
+		"uuid-1": {
+			Name:     "Service_testns/foo_TCP_cluster",
+			UUID:     "uuid-1",
+			Protocol: "tcp",
+			VIPs:     sets.NewString("192.168.1.1:80"),
+			Backends: map[string]string{"192.168.1.1:80": ""},
+			Options:  defaultLBOptions,
+			Switches: sets.NewString("switch-node-a"),
+			Routers:  sets.NewString("gr-node-a"),
+			Groups:   sets.String{},
+		},
+		"uuid-2": {
+			Name:     "Service_testns/foo_UDP_cluster",
+			UUID:     "uuid-2",
+			Protocol: "udp",
+			VIPs:     sets.NewString("192.168.1.1:53"),
+			Backends: map[string]string{"192.168.1.1:53": ""},
+			Options:  defaultLBOptions,
+			Switches: sets.NewString("switch-node-a"),
+			Routers:  sets.String{},
+			Groups:   sets.String{},
+		},
+		"uuid-3": {
+			Name:     "Service_testns/foo_TCP_node_router_node-a",
+			UUID:     "uuid-3",
+			Protocol: "tcp",
+			VIPs:     sets.NewString("10.0.0.1:8080"),
+			Switches: sets.String{},
+			Routers:  sets.NewString("gr-node-a"),
+			Groups:   sets.String{},
+		},
+	}
+
+	desired := []LB{
+		{
+			Name:     "Service_testns/foo_TCP_cluster",
+			Protocol: "TCP",
+			Rules: []LBRule{
+				{Source: Addr{IP: "192.168.1.1", Port: 80}},
+				{Source: Addr{IP: "192.168.1.2", Port: 80}},
+			},
+			Switches: []string{"switch-node-a", "switch-node-b"},
+		},
+		{
+			Name:     "Service_testns/foo_UDP_cluster",
+			Protocol: "UDP",
+			Rules:    []LBRule{{Source: Addr{IP: "192.168.1.1", Port: 53}}},
+			Switches: []string{"switch-node-a"},
+		},
+		{
+			Name:     "Service_testns/foo_SCTP_cluster",
+			Protocol: "SCTP",
+			Rules:    []LBRule{{Source: Addr{IP: "192.168.1.1", Port: 9000}}},
+			Groups:   []string{"clusterSwitchLBGroup"},
+		},
+	}
+
+	assert.Equal(t, []LBDiff{
+		{
+			Name:       "Service_testns/foo_SCTP_cluster",
+			Action:     LBDiffCreate,
+			Protocol:   "SCTP",
+			AddVIPs:    []string{"192.168.1.1:9000"},
+			SetOptions: defaultLBOptions,
+			AddGroups:  []string{"clusterSwitchLBGroup"},
+		},
+		{
+			Name:          "Service_testns/foo_TCP_cluster",
+			UUID:          "uuid-1",
+			Action:        LBDiffUpdate,
+			AddVIPs:       []string{"192.168.1.2:80"},
+			AddSwitches:   []string{"switch-node-b"},
+			RemoveRouters: []string{"gr-node-a"},
+		},
+		{
+			Name:   "Service_testns/foo_TCP_node_router_node-a",
+			UUID:   "uuid-3",
+			Action: LBDiffDelete,
+		},
+	}, DiffLBs(existing, desired))
+
+	assert.Equal(t, []LBDiff{}, DiffLBs(nil, nil))
+}
+
+func TestDiffLBsBackends(t *testing.T) {
+	existing := map[string]*CachedLB{
+		"uuid-1": {
+			Name:     "Service_testns/foo_TCP_cluster",
+			UUID:     "uuid-1",
+			Protocol: "tcp",
+			VIPs:     sets.NewString("192.168.1.1:80", "192.168.1.2:80"),
+			Backends: map[string]string{
+				"192.168.1.1:80": "10.128.0.2:8080",
+				"192.168.1.2:80": "10.128.0.2:8080",
+			},
+			Options:  defaultLBOptions,
+			Switches: sets.String{},
+			Routers:  sets.String{},
+			Groups:   sets.String{},
+		},
+	}
+
+	desired := []LB{
+		{
+			Name:     "Service_testns/foo_TCP_cluster",
+			Protocol: "TCP",
+			Rules: []LBRule{
+				{
+					Source:  Addr{IP: "192.168.1.1", Port: 80},
+					Targets: []Addr{{IP: "10.128.0.2", Port: 8080}, {IP: "10.128.1.2", Port: 8080}},
+				},
+				{
+					Source:  Addr{IP: "192.168.1.2", Port: 80},
+					Targets: []Addr{{IP: "10.128.0.2", Port: 8080}},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, []LBDiff{
+		{
+			Name:           "Service_testns/foo_TCP_cluster",
+			UUID:           "uuid-1",
+			Action:         LBDiffUpdate,
+			UpdateBackends: map[string]string{"192.168.1.1:80": "10.128.0.2:8080,10.128.1.2:8080"},
+		},
+	}, DiffLBs(existing, desired))
+
+	desired[0].Rules[0].Targets = desired[0].Rules[0].Targets[:1]
+	assert.Equal(t, []LBDiff{}, DiffLBs(existing, desired))
+}
+
+func TestDiffLBsOptions(t *testing.T) {
+	existing := map[string]*CachedLB{
+		"uuid-1": {
+			Name:     "Service_testns/foo_TCP_cluster",
+			UUID:     "uuid-1",
+			Protocol: "tcp",
+			VIPs:     sets.NewString("192.168.1.1:80"),
+			Backends: map[string]string{"192.168.1.1:80": ""},
+			Options: map[string]string{
+				"reject":           "true",
+				"event":            "false",
+				"skip_snat":        "false",
+				"affinity_timeout": "10800",
+			},
+			Switches: sets.String{},
+			Routers:  sets.String{},
+			Groups:   sets.String{},
+		},
+	}
+
+	desired := []LB{
+		{
+			Name:     "Service_testns/foo_TCP_cluster",
+			Protocol: "TCP",
+			Opts:     LBOpts{DropEmpty: true, HairpinSNATIPs: []string{"169.254.169.5"}},
+			Rules:    []LBRule{{Source: Addr{IP: "192.168.1.1", Port: 80}}},
+		},
+	}
+
+	assert.Equal(t, []LBDiff{
+		{
+			Name:   "Service_testns/foo_TCP_cluster",
+			UUID:   "uuid-1",
+			Action: LBDiffUpdate,
+			SetOptions: map[string]string{
+				"reject":          "false",
+				"hairpin_snat_ip": "169.254.169.5",
+			},
+			RemoveOptions: []string{"affinity_timeout"},
+		},
+	}, DiffLBs(existing, desired))
+}
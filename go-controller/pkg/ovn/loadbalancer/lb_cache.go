@@ -45,10 +45,16 @@ type CachedLB struct {
 	Protocol    string
 	UUID        string
 	ExternalIDs map[string]string
-	VIPs        sets.String // don't care about backend IPs, just the vips
+	VIPs        sets.String
+
+	// Backends maps each vip to its comma separated backends, and Options
+	// are the load balancer options, as set in nbdb
+	Backends map[string]string
+	Options  map[string]string
 
 	Switches sets.String
 	Routers  sets.String
+	Groups   sets.String
 }
 
 // update the database with any existing LBs, along with any
@@ -64,15 +70,19 @@ func (c *LBCache) update(existing []LB, toDelete []string) {
 		if lb.UUID == "" {
 			panic(fmt.Sprintf("coding error: cache add LB %s with no UUID", lb.Name))
 		}
+		blb, _ := buildLB(&lb)
 		c.existing[lb.UUID] = &CachedLB{
 			Name:        lb.Name,
 			UUID:        lb.UUID,
 			Protocol:    strings.ToLower(lb.Protocol),
 			ExternalIDs: lb.ExternalIDs,
 			VIPs:        getVips(&lb),
+			Backends:    blb.Vips,
+			Options:     blb.Options,
 
 			Switches: sets.NewString(lb.Switches...),
 			Routers:  sets.NewString(lb.Routers...),
+			Groups:   sets.NewString(lb.Groups...),
 		}
 	}
 }
@@ -90,6 +100,9 @@ func (c *LBCache) removeVips(toRemove []DeleteVIPEntry) {
 
 		// lb is a pointer, this is immediately effecting.
 		lb.VIPs.Delete(entry.VIPs...)
+		for _, vip := range entry.VIPs {
+			delete(lb.Backends, vip)
+		}
 	}
 }
 
@@ -192,6 +205,23 @@ func newCache(nbClient libovsdbclient.Client) (*LBCache, error) {
 		}
 	}
 
+	if !libovsdbops.LoadBalancerGroupsSupported(nbClient) {
+		return &c, nil
+	}
+
+	groups, err := libovsdbops.ListLoadBalancerGroups(nbClient)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		for _, lbuuid := range group.LoadBalancer {
+			if lb, ok := c.existing[lbuuid]; ok {
+				lb.Groups.Insert(group.Name)
+			}
+		}
+	}
+
 	return &c, nil
 }
 
@@ -209,16 +239,23 @@ func listLBs(nbClient libovsdbclient.Client) ([]CachedLB, error) {
 			Name:        lb.Name,
 			ExternalIDs: lb.ExternalIDs,
 			VIPs:        sets.String{},
+			Backends:    map[string]string{},
+			Options:     map[string]string{},
 			Switches:    sets.String{},
 			Routers:     sets.String{},
+			Groups:      sets.String{},
 		}
 
 		if lb.Protocol != nil {
 			res.Protocol = *lb.Protocol
 		}
 
-		for vip := range lb.Vips {
+		for vip, backends := range lb.Vips {
 			res.VIPs.Insert(vip)
+			res.Backends[vip] = backends
+		}
+		for key, value := range lb.Options {
+			res.Options[key] = value
 		}
 
 		out = append(out, res)
@@ -89,12 +89,18 @@ func TestNewCache(t *testing.T) {
 				"k8s.ovn.org/owner": "default/kubernetes",
 			},
 			VIPs: sets.NewString("192.168.0.1:6443", "[fe::1]:1"),
+			Backends: map[string]string{
+				"192.168.0.1:6443": "1.1.1.1:1,2.2.2.2:2",
+				"[fe::1]:1":        "[fe::2]:1,[fe::2]:2",
+			},
+			Options: map[string]string{},
 			Switches: sets.String{
 				"ovn-worker2": {},
 			},
 			Routers: sets.String{
 				"GR_ovn-control-plane": {},
 			},
+			Groups: sets.String{},
 		},
 		"7dc190c4-c615-467f-af83-9856d832c9a0": {
 			UUID:     "7dc190c4-c615-467f-af83-9856d832c9a0",
@@ -105,6 +111,11 @@ func TestNewCache(t *testing.T) {
 				"k8s.ovn.org/owner": "default/kubernetes",
 			},
 			VIPs: sets.NewString("192.168.0.1:6443", "[ff::1]:1"),
+			Backends: map[string]string{
+				"192.168.0.1:6443": "1.1.1.1:1,2.2.2.2:2",
+				"[ff::1]:1":        "[fe::2]:1,[fe::2]:2",
+			},
+			Options: map[string]string{},
 			Switches: sets.String{
 				"ovn-worker":        {},
 				"ovn-control-plane": {},
@@ -113,6 +124,7 @@ func TestNewCache(t *testing.T) {
 				"GR_ovn-worker":  {},
 				"GR_ovn-worker2": {},
 			},
+			Groups: sets.String{},
 		},
 	}, c.existing)
 
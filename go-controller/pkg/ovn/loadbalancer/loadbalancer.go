@@ -3,6 +3,7 @@ package loadbalancer
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -55,28 +56,41 @@ func EnsureLBs(nbClient libovsdbclient.Client, externalIDs map[string]string, LB
 	removeLBsFromSwitch := map[string][]*nbdb.LoadBalancer{}
 	addLBsToRouter := map[string][]*nbdb.LoadBalancer{}
 	removesLBsFromRouter := map[string][]*nbdb.LoadBalancer{}
+	addLBsToGroup := map[string][]*nbdb.LoadBalancer{}
+	removeLBsFromGroup := map[string][]*nbdb.LoadBalancer{}
 	wantedByName := make(map[string]*LB, len(LBs))
+	ops := []libovsdb.Operation{}
 	for i, lb := range LBs {
 		wantedByName[lb.Name] = &LBs[i]
-		blb := buildLB(&lb)
+		blb, healthChecks := buildLB(&lb)
 		lbs = append(lbs, blb)
 		existingLB := existingByName[lb.Name]
 		existingRouters := sets.String{}
 		existingSwitches := sets.String{}
+		existingGroups := sets.String{}
 		if existingLB != nil {
 			toDelete.Delete(existingLB.UUID)
 			existingRouters = existingLB.Routers
 			existingSwitches = existingLB.Switches
+			existingGroups = existingLB.Groups
 		}
 		wantRouters := sets.NewString(lb.Routers...)
 		wantSwitches := sets.NewString(lb.Switches...)
+		wantGroups := sets.NewString(lb.Groups...)
 		mapLBDifferenceByKey(addLBsToSwitch, wantSwitches, existingSwitches, blb)
 		mapLBDifferenceByKey(removeLBsFromSwitch, existingSwitches, wantSwitches, blb)
 		mapLBDifferenceByKey(addLBsToRouter, wantRouters, existingRouters, blb)
 		mapLBDifferenceByKey(removesLBsFromRouter, existingRouters, wantRouters, blb)
+		mapLBDifferenceByKey(addLBsToGroup, wantGroups, existingGroups, blb)
+		mapLBDifferenceByKey(removeLBsFromGroup, existingGroups, wantGroups, blb)
+
+		ops, err = libovsdbops.SetLoadBalancerHealthChecksOps(nbClient, ops, blb, healthChecks...)
+		if err != nil {
+			return err
+		}
 	}
 
-	ops, err := libovsdbops.CreateOrUpdateLoadBalancersOps(nbClient, nil, lbs...)
+	ops, err = libovsdbops.CreateOrUpdateLoadBalancersOps(nbClient, ops, lbs...)
 	if err != nil {
 		return err
 	}
@@ -129,6 +143,41 @@ func EnsureLBs(nbClient libovsdbclient.Client, externalIDs map[string]string, LB
 		}
 	}
 
+	// stale LBs are dropped from their groups in the same transaction rather
+	// than left to the weak reference cleanup of ovsdb-server
+	for _, lb := range existing {
+		if !toDelete.Has(lb.UUID) {
+			continue
+		}
+		for group := range lb.Groups {
+			removeLBsFromGroup[group] = append(removeLBsFromGroup[group], &nbdb.LoadBalancer{UUID: lb.UUID})
+		}
+	}
+
+	// cache groups for this round of ops
+	groups := map[string]*nbdb.LoadBalancerGroup{}
+	getGroup := func(name string) *nbdb.LoadBalancerGroup {
+		var group *nbdb.LoadBalancerGroup
+		var found bool
+		if group, found = groups[name]; !found {
+			group = &nbdb.LoadBalancerGroup{Name: name}
+			groups[name] = group
+		}
+		return group
+	}
+	for k, v := range addLBsToGroup {
+		ops, err = libovsdbops.AddLoadBalancersToGroupOps(nbClient, ops, getGroup(k), v...)
+		if err != nil {
+			return err
+		}
+	}
+	for k, v := range removeLBsFromGroup {
+		ops, err = libovsdbops.RemoveLoadBalancersFromGroupOps(nbClient, ops, getGroup(k), v...)
+		if err != nil {
+			return err
+		}
+	}
+
 	deleteLBs := make([]*nbdb.LoadBalancer, 0, len(toDelete))
 	for uuid := range toDelete {
 		deleteLBs = append(deleteLBs, &nbdb.LoadBalancer{UUID: uuid})
@@ -183,13 +232,20 @@ func mapLBDifferenceByKey(keyMap map[string][]*nbdb.LoadBalancer, keyIn sets.Str
 	}
 }
 
-func buildLB(lb *LB) *nbdb.LoadBalancer {
+// buildLB returns the OVN load balancer for an LB, along with the health
+// checks it references
+func buildLB(lb *LB) (*nbdb.LoadBalancer, []*nbdb.LoadBalancerHealthCheck) {
 	reject := "true"
 	event := "false"
 
 	if lb.Opts.Unidling {
 		reject = "false"
 		event = "true"
+	} else if lb.Opts.DropEmpty {
+		reject = "false"
+	} else if lb.Opts.EmptyEvent {
+		reject = "false"
+		event = "true"
 	}
 
 	skipSNAT := "false"
@@ -203,21 +259,66 @@ func buildLB(lb *LB) *nbdb.LoadBalancer {
 		"skip_snat": skipSNAT,
 	}
 
+	if len(lb.Opts.HairpinSNATIPs) > 0 {
+		options["hairpin_snat_ip"] = strings.Join(lb.Opts.HairpinSNATIPs, " ")
+	}
+
 	// Session affinity
+	// If a timeout is set, then OVN remembers the target of each client IP
 	// If enabled, then bucket flows by 3-tuple (proto, srcip, dstip)
-	// otherwise, use default ovn value
+	// otherwise, use the hash fields of the service, if any, or the default ovn value
 	selectionFields := []nbdb.LoadBalancerSelectionFields{}
-	if lb.Opts.Affinity {
+	if lb.Opts.AffinityTimeOut > 0 {
+		options["affinity_timeout"] = strconv.Itoa(int(lb.Opts.AffinityTimeOut))
+	} else if lb.Opts.Affinity {
 		selectionFields = []string{
 			nbdb.LoadBalancerSelectionFieldsIPSrc,
 			nbdb.LoadBalancerSelectionFieldsIPDst,
 		}
+	} else if len(lb.Opts.HashFields) > 0 {
+		selectionFields = append(selectionFields, lb.Opts.HashFields...)
 	}
 
 	// vipMap
 	vips := buildVipMap(lb.Rules)
 
-	return libovsdbops.BuildLoadBalancer(lb.Name, strings.ToLower(lb.Protocol), selectionFields, vips, options, lb.ExternalIDs)
+	olb := libovsdbops.BuildLoadBalancer(lb.Name, strings.ToLower(lb.Protocol), selectionFields, vips, options, lb.ExternalIDs)
+
+	// Health checks
+	// Only set for the services that opted in, the health checks themselves
+	// are referenced by EnsureLBs, which reuses the existing ones
+	healthChecks := buildHealthChecks(lb.Rules)
+	if len(healthChecks) > 0 {
+		olb.IPPortMappings = make(map[string]string, len(lb.IPPortMappings))
+		for ip, mapping := range lb.IPPortMappings {
+			olb.IPPortMappings[ip] = mapping
+		}
+	}
+
+	return olb, healthChecks
+}
+
+// buildHealthChecks returns a health check for every rule that wants one
+func buildHealthChecks(rules []LBRule) []*nbdb.LoadBalancerHealthCheck {
+	out := []*nbdb.LoadBalancerHealthCheck{}
+	for _, r := range rules {
+		if r.HealthCheck == nil {
+			continue
+		}
+		options := map[string]string{}
+		for key, value := range map[string]int32{
+			"interval":      r.HealthCheck.Interval,
+			"timeout":       r.HealthCheck.Timeout,
+			"success_count": r.HealthCheck.SuccessCount,
+			"failure_count": r.HealthCheck.FailureCount,
+		} {
+			if value > 0 {
+				options[key] = strconv.Itoa(int(value))
+			}
+		}
+		out = append(out, libovsdbops.BuildLoadBalancerHealthCheck(r.Source.String(), options))
+	}
+	return out
 }
 
 // buildVipMap returns a viups map from a set of rules
@@ -0,0 +1,334 @@
+package loadbalancer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/nbdb"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/libovsdbops"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing/libovsdb"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
+)
+
+func TestBuildLBSessionAffinity(t *testing.T) {
+	tests := []struct {
+		name               string
+		opts               LBOpts
+		expSelectionFields []nbdb.LoadBalancerSelectionFields
+		expAffinityTimeout string
+	}{
+		{
+			name:               "no affinity",
+			expSelectionFields: []nbdb.LoadBalancerSelectionFields{},
+		},
+		{
+			name: "hash affinity",
+			opts: LBOpts{Affinity: true},
+			expSelectionFields: []nbdb.LoadBalancerSelectionFields{
+				nbdb.LoadBalancerSelectionFieldsIPSrc,
+				nbdb.LoadBalancerSelectionFieldsIPDst,
+			},
+		},
+		{
+			name:               "affinity timeout",
+			opts:               LBOpts{Affinity: true, AffinityTimeOut: 10800},
+			expSelectionFields: []nbdb.LoadBalancerSelectionFields{},
+			expAffinityTimeout: "10800",
+		},
+		{
+			name: "hash fields",
+			opts: LBOpts{HashFields: []string{"ip_src", "tp_src"}},
+			expSelectionFields: []nbdb.LoadBalancerSelectionFields{
+				nbdb.LoadBalancerSelectionFieldsIPSrc,
+				nbdb.LoadBalancerSelectionFieldsTpSrc,
+			},
+		},
+		{
+			name: "affinity takes precedence over hash fields",
+			opts: LBOpts{Affinity: true, HashFields: []string{"tp_dst"}},
+			expSelectionFields: []nbdb.LoadBalancerSelectionFields{
+				nbdb.LoadBalancerSelectionFieldsIPSrc,
+				nbdb.LoadBalancerSelectionFieldsIPDst,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb, _ := buildLB(&LB{
+				Name:     "Service_testns/foo_TCP_cluster",
+				Protocol: "TCP",
+				Opts:     tt.opts,
+				Rules: []LBRule{{
+					Source:  Addr{IP: "192.168.1.1", Port: 80},
+					Targets: []Addr{{IP: "10.128.0.2", Port: 8080}},
+				}},
+			})
+			assert.Equal(t, tt.expSelectionFields, lb.SelectionFields)
+			assert.Equal(t, tt.expAffinityTimeout, lb.Options["affinity_timeout"])
+		})
+	}
+}
+
+func TestBuildLBEmptyBackends(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      LBOpts
+		expReject string
+		expEvent  string
+	}{
+		{
+			name:      "reject",
+			expReject: "true",
+			expEvent:  "false",
+		},
+		{
+			name:      "drop",
+			opts:      LBOpts{DropEmpty: true},
+			expReject: "false",
+			expEvent:  "false",
+		},
+		{
+			name:      "event",
+			opts:      LBOpts{EmptyEvent: true},
+			expReject: "false",
+			expEvent:  "true",
+		},
+		{
+			name:      "drop takes precedence over event",
+			opts:      LBOpts{DropEmpty: true, EmptyEvent: true},
+			expReject: "false",
+			expEvent:  "false",
+		},
+		{
+			name:      "unidling",
+			opts:      LBOpts{Unidling: true, DropEmpty: true},
+			expReject: "false",
+			expEvent:  "true",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb, _ := buildLB(&LB{
+				Name:     "Service_testns/foo_TCP_cluster",
+				Protocol: "TCP",
+				Opts:     tt.opts,
+			})
+			assert.Equal(t, tt.expReject, lb.Options["reject"])
+			assert.Equal(t, tt.expEvent, lb.Options["event"])
+		})
+	}
+}
+
+func TestBuildLBHairpinSNATIP(t *testing.T) {
+	lb, _ := buildLB(&LB{
+		Name:     "Service_testns/foo_TCP_cluster",
+		Protocol: "TCP",
+		Opts:     LBOpts{HairpinSNATIPs: []string{"169.254.169.5", "fd69::5"}},
+	})
+	assert.Equal(t, "169.254.169.5 fd69::5", lb.Options["hairpin_snat_ip"])
+
+	lb, _ = buildLB(&LB{
+		Name:     "Service_testns/foo_TCP_cluster",
+		Protocol: "TCP",
+	})
+	assert.NotContains(t, lb.Options, "hairpin_snat_ip")
+}
+
+func TestBuildLBHealthChecks(t *testing.T) {
+	lb, healthChecks := buildLB(&LB{
+		Name:     "Service_testns/foo_TCP_cluster",
+		Protocol: "TCP",
+		Rules: []LBRule{
+			{
+				Source:      Addr{IP: "192.168.1.1", Port: 80},
+				Targets:     []Addr{{IP: "10.128.0.2", Port: 8080}},
+				HealthCheck: &HealthCheck{Interval: 5, SuccessCount: 3},
+			},
+			{
+				Source:  Addr{IP: "fe80::1", Port: 80},
+				Targets: []Addr{{IP: "fe90::1", Port: 8080}},
+			},
+		},
+		IPPortMappings: map[string]string{
+			"10.128.0.2": "testns_pod-a:10.128.0.254",
+		},
+	})
+	assert.Len(t, healthChecks, 1)
+	assert.Equal(t, "192.168.1.1:80", healthChecks[0].Vip)
+	assert.Equal(t, map[string]string{"interval": "5", "success_count": "3"}, healthChecks[0].Options)
+	assert.Equal(t, map[string]string{"10.128.0.2": "testns_pod-a:10.128.0.254"}, lb.IPPortMappings)
+
+	// services that did not opt in leave the columns alone
+	lb, healthChecks = buildLB(&LB{
+		Name:     "Service_testns/foo_TCP_cluster",
+		Protocol: "TCP",
+		Rules: []LBRule{{
+			Source:  Addr{IP: "192.168.1.1", Port: 80},
+			Targets: []Addr{{IP: "10.128.0.2", Port: 8080}},
+		}},
+	})
+	assert.Empty(t, healthChecks)
+	assert.Nil(t, lb.HealthCheck)
+	assert.Nil(t, lb.IPPortMappings)
+}
+
+func TestEnsureLBsHealthChecks(t *testing.T) {
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+	nbClient, err := libovsdb.NewNBTestHarness(libovsdb.TestSetup{NBData: []libovsdb.TestData{
+		&nbdb.LoadBalancerHealthCheck{
+			UUID:    "a4a5d2fc-814d-419e-8e31-7b5b53fd7e26",
+			Vip:     "192.168.1.1:80",
+			Options: map[string]string{"interval": "5"},
+		},
+		&nbdb.LoadBalancer{
+			UUID:        "d99fd392-4846-462e-a2d6-c4058b5c78b3",
+			Name:        "Service_testns/foo_TCP_cluster",
+			Protocol:    &nbdb.LoadBalancerProtocolTCP,
+			Vips:        map[string]string{"192.168.1.1:80": "10.128.0.2:8080"},
+			HealthCheck: []string{"a4a5d2fc-814d-419e-8e31-7b5b53fd7e26"},
+			IPPortMappings: map[string]string{
+				"10.128.0.2": "testns_pod-a:10.128.0.254",
+			},
+			ExternalIDs: map[string]string{"k8s.ovn.org/owner": "testns/foo"},
+		},
+	}}, stopChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	TestOnlySetCache(nil)
+	defer TestOnlySetCache(nil)
+
+	externalIDs := map[string]string{"k8s.ovn.org/owner": "testns/foo"}
+	lb := func(healthCheck *HealthCheck) LB {
+		return LB{
+			Name:        "Service_testns/foo_TCP_cluster",
+			Protocol:    "TCP",
+			ExternalIDs: externalIDs,
+			Rules: []LBRule{{
+				Source:      Addr{IP: "192.168.1.1", Port: 80},
+				Targets:     []Addr{{IP: "10.128.0.2", Port: 8080}},
+				HealthCheck: healthCheck,
+			}},
+			IPPortMappings: map[string]string{
+				"10.128.0.2": "testns_pod-a:10.128.0.254",
+			},
+		}
+	}
+	get := func() (*nbdb.LoadBalancer, []nbdb.LoadBalancerHealthCheck) {
+		lbs, err := libovsdbops.ListLoadBalancers(nbClient)
+		if !assert.NoError(t, err) || !assert.Len(t, lbs, 1) {
+			t.FailNow()
+		}
+		healthChecks := []nbdb.LoadBalancerHealthCheck{}
+		for _, uuid := range lbs[0].HealthCheck {
+			hc := nbdb.LoadBalancerHealthCheck{UUID: uuid}
+			ctx, cancel := context.WithTimeout(context.Background(), types.OVSDBTimeout)
+			err := nbClient.Get(ctx, &hc)
+			cancel()
+			assert.NoError(t, err)
+			healthChecks = append(healthChecks, hc)
+		}
+		return &lbs[0], healthChecks
+	}
+
+	// the existing health check is updated in place
+	err = EnsureLBs(nbClient, externalIDs, []LB{lb(&HealthCheck{Interval: 10})})
+	assert.NoError(t, err)
+	olb, healthChecks := get()
+	assert.Equal(t, []nbdb.LoadBalancerHealthCheck{{
+		UUID:    "a4a5d2fc-814d-419e-8e31-7b5b53fd7e26",
+		Vip:     "192.168.1.1:80",
+		Options: map[string]string{"interval": "10"},
+	}}, healthChecks)
+	assert.Equal(t, map[string]string{"10.128.0.2": "testns_pod-a:10.128.0.254"}, olb.IPPortMappings)
+
+	// the health checks and mappings are cleared once no longer wanted
+	err = EnsureLBs(nbClient, externalIDs, []LB{lb(nil)})
+	assert.NoError(t, err)
+	olb, healthChecks = get()
+	assert.Empty(t, healthChecks)
+	assert.Empty(t, olb.IPPortMappings)
+}
+
+func TestEnsureLBsGroups(t *testing.T) {
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+	nbClient, err := libovsdb.NewNBTestHarness(libovsdb.TestSetup{NBData: []libovsdb.TestData{
+		&nbdb.LoadBalancerGroup{
+			UUID: "clusterSwitchLBGroup",
+			Name: "clusterSwitchLBGroup",
+		},
+		&nbdb.LoadBalancerGroup{
+			UUID: "clusterRouterLBGroup",
+			Name: "clusterRouterLBGroup",
+		},
+		&nbdb.LogicalSwitch{
+			UUID: "switch-node-a",
+			Name: "switch-node-a",
+		},
+	}}, stopChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	TestOnlySetCache(nil)
+	defer TestOnlySetCache(nil)
+
+	externalIDs := map[string]string{"k8s.ovn.org/owner": "testns/foo"}
+	lb := func(groups ...string) LB {
+		return LB{
+			Name:        "Service_testns/foo_TCP_cluster",
+			Protocol:    "TCP",
+			ExternalIDs: externalIDs,
+			Rules: []LBRule{{
+				Source:  Addr{IP: "192.168.1.1", Port: 80},
+				Targets: []Addr{{IP: "10.128.0.2", Port: 8080}},
+			}},
+			Groups: groups,
+		}
+	}
+	groupMembers := func() map[string][]string {
+		groups, err := libovsdbops.ListLoadBalancerGroups(nbClient)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		members := map[string][]string{}
+		for _, group := range groups {
+			members[group.Name] = append([]string{}, group.LoadBalancer...)
+		}
+		return members
+	}
+
+	// the load balancer is added to the groups and not to any switch
+	err = EnsureLBs(nbClient, externalIDs, []LB{lb("clusterSwitchLBGroup", "clusterRouterLBGroup")})
+	assert.NoError(t, err)
+	lbs, err := libovsdbops.ListLoadBalancers(nbClient)
+	if !assert.NoError(t, err) || !assert.Len(t, lbs, 1) {
+		t.FailNow()
+	}
+	assert.Equal(t, map[string][]string{
+		"clusterSwitchLBGroup": {lbs[0].UUID},
+		"clusterRouterLBGroup": {lbs[0].UUID},
+	}, groupMembers())
+	switches, err := libovsdbops.ListSwitchesWithLoadBalancers(nbClient)
+	assert.NoError(t, err)
+	assert.Empty(t, switches)
+
+	// it is removed from the groups it no longer belongs to
+	err = EnsureLBs(nbClient, externalIDs, []LB{lb("clusterSwitchLBGroup")})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"clusterSwitchLBGroup": {lbs[0].UUID},
+		"clusterRouterLBGroup": {},
+	}, groupMembers())
+
+	// and deleting it drops it from the groups
+	err = EnsureLBs(nbClient, externalIDs, []LB{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"clusterSwitchLBGroup": {},
+		"clusterRouterLBGroup": {},
+	}, groupMembers())
+}
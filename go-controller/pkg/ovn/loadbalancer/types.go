@@ -15,6 +15,13 @@ type LB struct {
 	// the names of logical switches and routers that this LB should be attached to
 	Switches []string
 	Routers  []string
+
+	// the names of the load balancer groups that this LB should be in
+	Groups []string
+
+	// IPPortMappings maps the targets of rules with health checks to the
+	// "logical_port:source_ip" OVN probes them through
+	IPPortMappings map[string]string
 }
 
 type LBOpts struct {
@@ -24,8 +31,28 @@ type LBOpts struct {
 	// If true, then enable per-client-IP affinity.
 	Affinity bool
 
+	// If > 0, then per-client-IP affinity expires after this many seconds
+	// without traffic, rather than hashing clients to targets. Requires OVN 22.09.
+	AffinityTimeOut int32
+
+	// If set, then the fields hashed to select the target of a connection,
+	// instead of the OVN default. Ignored when Affinity is set.
+	HashFields []string
+
+	// If set, then hairpin traffic is SNATed to these IPs, at most one per
+	// IP family, instead of the vip. Requires OVN 21.03.
+	HairpinSNATIPs []string
+
 	// If true, then disable SNAT entirely
 	SkipSNAT bool
+
+	// If true, then drop traffic to vips without targets instead of
+	// rejecting it. Unidling takes precedence.
+	DropEmpty bool
+
+	// If true, then drop traffic to vips without targets and generate an
+	// empty_lb_backends controller event. Unidling and DropEmpty take precedence.
+	EmptyEvent bool
 }
 
 type Addr struct {
@@ -36,6 +63,22 @@ type Addr struct {
 type LBRule struct {
 	Source  Addr
 	Targets []Addr
+
+	// If set, then targets that fail their probes are not load balanced to
+	HealthCheck *HealthCheck
+}
+
+// HealthCheck configures OVN to probe the targets of a rule.
+// Zero values leave the OVN default in place.
+type HealthCheck struct {
+	// Interval is the number of seconds between probes
+	Interval int32
+	// Timeout is the number of seconds to wait for a probe response
+	Timeout int32
+	// SuccessCount is the number of successful probes before a target is used again
+	SuccessCount int32
+	// FailureCount is the number of failed probes before a target is no longer used
+	FailureCount int32
 }
 
 // JoinJostsPort takes a list of IPs and a port and converts it to a list of Addrs
@@ -13,6 +13,7 @@ import (
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
 	"k8s.io/klog/v2"
+	utilnet "k8s.io/utils/net"
 )
 
 // logicalSwitchInfo contains information corresponding to the node. It holds the
@@ -32,6 +33,9 @@ type LogicalSwitchManager struct {
 	// A RW mutex for LogicalSwitchManager which holds logicalSwitch information
 	sync.RWMutex
 	ipamFunc ipamFactoryFunc
+	// whether the IPAM reserves the service monitor address of the subnets,
+	// which is then never released
+	reservesServiceMonitorIP bool
 }
 
 // NewIPAMAllocator provides an ipam interface which can be used for IPAM
@@ -53,7 +57,8 @@ func NewIPAMAllocator(cidr *net.IPNet) (ipam.Interface, error) {
 }
 
 // Helper function to reserve certain subnet IPs as special
-// These are the .1, .2 and .3 addresses in particular
+// These are the .1, .2 and .3 addresses in particular, and the service
+// monitor address at the end of the subnet
 func reserveIPs(subnet *net.IPNet, ipam ipam.Interface) error {
 	gwIfAddr := util.GetNodeGatewayIfAddr(subnet)
 	err := ipam.Allocate(gwIfAddr.IP)
@@ -76,6 +81,15 @@ func reserveIPs(subnet *net.IPNet, ipam ipam.Interface) error {
 			return err
 		}
 	}
+	if config.OVNKubernetesFeature.EnableServiceHealthChecks && !utilnet.IsIPv6CIDR(subnet) {
+		svcMonitorIfAddr := util.GetNodeServiceMonitorIfAddr(subnet)
+
+		err = ipam.Allocate(svcMonitorIfAddr.IP)
+		if err != nil {
+			klog.Errorf("Unable to allocate subnet's service monitor IP: %s", svcMonitorIfAddr.IP)
+			return err
+		}
+	}
 
 	return nil
 }
@@ -83,9 +97,10 @@ func reserveIPs(subnet *net.IPNet, ipam ipam.Interface) error {
 // Initializes a new logical switch manager
 func NewLogicalSwitchManager() *LogicalSwitchManager {
 	return &LogicalSwitchManager{
-		cache:    make(map[string]logicalSwitchInfo),
-		RWMutex:  sync.RWMutex{},
-		ipamFunc: NewIPAMAllocator,
+		cache:                    make(map[string]logicalSwitchInfo),
+		RWMutex:                  sync.RWMutex{},
+		ipamFunc:                 NewIPAMAllocator,
+		reservesServiceMonitorIP: true,
 	}
 }
 
@@ -276,6 +291,13 @@ func (manager *LogicalSwitchManager) ReleaseIPs(nodeName string, ipnets []*net.I
 		for _, ipam := range lsi.ipams {
 			cidr := ipam.CIDR()
 			if cidr.Contains(ipnet.IP) {
+				if manager.isServiceMonitorIP(&cidr, ipnet.IP) {
+					// a pod got the address before it was reserved, e.g.
+					// before health checks were enabled: keep it reserved
+					// now that the pod is gone
+					klog.Warningf("Not releasing the service monitor IP %s of node %s", ipnet.IP, nodeName)
+					break
+				}
 				if err := ipam.Release(ipnet.IP); err != nil {
 					return err
 				}
@@ -286,6 +308,15 @@ func (manager *LogicalSwitchManager) ReleaseIPs(nodeName string, ipnets []*net.I
 	return nil
 }
 
+// isServiceMonitorIP returns whether ip is the reserved service monitor
+// address of subnet
+func (manager *LogicalSwitchManager) isServiceMonitorIP(subnet *net.IPNet, ip net.IP) bool {
+	if !manager.reservesServiceMonitorIP || !config.OVNKubernetesFeature.EnableServiceHealthChecks || utilnet.IsIPv6CIDR(subnet) {
+		return false
+	}
+	return util.GetNodeServiceMonitorIfAddr(subnet).IP.Equal(ip)
+}
+
 // IP allocator manager for join switch's IPv4 and IPv6 subnets.
 type JoinSwitchIPManager struct {
 	lsm            *LogicalSwitchManager
@@ -5,6 +5,7 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/ipallocator"
 	ovntest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing"
 
 	"github.com/onsi/ginkgo"
@@ -209,6 +210,28 @@ var _ = ginkgo.Describe("OVN Logical Switch Manager operations", func() {
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
 		})
 
+		ginkgo.It("keeps the service monitor IP reserved when a pod holding it is released", func() {
+			app.Action = func(ctx *cli.Context) error {
+				_, err := config.InitConfig(ctx, fexec, nil)
+				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+				config.OVNKubernetesFeature.EnableServiceHealthChecks = true
+
+				err = lsManager.AddNode("testNode1", ovntest.MustParseIPNets("10.1.1.0/24"))
+				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+				// the address of a pod started before health checks were enabled
+				err = lsManager.AllocateIPs("testNode1", ovntest.MustParseIPNets("10.1.1.254/24"))
+				gomega.Expect(err).To(gomega.MatchError(ipallocator.ErrAllocated))
+
+				err = lsManager.ReleaseIPs("testNode1", ovntest.MustParseIPNets("10.1.1.254/24"))
+				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+				err = lsManager.AllocateIPs("testNode1", ovntest.MustParseIPNets("10.1.1.254/24"))
+				gomega.Expect(err).To(gomega.MatchError(ipallocator.ErrAllocated))
+				return nil
+			}
+			err := app.Run([]string{app.Name})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		})
+
 		ginkgo.It("releases IPs for other host subnet nodes when any host subnets allocation fails", func() {
 			app.Action = func(ctx *cli.Context) error {
 				_, err := config.InitConfig(ctx, fexec, nil)
@@ -393,6 +393,24 @@ func (oc *Controller) SetupMaster(masterNodeName string, existingNodeNames []str
 		klog.Info("SCTP support detected in OVN")
 	}
 
+	// Create the load balancer groups for the cluster-wide service load balancers
+	if libovsdbops.LoadBalancerGroupsSupported(oc.nbClient) {
+		clusterSwitchLBGroup := nbdb.LoadBalancerGroup{Name: types.ClusterSwitchLBGroupName}
+		if err := libovsdbops.CreateLoadBalancerGroup(oc.nbClient, &clusterSwitchLBGroup); err != nil {
+			return fmt.Errorf("failed to create load balancer group %s: %v", clusterSwitchLBGroup.Name, err)
+		}
+		oc.clusterSwitchLBGroupUUID = clusterSwitchLBGroup.UUID
+		clusterRouterLBGroup := nbdb.LoadBalancerGroup{Name: types.ClusterRouterLBGroupName}
+		if err := libovsdbops.CreateLoadBalancerGroup(oc.nbClient, &clusterRouterLBGroup); err != nil {
+			return fmt.Errorf("failed to create load balancer group %s: %v", clusterRouterLBGroup.Name, err)
+		}
+		oc.clusterRouterLBGroupUUID = clusterRouterLBGroup.UUID
+		klog.Info("Load balancer group support detected in OVN")
+	} else {
+		klog.Warningf("Load balancer groups unsupported by this version of OVN. " +
+			"Service load balancers will be attached to every node")
+	}
+
 	// Create a cluster-wide port group that all logical switch ports are part of
 	pg := libovsdbops.BuildPortGroup(types.ClusterPortGroupName, types.ClusterPortGroupName, nil, nil)
 	err = libovsdbops.CreateOrUpdatePortGroups(oc.nbClient, pg)
@@ -582,7 +600,13 @@ func (oc *Controller) syncNodeManagementPort(node *kapi.Node, hostSubnets []*net
 		mgmtIfAddr := util.GetNodeManagementIfAddr(hostSubnet)
 		addresses += " " + mgmtIfAddr.IP.String()
 
-		if err := addAllowACLFromNode(node.Name, mgmtIfAddr.IP, oc.ovnNBClient); err != nil {
+		if config.OVNKubernetesFeature.EnableHostNetworkPolicy {
+			// traffic from the node is only allowed by policies that
+			// explicitly select host network pods
+			if err := removeAllowACLFromNode(node.Name, mgmtIfAddr.IP, oc.ovnNBClient); err != nil {
+				return err
+			}
+		} else if err := addAllowACLFromNode(node.Name, mgmtIfAddr.IP, oc.ovnNBClient); err != nil {
 			return err
 		}
 
@@ -859,6 +883,13 @@ func (oc *Controller) ensureNodeLogicalNetwork(node *kapi.Node, hostSubnets []*n
 		}
 	}
 
+	logicalSwitchUpdates := []interface{}{&logicalSwitch.OtherConfig}
+	// Attach the cluster-wide service load balancers
+	if oc.clusterSwitchLBGroupUUID != "" {
+		logicalSwitch.LoadBalancerGroup = []string{oc.clusterSwitchLBGroupUUID}
+		logicalSwitchUpdates = append(logicalSwitchUpdates, &logicalSwitch.LoadBalancerGroup)
+	}
+
 	logicalRouterPortName := types.RouterToSwitchPrefix + nodeName
 	logicalRouterPort := nbdb.LogicalRouterPort{
 		Name:     logicalRouterPortName,
@@ -888,9 +919,7 @@ func (oc *Controller) ensureNodeLogicalNetwork(node *kapi.Node, hostSubnets []*n
 		{
 			Model:          &logicalSwitch,
 			ModelPredicate: func(ls *nbdb.LogicalSwitch) bool { return ls.Name == nodeName },
-			OnModelUpdates: []interface{}{
-				&logicalSwitch.OtherConfig,
-			},
+			OnModelUpdates: logicalSwitchUpdates,
 		},
 	}
 	if _, err := oc.modelClient.CreateOrUpdate(opModels...); err != nil {
@@ -1270,6 +1299,10 @@ func (oc *Controller) deleteNode(nodeName string, hostSubnets []*net.IPNet, node
 		klog.Errorf("Failed to clean up GR LRP IPs for node %s: %v", nodeName, err)
 	}
 
+	if err := oc.deleteNodeHostNetworkAddressSet(nodeName); err != nil {
+		klog.Errorf("Failed to delete host network address set for node %s: %v", nodeName, err)
+	}
+
 	if err := oc.deleteNodeChassis(nodeName); err != nil {
 		klog.Errorf("Failed to remove the chassis associated with node %s in the OVN SB Chassis table: %v", nodeName, err)
 	}
@@ -1440,6 +1473,8 @@ func (oc *Controller) syncNodes(nodes []interface{}) {
 	}
 	metrics.RecordSubnetUsage(oc.v4HostSubnetsUsed, oc.v6HostSubnetsUsed)
 
+	oc.syncHostNetworkAddressSets(foundNodes)
+
 	// We only deal with cleaning up nodes that shouldn't exist here, since
 	// watchNodes() will be called for all existing nodes at startup anyway.
 	// Note that this list will include the 'join' cluster switch, which we
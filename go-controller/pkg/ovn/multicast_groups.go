@@ -0,0 +1,334 @@
+package ovn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/factory"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/nbdb"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/libovsdbops"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+
+	kapi "k8s.io/api/core/v1"
+	knet "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	utilnet "k8s.io/utils/net"
+)
+
+// multicastGroups is the value of the k8s.ovn.org/multicast-groups namespace
+// annotation. It allows the pods of the namespace selected by PodSelector, or
+// all of them if it is not set, to receive multicast traffic sent to the
+// Ingress groups and to send multicast traffic to the Egress groups. Groups
+// are IPv4 or IPv6 multicast addresses or CIDRs.
+type multicastGroups struct {
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+	Ingress     []string              `json:"ingress,omitempty"`
+	Egress      []string              `json:"egress,omitempty"`
+}
+
+// multicastGroupsPolicy holds the state of the multicast groups policy of a
+// namespace, made of a port group with the selected pods and the ACLs allowing
+// the groups.
+type multicastGroupsPolicy struct {
+	sync.Mutex
+	// annotation is the raw annotation the policy was built from
+	annotation    string
+	portGroupName string
+	podHandler    *factory.Handler
+	deleted       bool
+}
+
+// multicastGroupsPortGroupSuffix is appended to the namespace name to form the
+// readable name of the multicast groups port group
+const multicastGroupsPortGroupSuffix = "_multicastGroups"
+
+// getMulticastGroupsPortGroupName returns the name of the port group holding the
+// pods selected by the multicast groups policy of a namespace
+func getMulticastGroupsPortGroupName(ns string) string {
+	return hashedPortGroup(getMulticastGroupsPortGroupReadableName(ns))
+}
+
+func getMulticastGroupsPortGroupReadableName(ns string) string {
+	return ns + multicastGroupsPortGroupSuffix
+}
+
+// syncMulticastGroups deletes the multicast groups port groups, along with
+// their ACLs, of the namespaces that no longer have the multicast groups
+// annotation, e.g. because it was removed while the master was down
+func (oc *Controller) syncMulticastGroups(multicastGroupsNs map[string]bool) error {
+	stalePGs := []nbdb.PortGroup{}
+	ctx, cancel := context.WithTimeout(context.Background(), types.OVSDBTimeout)
+	defer cancel()
+	if err := oc.nbClient.WhereCache(func(pg *nbdb.PortGroup) bool {
+		name := pg.ExternalIDs["name"]
+		if !strings.HasSuffix(name, multicastGroupsPortGroupSuffix) {
+			return false
+		}
+		ns := strings.TrimSuffix(name, multicastGroupsPortGroupSuffix)
+		return pg.Name == getMulticastGroupsPortGroupName(ns) && !multicastGroupsNs[ns]
+	}).List(ctx, &stalePGs); err != nil {
+		return fmt.Errorf("failed to list multicast groups port groups: %v", err)
+	}
+	if len(stalePGs) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(stalePGs))
+	for _, pg := range stalePGs {
+		klog.Infof("Deleting stale multicast groups port group %s", pg.ExternalIDs["name"])
+		names = append(names, pg.Name)
+	}
+	return libovsdbops.DeletePortGroups(oc.nbClient, names...)
+}
+
+// parseMulticastGroupCIDR parses a multicast group, either an IP address or a
+// CIDR, and returns it as a CIDR
+func parseMulticastGroupCIDR(group string) (*net.IPNet, error) {
+	if !strings.Contains(group, "/") {
+		ip := net.ParseIP(group)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid multicast group %q", group)
+		}
+		if ip.To4() != nil {
+			group += "/32"
+		} else {
+			group += "/128"
+		}
+	}
+	ip, cidr, err := net.ParseCIDR(group)
+	if err != nil {
+		return nil, fmt.Errorf("invalid multicast group %q: %v", group, err)
+	}
+	if !ip.Equal(cidr.IP) {
+		return nil, fmt.Errorf("invalid multicast group %q: host bits set", group)
+	}
+	// the whole range must be multicast: 224.0.0.0/4 or ff00::/8
+	ones, _ := cidr.Mask.Size()
+	if !ip.IsMulticast() || (!utilnet.IsIPv6(ip) && ones < 4) || (utilnet.IsIPv6(ip) && ones < 8) {
+		return nil, fmt.Errorf("invalid multicast group %q: not a multicast range", group)
+	}
+	return cidr, nil
+}
+
+// parseMulticastGroupsAnnotation parses and validates the multicast groups
+// annotation of a namespace
+func parseMulticastGroupsAnnotation(annotation string) (*multicastGroups, error) {
+	groups := &multicastGroups{}
+	if err := json.Unmarshal([]byte(annotation), groups); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s annotation: %v", nsMulticastGroupsAnnotation, err)
+	}
+	if groups.PodSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(groups.PodSelector); err != nil {
+			return nil, fmt.Errorf("invalid pod selector in %s annotation: %v", nsMulticastGroupsAnnotation, err)
+		}
+	}
+	for _, group := range append(append([]string{}, groups.Ingress...), groups.Egress...) {
+		if _, err := parseMulticastGroupCIDR(group); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+// getMulticastGroupsMatch creates the match string used for ACLs allowing
+// multicast traffic to the given groups. IGMP and MLD are always allowed so
+// that pods can join groups and answer queries.
+func getMulticastGroupsMatch(groups []string) string {
+	var v4Groups, v6Groups []string
+	for _, group := range groups {
+		// groups were validated when the annotation was parsed
+		cidr, _ := parseMulticastGroupCIDR(group)
+		if utilnet.IsIPv6CIDR(cidr) {
+			v6Groups = append(v6Groups, cidr.String())
+		} else {
+			v4Groups = append(v4Groups, cidr.String())
+		}
+	}
+
+	var ipv4Match, ipv6Match string
+	if config.IPv4Mode {
+		ipv4Match = "igmp"
+		if len(v4Groups) > 0 {
+			ipv4Match = fmt.Sprintf("(igmp || (ip4.mcast && ip4.dst == {%s}))", strings.Join(v4Groups, ", "))
+		}
+	}
+	if config.IPv6Mode {
+		ipv6Match = "(mldv1 || mldv2)"
+		if len(v6Groups) > 0 {
+			ipv6Match = fmt.Sprintf("(mldv1 || mldv2 || (%s && ip6.dst == {%s}))",
+				ipv6DynamicMulticastMatch, strings.Join(v6Groups, ", "))
+		}
+	}
+	return getACLMatchAF(ipv4Match, ipv6Match)
+}
+
+// buildMulticastGroupsACLs builds the ACLs allowing the multicast groups of the
+// namespace for the pods in the port group
+func buildMulticastGroupsACLs(ns, portGroupName string, groups *multicastGroups) []*nbdb.ACL {
+	egressMatch := getACLMatch(portGroupName, getMulticastGroupsMatch(groups.Egress), knet.PolicyTypeEgress)
+	egressACL := buildACL(ns, portGroupName, "MulticastGroupsAllowEgress", nbdb.ACLDirectionFromLport,
+		types.DefaultMcastAllowPriority, egressMatch, nbdb.ACLActionAllow, "", knet.PolicyTypeEgress)
+	ingressMatch := getACLMatch(portGroupName, getMulticastGroupsMatch(groups.Ingress), knet.PolicyTypeIngress)
+	ingressACL := buildACL(ns, portGroupName, "MulticastGroupsAllowIngress", nbdb.ACLDirectionToLport,
+		types.DefaultMcastAllowPriority, ingressMatch, nbdb.ACLActionAllow, "", knet.PolicyTypeIngress)
+	return []*nbdb.ACL{egressACL, ingressACL}
+}
+
+// getMulticastGroupsPodPorts returns the UUIDs of the logical switch ports of
+// the given pods
+func (oc *Controller) getMulticastGroupsPodPorts(objs ...interface{}) []string {
+	ports := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		pod := obj.(*kapi.Pod)
+		if !util.PodWantsNetwork(pod) || pod.Spec.NodeName == "" {
+			continue
+		}
+		portName := util.GetLogicalPortName(pod.Namespace, pod.Name)
+		portInfo, err := oc.logicalPortCache.get(portName)
+		if err != nil {
+			// the port is added on the pod update that follows its creation
+			klog.V(5).Infof("Multicast groups: %v", err)
+			continue
+		}
+		ports = append(ports, portInfo.uuid)
+	}
+	return ports
+}
+
+// createMulticastGroupsPolicy creates the multicast groups policy of a
+// namespace and starts a pod handler that keeps its port group up to date.
+func (oc *Controller) createMulticastGroupsPolicy(ns, annotation string) (*multicastGroupsPolicy, error) {
+	groups, err := parseMulticastGroupsAnnotation(annotation)
+	if err != nil {
+		return nil, err
+	}
+
+	mgp := &multicastGroupsPolicy{
+		annotation:    annotation,
+		portGroupName: getMulticastGroupsPortGroupName(ns),
+	}
+	acls := buildMulticastGroupsACLs(ns, mgp.portGroupName, groups)
+	ops, err := libovsdbops.CreateOrUpdateACLsOps(oc.nbClient, nil, acls...)
+	if err != nil {
+		return nil, err
+	}
+	pg := libovsdbops.BuildPortGroup(mgp.portGroupName, getMulticastGroupsPortGroupReadableName(ns), nil, acls)
+	ops, err = libovsdbops.CreateOrUpdatePortGroupsOps(oc.nbClient, ops, pg)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = libovsdbops.TransactAndCheck(oc.nbClient, ops); err != nil {
+		return nil, err
+	}
+
+	sel := labels.Everything()
+	if groups.PodSelector != nil {
+		sel, _ = metav1.LabelSelectorAsSelector(groups.PodSelector)
+	}
+	addPods := func(objs ...interface{}) {
+		mgp.Lock()
+		defer mgp.Unlock()
+		if mgp.deleted {
+			return
+		}
+		if err := libovsdbops.AddPortsToPortGroup(oc.nbClient, mgp.portGroupName,
+			oc.getMulticastGroupsPodPorts(objs...)...); err != nil {
+			klog.Errorf("Failed to add pods to multicast groups port group of namespace %s: %v", ns, err)
+		}
+	}
+	mgp.podHandler = oc.watchFactory.AddFilteredPodHandler(ns, sel,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				addPods(obj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				mgp.Lock()
+				defer mgp.Unlock()
+				if mgp.deleted {
+					return
+				}
+				if err := libovsdbops.DeletePortsFromPortGroup(oc.nbClient, mgp.portGroupName,
+					oc.getMulticastGroupsPodPorts(obj)...); err != nil {
+					klog.Errorf("Failed to delete pod from multicast groups port group of namespace %s: %v", ns, err)
+				}
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				addPods(newObj)
+			},
+		}, func(objs []interface{}) {
+			addPods(objs...)
+		})
+	return mgp, nil
+}
+
+// deleteMulticastGroupsPolicy stops the pod handler of the multicast groups
+// policy of a namespace and removes its port group. Its ACLs are garbage
+// collected along with the port group.
+func (oc *Controller) deleteMulticastGroupsPolicy(mgp *multicastGroupsPolicy) error {
+	mgp.Lock()
+	mgp.deleted = true
+	mgp.Unlock()
+	oc.watchFactory.RemovePodHandler(mgp.podHandler)
+	return libovsdbops.DeletePortGroups(oc.nbClient, mgp.portGroupName)
+}
+
+// multicastGroupsUpdateNamespace creates, updates or removes the multicast
+// groups policy of a namespace from its annotation. Caller must hold the
+// namespace's namespaceInfo object lock.
+func (oc *Controller) multicastGroupsUpdateNamespace(ns *kapi.Namespace, nsInfo *namespaceInfo) {
+	if !oc.multicastSupport {
+		return
+	}
+
+	annotation := ns.Annotations[nsMulticastGroupsAnnotation]
+	if nsInfo.multicastGroups != nil && nsInfo.multicastGroups.annotation == annotation {
+		return
+	}
+	if annotation != "" {
+		// validate before tearing down the current policy
+		if _, err := parseMulticastGroupsAnnotation(annotation); err != nil {
+			klog.Warningf("Namespace %s: multicast groups are not updated due to malformed annotation: %v", ns.Name, err)
+			return
+		}
+	}
+
+	if nsInfo.multicastGroups != nil {
+		if err := oc.deleteMulticastGroupsPolicy(nsInfo.multicastGroups); err != nil {
+			klog.Errorf("Failed to delete multicast groups policy of namespace %s: %v", ns.Name, err)
+			return
+		}
+		nsInfo.multicastGroups = nil
+	}
+	if annotation == "" {
+		return
+	}
+
+	mgp, err := oc.createMulticastGroupsPolicy(ns.Name, annotation)
+	if err != nil {
+		klog.Errorf("Failed to create multicast groups policy of namespace %s: %v", ns.Name, err)
+		return
+	}
+	nsInfo.multicastGroups = mgp
+	klog.Infof("Namespace %s: multicast groups set to %s", ns.Name, annotation)
+}
+
+// multicastGroupsDeleteNamespace cleans up the multicast groups policy of a
+// namespace. Caller must hold the namespace's namespaceInfo object lock.
+func (oc *Controller) multicastGroupsDeleteNamespace(ns *kapi.Namespace, nsInfo *namespaceInfo) {
+	if nsInfo.multicastGroups == nil {
+		return
+	}
+	if err := oc.deleteMulticastGroupsPolicy(nsInfo.multicastGroups); err != nil {
+		klog.Errorf("Failed to delete multicast groups policy of namespace %s: %v", ns.Name, err)
+	}
+	nsInfo.multicastGroups = nil
+}
@@ -0,0 +1,98 @@
+package ovn
+
+import (
+	"testing"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMulticastGroupsAnnotation(t *testing.T) {
+	tests := []struct {
+		desc       string
+		annotation string
+		errExp     bool
+	}{
+		{
+			desc:       "IPv4 and IPv6 groups and CIDRs",
+			annotation: `{"podSelector": {"matchLabels": {"app": "mcast"}}, "ingress": ["239.1.1.0/24", "ff3e::8000:1"], "egress": ["239.1.1.1"]}`,
+		},
+		{
+			desc:       "no groups",
+			annotation: `{}`,
+		},
+		{
+			desc:       "malformed JSON",
+			annotation: `{"ingress": "239.1.1.1"}`,
+			errExp:     true,
+		},
+		{
+			desc:       "unicast address",
+			annotation: `{"ingress": ["10.0.0.1"]}`,
+			errExp:     true,
+		},
+		{
+			desc:       "CIDR wider than the multicast range",
+			annotation: `{"egress": ["224.0.0.0/3"]}`,
+			errExp:     true,
+		},
+		{
+			desc:       "CIDR with host bits set",
+			annotation: `{"egress": ["239.1.1.1/24"]}`,
+			errExp:     true,
+		},
+		{
+			desc:       "invalid pod selector",
+			annotation: `{"podSelector": {"matchExpressions": [{"key": "app", "operator": "Foo"}]}}`,
+			errExp:     true,
+		},
+	}
+	for _, tc := range tests {
+		_, err := parseMulticastGroupsAnnotation(tc.annotation)
+		if tc.errExp {
+			assert.Error(t, err, tc.desc)
+		} else {
+			assert.NoError(t, err, tc.desc)
+		}
+	}
+}
+
+func TestGetMulticastGroupsMatch(t *testing.T) {
+	tests := []struct {
+		desc     string
+		ipv4Mode bool
+		ipv6Mode bool
+		groups   []string
+		expMatch string
+	}{
+		{
+			desc:     "IPv4 without groups only allows IGMP",
+			ipv4Mode: true,
+			expMatch: "igmp",
+		},
+		{
+			desc:     "IPv4 groups",
+			ipv4Mode: true,
+			groups:   []string{"239.1.1.0/24", "239.2.2.2", "ff3e::8000:1"},
+			expMatch: "(igmp || (ip4.mcast && ip4.dst == {239.1.1.0/24, 239.2.2.2/32}))",
+		},
+		{
+			desc:     "IPv6 groups",
+			ipv6Mode: true,
+			groups:   []string{"239.1.1.0/24", "ff3e::8000:1"},
+			expMatch: "(mldv1 || mldv2 || (" + ipv6DynamicMulticastMatch + " && ip6.dst == {ff3e::8000:1/128}))",
+		},
+		{
+			desc:     "dual stack without IPv6 groups",
+			ipv4Mode: true,
+			ipv6Mode: true,
+			groups:   []string{"239.1.1.0/24"},
+			expMatch: "((igmp || (ip4.mcast && ip4.dst == {239.1.1.0/24})) || (mldv1 || mldv2))",
+		},
+	}
+	for _, tc := range tests {
+		config.IPv4Mode = tc.ipv4Mode
+		config.IPv6Mode = tc.ipv6Mode
+		assert.Equal(t, tc.expMatch, getMulticastGroupsMatch(tc.groups), tc.desc)
+	}
+}
@@ -0,0 +1,269 @@
+package ovn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ovn-org/libovsdb/ovsdb"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/metrics"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/nbdb"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/sbdb"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// multicastGroupsUpdateInterval is how often the multicast group membership
+// metrics are refreshed from the southbound database
+const multicastGroupsUpdateInterval = 30 * time.Second
+
+// multicastGroupMember is a port that joined a multicast group. Namespace and
+// Pod are only set for pod logical switch ports.
+type multicastGroupMember struct {
+	Port      string `json:"port"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Chassis   string `json:"chassis,omitempty"`
+}
+
+// multicastGroupMembership lists the ports of a logical switch that joined a
+// multicast group, as learnt by IGMP/MLD snooping on all chassis
+type multicastGroupMembership struct {
+	Group   string                 `json:"group"`
+	Switch  string                 `json:"switch"`
+	Members []multicastGroupMember `json:"members"`
+}
+
+// getMulticastGroupMembers resolves the port bindings of an IGMP_Group row to
+// group members, the namespace and pod of pod logical switch ports coming from
+// their external IDs
+func getMulticastGroupMembers(group *sbdb.IGMPGroup, portBindings map[string]string,
+	switchPorts map[string]*nbdb.LogicalSwitchPort, chassis string) []multicastGroupMember {
+	members := make([]multicastGroupMember, 0, len(group.Ports))
+	for _, portUUID := range group.Ports {
+		member := multicastGroupMember{Port: portUUID, Chassis: chassis}
+		if portName, ok := portBindings[portUUID]; ok {
+			member.Port = portName
+			if lsp := switchPorts[portName]; lsp != nil && lsp.ExternalIDs["pod"] == "true" {
+				member.Namespace = lsp.ExternalIDs["namespace"]
+				member.Pod = strings.TrimPrefix(portName, member.Namespace+"_")
+			}
+		}
+		members = append(members, member)
+	}
+	return members
+}
+
+// selectSBRows returns the given columns of the rows of an SB table with the
+// given UUIDs, by UUID. The datapath and port bindings are not monitored, as
+// only those of the multicast group members are needed.
+func (oc *Controller) selectSBRows(ctx context.Context, table string, uuids map[string]bool, columns ...string) (map[string]ovsdb.Row, error) {
+	rows := make(map[string]ovsdb.Row, len(uuids))
+	if len(uuids) == 0 {
+		return rows, nil
+	}
+	ids := make([]string, 0, len(uuids))
+	ops := make([]ovsdb.Operation, 0, len(uuids))
+	for uuid := range uuids {
+		ids = append(ids, uuid)
+		ops = append(ops, ovsdb.Operation{
+			Op:      ovsdb.OperationSelect,
+			Table:   table,
+			Where:   []ovsdb.Condition{ovsdb.NewCondition("_uuid", ovsdb.ConditionEqual, ovsdb.UUID{GoUUID: uuid})},
+			Columns: columns,
+		})
+	}
+	results, err := oc.sbClient.Transact(ctx, ops...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select %s rows: %v", table, err)
+	}
+	for i, result := range results {
+		if result.Error != "" {
+			return nil, fmt.Errorf("failed to select %s rows: %s: %s", table, result.Error, result.Details)
+		}
+		if i < len(ids) && len(result.Rows) == 1 {
+			rows[ids[i]] = result.Rows[0]
+		}
+	}
+	return rows, nil
+}
+
+// getMulticastGroupMemberships returns the multicast groups joined by logical
+// switch ports, merging the IGMP_Group rows of all chassis
+func (oc *Controller) getMulticastGroupMemberships() ([]multicastGroupMembership, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), types.OVSDBTimeout)
+	defer cancel()
+
+	igmpGroups := []sbdb.IGMPGroup{}
+	if err := oc.sbClient.List(ctx, &igmpGroups); err != nil {
+		return nil, fmt.Errorf("failed to list IGMP groups: %v", err)
+	}
+	chassisList := []sbdb.Chassis{}
+	if err := oc.sbClient.List(ctx, &chassisList); err != nil {
+		return nil, fmt.Errorf("failed to list chassis: %v", err)
+	}
+
+	datapathUUIDs := map[string]bool{}
+	portUUIDs := map[string]bool{}
+	for _, group := range igmpGroups {
+		if group.Datapath != nil {
+			datapathUUIDs[*group.Datapath] = true
+		}
+		for _, port := range group.Ports {
+			portUUIDs[port] = true
+		}
+	}
+	datapathRows, err := oc.selectSBRows(ctx, "Datapath_Binding", datapathUUIDs, "external_ids")
+	if err != nil {
+		return nil, err
+	}
+	portRows, err := oc.selectSBRows(ctx, "Port_Binding", portUUIDs, "logical_port")
+	if err != nil {
+		return nil, err
+	}
+
+	switches := make(map[string]string, len(datapathRows))
+	for uuid, row := range datapathRows {
+		if externalIDs, ok := row["external_ids"].(ovsdb.OvsMap); ok {
+			if name, ok := externalIDs.GoMap["name"].(string); ok {
+				switches[uuid] = name
+			}
+		}
+	}
+	portBindings := make(map[string]string, len(portRows))
+	portNames := make(map[string]bool, len(portRows))
+	for uuid, row := range portRows {
+		if name, ok := row["logical_port"].(string); ok {
+			portBindings[uuid] = name
+			portNames[name] = true
+		}
+	}
+	lsps := []nbdb.LogicalSwitchPort{}
+	if err := oc.nbClient.WhereCache(func(lsp *nbdb.LogicalSwitchPort) bool {
+		return portNames[lsp.Name]
+	}).List(ctx, &lsps); err != nil {
+		return nil, fmt.Errorf("failed to list the logical switch ports of the group members: %v", err)
+	}
+	switchPorts := make(map[string]*nbdb.LogicalSwitchPort, len(lsps))
+	for i := range lsps {
+		switchPorts[lsps[i].Name] = &lsps[i]
+	}
+	chassisNames := make(map[string]string, len(chassisList))
+	for _, chassis := range chassisList {
+		chassisNames[chassis.UUID] = chassis.Hostname
+		if chassis.Hostname == "" {
+			chassisNames[chassis.UUID] = chassis.Name
+		}
+	}
+
+	memberships := map[string]*multicastGroupMembership{}
+	for i := range igmpGroups {
+		group := &igmpGroups[i]
+		var switchName, chassis string
+		if group.Datapath != nil {
+			switchName = switches[*group.Datapath]
+		}
+		if group.Chassis != nil {
+			chassis = chassisNames[*group.Chassis]
+		}
+		key := switchName + "/" + group.Address
+		membership, ok := memberships[key]
+		if !ok {
+			membership = &multicastGroupMembership{Group: group.Address, Switch: switchName}
+			memberships[key] = membership
+		}
+		membership.Members = append(membership.Members, getMulticastGroupMembers(group, portBindings, switchPorts, chassis)...)
+	}
+
+	result := make([]multicastGroupMembership, 0, len(memberships))
+	for _, membership := range memberships {
+		sort.Slice(membership.Members, func(i, j int) bool {
+			return membership.Members[i].Port < membership.Members[j].Port
+		})
+		result = append(result, *membership)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Switch != result[j].Switch {
+			return result[i].Switch < result[j].Switch
+		}
+		return result[i].Group < result[j].Group
+	})
+	return result, nil
+}
+
+// updateMulticastGroupMetrics records the number of pods of each namespace that
+// joined each multicast group
+func (oc *Controller) updateMulticastGroupMetrics() {
+	memberships, err := oc.getMulticastGroupMemberships()
+	if err != nil {
+		klog.Errorf("Failed to update multicast group metrics: %v", err)
+		return
+	}
+	members := map[string]map[string]int{}
+	groups := map[string]bool{}
+	for _, membership := range memberships {
+		if len(membership.Members) > 0 {
+			groups[membership.Group] = true
+		}
+		for _, member := range membership.Members {
+			if member.Namespace == "" {
+				continue
+			}
+			if members[member.Namespace] == nil {
+				members[member.Namespace] = map[string]int{}
+			}
+			members[member.Namespace][membership.Group]++
+		}
+	}
+	metrics.RecordMulticastGroupMembers(members, len(groups))
+}
+
+// serveMulticastGroups lists the current multicast groups and their members
+func (oc *Controller) serveMulticastGroups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	memberships, err := oc.getMulticastGroupMemberships()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if namespace := r.URL.Query().Get("namespace"); namespace != "" {
+		filtered := []multicastGroupMembership{}
+		for _, membership := range memberships {
+			members := []multicastGroupMember{}
+			for _, member := range membership.Members {
+				if member.Namespace == namespace {
+					members = append(members, member)
+				}
+			}
+			if len(members) > 0 {
+				membership.Members = members
+				filtered = append(filtered, membership)
+			}
+		}
+		memberships = filtered
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(memberships); err != nil {
+		klog.Errorf("Failed to write multicast groups: %v", err)
+	}
+}
+
+// startMulticastGroupsMonitor periodically exports the multicast group
+// membership as metrics and serves it on the metrics server at
+// /debug/ovnkube/multicast-groups
+func (oc *Controller) startMulticastGroupsMonitor() {
+	if !oc.multicastSupport {
+		return
+	}
+	metrics.RegisterDebugHandler("multicast-groups", http.HandlerFunc(oc.serveMulticastGroups))
+	go wait.Until(oc.updateMulticastGroupMetrics, multicastGroupsUpdateInterval, oc.stopChan)
+}
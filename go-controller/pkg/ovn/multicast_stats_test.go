@@ -0,0 +1,112 @@
+package ovn
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/nbdb"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/sbdb"
+	libovsdbtest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing/libovsdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMulticastGroupMemberships(t *testing.T) {
+	config.PrepareTestConfig()
+	config.EnableMulticast = true
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+
+	node1Chassis := "node1-chassis-uuid"
+	node2Chassis := "node2-chassis-uuid"
+	node1Switch := "node1-datapath-uuid"
+	node2Switch := "node2-datapath-uuid"
+	nbClient, sbClient, err := libovsdbtest.NewNBSBTestHarness(libovsdbtest.TestSetup{
+		NBData: []libovsdbtest.TestData{
+			&nbdb.LogicalSwitchPort{Name: "ns1_pod1", ExternalIDs: map[string]string{"pod": "true", "namespace": "ns1"}},
+			&nbdb.LogicalSwitchPort{Name: "ns2_pod2", ExternalIDs: map[string]string{"pod": "true", "namespace": "ns2"}},
+			&nbdb.LogicalSwitchPort{Name: "ns1_pod3", ExternalIDs: map[string]string{"pod": "true", "namespace": "ns1"}},
+			&nbdb.LogicalSwitchPort{Name: "stor-node1", Type: "router"},
+		},
+		SBData: []libovsdbtest.TestData{
+			&sbdb.Chassis{UUID: node1Chassis, Name: "chassis1", Hostname: "node1"},
+			&sbdb.Chassis{UUID: node2Chassis, Name: "chassis2"},
+			&sbdb.DatapathBinding{UUID: node1Switch, ExternalIDs: map[string]string{"name": "node1"}},
+			&sbdb.DatapathBinding{UUID: node2Switch, ExternalIDs: map[string]string{"name": "node2"}},
+			&sbdb.PortBinding{UUID: "pod1-port-uuid", LogicalPort: "ns1_pod1", Datapath: node1Switch},
+			&sbdb.PortBinding{UUID: "pod2-port-uuid", LogicalPort: "ns2_pod2", Datapath: node1Switch},
+			&sbdb.PortBinding{UUID: "pod3-port-uuid", LogicalPort: "ns1_pod3", Datapath: node2Switch},
+			&sbdb.PortBinding{UUID: "router-port-uuid", LogicalPort: "stor-node1", Datapath: node1Switch},
+			&sbdb.IGMPGroup{
+				Address:  "239.1.1.1",
+				Chassis:  &node1Chassis,
+				Datapath: &node1Switch,
+				Ports:    []string{"pod2-port-uuid", "pod1-port-uuid"},
+			},
+			&sbdb.IGMPGroup{
+				Address:  "239.1.1.1",
+				Chassis:  &node2Chassis,
+				Datapath: &node2Switch,
+				Ports:    []string{"pod3-port-uuid"},
+			},
+			&sbdb.IGMPGroup{
+				Address:  "ff3e::8000:1",
+				Chassis:  &node1Chassis,
+				Datapath: &node1Switch,
+				Ports:    []string{"pod1-port-uuid", "router-port-uuid"},
+			},
+		},
+	}, stopChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oc := &Controller{nbClient: nbClient, sbClient: sbClient, multicastSupport: true}
+	memberships, err := oc.getMulticastGroupMemberships()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []multicastGroupMembership{
+		{
+			Group:  "239.1.1.1",
+			Switch: "node1",
+			Members: []multicastGroupMember{
+				{Port: "ns1_pod1", Namespace: "ns1", Pod: "pod1", Chassis: "node1"},
+				{Port: "ns2_pod2", Namespace: "ns2", Pod: "pod2", Chassis: "node1"},
+			},
+		},
+		{
+			Group:  "ff3e::8000:1",
+			Switch: "node1",
+			Members: []multicastGroupMember{
+				{Port: "ns1_pod1", Namespace: "ns1", Pod: "pod1", Chassis: "node1"},
+				{Port: "stor-node1", Chassis: "node1"},
+			},
+		},
+		{
+			Group:  "239.1.1.1",
+			Switch: "node2",
+			Members: []multicastGroupMember{
+				{Port: "ns1_pod3", Namespace: "ns1", Pod: "pod3", Chassis: "chassis2"},
+			},
+		},
+	}, memberships)
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/ovnkube/multicast-groups?namespace=ns2", nil)
+	rec := httptest.NewRecorder()
+	oc.serveMulticastGroups(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	filtered := []multicastGroupMembership{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &filtered))
+	assert.Equal(t, []multicastGroupMembership{
+		{
+			Group:  "239.1.1.1",
+			Switch: "node1",
+			Members: []multicastGroupMember{
+				{Port: "ns2_pod2", Namespace: "ns2", Pod: "pod2", Chassis: "node1"},
+			},
+		},
+	}, filtered)
+}
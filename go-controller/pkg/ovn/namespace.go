@@ -18,17 +18,24 @@ import (
 
 const (
 	// Annotation used to enable/disable multicast in the namespace
-	nsMulticastAnnotation        = "k8s.ovn.org/multicast-enabled"
+	nsMulticastAnnotation = "k8s.ovn.org/multicast-enabled"
+	// Annotation used to allow multicast groups for selected pods in the namespace
+	nsMulticastGroupsAnnotation  = "k8s.ovn.org/multicast-groups"
 	routingExternalGWsAnnotation = "k8s.ovn.org/routing-external-gws"
 	routingNamespaceAnnotation   = "k8s.ovn.org/routing-namespaces"
 	routingNetworkAnnotation     = "k8s.ovn.org/routing-network"
 	bfdAnnotation                = "k8s.ovn.org/bfd-enabled"
+	// Annotation used to select the node egress interface of the pods of the namespace
+	egressInterfaceAnnotation = "k8s.ovn.org/egress-interface"
+	// Annotation used to disable the per pod SNAT of the namespace, when the node subnets are advertised over BGP
+	routedEgressAnnotation = "k8s.ovn.org/routed-egress"
 	// Annotation for enabling ACL logging to controller's log file
 	aclLoggingAnnotation = "k8s.ovn.org/acl-logging"
 )
 
 func (oc *Controller) syncNamespaces(namespaces []interface{}) {
 	expectedNs := make(map[string]bool)
+	multicastGroupsNs := make(map[string]bool)
 	for _, nsInterface := range namespaces {
 		ns, ok := nsInterface.(*kapi.Namespace)
 		if !ok {
@@ -36,6 +43,13 @@ func (oc *Controller) syncNamespaces(namespaces []interface{}) {
 			continue
 		}
 		expectedNs[ns.Name] = true
+		if oc.multicastSupport && ns.Annotations[nsMulticastGroupsAnnotation] != "" {
+			multicastGroupsNs[ns.Name] = true
+		}
+	}
+
+	if err := oc.syncMulticastGroups(multicastGroupsNs); err != nil {
+		klog.Errorf("Error in syncing multicast groups: %v", err)
 	}
 
 	err := oc.addressSetFactory.ProcessEachAddressSet(func(addrSetName, namespaceName, nameSuffix string) {
@@ -76,20 +90,20 @@ func (oc *Controller) getRoutingPodGWs(nsInfo *namespaceInfo) map[string]*gatewa
 }
 
 // addPodToNamespace adds the pod's IP to the namespace's address set and returns
-// pod's routing gateway info
-func (oc *Controller) addPodToNamespace(ns string, ips []*net.IPNet) (*gatewayInfo, map[string]*gatewayInfo, error) {
+// pod's routing gateway info, and whether the pod egresses without SNAT
+func (oc *Controller) addPodToNamespace(ns string, ips []*net.IPNet) (*gatewayInfo, map[string]*gatewayInfo, string, bool, error) {
 	nsInfo, nsUnlock, err := oc.ensureNamespaceLocked(ns, true, nil)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to ensure namespace locked: %v", err)
+		return nil, nil, "", false, fmt.Errorf("failed to ensure namespace locked: %v", err)
 	}
 
 	defer nsUnlock()
 
 	if err := nsInfo.addressSet.AddIPs(createIPAddressSlice(ips)); err != nil {
-		return nil, nil, err
+		return nil, nil, "", false, err
 	}
 
-	return oc.getRoutingExternalGWs(nsInfo), oc.getRoutingPodGWs(nsInfo), nil
+	return oc.getRoutingExternalGWs(nsInfo), oc.getRoutingPodGWs(nsInfo), nsInfo.egressInterface, nsInfo.routedEgress, nil
 }
 
 func (oc *Controller) deletePodFromNamespace(ns string, portInfo *lpInfo) error {
@@ -214,6 +228,13 @@ func (oc *Controller) configureNamespace(nsInfo *namespaceInfo, ns *kapi.Namespa
 		}
 	}
 
+	nsInfo.egressInterface = ns.Annotations[egressInterfaceAnnotation]
+	if egressInterface := nsInfo.activeEgressInterface(); egressInterface != "" {
+		if err := oc.addEgressInterfaceRoutesForNamespace(ns.Name, egressInterface); err != nil {
+			klog.Error(err.Error())
+		}
+	}
+
 	annotation := ns.Annotations[aclLoggingAnnotation]
 	if annotation != "" {
 		if oc.aclLoggingCanEnable(annotation, nsInfo) {
@@ -230,6 +251,8 @@ func (oc *Controller) configureNamespace(nsInfo *namespaceInfo, ns *kapi.Namespa
 
 	// If multicast enabled, adds all current pods in the namespace to the allow policy
 	oc.multicastUpdateNamespace(ns, nsInfo)
+	oc.multicastGroupsUpdateNamespace(ns, nsInfo)
+	oc.routedEgressUpdateNamespace(ns, nsInfo)
 }
 
 func (oc *Controller) updateNamespace(old, newer *kapi.Namespace) {
@@ -242,6 +265,7 @@ func (oc *Controller) updateNamespace(old, newer *kapi.Namespace) {
 	}
 	defer nsUnlock()
 
+	oldEgressInterface := nsInfo.activeEgressInterface()
 	gwAnnotation := newer.Annotations[routingExternalGWsAnnotation]
 	oldGWAnnotation := old.Annotations[routingExternalGWsAnnotation]
 	_, newBFDEnabled := newer.Annotations[bfdAnnotation]
@@ -280,7 +304,8 @@ func (oc *Controller) updateNamespace(old, newer *kapi.Namespace) {
 		}
 		// if new annotation is empty, exgws were removed, may need to add SNAT per pod
 		// check if there are any pod gateways serving this namespace as well
-		if gwAnnotation == "" && len(nsInfo.routingExternalPodGWs) == 0 && config.Gateway.DisableSNATMultipleGWs {
+		if gwAnnotation == "" && len(nsInfo.routingExternalPodGWs) == 0 && config.Gateway.DisableSNATMultipleGWs &&
+			!nsInfo.routedEgress {
 			existingPods, err := oc.watchFactory.GetPods(old.Name)
 			if err != nil {
 				klog.Errorf("Failed to get all the pods (%v)", err)
@@ -297,6 +322,21 @@ func (oc *Controller) updateNamespace(old, newer *kapi.Namespace) {
 			}
 		}
 	}
+	nsInfo.egressInterface = newer.Annotations[egressInterfaceAnnotation]
+	if egressInterface := nsInfo.activeEgressInterface(); egressInterface != oldEgressInterface {
+		if egressInterface != "" {
+			if err := oc.addEgressInterfaceRoutesForNamespace(old.Name, egressInterface); err != nil {
+				klog.Error(err.Error())
+			}
+		} else {
+			// the pods go back to the gateway interface, or to the external
+			// gateways of the namespace which already replaced their SNAT
+			restorePerPodSNAT := config.Gateway.DisableSNATMultipleGWs && gwAnnotation == "" &&
+				len(nsInfo.routingExternalPodGWs) == 0 && !nsInfo.routedEgress
+			oc.deleteEgressInterfaceRoutesForNamespace(old.Name, restorePerPodSNAT)
+		}
+	}
+
 	aclAnnotation := newer.Annotations[aclLoggingAnnotation]
 	oldACLAnnotation := old.Annotations[aclLoggingAnnotation]
 	// support for ACL logging update, if new annotation is empty, make sure we propagate new setting
@@ -311,6 +351,8 @@ func (oc *Controller) updateNamespace(old, newer *kapi.Namespace) {
 		}
 	}
 	oc.multicastUpdateNamespace(newer, nsInfo)
+	oc.multicastGroupsUpdateNamespace(newer, nsInfo)
+	oc.routedEgressUpdateNamespace(newer, nsInfo)
 }
 
 func (oc *Controller) deleteNamespace(ns *kapi.Namespace) {
@@ -329,6 +371,7 @@ func (oc *Controller) deleteNamespace(ns *kapi.Namespace) {
 	}
 	oc.deleteGWRoutesForNamespace(ns.Name)
 	oc.multicastDeleteNamespace(ns, nsInfo)
+	oc.multicastGroupsDeleteNamespace(ns, nsInfo)
 }
 
 // getNamespaceLocked locks namespacesMutex, looks up ns, and (if found), returns it with
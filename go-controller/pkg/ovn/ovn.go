@@ -24,6 +24,7 @@ import (
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/metrics"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/nbdb"
 	addressset "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/address_set"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/controller/loadbalancerip"
 	svccontroller "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/controller/services"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/controller/unidling"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/ipallocator"
@@ -91,8 +92,20 @@ type namespaceInfo struct {
 	// exgw IPs
 	routingExternalPodGWs map[string]gatewayInfo
 
+	// egressInterface is the name of the node egress interface the pods of
+	// the namespace egress through, from annotation k8s.ovn.org/egress-interface
+	egressInterface string
+
+	// routedEgress is true if the pods of the namespace egress the gateway
+	// interface without SNAT, from annotation k8s.ovn.org/routed-egress
+	routedEgress bool
+
 	multicastEnabled bool
 
+	// multicastGroups is the multicast groups policy built from annotation
+	// k8s.ovn.org/multicast-groups, or nil if the annotation is not set
+	multicastGroups *multicastGroupsPolicy
+
 	// If not empty, then it has to be set to a logging a severity level, e.g. "notice", "alert", etc
 	aclLogging ACLLoggingLevels
 
@@ -119,6 +132,11 @@ type Controller struct {
 
 	SCTPSupport bool
 
+	// UUIDs of the load balancer groups the cluster-wide service load
+	// balancers are attached through. Empty if OVN doesn't support them.
+	clusterSwitchLBGroupUUID string
+	clusterRouterLBGroupUUID string
+
 	// For TCP, UDP, and SCTP type traffic, cache OVN load-balancers used for the
 	// cluster's east-west traffic.
 	loadbalancerClusterCache map[kapi.Protocol]string
@@ -156,6 +174,11 @@ type Controller struct {
 	// A mutex for lspIngressDenyCache and lspEgressDenyCache
 	lspMutex *sync.Mutex
 
+	// Address sets shared by the network policy peers with identical selectors,
+	// keyed by selector. sharedPeerAddressSetsMutex must be held to access the map.
+	sharedPeerAddressSets      map[string]*sharedPeerAddressSet
+	sharedPeerAddressSetsMutex sync.Mutex
+
 	// Supports multicast?
 	multicastSupport bool
 
@@ -270,6 +293,7 @@ func NewOvnController(ovnClient *util.OVNClientset, wf *factory.WatchFactory, st
 		lspIngressDenyCache:       make(map[string]int),
 		lspEgressDenyCache:        make(map[string]int),
 		lspMutex:                  &sync.Mutex{},
+		sharedPeerAddressSets:     make(map[string]*sharedPeerAddressSet),
 		eIPC: egressIPController{
 			assignmentRetryMutex:  &sync.Mutex{},
 			assignmentRetry:       make(map[string]bool),
@@ -353,6 +377,8 @@ func (oc *Controller) Run(wg *sync.WaitGroup, nodeName string) error {
 
 	klog.Infof("Completing all the Watchers took %v", time.Since(start))
 
+	oc.startMulticastGroupsMonitor()
+
 	if config.Kubernetes.OVNEmptyLbEvents {
 		klog.Infof("Starting unidling controller")
 		unidlingController := unidling.NewController(
@@ -367,6 +393,24 @@ func (oc *Controller) Run(wg *sync.WaitGroup, nodeName string) error {
 		}()
 	}
 
+	if len(config.Kubernetes.LoadBalancerIPPools) > 0 {
+		klog.Infof("Starting load balancer IP allocator")
+		lbIPController, err := loadbalancerip.NewController(
+			oc.client,
+			oc.recorder,
+			oc.watchFactory.ServiceInformer(),
+			config.Kubernetes.LoadBalancerIPPools,
+		)
+		if err != nil {
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lbIPController.Run(oc.stopChan)
+		}()
+	}
+
 	if oc.hoMaster != nil {
 		wg.Add(1)
 		go func() {
@@ -984,6 +1028,13 @@ func (oc *Controller) WatchNamespaces() {
 
 // syncNodeGateway ensures a node's gateway router is configured
 func (oc *Controller) syncNodeGateway(node *kapi.Node, hostSubnets []*net.IPNet) error {
+	// the node is tearing down its gateway of the previous mode; its gateway
+	// router is reprogrammed once the gateway of the new mode is up
+	if util.NodeGatewayModeMigrating(node) {
+		klog.Infof("Node %s is switching its gateway mode, waiting for it to finish before configuring its gateway", node.Name)
+		return nil
+	}
+
 	l3GatewayConfig, err := util.ParseNodeL3GatewayAnnotation(node)
 	if err != nil {
 		return err
@@ -1025,6 +1076,7 @@ func (oc *Controller) WatchNodes() {
 	var mgmtPortFailed sync.Map
 	var addNodeFailed sync.Map
 	var nodeClusterRouterPortFailed sync.Map
+	var hostNetworkAddressSetFailed sync.Map
 
 	start := time.Now()
 	oc.watchFactory.AddNodeHandler(cache.ResourceEventHandlerFuncs{
@@ -1070,6 +1122,13 @@ func (oc *Controller) WatchNodes() {
 				gatewaysFailed.Store(node.Name, true)
 			}
 
+			if config.OVNKubernetesFeature.EnableHostNetworkPolicy {
+				if err := oc.syncNodeHostNetworkAddressSet(node, hostSubnets); err != nil {
+					klog.Errorf("Error creating host network address set for node %s: %v", node.Name, err)
+					hostNetworkAddressSetFailed.Store(node.Name, true)
+				}
+			}
+
 			// ensure pods that already exist on this node have their logical ports created
 			options := metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node.Name).String()}
 			pods, err := oc.client.CoreV1().Pods(metav1.NamespaceAll).List(context.TODO(), options)
@@ -1147,6 +1206,20 @@ func (oc *Controller) WatchNodes() {
 					gatewaysFailed.Store(node.Name, true)
 				} else {
 					gatewaysFailed.Delete(node.Name)
+					if failed || egressInterfacesChanged(oldNode, node) {
+						oc.syncEgressInterfacePodsOnNode(node.Name)
+					}
+				}
+			}
+
+			_, failed = hostNetworkAddressSetFailed.Load(node.Name)
+			if config.OVNKubernetesFeature.EnableHostNetworkPolicy &&
+				(failed || nodeSubnetChanged(oldNode, node) || hostAddressesChanged(oldNode, node)) {
+				if err := oc.syncNodeHostNetworkAddressSet(node, hostSubnets); err != nil {
+					klog.Errorf("Error updating host network address set for node %s: %v", node.Name, err)
+					hostNetworkAddressSetFailed.Store(node.Name, true)
+				} else {
+					hostNetworkAddressSetFailed.Delete(node.Name)
 				}
 			}
 		},
@@ -1163,6 +1236,7 @@ func (oc *Controller) WatchNodes() {
 			mgmtPortFailed.Delete(node.Name)
 			gatewaysFailed.Delete(node.Name)
 			nodeClusterRouterPortFailed.Delete(node.Name)
+			hostNetworkAddressSetFailed.Delete(node.Name)
 		},
 	}, oc.syncNodes)
 	klog.Infof("Bootstrapping existing nodes and cleaning stale nodes took %v", time.Since(start))
@@ -1211,7 +1285,7 @@ func (oc *Controller) aclLoggingCanEnable(annotation string, nsInfo *namespaceIn
 func gatewayChanged(oldNode, newNode *kapi.Node) bool {
 	oldL3GatewayConfig, _ := util.ParseNodeL3GatewayAnnotation(oldNode)
 	l3GatewayConfig, _ := util.ParseNodeL3GatewayAnnotation(newNode)
-	return !reflect.DeepEqual(oldL3GatewayConfig, l3GatewayConfig)
+	return !reflect.DeepEqual(oldL3GatewayConfig, l3GatewayConfig) || util.NodeGatewayModeMigrationChanged(oldNode, newNode)
 }
 
 // hostAddressesChanged compares old annotations to new and returns true if the something has changed.
@@ -153,6 +153,7 @@ func (oc *Controller) deleteLogicalPort(pod *kapi.Pod) {
 	}
 	podNsName := ktypes.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
 	oc.deleteGWRoutesForPod(podNsName, portInfo.ips)
+	oc.deleteEgressInterfaceRoutesForPod(pod.Spec.NodeName, portInfo.ips, "")
 
 	oc.logicalPortCache.remove(logicalPort)
 }
@@ -438,7 +439,7 @@ func (oc *Controller) addLogicalPort(pod *kapi.Pod) (err error) {
 	}
 
 	// Ensure the namespace/nsInfo exists
-	routingExternalGWs, routingPodGWs, err := oc.addPodToNamespace(pod.Namespace, podIfAddrs)
+	routingExternalGWs, routingPodGWs, egressInterface, routedEgress, err := oc.addPodToNamespace(pod.Namespace, podIfAddrs)
 	if err != nil {
 		return err
 	}
@@ -457,15 +458,26 @@ func (oc *Controller) addLogicalPort(pod *kapi.Pod) (err error) {
 		}
 	}
 
+	// external gateways take precedence over the egress interface of the
+	// namespace
+	egressInterfaceRouted := false
+	if len(gateways) == 0 && egressInterface != "" {
+		egressInterfaceRouted, err = oc.addEgressInterfaceRoutesForPod(pod, podIfAddrs, egressInterface)
+		if err != nil {
+			return err
+		}
+	}
+
 	if len(gateways) > 0 {
 		podNsName := ktypes.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
 		err = oc.addGWRoutesForPod(gateways, podIfAddrs, podNsName, pod.Spec.NodeName)
 		if err != nil {
 			return err
 		}
-	} else if config.Gateway.DisableSNATMultipleGWs {
+	} else if config.Gateway.DisableSNATMultipleGWs && !egressInterfaceRouted && !routedEgress {
 		// Add NAT rules to pods if disable SNAT is set and does not have
-		// namespace annotations to go through external egress router
+		// namespace annotations to go through external egress router, or to
+		// egress with the pod IP
 		if err = oc.addPerPodGRSNAT(pod, podIfAddrs); err != nil {
 			return err
 		}
@@ -108,6 +108,7 @@ func hashedPortGroup(s string) string {
 
 func (oc *Controller) syncNetworkPolicies(networkPolicies []interface{}) {
 	expectedPolicies := make(map[string]map[string]bool)
+	policies := make([]*knet.NetworkPolicy, 0, len(networkPolicies))
 	for _, npInterface := range networkPolicies {
 		policy, ok := npInterface.(*knet.NetworkPolicy)
 		if !ok {
@@ -115,6 +116,7 @@ func (oc *Controller) syncNetworkPolicies(networkPolicies []interface{}) {
 				npInterface)
 			continue
 		}
+		policies = append(policies, policy)
 
 		if nsMap, ok := expectedPolicies[policy.Namespace]; ok {
 			nsMap[policy.Name] = true
@@ -127,6 +129,10 @@ func (oc *Controller) syncNetworkPolicies(networkPolicies []interface{}) {
 
 	stalePGs := []string{}
 	err := oc.addressSetFactory.ProcessEachAddressSet(func(addrSetName, namespaceName, policyName string) {
+		if namespaceName == sharedPeerAddressSetPrefix || namespaceName == hostNetworkAddressSetPrefix {
+			// shared peer and host network address sets are synced separately
+			return
+		}
 		if policyName != "" && !expectedPolicies[namespaceName][policyName] {
 			// policy doesn't exist on k8s. Delete the port group
 			portGroupName := fmt.Sprintf("%s_%s", namespaceName, policyName)
@@ -141,6 +147,7 @@ func (oc *Controller) syncNetworkPolicies(networkPolicies []interface{}) {
 	if err != nil {
 		klog.Errorf("Error in syncing network policies: %v", err)
 	}
+	oc.syncSharedPeerAddressSets(policies)
 
 	if len(stalePGs) > 0 {
 		err = libovsdbops.DeletePortGroups(oc.nbClient, stalePGs...)
@@ -553,6 +560,20 @@ func podDeleteAllowMulticastPolicy(nbClient libovsdbclient.Client, ns string, po
 	return libovsdbops.DeletePortsFromPortGroup(nbClient, hashedPortGroup(ns), portInfo.uuid)
 }
 
+// hasIngressDefaultDeny returns whether the pods selected by the policy get a
+// default ingress deny rule: that is the case unless the policy is egress only.
+func hasIngressDefaultDeny(policy *knet.NetworkPolicy) bool {
+	return !(len(policy.Spec.PolicyTypes) == 1 && policy.Spec.PolicyTypes[0] == knet.PolicyTypeEgress)
+}
+
+// hasEgressDefaultDeny returns whether the pods selected by the policy get a
+// default egress deny rule: that is the case if the policy has egress rules or
+// its PolicyTypes include egress.
+func hasEgressDefaultDeny(policy *knet.NetworkPolicy) bool {
+	return (len(policy.Spec.PolicyTypes) == 1 && policy.Spec.PolicyTypes[0] == knet.PolicyTypeEgress) ||
+		len(policy.Spec.Egress) > 0 || len(policy.Spec.PolicyTypes) == 2
+}
+
 // localPodAddDefaultDeny ensures ports (i.e. pods) are in the correct
 // default-deny portgroups. Whether or not pods are in default-deny depends
 // on whether or not any policies select this pod, so there is a reference
@@ -576,7 +597,7 @@ func (oc *Controller) localPodAddDefaultDeny(policy *knet.NetworkPolicy,
 	egressDenyPorts = []string{}
 
 	// Handle condition 1 above.
-	if !(len(policy.Spec.PolicyTypes) == 1 && policy.Spec.PolicyTypes[0] == knet.PolicyTypeEgress) {
+	if hasIngressDefaultDeny(policy) {
 		for _, portInfo := range ports {
 			// if this is the first NP referencing this pod, then we
 			// need to add it to the port group.
@@ -590,8 +611,7 @@ func (oc *Controller) localPodAddDefaultDeny(policy *knet.NetworkPolicy,
 	}
 
 	// Handle condition 2 above.
-	if (len(policy.Spec.PolicyTypes) == 1 && policy.Spec.PolicyTypes[0] == knet.PolicyTypeEgress) ||
-		len(policy.Spec.Egress) > 0 || len(policy.Spec.PolicyTypes) == 2 {
+	if hasEgressDefaultDeny(policy) {
 		for _, portInfo := range ports {
 			if oc.lspEgressDenyCache[portInfo.name] == 0 {
 				// again, reference count is 0, so add to port
@@ -851,6 +871,9 @@ func (oc *Controller) createNetworkPolicy(np *networkPolicy, policy *knet.Networ
 		podSelector       *metav1.LabelSelector
 	}
 	var policyHandlers []policyHandler
+	// ingress rules that reference the shared address set of the services in
+	// the policy namespace
+	var sharedServiceGresses []*gressPolicy
 	// Go through each ingress rule.  For each ingress rule, create an
 	// addressSet for the peer pods.
 	for i, ingressJSON := range policy.Spec.Ingress {
@@ -865,12 +888,16 @@ func (oc *Controller) createNetworkPolicy(np *networkPolicy, policy *knet.Networ
 
 		if hasAnyLabelSelector(ingressJSON.From) {
 			klog.V(5).Infof("Network policy %s with ingress rule %s has a selector", policy.Name, ingress.policyName)
-			if err := ingress.ensurePeerAddressSet(oc.addressSetFactory); err != nil {
-				klog.Errorf(err.Error())
-				continue
+			if config.OVNKubernetesFeature.EnableSharedPeerAddressSets {
+				sharedServiceGresses = append(sharedServiceGresses, ingress)
+			} else {
+				if err := ingress.ensurePeerAddressSet(oc.addressSetFactory); err != nil {
+					klog.Errorf(err.Error())
+					continue
+				}
+				// Start service handlers ONLY if there's an ingress Address Set
+				oc.handlePeerService(policy, ingress, np)
 			}
-			// Start service handlers ONLY if there's an ingress Address Set
-			oc.handlePeerService(policy, ingress, np)
 		}
 
 		for _, fromJSON := range ingressJSON.From {
@@ -902,9 +929,11 @@ func (oc *Controller) createNetworkPolicy(np *networkPolicy, policy *knet.Networ
 
 		if hasAnyLabelSelector(egressJSON.To) {
 			klog.V(5).Infof("Network policy %s with egress rule %s has a selector", policy.Name, egress.policyName)
-			if err := egress.ensurePeerAddressSet(oc.addressSetFactory); err != nil {
-				klog.Errorf(err.Error())
-				continue
+			if !config.OVNKubernetesFeature.EnableSharedPeerAddressSets {
+				if err := egress.ensurePeerAddressSet(oc.addressSetFactory); err != nil {
+					klog.Errorf(err.Error())
+					continue
+				}
 			}
 		}
 
@@ -924,8 +953,19 @@ func (oc *Controller) createNetworkPolicy(np *networkPolicy, policy *knet.Networ
 	}
 	np.Unlock()
 
+	for _, gress := range sharedServiceGresses {
+		oc.addSharedPeerAddressSetToGress(np, gress, getSharedPeerServicesKey(policy.Namespace),
+			oc.startSharedPeerServices(policy.Namespace))
+	}
+
 	for _, handler := range policyHandlers {
-		if handler.namespaceSelector != nil && handler.podSelector != nil {
+		if config.OVNKubernetesFeature.EnableSharedPeerAddressSets && handler.podSelector != nil {
+			// Peers with a pod selector share a single address set with all
+			// the peers in the cluster using the same selectors
+			key := getSharedPeerPodsKey(policy.Namespace, handler.namespaceSelector, handler.podSelector)
+			oc.addSharedPeerAddressSetToGress(np, handler.gress, key,
+				oc.startSharedPeerPodSelector(policy.Namespace, handler.namespaceSelector, handler.podSelector))
+		} else if handler.namespaceSelector != nil && handler.podSelector != nil {
 			// For each rule that contains both peer namespace selector and
 			// peer pod selector, we create a watcher for each matching namespace
 			// that populates the addressSet
@@ -1130,39 +1170,61 @@ func (oc *Controller) destroyNetworkPolicy(np *networkPolicy, nsInfo *namespaceI
 		if err := policy.destroy(); err != nil {
 			klog.Errorf(err.Error())
 		}
+		oc.releaseGressSharedPeerAddressSets(policy)
 	}
 	for _, policy := range np.egressPolicies {
 		if err := policy.destroy(); err != nil {
 			klog.Errorf(err.Error())
 		}
+		oc.releaseGressSharedPeerAddressSets(policy)
 	}
 }
 
 // handlePeerPodSelectorAddUpdate adds the IP address of a pod that has been
 // selected as a peer by a NetworkPolicy's ingress/egress section to that
 // ingress/egress address set
-func (oc *Controller) handlePeerPodSelectorAddUpdate(gp *gressPolicy, objs ...interface{}) {
+func (oc *Controller) handlePeerPodSelectorAddUpdate(np *networkPolicy, gp *gressPolicy, objs ...interface{}) {
 	pods := make([]*kapi.Pod, 0, len(objs))
+	var hostNetworkPods []*kapi.Pod
 	for _, obj := range objs {
 		pod := obj.(*kapi.Pod)
 		if pod.Spec.NodeName == "" {
 			continue
 		}
+		if config.OVNKubernetesFeature.EnableHostNetworkPolicy && pod.Spec.HostNetwork {
+			hostNetworkPods = append(hostNetworkPods, pod)
+			continue
+		}
 		pods = append(pods, pod)
 	}
 	if err := gp.addPeerPods(pods...); err != nil {
 		klog.Errorf(err.Error())
 	}
+	if len(hostNetworkPods) > 0 {
+		oc.handlePeerHostNetworkPods(np, gp, func() bool {
+			changed := false
+			for _, pod := range hostNetworkPods {
+				changed = gp.addHostNetworkPeerPod(pod) || changed
+			}
+			return changed
+		})
+	}
 }
 
 // handlePeerPodSelectorDelete removes the IP address of a pod that no longer
 // matches a NetworkPolicy ingress/egress section's selectors from that
 // ingress/egress address set
-func (oc *Controller) handlePeerPodSelectorDelete(gp *gressPolicy, obj interface{}) {
+func (oc *Controller) handlePeerPodSelectorDelete(np *networkPolicy, gp *gressPolicy, obj interface{}) {
 	pod := obj.(*kapi.Pod)
 	if pod.Spec.NodeName == "" {
 		return
 	}
+	if config.OVNKubernetesFeature.EnableHostNetworkPolicy && pod.Spec.HostNetwork {
+		oc.handlePeerHostNetworkPods(np, gp, func() bool {
+			return gp.delHostNetworkPeerPod(pod)
+		})
+		return
+	}
 	if err := gp.deletePeerPod(pod); err != nil {
 		klog.Errorf(err.Error())
 	}
@@ -1233,16 +1295,16 @@ func (oc *Controller) handlePeerPodSelector(
 	h := oc.watchFactory.AddFilteredPodHandler(policy.Namespace, sel,
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
-				oc.handlePeerPodSelectorAddUpdate(gp, obj)
+				oc.handlePeerPodSelectorAddUpdate(np, gp, obj)
 			},
 			DeleteFunc: func(obj interface{}) {
-				oc.handlePeerPodSelectorDelete(gp, obj)
+				oc.handlePeerPodSelectorDelete(np, gp, obj)
 			},
 			UpdateFunc: func(oldObj, newObj interface{}) {
-				oc.handlePeerPodSelectorAddUpdate(gp, newObj)
+				oc.handlePeerPodSelectorAddUpdate(np, gp, newObj)
 			},
 		}, func(objs []interface{}) {
-			oc.handlePeerPodSelectorAddUpdate(gp, objs...)
+			oc.handlePeerPodSelectorAddUpdate(np, gp, objs...)
 		})
 	np.podHandlerList = append(np.podHandlerList, h)
 }
@@ -1273,16 +1335,16 @@ func (oc *Controller) handlePeerNamespaceAndPodSelector(
 				podHandler := oc.watchFactory.AddFilteredPodHandler(namespace.Name, podSel,
 					cache.ResourceEventHandlerFuncs{
 						AddFunc: func(obj interface{}) {
-							oc.handlePeerPodSelectorAddUpdate(gp, obj)
+							oc.handlePeerPodSelectorAddUpdate(np, gp, obj)
 						},
 						DeleteFunc: func(obj interface{}) {
-							oc.handlePeerPodSelectorDelete(gp, obj)
+							oc.handlePeerPodSelectorDelete(np, gp, obj)
 						},
 						UpdateFunc: func(oldObj, newObj interface{}) {
-							oc.handlePeerPodSelectorAddUpdate(gp, newObj)
+							oc.handlePeerPodSelectorAddUpdate(np, gp, newObj)
 						},
 					}, func(objs []interface{}) {
-						oc.handlePeerPodSelectorAddUpdate(gp, objs...)
+						oc.handlePeerPodSelectorAddUpdate(np, gp, objs...)
 					})
 				np.Lock()
 				defer np.Unlock()
@@ -1299,7 +1361,7 @@ func (oc *Controller) handlePeerNamespaceAndPodSelector(
 				pods, _ := oc.watchFactory.GetPods(namespace.Name)
 
 				for _, pod := range pods {
-					oc.handlePeerPodSelectorDelete(gp, pod)
+					oc.handlePeerPodSelectorDelete(np, gp, pod)
 				}
 
 			},
@@ -0,0 +1,343 @@
+package ovn
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+
+	kapi "k8s.io/api/core/v1"
+	knet "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PolicyEndpoint is one end of a connection evaluated by the
+// NetworkPolicyEvaluator. Either Pod or IP must be set; when only IP is set the
+// evaluator looks for a pod with that IP, and otherwise considers the endpoint
+// to be outside of the cluster.
+type PolicyEndpoint struct {
+	Pod *kapi.Pod
+	IP  net.IP
+}
+
+// PolicyConnection describes a connection from Src to Dst
+type PolicyConnection struct {
+	Src      PolicyEndpoint
+	Dst      PolicyEndpoint
+	Protocol kapi.Protocol
+	Port     int32
+}
+
+// PolicyRuleVerdict is the verdict of a single NetworkPolicy for a connection
+type PolicyRuleVerdict struct {
+	Namespace  string
+	Name       string
+	PolicyType knet.PolicyType
+	// Rule is the index of the first rule of the policy allowing the
+	// connection, or -1 if none of the rules does
+	Rule int
+}
+
+// PolicyDirectionVerdict is the verdict for one direction (egress from the
+// source pod, or ingress to the destination pod) of a connection
+type PolicyDirectionVerdict struct {
+	// Isolated is set when at least one policy selects the pod for this
+	// direction. Pods that are not isolated allow all traffic.
+	Isolated bool
+	Allowed  bool
+	// Policies lists all the policies selecting the pod for this direction
+	Policies []PolicyRuleVerdict
+}
+
+// PolicyVerdict is the verdict of the NetworkPolicyEvaluator for a connection
+type PolicyVerdict struct {
+	Allowed bool
+	Egress  PolicyDirectionVerdict
+	Ingress PolicyDirectionVerdict
+}
+
+// String returns a human readable description of the verdict
+func (v *PolicyVerdict) String() string {
+	var sb strings.Builder
+	verdict := func(allowed bool) string {
+		if allowed {
+			return "ALLOWED"
+		}
+		return "DENIED"
+	}
+	fmt.Fprintf(&sb, "Connection %s\n", verdict(v.Allowed))
+	for _, dv := range []PolicyDirectionVerdict{v.Egress, v.Ingress} {
+		if !dv.Isolated {
+			continue
+		}
+		for _, pv := range dv.Policies {
+			if pv.Rule >= 0 {
+				fmt.Fprintf(&sb, "  %s %s/%s: allowed by rule %d\n", pv.PolicyType, pv.Namespace, pv.Name, pv.Rule)
+			} else {
+				fmt.Fprintf(&sb, "  %s %s/%s: no rule matches\n", pv.PolicyType, pv.Namespace, pv.Name)
+			}
+		}
+	}
+	fmt.Fprintf(&sb, "  Egress: %s", verdict(v.Egress.Allowed))
+	if !v.Egress.Isolated {
+		sb.WriteString(" (source not isolated)")
+	}
+	fmt.Fprintf(&sb, "\n  Ingress: %s", verdict(v.Ingress.Allowed))
+	if !v.Ingress.Isolated {
+		sb.WriteString(" (destination not isolated)")
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// evaluatorGressPolicy is a gressPolicy along with the peers of its rule,
+// which ovnkube-master tracks through address sets
+type evaluatorGressPolicy struct {
+	*gressPolicy
+	peers []knet.NetworkPolicyPeer
+}
+
+// NetworkPolicyEvaluator computes offline whether NetworkPolicies allow a
+// connection, using the same matching logic the 'gress policies use to build
+// OVN ACLs, without any access to OVN.
+type NetworkPolicyEvaluator struct {
+	namespaces map[string]*kapi.Namespace
+	pods       []*kapi.Pod
+	policies   []*knet.NetworkPolicy
+}
+
+// NewNetworkPolicyEvaluator returns an evaluator for the given objects
+func NewNetworkPolicyEvaluator(namespaces []*kapi.Namespace, pods []*kapi.Pod,
+	policies []*knet.NetworkPolicy) *NetworkPolicyEvaluator {
+	e := &NetworkPolicyEvaluator{
+		namespaces: make(map[string]*kapi.Namespace, len(namespaces)),
+		pods:       pods,
+		policies:   policies,
+	}
+	for _, ns := range namespaces {
+		e.namespaces[ns.Name] = ns
+	}
+	return e
+}
+
+// GetPod returns the pod with the given namespace and name
+func (e *NetworkPolicyEvaluator) GetPod(namespace, name string) (*kapi.Pod, error) {
+	for _, pod := range e.pods {
+		if pod.Namespace == namespace && pod.Name == name {
+			return pod, nil
+		}
+	}
+	return nil, fmt.Errorf("pod %s/%s not found", namespace, name)
+}
+
+// Evaluate returns the verdict of the NetworkPolicies for the connection
+func (e *NetworkPolicyEvaluator) Evaluate(conn *PolicyConnection) (*PolicyVerdict, error) {
+	src, srcIPs, err := e.resolveEndpoint(&conn.Src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source: %v", err)
+	}
+	dst, dstIPs, err := e.resolveEndpoint(&conn.Dst)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination: %v", err)
+	}
+	protocol := conn.Protocol
+	if protocol == "" {
+		protocol = kapi.ProtocolTCP
+	}
+
+	verdict := &PolicyVerdict{
+		Egress:  e.evaluateDirection(knet.PolicyTypeEgress, src, dst, dstIPs, protocol, conn.Port),
+		Ingress: e.evaluateDirection(knet.PolicyTypeIngress, dst, src, srcIPs, protocol, conn.Port),
+	}
+	verdict.Allowed = verdict.Egress.Allowed && verdict.Ingress.Allowed
+	return verdict, nil
+}
+
+// resolveEndpoint returns the pod and IPs of the endpoint
+func (e *NetworkPolicyEvaluator) resolveEndpoint(ep *PolicyEndpoint) (*kapi.Pod, []net.IP, error) {
+	if ep.Pod != nil {
+		ips, err := util.GetAllPodIPs(ep.Pod)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ep.Pod, ips, nil
+	}
+	if ep.IP == nil {
+		return nil, nil, fmt.Errorf("either a pod or an IP must be given")
+	}
+	for _, pod := range e.pods {
+		if pod.Spec.HostNetwork {
+			continue
+		}
+		ips, err := util.GetAllPodIPs(pod)
+		if err != nil {
+			continue
+		}
+		for _, ip := range ips {
+			if ip.Equal(ep.IP) {
+				return pod, []net.IP{ep.IP}, nil
+			}
+		}
+	}
+	return nil, []net.IP{ep.IP}, nil
+}
+
+// evaluateDirection evaluates the policies selecting pod for the given policy
+// type against the peer of the connection
+func (e *NetworkPolicyEvaluator) evaluateDirection(policyType knet.PolicyType, pod, peer *kapi.Pod,
+	peerIPs []net.IP, protocol kapi.Protocol, port int32) PolicyDirectionVerdict {
+	dv := PolicyDirectionVerdict{Allowed: true}
+	// Host network pods and endpoints outside of the cluster have no
+	// logical switch port, so no ACL ever applies to them
+	if pod == nil || pod.Spec.HostNetwork {
+		return dv
+	}
+
+	for _, policy := range e.policies {
+		if policy.Namespace != pod.Namespace {
+			continue
+		}
+		if policyType == knet.PolicyTypeIngress && !hasIngressDefaultDeny(policy) {
+			continue
+		}
+		if policyType == knet.PolicyTypeEgress && !hasEgressDefaultDeny(policy) {
+			continue
+		}
+		// NetworkPolicy is validated by the apiserver; this can't fail.
+		sel, _ := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+		if !sel.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+
+		dv.Isolated = true
+		pv := PolicyRuleVerdict{
+			Namespace:  policy.Namespace,
+			Name:       policy.Name,
+			PolicyType: policyType,
+			Rule:       -1,
+		}
+		for _, gp := range buildEvaluatorGressPolicies(policy, policyType) {
+			if e.gressPolicyMatches(gp, peer, peerIPs, protocol, port) {
+				pv.Rule = gp.idx
+				break
+			}
+		}
+		dv.Policies = append(dv.Policies, pv)
+	}
+	if dv.Isolated {
+		dv.Allowed = false
+		for _, pv := range dv.Policies {
+			if pv.Rule >= 0 {
+				dv.Allowed = true
+				break
+			}
+		}
+	}
+	return dv
+}
+
+// buildEvaluatorGressPolicies builds the 'gress policies for the rules of the
+// policy of the given type, the same way createNetworkPolicy does
+func buildEvaluatorGressPolicies(policy *knet.NetworkPolicy, policyType knet.PolicyType) []*evaluatorGressPolicy {
+	gps := []*evaluatorGressPolicy{}
+	addRule := func(idx int, ports []knet.NetworkPolicyPort, peers []knet.NetworkPolicyPeer) {
+		gp := &evaluatorGressPolicy{
+			gressPolicy: newGressPolicy(policyType, idx, policy.Namespace, policy.Name),
+			peers:       peers,
+		}
+		for _, portJSON := range ports {
+			if portJSON.Protocol == nil {
+				// the apiserver defaults the protocol, objects loaded from
+				// files may not have it set
+				tcp := kapi.ProtocolTCP
+				portJSON.Protocol = &tcp
+			}
+			gp.addPortPolicy(&portJSON)
+		}
+		for _, peer := range peers {
+			if peer.IPBlock != nil {
+				gp.addIPBlock(peer.IPBlock)
+			}
+		}
+		gps = append(gps, gp)
+	}
+
+	if policyType == knet.PolicyTypeIngress {
+		for i, ingressJSON := range policy.Spec.Ingress {
+			addRule(i, ingressJSON.Ports, ingressJSON.From)
+		}
+	} else {
+		for i, egressJSON := range policy.Spec.Egress {
+			addRule(i, egressJSON.Ports, egressJSON.To)
+		}
+	}
+	return gps
+}
+
+// gressPolicyMatches returns whether the 'gress policy allows the connection
+// with the peer, mirroring the ACLs built by buildLocalPodACLs
+func (e *NetworkPolicyEvaluator) gressPolicyMatches(gp *evaluatorGressPolicy, peer *kapi.Pod,
+	peerIPs []net.IP, protocol kapi.Protocol, port int32) bool {
+	if len(gp.portPolicies) > 0 {
+		portMatches := false
+		for _, pp := range gp.portPolicies {
+			if pp.matchesPort(string(protocol), port) {
+				portMatches = true
+				break
+			}
+		}
+		if !portMatches {
+			return false
+		}
+	}
+
+	for _, ip := range peerIPs {
+		if gp.ipBlockMatches(ip) {
+			return true
+		}
+	}
+
+	if !hasAnyLabelSelector(gp.peers) {
+		// if the NetworkPolicyPeer is empty, then allow from all sources or
+		// to all destinations
+		return len(gp.ipBlock) == 0
+	}
+	if peer == nil || peer.Spec.NodeName == "" {
+		return false
+	}
+	for _, p := range gp.peers {
+		if e.peerSelectsPod(gp.policyNamespace, &p, peer) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerSelectsPod returns whether the pod selector and/or namespace selector of
+// the peer select the pod
+func (e *NetworkPolicyEvaluator) peerSelectsPod(policyNamespace string, peer *knet.NetworkPolicyPeer, pod *kapi.Pod) bool {
+	if peer.PodSelector == nil && peer.NamespaceSelector == nil {
+		return false
+	}
+	if peer.NamespaceSelector != nil {
+		ns, ok := e.namespaces[pod.Namespace]
+		if !ok {
+			return false
+		}
+		nsSel, _ := metav1.LabelSelectorAsSelector(peer.NamespaceSelector)
+		if !nsSel.Matches(labels.Set(ns.Labels)) {
+			return false
+		}
+		if peer.PodSelector == nil {
+			// namespace address sets only hold the IPs of pods with a
+			// logical switch port
+			return !pod.Spec.HostNetwork
+		}
+	} else if pod.Namespace != policyNamespace {
+		return false
+	}
+	podSel, _ := metav1.LabelSelectorAsSelector(peer.PodSelector)
+	return podSel.Matches(labels.Set(pod.Labels))
+}
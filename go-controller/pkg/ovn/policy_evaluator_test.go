@@ -0,0 +1,170 @@
+package ovn
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	kapi "k8s.io/api/core/v1"
+	knet "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func newEvaluatorPod(namespace, name, ip string, labels map[string]string) *kapi.Pod {
+	return &kapi.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    labels,
+		},
+		Spec: kapi.PodSpec{
+			NodeName: "node1",
+		},
+		Status: kapi.PodStatus{
+			PodIP:  ip,
+			PodIPs: []kapi.PodIP{{IP: ip}},
+		},
+	}
+}
+
+func TestNetworkPolicyEvaluator(t *testing.T) {
+	tcp := kapi.ProtocolTCP
+	port80 := intstr.FromInt(80)
+
+	namespaces := []*kapi.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "ns1", Labels: map[string]string{"team": "a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "ns2", Labels: map[string]string{"team": "b"}}},
+	}
+	server := newEvaluatorPod("ns1", "server", "10.128.1.3", map[string]string{"app": "server"})
+	client := newEvaluatorPod("ns1", "client", "10.128.1.4", map[string]string{"app": "client"})
+	other := newEvaluatorPod("ns2", "other", "10.128.2.3", map[string]string{"app": "client"})
+	pods := []*kapi.Pod{server, client, other}
+
+	allowClientsOn80 := &knet.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "allow-clients"},
+		Spec: knet.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "server"}},
+			Ingress: []knet.NetworkPolicyIngressRule{{
+				Ports: []knet.NetworkPolicyPort{{Protocol: &tcp, Port: &port80}},
+				From: []knet.NetworkPolicyPeer{{
+					PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "client"}},
+				}},
+			}},
+		},
+	}
+	allowTeamBFromCIDR := &knet.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "allow-team-b"},
+		Spec: knet.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "server"}},
+			Ingress: []knet.NetworkPolicyIngressRule{
+				{
+					From: []knet.NetworkPolicyPeer{{
+						IPBlock: &knet.IPBlock{CIDR: "192.168.0.0/16", Except: []string{"192.168.1.0/24"}},
+					}},
+				},
+				{
+					From: []knet.NetworkPolicyPeer{{
+						NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}},
+					}},
+				},
+			},
+		},
+	}
+	denyEgress := &knet.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "deny-egress"},
+		Spec: knet.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "client"}},
+			PolicyTypes: []knet.PolicyType{knet.PolicyTypeEgress},
+		},
+	}
+
+	testcases := []struct {
+		desc     string
+		policies []*knet.NetworkPolicy
+		conn     PolicyConnection
+		allowed  bool
+		rule     int
+	}{
+		{
+			desc:    "no policies allow everything",
+			conn:    PolicyConnection{Src: PolicyEndpoint{Pod: client}, Dst: PolicyEndpoint{Pod: server}, Port: 8080},
+			allowed: true,
+			rule:    -1,
+		},
+		{
+			desc:     "pod selector and port match",
+			policies: []*knet.NetworkPolicy{allowClientsOn80},
+			conn:     PolicyConnection{Src: PolicyEndpoint{Pod: client}, Dst: PolicyEndpoint{Pod: server}, Port: 80},
+			allowed:  true,
+			rule:     0,
+		},
+		{
+			desc:     "port does not match",
+			policies: []*knet.NetworkPolicy{allowClientsOn80},
+			conn:     PolicyConnection{Src: PolicyEndpoint{Pod: client}, Dst: PolicyEndpoint{Pod: server}, Port: 8080},
+			allowed:  false,
+			rule:     -1,
+		},
+		{
+			desc:     "protocol does not match",
+			policies: []*knet.NetworkPolicy{allowClientsOn80},
+			conn: PolicyConnection{Src: PolicyEndpoint{Pod: client}, Dst: PolicyEndpoint{Pod: server},
+				Protocol: kapi.ProtocolUDP, Port: 80},
+			allowed: false,
+			rule:    -1,
+		},
+		{
+			desc:     "pod selector only selects pods in the policy namespace",
+			policies: []*knet.NetworkPolicy{allowClientsOn80},
+			conn:     PolicyConnection{Src: PolicyEndpoint{Pod: other}, Dst: PolicyEndpoint{Pod: server}, Port: 80},
+			allowed:  false,
+			rule:     -1,
+		},
+		{
+			desc:     "namespace selector match",
+			policies: []*knet.NetworkPolicy{allowTeamBFromCIDR},
+			conn:     PolicyConnection{Src: PolicyEndpoint{IP: net.ParseIP("10.128.2.3")}, Dst: PolicyEndpoint{Pod: server}},
+			allowed:  true,
+			rule:     1,
+		},
+		{
+			desc:     "ipBlock match",
+			policies: []*knet.NetworkPolicy{allowTeamBFromCIDR},
+			conn:     PolicyConnection{Src: PolicyEndpoint{IP: net.ParseIP("192.168.2.1")}, Dst: PolicyEndpoint{Pod: server}},
+			allowed:  true,
+			rule:     0,
+		},
+		{
+			desc:     "ipBlock except",
+			policies: []*knet.NetworkPolicy{allowTeamBFromCIDR},
+			conn:     PolicyConnection{Src: PolicyEndpoint{IP: net.ParseIP("192.168.1.1")}, Dst: PolicyEndpoint{Pod: server}},
+			allowed:  false,
+			rule:     -1,
+		},
+		{
+			desc:     "any policy allowing the connection is enough",
+			policies: []*knet.NetworkPolicy{allowClientsOn80, allowTeamBFromCIDR},
+			conn:     PolicyConnection{Src: PolicyEndpoint{Pod: other}, Dst: PolicyEndpoint{Pod: server}, Port: 80},
+			allowed:  true,
+			rule:     -1,
+		},
+		{
+			desc:     "egress default deny",
+			policies: []*knet.NetworkPolicy{allowClientsOn80, denyEgress},
+			conn:     PolicyConnection{Src: PolicyEndpoint{Pod: client}, Dst: PolicyEndpoint{Pod: server}, Port: 80},
+			allowed:  false,
+			rule:     0,
+		},
+	}
+
+	for _, tc := range testcases {
+		e := NewNetworkPolicyEvaluator(namespaces, pods, tc.policies)
+		verdict, err := e.Evaluate(&tc.conn)
+		assert.NoError(t, err, tc.desc)
+		assert.Equal(t, tc.allowed, verdict.Allowed, tc.desc)
+		if len(verdict.Ingress.Policies) > 0 {
+			assert.Equal(t, tc.rule, verdict.Ingress.Policies[0].Rule, tc.desc)
+		}
+	}
+}
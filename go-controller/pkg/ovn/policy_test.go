@@ -2,6 +2,7 @@ package ovn
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strconv"
@@ -412,6 +413,28 @@ func (p multicastPolicy) getMulticastPolicyExpectedData(ns string, ports []strin
 	}
 }
 
+func (p multicastPolicy) getMulticastGroupsExpectedData(ns string, groups *multicastGroups, ports []string) []libovsdb.TestData {
+	pgName := getMulticastGroupsPortGroupName(ns)
+	acls := buildMulticastGroupsACLs(ns, pgName, groups)
+	for _, acl := range acls {
+		acl.UUID = libovsdbops.BuildNamedUUID()
+	}
+
+	lsps := []*nbdb.LogicalSwitchPort{}
+	for _, uuid := range ports {
+		lsps = append(lsps, &nbdb.LogicalSwitchPort{UUID: uuid})
+	}
+
+	pg := libovsdbops.BuildPortGroup(pgName, getMulticastGroupsPortGroupReadableName(ns), lsps, acls)
+	pg.UUID = libovsdbops.BuildNamedUUID()
+
+	return []libovsdb.TestData{
+		acls[0],
+		acls[1],
+		pg,
+	}
+}
+
 var _ = ginkgo.Describe("OVN NetworkPolicy Operations with IP Address Family", func() {
 	const (
 		namespaceName1 = "namespace1"
@@ -675,7 +698,145 @@ var _ = ginkgo.Describe("OVN NetworkPolicy Operations with IP Address Family", f
 				err := app.Run([]string{app.Name})
 				gomega.Expect(err).NotTo(gomega.HaveOccurred())
 			})
+
+			ginkgo.It("tests allowing multicast groups for selected pods in a namespace "+ipModeStr(m), func() {
+				app.Action = func(ctx *cli.Context) error {
+					namespace1 := *newNamespace(namespaceName1)
+					nPodTestV4 := newTPod(
+						"node1",
+						"10.128.1.0/24",
+						"10.128.1.2",
+						"10.128.1.1",
+						"myPod1",
+						"10.128.1.3",
+						"0a:58:0a:80:01:03",
+						namespace1.Name,
+					)
+					nPodTestV6 := newTPod(
+						"node1",
+						"fd00:10:244::/64",
+						"fd00:10:244::2",
+						"fd00:10:244::1",
+						"myPod2",
+						"fd00:10:244::3",
+						"0a:58:dd:33:05:d8",
+						namespace1.Name,
+					)
+					var tPods []testPod
+					if m.IPv4Mode {
+						tPods = append(tPods, nPodTestV4)
+					}
+					if m.IPv6Mode {
+						tPods = append(tPods, nPodTestV6)
+					}
+
+					var pods []v1.Pod
+					ports := []string{}
+					for _, tPod := range tPods {
+						pod := newPod(tPod.namespace, tPod.podName, tPod.nodeName, tPod.podIP)
+						pod.Labels = map[string]string{"app": "mcast"}
+						pods = append(pods, *pod)
+						ports = append(ports, tPod.portUUID)
+					}
+
+					fakeOvn.startWithDBSetup(ctx, initialDB,
+						&v1.NamespaceList{
+							Items: []v1.Namespace{
+								namespace1,
+							},
+						},
+						&v1.PodList{
+							Items: pods,
+						},
+					)
+					setIpMode(m)
+
+					for _, tPod := range tPods {
+						tPod.populateLogicalSwitchCache(fakeOvn)
+					}
+
+					fakeOvn.controller.WatchNamespaces()
+					fakeOvn.controller.WatchPods()
+					ns, err := fakeOvn.fakeClient.KubeClient.CoreV1().Namespaces().Get(context.TODO(), namespace1.Name, metav1.GetOptions{})
+					gomega.Expect(err).NotTo(gomega.HaveOccurred())
+					gomega.Expect(ns).NotTo(gomega.BeNil())
+
+					// Allow multicast groups for the selected pods.
+					groups := &multicastGroups{
+						PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "mcast"}},
+						Ingress:     []string{"239.1.1.0/24", "ff3e::8000:1"},
+						Egress:      []string{"239.1.1.1"},
+					}
+					annotation, err := json.Marshal(groups)
+					gomega.Expect(err).NotTo(gomega.HaveOccurred())
+					mcastPolicy := multicastPolicy{}
+					expectedData := mcastPolicy.getMulticastGroupsExpectedData(namespace1.Name, groups, ports)
+					expectedData = append(expectedData, getExpectedDataPodsAndSwitches(tPods, []string{"node1"})...)
+					ns.Annotations[nsMulticastGroupsAnnotation] = string(annotation)
+					_, err = fakeOvn.fakeClient.KubeClient.CoreV1().Namespaces().Update(context.TODO(), ns, metav1.UpdateOptions{})
+					gomega.Expect(err).NotTo(gomega.HaveOccurred())
+					gomega.Eventually(fakeOvn.nbClient).Should(libovsdb.HaveData(expectedData...))
+
+					// A malformed annotation leaves the policy untouched.
+					ns.Annotations[nsMulticastGroupsAnnotation] = `{"ingress": ["10.0.0.1"]}`
+					_, err = fakeOvn.fakeClient.KubeClient.CoreV1().Namespaces().Update(context.TODO(), ns, metav1.UpdateOptions{})
+					gomega.Expect(err).NotTo(gomega.HaveOccurred())
+					gomega.Consistently(fakeOvn.nbClient).Should(libovsdb.HaveData(expectedData...))
+
+					// Remove the multicast groups policy.
+					delete(ns.Annotations, nsMulticastGroupsAnnotation)
+					_, err = fakeOvn.fakeClient.KubeClient.CoreV1().Namespaces().Update(context.TODO(), ns, metav1.UpdateOptions{})
+					gomega.Expect(err).NotTo(gomega.HaveOccurred())
+					acls := []libovsdb.TestData{expectedData[0], expectedData[1]}
+					acls = append(acls, getExpectedDataPodsAndSwitches(tPods, []string{"node1"})...)
+					gomega.Eventually(fakeOvn.nbClient).Should(libovsdb.HaveData(acls...))
+					return nil
+				}
+
+				err := app.Run([]string{app.Name})
+				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			})
 		}
+
+		ginkgo.It("deletes the multicast groups port groups of namespaces without the annotation on startup", func() {
+			app.Action = func(ctx *cli.Context) error {
+				namespace1 := *newNamespace(namespaceName1)
+				namespace2 := *newNamespace(namespaceName2)
+				groups := &multicastGroups{Ingress: []string{"239.1.1.0/24"}}
+				annotation, err := json.Marshal(groups)
+				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+				namespace2.Annotations[nsMulticastGroupsAnnotation] = string(annotation)
+
+				// the annotation of namespace1 was removed while the master was down
+				mcastPolicy := multicastPolicy{}
+				staleData := mcastPolicy.getMulticastGroupsExpectedData(namespace1.Name, groups, nil)
+				keptData := mcastPolicy.getMulticastGroupsExpectedData(namespace2.Name, groups, nil)
+				initialDB.NBData = append(initialDB.NBData, staleData...)
+				initialDB.NBData = append(initialDB.NBData, keptData...)
+
+				fakeOvn.startWithDBSetup(ctx, initialDB,
+					&v1.NamespaceList{
+						Items: []v1.Namespace{
+							namespace1,
+							namespace2,
+						},
+					},
+				)
+				fakeOvn.controller.WatchNamespaces()
+
+				expectedData := []libovsdb.TestData{staleData[0], staleData[1]}
+				expectedData = append(expectedData, keptData...)
+				expectedData = append(expectedData, &nbdb.LogicalSwitch{
+					UUID: libovsdbops.BuildNamedUUID(),
+					Name: "node1",
+				})
+				gomega.Eventually(fakeOvn.nbClient).Should(libovsdb.HaveData(expectedData...))
+				return nil
+			}
+
+			err := app.Run([]string{app.Name})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		})
 	})
 })
 
@@ -1806,6 +1967,164 @@ var _ = ginkgo.Describe("OVN NetworkPolicy Operations", func() {
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
 		})
 
+		ginkgo.It("shares peer address sets between networkpolicies with identical selectors", func() {
+			app.Action = func(ctx *cli.Context) error {
+				namespace1 := *newNamespace(namespaceName1)
+
+				nPodTest := newTPod(
+					"node1",
+					"10.128.1.0/24",
+					"10.128.1.2",
+					"10.128.1.1",
+					"myPod",
+					"10.128.1.3",
+					"0a:58:0a:80:01:03",
+					namespace1.Name,
+				)
+				peerSelector := &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"name": nPodTest.podName,
+					},
+				}
+				ingress := []knet.NetworkPolicyIngressRule{
+					{
+						From: []knet.NetworkPolicyPeer{
+							{
+								PodSelector: peerSelector,
+							},
+						},
+					},
+				}
+				networkPolicy1 := newNetworkPolicy("networkpolicy1", namespace1.Name,
+					metav1.LabelSelector{}, ingress, nil)
+				networkPolicy2 := newNetworkPolicy("networkpolicy2", namespace1.Name,
+					metav1.LabelSelector{}, ingress, nil)
+
+				fakeOvn.startWithDBSetup(ctx, initialDB,
+					&v1.NamespaceList{
+						Items: []v1.Namespace{
+							namespace1,
+						},
+					},
+					&v1.PodList{
+						Items: []v1.Pod{
+							*newPod(nPodTest.namespace, nPodTest.podName, nPodTest.nodeName, nPodTest.podIP),
+						},
+					},
+					&knet.NetworkPolicyList{
+						Items: []knet.NetworkPolicy{
+							*networkPolicy1,
+							*networkPolicy2,
+						},
+					},
+				)
+				nPodTest.populateLogicalSwitchCache(fakeOvn)
+				fakeOvn.controller.WatchNamespaces()
+				fakeOvn.controller.WatchPods()
+				fakeOvn.controller.WatchNetworkPolicy()
+
+				podsASName := getSharedPeerAddressSetName(getSharedPeerPodsKey(namespace1.Name, nil, peerSelector))
+				svcsASName := getSharedPeerAddressSetName(getSharedPeerServicesKey(namespace1.Name))
+				fakeOvn.asf.EventuallyExpectAddressSetWithIPs(podsASName, []string{nPodTest.podIP})
+				fakeOvn.asf.EventuallyExpectEmptyAddressSetExist(svcsASName)
+				eventuallyExpectNoAddressSets(fakeOvn, networkPolicy1)
+				eventuallyExpectNoAddressSets(fakeOvn, networkPolicy2)
+
+				err := fakeOvn.fakeClient.KubeClient.NetworkingV1().NetworkPolicies(networkPolicy1.Namespace).Delete(context.TODO(), networkPolicy1.Name, *metav1.NewDeleteOptions(0))
+				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+				gomega.Eventually(func() bool {
+					fakeOvn.controller.sharedPeerAddressSetsMutex.Lock()
+					defer fakeOvn.controller.sharedPeerAddressSetsMutex.Unlock()
+					sas, ok := fakeOvn.controller.sharedPeerAddressSets[getSharedPeerPodsKey(namespace1.Name, nil, peerSelector)]
+					return ok && sas.refCount == 1
+				}).Should(gomega.BeTrue())
+				fakeOvn.asf.ExpectAddressSetWithIPs(podsASName, []string{nPodTest.podIP})
+
+				err = fakeOvn.fakeClient.KubeClient.NetworkingV1().NetworkPolicies(networkPolicy2.Namespace).Delete(context.TODO(), networkPolicy2.Name, *metav1.NewDeleteOptions(0))
+				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+				fakeOvn.asf.EventuallyExpectNoAddressSet(podsASName)
+				fakeOvn.asf.EventuallyExpectNoAddressSet(svcsASName)
+
+				return nil
+			}
+
+			err := app.Run([]string{app.Name, "--enable-shared-peer-address-sets"})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		})
+
+		ginkgo.It("references the host network address set of nodes running host network peer pods", func() {
+			app.Action = func(ctx *cli.Context) error {
+				namespace1 := *newNamespace(namespaceName1)
+
+				hostPod := newPod(namespace1.Name, "hostPod", "node1", "192.168.1.10")
+				hostPod.Labels = map[string]string{"app": "host"}
+				hostPod.Spec.HostNetwork = true
+				networkPolicy := newNetworkPolicy("networkpolicy1", namespace1.Name,
+					metav1.LabelSelector{
+						MatchLabels: map[string]string{"app": "server"},
+					},
+					[]knet.NetworkPolicyIngressRule{
+						{
+							From: []knet.NetworkPolicyPeer{
+								{
+									PodSelector: &metav1.LabelSelector{
+										MatchLabels: hostPod.Labels,
+									},
+								},
+							},
+						},
+					}, nil)
+
+				npTest := kNetworkPolicy{}
+				defaultDenyExpectedData := npTest.getDefaultDenyData(networkPolicy, nil, nbdb.ACLSeverityInfo)
+				nodeSwitch := &nbdb.LogicalSwitch{
+					UUID: libovsdbops.BuildNamedUUID(),
+					Name: "node1",
+				}
+
+				fakeOvn.startWithDBSetup(ctx, initialDB,
+					&v1.NamespaceList{
+						Items: []v1.Namespace{
+							namespace1,
+						},
+					},
+					&v1.PodList{
+						Items: []v1.Pod{
+							*hostPod,
+						},
+					},
+					&knet.NetworkPolicyList{
+						Items: []knet.NetworkPolicy{
+							*networkPolicy,
+						},
+					},
+				)
+				fakeOvn.controller.WatchNamespaces()
+				fakeOvn.controller.WatchPods()
+				fakeOvn.controller.WatchNetworkPolicy()
+
+				// the host network pod IP is not added to the peer address set,
+				// the ACL references the host network address set of its node
+				eventuallyExpectEmptyAddressSetsExist(fakeOvn, networkPolicy)
+				expectedData := append([]libovsdb.TestData{nodeSwitch}, defaultDenyExpectedData...)
+				expectedData = append(expectedData, npTest.getPolicyData(networkPolicy, nil,
+					[]string{getHostNetworkAddressSetName("node1")}, nil, nbdb.ACLSeverityInfo)...)
+				gomega.Eventually(fakeOvn.nbClient).Should(libovsdb.HaveData(expectedData...))
+
+				err := fakeOvn.fakeClient.KubeClient.CoreV1().Pods(hostPod.Namespace).Delete(context.TODO(), hostPod.Name, *metav1.NewDeleteOptions(0))
+				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+				expectedData = append([]libovsdb.TestData{nodeSwitch}, defaultDenyExpectedData...)
+				expectedData = append(expectedData, npTest.getPolicyData(networkPolicy, nil,
+					[]string{}, nil, nbdb.ACLSeverityInfo)...)
+				gomega.Eventually(fakeOvn.nbClient).Should(libovsdb.HaveData(expectedData...))
+
+				return nil
+			}
+
+			err := app.Run([]string{app.Name, "--enable-host-network-policy"})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		})
+
 		ginkgo.Context("ACL logging for network policies", func() {
 			const (
 				firstNetworkPolicyName  = "networkpolicy1"
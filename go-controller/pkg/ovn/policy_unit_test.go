@@ -101,3 +101,64 @@ func TestAddAllowACLFromNode(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoveAllowACLFromNode(t *testing.T) {
+	mockGoOvnNBClient := new(goovn_mock.Client)
+
+	tests := []struct {
+		desc                      string
+		inpSwName                 string
+		inpMgmtIp                 net.IP
+		errMatch                  error
+		onRetArgMockGoOvnNBClient []ovntest.TestifyMockHelper
+	}{
+		{
+			desc:      "test error when ovnNBClient.ACLDel() fails",
+			inpSwName: "testSW",
+			inpMgmtIp: ovntest.MustParseIP("192.168.10.10"),
+			errMatch:  fmt.Errorf("ACLDel() error when removing node acl for logical switch"),
+			onRetArgMockGoOvnNBClient: []ovntest.TestifyMockHelper{
+				{
+					OnCallMethodName: "ACLDel", OnCallMethodArgType: []string{"string", "string", "string", "int", "map[string]string"}, RetArgList: []interface{}{nil, goovn.ErrorSchema},
+				},
+			},
+		},
+		{
+			desc:      "test when ACL does not exist and confirm no error returned",
+			inpSwName: "testSW",
+			inpMgmtIp: ovntest.MustParseIP("192.168.10.10"),
+			onRetArgMockGoOvnNBClient: []ovntest.TestifyMockHelper{
+				{
+					OnCallMethodName: "ACLDel", OnCallMethodArgType: []string{"string", "string", "string", "int", "map[string]string"}, RetArgList: []interface{}{nil, goovn.ErrorNotFound},
+				},
+			},
+		},
+		{
+			desc:      "positive: test ip6 management ip",
+			inpSwName: "testSW",
+			inpMgmtIp: ovntest.MustParseIP("fd01::1234"),
+			onRetArgMockGoOvnNBClient: []ovntest.TestifyMockHelper{
+				{
+					OnCallMethodName: "ACLDel", OnCallMethodArgType: []string{"string", "string", "string", "int", "map[string]string"}, RetArgList: []interface{}{&goovn.OvnCommand{}, nil},
+				},
+				{
+					OnCallMethodName: "Execute", OnCallMethodArgType: []string{"*goovn.OvnCommand"}, RetArgList: []interface{}{nil},
+				},
+			},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.desc), func(t *testing.T) {
+			ovntest.ProcessMockFnList(&mockGoOvnNBClient.Mock, tc.onRetArgMockGoOvnNBClient)
+
+			err := removeAllowACLFromNode(tc.inpSwName, tc.inpMgmtIp, mockGoOvnNBClient)
+			if tc.errMatch != nil {
+				assert.Contains(t, err.Error(), tc.errMatch.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+			mockGoOvnNBClient.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package ovn
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/nbdb"
+	libovsdbops "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/libovsdbops"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+
+	kapi "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	utilnet "k8s.io/utils/net"
+)
+
+// isNamespaceRoutedEgressEnabled returns true if the pods of the namespace
+// egress through the gateway interface with their own IPs. The pod subnets are
+// then reachable from outside the cluster only through the routes the nodes
+// advertise over BGP, and the per pod SNAT is only configured when it is not
+// disabled globally.
+func isNamespaceRoutedEgressEnabled(annotations map[string]string) bool {
+	return config.BGP.Enabled && config.Gateway.DisableSNATMultipleGWs &&
+		annotations[routedEgressAnnotation] == "true"
+}
+
+// routedEgressUpdateNamespace removes the per pod SNAT of the existing pods of
+// the namespace when routed egress gets enabled on it, and adds it back when it
+// gets disabled
+// must be called with nsInfo lock
+func (oc *Controller) routedEgressUpdateNamespace(ns *kapi.Namespace, nsInfo *namespaceInfo) {
+	enabled := isNamespaceRoutedEgressEnabled(ns.Annotations)
+	if enabled == nsInfo.routedEgress {
+		return
+	}
+	nsInfo.routedEgress = enabled
+	klog.Infof("Namespace %s: routed egress is set to %t", ns.Name, enabled)
+
+	// the external gateways of the namespace already replaced the per pod SNAT
+	if len(nsInfo.routingExternalGWs.gws) > 0 || len(nsInfo.routingExternalPodGWs) > 0 {
+		return
+	}
+	egressInterface := nsInfo.activeEgressInterface()
+	existingPods, err := oc.watchFactory.GetPods(ns.Name)
+	if err != nil {
+		klog.Errorf("Failed to get all the pods (%v)", err)
+		return
+	}
+	for _, pod := range existingPods {
+		if pod.Spec.HostNetwork || !util.PodScheduled(pod) {
+			continue
+		}
+		podAnnotation, err := util.UnmarshalPodAnnotation(pod.Annotations)
+		if err != nil {
+			continue
+		}
+		if egressInterface != "" {
+			// the pods routed out of the egress interface of their node
+			// keep the SNAT to the IP of that interface
+			routed, err := oc.addEgressInterfaceRoutesForPod(pod, podAnnotation.IPs, egressInterface)
+			if err != nil {
+				klog.Error(err.Error())
+				continue
+			}
+			if routed {
+				continue
+			}
+		}
+		if enabled {
+			err = oc.deleteRoutedEgressPodSNAT(pod, podAnnotation.IPs)
+		} else {
+			err = oc.addPerPodGRSNAT(pod, podAnnotation.IPs)
+		}
+		if err != nil {
+			klog.Error(err.Error())
+		}
+	}
+}
+
+// deleteRoutedEgressPodSNAT removes the per pod SNAT of a pod to the IPs of the
+// gateway interface of its node, leaving any other SNAT of the pod in place
+func (oc *Controller) deleteRoutedEgressPodSNAT(pod *kapi.Pod, podIfAddrs []*net.IPNet) error {
+	l3GWConfig, err := oc.getNodeL3GatewayConfig(pod.Spec.NodeName)
+	if err != nil {
+		return err
+	}
+	nats := make([]*nbdb.NAT, 0, len(l3GWConfig.IPAddresses)*len(podIfAddrs))
+	for _, gwIPNet := range l3GWConfig.IPAddresses {
+		for _, podIPNet := range podIfAddrs {
+			if utilnet.IsIPv6(gwIPNet.IP) != utilnet.IsIPv6(podIPNet.IP) {
+				continue
+			}
+			podIP := podIPNet.IP.String()
+			_, fullMaskPodNet, err := net.ParseCIDR(podIP + GetIPFullMask(podIP))
+			if err != nil {
+				return fmt.Errorf("invalid pod IP %s: %v", podIP, err)
+			}
+			nats = append(nats, libovsdbops.BuildRouterSNAT(&gwIPNet.IP, fullMaskPodNet, "", nil))
+		}
+	}
+	gr := types.GWRouterPrefix + pod.Spec.NodeName
+	if err := libovsdbops.DeleteNatsFromRouter(oc.nbClient, gr, nats...); err != nil {
+		return fmt.Errorf("failed to delete the SNAT of pod %s/%s from router %s: %v", pod.Namespace, pod.Name, gr, err)
+	}
+	return nil
+}
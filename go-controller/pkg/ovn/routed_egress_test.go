@@ -0,0 +1,163 @@
+package ovn
+
+import (
+	"context"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/nbdb"
+	libovsdbtest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing/libovsdb"
+
+	"github.com/urfave/cli/v2"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("OVN Routed Egress Operations", func() {
+	var (
+		app     *cli.App
+		fakeOvn *FakeOVN
+		// bgpEnabled is applied once the config is initialized, since BGP
+		// advertisement can't be enabled without a gateway
+		bgpEnabled bool
+	)
+
+	ginkgo.BeforeEach(func() {
+		// Restore global default values before each testcase
+		config.PrepareTestConfig()
+		config.Gateway.DisableSNATMultipleGWs = true
+		bgpEnabled = true
+
+		app = cli.NewApp()
+		app.Name = "test"
+		app.Flags = config.Flags
+
+		fakeOvn = NewFakeOVN(nil)
+	})
+
+	ginkgo.AfterEach(func() {
+		fakeOvn.shutdown()
+	})
+
+	// startWithPod starts the controller with a pod in a namespace with
+	// annotations
+	startWithPod := func(ctx *cli.Context, annotations map[string]string) v1.Namespace {
+		namespaceT := *newNamespace("namespace1")
+		namespaceT.Annotations = annotations
+		t := newTPod(
+			"node1",
+			"10.128.1.0/24",
+			"10.128.1.2",
+			"10.128.1.1",
+			"myPod",
+			"10.128.1.3",
+			"0a:58:0a:80:01:03",
+			namespaceT.Name,
+		)
+
+		fakeOvn.startWithDBSetup(ctx,
+			libovsdbtest.TestSetup{
+				NBData: []libovsdbtest.TestData{
+					&nbdb.LogicalSwitch{
+						UUID: "node1",
+						Name: "node1",
+					},
+					&nbdb.LogicalRouter{
+						UUID: "GR_node1-UUID",
+						Name: "GR_node1",
+					},
+				},
+			},
+			&v1.NamespaceList{
+				Items: []v1.Namespace{
+					namespaceT,
+				},
+			},
+			&v1.PodList{
+				Items: []v1.Pod{
+					*newPod(t.namespace, t.podName, t.nodeName, t.podIP),
+				},
+			},
+		)
+		t.populateLogicalSwitchCache(fakeOvn)
+		config.BGP.Enabled = bgpEnabled
+
+		node := &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "node1",
+				Annotations: map[string]string{
+					"k8s.ovn.org/l3-gateway-config": `{"default":{"mode":"shared","mac-address":"7e:57:f8:f0:3c:49","ip-address":"169.254.33.2/24","next-hop":"169.254.33.1"}}`,
+					"k8s.ovn.org/node-chassis-id":   "79fdcfc4-6fe6-4cd3-8242-c0f85a4668ec",
+				},
+			},
+		}
+		err := fakeOvn.controller.watchFactory.NodeInformer().GetStore().Add(node)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		fakeOvn.controller.WatchNamespaces()
+		fakeOvn.controller.WatchPods()
+
+		gomega.Eventually(func() string { return getPodAnnotations(fakeOvn.fakeClient.KubeClient, t.namespace, t.podName) }, 2).ShouldNot(gomega.BeEmpty())
+		return namespaceT
+	}
+
+	// snats returns the logical and external IPs of the SNATs of the NB
+	snats := func() []string {
+		nats := []nbdb.NAT{}
+		err := fakeOvn.controller.nbClient.List(context.Background(), &nats)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		res := []string{}
+		for _, nat := range nats {
+			if nat.Type == nbdb.NATTypeSNAT {
+				res = append(res, nat.LogicalIP+"->"+nat.ExternalIP)
+			}
+		}
+		return res
+	}
+
+	ginkgo.It("does not add the per pod SNAT of a new pod in a routed egress namespace", func() {
+		app.Action = func(ctx *cli.Context) error {
+			startWithPod(ctx, map[string]string{"k8s.ovn.org/routed-egress": "true"})
+			gomega.Consistently(snats).Should(gomega.BeEmpty())
+			return nil
+		}
+
+		err := app.Run([]string{app.Name})
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+
+	ginkgo.It("keeps the per pod SNAT when BGP advertisement is disabled", func() {
+		app.Action = func(ctx *cli.Context) error {
+			bgpEnabled = false
+			startWithPod(ctx, map[string]string{"k8s.ovn.org/routed-egress": "true"})
+			gomega.Eventually(snats).Should(gomega.ConsistOf("10.128.1.3->169.254.33.2"))
+			return nil
+		}
+
+		err := app.Run([]string{app.Name})
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+
+	ginkgo.It("removes and restores the per pod SNAT of the existing pods when the annotation is toggled", func() {
+		app.Action = func(ctx *cli.Context) error {
+			namespaceT := startWithPod(ctx, nil)
+			gomega.Eventually(snats).Should(gomega.ConsistOf("10.128.1.3->169.254.33.2"))
+
+			namespaceT.Annotations = map[string]string{"k8s.ovn.org/routed-egress": "true"}
+			_, err := fakeOvn.fakeClient.KubeClient.CoreV1().Namespaces().Update(context.Background(), &namespaceT, metav1.UpdateOptions{})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Eventually(snats).Should(gomega.BeEmpty())
+
+			namespaceT.Annotations = nil
+			_, err = fakeOvn.fakeClient.KubeClient.CoreV1().Namespaces().Update(context.Background(), &namespaceT, metav1.UpdateOptions{})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Eventually(snats).Should(gomega.ConsistOf("10.128.1.3->169.254.33.2"))
+			return nil
+		}
+
+		err := app.Run([]string{app.Name})
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+})
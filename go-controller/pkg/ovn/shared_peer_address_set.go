@@ -0,0 +1,418 @@
+package ovn
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/factory"
+	addressset "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/ovn/address_set"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
+
+	kapi "k8s.io/api/core/v1"
+	knet "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// sharedPeerAddressSetPrefix is the first component of the name of every shared
+// peer address set. It contains an underscore so that it can never clash with a
+// namespace name when address sets are processed by ProcessEachAddressSet.
+const sharedPeerAddressSetPrefix = "_peer"
+
+// sharedPeerAddressSet is an address set holding the IPs of all the pods (or
+// service VIPs) matched by a NetworkPolicy peer. Every gressPolicy in the cluster
+// with an identical peer references the same sharedPeerAddressSet, which is
+// destroyed along with its handlers once the last reference is released.
+type sharedPeerAddressSet struct {
+	sync.Mutex
+	key        string
+	addressSet addressset.AddressSet
+	// refCount is the number of gressPolicies using this address set. It is
+	// protected by the controller's sharedPeerAddressSetsMutex.
+	refCount int
+	// deleted is set once the address set has been destroyed, so that handlers
+	// racing with the removal do not register themselves again
+	deleted        bool
+	podHandlerList []*factory.Handler
+	nsHandlerList  []*factory.Handler
+	svcHandlerList []*factory.Handler
+	// nsPodHandlers holds the pod handlers created for each namespace matched
+	// by a namespace and pod selector peer
+	nsPodHandlers map[string]*factory.Handler
+}
+
+// getSharedPeerPodsKey returns the key identifying the shared address set of a
+// peer with a pod selector, and optionally a namespace selector. A peer with only
+// a pod selector selects pods in the namespace of the policy.
+func getSharedPeerPodsKey(policyNamespace string, namespaceSelector, podSelector *metav1.LabelSelector) string {
+	// NetworkPolicy is validated by the apiserver; this can't fail.
+	podSel, _ := metav1.LabelSelectorAsSelector(podSelector)
+	if namespaceSelector == nil {
+		return fmt.Sprintf("pods/%s/%s", policyNamespace, podSel.String())
+	}
+	nsSel, _ := metav1.LabelSelectorAsSelector(namespaceSelector)
+	return fmt.Sprintf("nspods/%s/%s", nsSel.String(), podSel.String())
+}
+
+// getSharedPeerServicesKey returns the key identifying the shared address set
+// holding the VIPs of all services in a namespace.
+func getSharedPeerServicesKey(namespace string) string {
+	return fmt.Sprintf("svcs/%s", namespace)
+}
+
+// getSharedPeerAddressSetName returns the name of the address set for a key
+func getSharedPeerAddressSetName(key string) string {
+	return fmt.Sprintf("%s.%s", sharedPeerAddressSetPrefix, util.HashForOVN(key))
+}
+
+// getSharedPeerAddressSetNames returns the names of all the shared address sets
+// the given policy references.
+func getSharedPeerAddressSetNames(policy *knet.NetworkPolicy) sets.String {
+	names := sets.String{}
+	for _, ingressJSON := range policy.Spec.Ingress {
+		if hasAnyLabelSelector(ingressJSON.From) {
+			names.Insert(getSharedPeerAddressSetName(getSharedPeerServicesKey(policy.Namespace)))
+		}
+		for _, fromJSON := range ingressJSON.From {
+			if fromJSON.PodSelector != nil {
+				key := getSharedPeerPodsKey(policy.Namespace, fromJSON.NamespaceSelector, fromJSON.PodSelector)
+				names.Insert(getSharedPeerAddressSetName(key))
+			}
+		}
+	}
+	for _, egressJSON := range policy.Spec.Egress {
+		for _, toJSON := range egressJSON.To {
+			if toJSON.PodSelector != nil {
+				key := getSharedPeerPodsKey(policy.Namespace, toJSON.NamespaceSelector, toJSON.PodSelector)
+				names.Insert(getSharedPeerAddressSetName(key))
+			}
+		}
+	}
+	return names
+}
+
+// syncSharedPeerAddressSets deletes the shared address sets that none of the
+// given policies reference anymore.
+func (oc *Controller) syncSharedPeerAddressSets(networkPolicies []*knet.NetworkPolicy) {
+	expectedNames := sets.String{}
+	if config.OVNKubernetesFeature.EnableSharedPeerAddressSets {
+		for _, policy := range networkPolicies {
+			expectedNames = expectedNames.Union(getSharedPeerAddressSetNames(policy))
+		}
+	}
+
+	staleNames := []string{}
+	err := oc.addressSetFactory.ProcessEachAddressSet(func(addrSetName, prefix, _ string) {
+		if prefix == sharedPeerAddressSetPrefix && !expectedNames.Has(addrSetName) {
+			staleNames = append(staleNames, addrSetName)
+		}
+	})
+	if err != nil {
+		klog.Errorf("Error in syncing shared peer address sets: %v", err)
+	}
+
+	for _, name := range staleNames {
+		if err := oc.addressSetFactory.DestroyAddressSetInBackingStore(name); err != nil {
+			klog.Errorf(err.Error())
+		}
+	}
+}
+
+// getSharedPeerAddressSet returns the shared address set for the given key and
+// takes a reference on it. If the address set does not exist yet it is created
+// and startHandlers is called to set up the handlers that populate it. Every
+// successful call must be balanced by a call to releaseSharedPeerAddressSet.
+func (oc *Controller) getSharedPeerAddressSet(key string, startHandlers func(sas *sharedPeerAddressSet)) (*sharedPeerAddressSet, error) {
+	oc.sharedPeerAddressSetsMutex.Lock()
+	defer oc.sharedPeerAddressSetsMutex.Unlock()
+
+	if sas, ok := oc.sharedPeerAddressSets[key]; ok {
+		sas.refCount++
+		return sas, nil
+	}
+
+	as, err := oc.addressSetFactory.NewAddressSet(getSharedPeerAddressSetName(key), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shared peer address set for %s: %v", key, err)
+	}
+	sas := &sharedPeerAddressSet{
+		key:            key,
+		addressSet:     as,
+		refCount:       1,
+		podHandlerList: make([]*factory.Handler, 0),
+		nsHandlerList:  make([]*factory.Handler, 0),
+		svcHandlerList: make([]*factory.Handler, 0),
+		nsPodHandlers:  make(map[string]*factory.Handler),
+	}
+	oc.sharedPeerAddressSets[key] = sas
+	klog.V(5).Infof("Created shared peer address set %s for %s", as.GetName(), key)
+	startHandlers(sas)
+	return sas, nil
+}
+
+// releaseSharedPeerAddressSet drops a reference on a shared address set. Once the
+// last reference is dropped the handlers populating the address set are removed
+// and the address set is destroyed.
+func (oc *Controller) releaseSharedPeerAddressSet(sas *sharedPeerAddressSet) error {
+	oc.sharedPeerAddressSetsMutex.Lock()
+	defer oc.sharedPeerAddressSetsMutex.Unlock()
+
+	sas.refCount--
+	if sas.refCount > 0 {
+		return nil
+	}
+	delete(oc.sharedPeerAddressSets, sas.key)
+
+	sas.Lock()
+	sas.deleted = true
+	podHandlers := sas.podHandlerList
+	for _, handler := range sas.nsPodHandlers {
+		podHandlers = append(podHandlers, handler)
+	}
+	nsHandlers := sas.nsHandlerList
+	svcHandlers := sas.svcHandlerList
+	sas.Unlock()
+
+	for _, handler := range nsHandlers {
+		oc.watchFactory.RemoveNamespaceHandler(handler)
+	}
+	for _, handler := range podHandlers {
+		oc.watchFactory.RemovePodHandler(handler)
+	}
+	for _, handler := range svcHandlers {
+		oc.watchFactory.RemoveServiceHandler(handler)
+	}
+
+	klog.V(5).Infof("Destroying shared peer address set %s for %s", sas.addressSet.GetName(), sas.key)
+	return sas.addressSet.Destroy()
+}
+
+// addPods adds the IPs of the given pods to the shared address set
+func (sas *sharedPeerAddressSet) addPods(objs ...interface{}) {
+	podIPFactor := 1
+	if config.IPv4Mode && config.IPv6Mode {
+		podIPFactor = 2
+	}
+	ips := make([]net.IP, 0, len(objs)*podIPFactor)
+	for _, obj := range objs {
+		pod := obj.(*kapi.Pod)
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		podIPs, err := util.GetAllPodIPs(pod)
+		if err != nil {
+			klog.Errorf(err.Error())
+			continue
+		}
+		ips = append(ips, podIPs...)
+	}
+	if err := sas.addressSet.AddIPs(ips); err != nil {
+		klog.Errorf(err.Error())
+	}
+}
+
+// deletePods removes the IPs of the given pods from the shared address set
+func (sas *sharedPeerAddressSet) deletePods(objs ...interface{}) {
+	ips := make([]net.IP, 0, len(objs))
+	for _, obj := range objs {
+		pod := obj.(*kapi.Pod)
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		podIPs, err := util.GetAllPodIPs(pod)
+		if err != nil {
+			continue
+		}
+		ips = append(ips, podIPs...)
+	}
+	if err := sas.addressSet.DeleteIPs(ips); err != nil {
+		klog.Errorf(err.Error())
+	}
+}
+
+// addSharedPeerPodHandler starts a pod handler populating the shared address set with
+// the pods matching podSelector in namespace.
+func (oc *Controller) addSharedPeerPodHandler(sas *sharedPeerAddressSet, namespace string,
+	podSelector *metav1.LabelSelector) *factory.Handler {
+	// NetworkPolicy is validated by the apiserver; this can't fail.
+	sel, _ := metav1.LabelSelectorAsSelector(podSelector)
+
+	return oc.watchFactory.AddFilteredPodHandler(namespace, sel,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				sas.addPods(obj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				sas.deletePods(obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				sas.addPods(newObj)
+			},
+		}, func(objs []interface{}) {
+			sas.addPods(objs...)
+		})
+}
+
+// startSharedPeerPodSelector returns the function starting the handlers that
+// populate a shared address set for a pod selector peer, with or without a
+// namespace selector.
+func (oc *Controller) startSharedPeerPodSelector(policyNamespace string,
+	namespaceSelector, podSelector *metav1.LabelSelector) func(sas *sharedPeerAddressSet) {
+	return func(sas *sharedPeerAddressSet) {
+		if namespaceSelector == nil {
+			h := oc.addSharedPeerPodHandler(sas, policyNamespace, podSelector)
+			sas.Lock()
+			defer sas.Unlock()
+			sas.podHandlerList = append(sas.podHandlerList, h)
+			return
+		}
+
+		// NetworkPolicy is validated by the apiserver; this can't fail.
+		nsSel, _ := metav1.LabelSelectorAsSelector(namespaceSelector)
+		h := oc.watchFactory.AddFilteredNamespaceHandler("", nsSel,
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+					namespace := obj.(*kapi.Namespace)
+					sas.Lock()
+					_, exists := sas.nsPodHandlers[namespace.Name]
+					deleted := sas.deleted
+					sas.Unlock()
+					if exists || deleted {
+						return
+					}
+
+					// The AddFilteredPodHandler call will add existing pods
+					// so we can't be holding the lock at this point
+					podHandler := oc.addSharedPeerPodHandler(sas, namespace.Name, podSelector)
+					sas.Lock()
+					defer sas.Unlock()
+					if sas.deleted {
+						oc.watchFactory.RemovePodHandler(podHandler)
+						return
+					}
+					sas.nsPodHandlers[namespace.Name] = podHandler
+				},
+				DeleteFunc: func(obj interface{}) {
+					// when the namespace labels no longer apply remove the
+					// namespace's pods from the address set
+					namespace := obj.(*kapi.Namespace)
+					sas.Lock()
+					podHandler, exists := sas.nsPodHandlers[namespace.Name]
+					delete(sas.nsPodHandlers, namespace.Name)
+					sas.Unlock()
+					if exists {
+						oc.watchFactory.RemovePodHandler(podHandler)
+					}
+
+					pods, _ := oc.watchFactory.GetPods(namespace.Name)
+					objs := make([]interface{}, 0, len(pods))
+					for _, pod := range pods {
+						objs = append(objs, pod)
+					}
+					sas.deletePods(objs...)
+				},
+				UpdateFunc: func(oldObj, newObj interface{}) {
+				},
+			}, nil)
+		sas.Lock()
+		defer sas.Unlock()
+		sas.nsHandlerList = append(sas.nsHandlerList, h)
+	}
+}
+
+// startSharedPeerServices returns the function starting the handler that
+// populates a shared address set with the VIPs of the services in namespace,
+// to account for hairpinned traffic.
+func (oc *Controller) startSharedPeerServices(namespace string) func(sas *sharedPeerAddressSet) {
+	return func(sas *sharedPeerAddressSet) {
+		addService := func(obj interface{}) {
+			service := obj.(*kapi.Service)
+			ips := getSvcVips(oc.nbClient, service)
+			klog.V(5).Infof("Adding service %s VIPs %v to shared peer address set %s",
+				service.Name, ips, sas.addressSet.GetName())
+			if err := sas.addressSet.AddIPs(ips); err != nil {
+				klog.Errorf(err.Error())
+			}
+		}
+		deleteService := func(obj interface{}) {
+			service := obj.(*kapi.Service)
+			ips := getSvcVips(oc.nbClient, service)
+			klog.V(5).Infof("Deleting service %s VIPs %v from shared peer address set %s",
+				service.Name, ips, sas.addressSet.GetName())
+			if err := sas.addressSet.DeleteIPs(ips); err != nil {
+				klog.Errorf(err.Error())
+			}
+		}
+
+		h := oc.watchFactory.AddFilteredServiceHandler(namespace,
+			cache.ResourceEventHandlerFuncs{
+				AddFunc:    addService,
+				DeleteFunc: deleteService,
+				UpdateFunc: func(oldObj, newObj interface{}) {
+					oldSvc := oldObj.(*kapi.Service)
+					newSvc := newObj.(*kapi.Service)
+					if reflect.DeepEqual(newSvc.Spec.ExternalIPs, oldSvc.Spec.ExternalIPs) &&
+						reflect.DeepEqual(newSvc.Spec.ClusterIP, oldSvc.Spec.ClusterIP) &&
+						reflect.DeepEqual(newSvc.Spec.Type, oldSvc.Spec.Type) &&
+						reflect.DeepEqual(newSvc.Status.LoadBalancer.Ingress, oldSvc.Status.LoadBalancer.Ingress) {
+						return
+					}
+					deleteService(oldObj)
+					addService(newObj)
+				},
+			}, nil)
+		sas.Lock()
+		defer sas.Unlock()
+		sas.svcHandlerList = append(sas.svcHandlerList, h)
+	}
+}
+
+// addSharedPeerAddressSet makes the gress policy reference the given shared
+// address set. Caller must hold the policy's write lock.
+func (gp *gressPolicy) addSharedPeerAddressSet(sas *sharedPeerAddressSet) {
+	gp.sharedPeerAddressSets = append(gp.sharedPeerAddressSets, sas)
+	ipv4HashedAS, ipv6HashedAS := sas.addressSet.GetASHashNames()
+	if ipv4HashedAS != "" {
+		gp.peerV4AddressSets.Insert("$" + ipv4HashedAS)
+	}
+	if ipv6HashedAS != "" {
+		gp.peerV6AddressSets.Insert("$" + ipv6HashedAS)
+	}
+}
+
+// addSharedPeerAddressSetToGress takes a reference on the shared address set
+// for key and adds it to the peers of the gress policy.
+func (oc *Controller) addSharedPeerAddressSetToGress(np *networkPolicy, gp *gressPolicy, key string,
+	startHandlers func(sas *sharedPeerAddressSet)) {
+	sas, err := oc.getSharedPeerAddressSet(key, startHandlers)
+	if err != nil {
+		klog.Errorf(err.Error())
+		return
+	}
+
+	np.Lock()
+	defer np.Unlock()
+	if np.deleted {
+		if err := oc.releaseSharedPeerAddressSet(sas); err != nil {
+			klog.Errorf(err.Error())
+		}
+		return
+	}
+	gp.addSharedPeerAddressSet(sas)
+}
+
+// releaseGressSharedPeerAddressSets drops the references the gress policy holds
+// on shared address sets. Caller must hold the policy's write lock.
+func (oc *Controller) releaseGressSharedPeerAddressSets(gp *gressPolicy) {
+	for _, sas := range gp.sharedPeerAddressSets {
+		if err := oc.releaseSharedPeerAddressSet(sas); err != nil {
+			klog.Errorf(err.Error())
+		}
+	}
+	gp.sharedPeerAddressSets = nil
+}
@@ -12,6 +12,9 @@ const (
 	// access to physical/external network
 	PhysicalNetworkName     = "physnet"
 	PhysicalNetworkExGwName = "exgwphysnet"
+	// PhysicalNetworkEgressPrefix prefixes the name of the physical network
+	// of a named egress interface
+	PhysicalNetworkEgressPrefix = "egressphysnet-"
 
 	// LocalNetworkName is the name that maps to an OVS bridge that provides
 	// access to local service
@@ -39,6 +42,9 @@ const (
 	EXTSwitchToGWRouterPrefix    = "etor-"
 	GWRouterToExtSwitchPrefix    = "rtoe-"
 	EgressGWSwitchPrefix         = "exgw-"
+	// EgressInterfaceSwitchPrefix, followed by the name of an egress
+	// interface and "-", prefixes the external switch of that interface
+	EgressInterfaceSwitchPrefix = "egress-"
 
 	NodeLocalSwitch = "node_local_switch"
 
@@ -123,5 +129,10 @@ const (
 	ClusterPortGroupName    = "clusterPortGroup"
 	ClusterRtrPortGroupName = "clusterRtrPortGroup"
 
+	// Load balancer groups the cluster-wide service load balancers are
+	// attached through, to all node switches and to all gateway routers
+	ClusterSwitchLBGroupName = "clusterSwitchLBGroup"
+	ClusterRouterLBGroupName = "clusterRouterLBGroup"
+
 	OVSDBTimeout = 10 * time.Second
 )
@@ -3,6 +3,7 @@ package util
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -26,6 +27,7 @@ import (
 
 	egressfirewallclientset "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/egressfirewall/v1/apis/clientset/versioned"
 	egressipclientset "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/egressip/v1/apis/clientset/versioned"
+	hostnetworkpolicyclientset "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/crd/hostnetworkpolicy/v1/apis/clientset/versioned"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/types"
 
 	cnitypes "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/cni/types"
@@ -34,9 +36,10 @@ import (
 
 // OVNClientset is a wrapper around all clientsets used by OVN-Kubernetes
 type OVNClientset struct {
-	KubeClient           kubernetes.Interface
-	EgressIPClient       egressipclientset.Interface
-	EgressFirewallClient egressfirewallclientset.Interface
+	KubeClient              kubernetes.Interface
+	EgressIPClient          egressipclientset.Interface
+	EgressFirewallClient    egressfirewallclientset.Interface
+	HostNetworkPolicyClient hostnetworkpolicyclientset.Interface
 }
 
 func adjustCommit() string {
@@ -130,10 +133,15 @@ func NewOVNClientset(conf *config.KubernetesConfig) (*OVNClientset, error) {
 	if err != nil {
 		return nil, err
 	}
+	hostNetworkPolicyClientset, err := hostnetworkpolicyclientset.NewForConfig(kconfig)
+	if err != nil {
+		return nil, err
+	}
 	return &OVNClientset{
-		KubeClient:           kclientset,
-		EgressIPClient:       egressIPClientset,
-		EgressFirewallClient: egressFirewallClientset,
+		KubeClient:              kclientset,
+		EgressIPClient:          egressIPClientset,
+		EgressFirewallClient:    egressFirewallClientset,
+		HostNetworkPolicyClient: hostNetworkPolicyClientset,
 	}, nil
 }
 
@@ -284,12 +292,105 @@ type LbEndpoints struct {
 	V4IPs []string
 	V6IPs []string
 	Port  int32
+	// ZoneHints maps each endpoint IP to the zones it is hinted for by the
+	// EndpointSlice controller. It is nil unless all the endpoints have hints.
+	ZoneHints map[string]sets.String
+	// TerminatingV4IPs and TerminatingV6IPs are the endpoints that are
+	// terminating but still serving. They are not part of V4IPs and V6IPs:
+	// only the traffic policy local endpoints fall back to them, see InSubnets.
+	TerminatingV4IPs []string
+	TerminatingV6IPs []string
+}
+
+// ForZone returns the endpoints hinted for the given zone. As kube-proxy does,
+// all the endpoints of an IP family are returned when the endpoints are not all
+// hinted or when none of them is hinted for the zone.
+func (eps LbEndpoints) ForZone(zone string) LbEndpoints {
+	if eps.ZoneHints == nil || zone == "" {
+		return eps
+	}
+	filter := func(ips []string) []string {
+		out := make([]string, 0, len(ips))
+		for _, ip := range ips {
+			if eps.ZoneHints[ip].Has(zone) {
+				out = append(out, ip)
+			}
+		}
+		if len(out) == 0 {
+			return ips
+		}
+		return out
+	}
+	return LbEndpoints{
+		V4IPs:            filter(eps.V4IPs),
+		V6IPs:            filter(eps.V6IPs),
+		Port:             eps.Port,
+		TerminatingV4IPs: eps.TerminatingV4IPs,
+		TerminatingV6IPs: eps.TerminatingV6IPs,
+	}
+}
+
+// FiltersByZone returns true if the endpoints returned by ForZone differ
+// between zones, that is if some zone is hinted for only part of the endpoints
+// of an IP family. Otherwise every zone gets all the endpoints.
+func (eps LbEndpoints) FiltersByZone() bool {
+	if eps.ZoneHints == nil {
+		return false
+	}
+	zones := sets.NewString()
+	for _, hints := range eps.ZoneHints {
+		zones.Insert(hints.UnsortedList()...)
+	}
+	partial := func(ips []string, zone string) bool {
+		hinted := 0
+		for _, ip := range ips {
+			if eps.ZoneHints[ip].Has(zone) {
+				hinted++
+			}
+		}
+		return hinted > 0 && hinted < len(ips)
+	}
+	for zone := range zones {
+		if partial(eps.V4IPs, zone) || partial(eps.V6IPs, zone) {
+			return true
+		}
+	}
+	return false
+}
+
+// InSubnets returns the endpoints in the given subnets. As kube-proxy does with
+// ProxyTerminatingEndpoints, the terminating endpoints that are still serving
+// are returned for an IP family when none of its ready endpoints are in them.
+func (eps LbEndpoints) InSubnets(subnets []net.IPNet) LbEndpoints {
+	filter := func(ips, terminatingIPs []string) []string {
+		out := FilterIPsSlice(ips, subnets, true)
+		if len(out) == 0 && len(terminatingIPs) > 0 {
+			out = FilterIPsSlice(terminatingIPs, subnets, true)
+		}
+		return out
+	}
+	return LbEndpoints{
+		V4IPs: filter(eps.V4IPs, eps.TerminatingV4IPs),
+		V6IPs: filter(eps.V6IPs, eps.TerminatingV6IPs),
+		Port:  eps.Port,
+	}
+}
+
+// IsServingTerminatingEndpoint returns true if an endpoint is terminating, but
+// still serving and so can be used when there are no ready endpoints
+func IsServingTerminatingEndpoint(conditions discovery.EndpointConditions) bool {
+	return conditions.Serving != nil && *conditions.Serving &&
+		conditions.Terminating != nil && *conditions.Terminating
 }
 
 // GetLbEndpoints return the endpoints that belong to the IPFamily as a slice of IPs
 func GetLbEndpoints(slices []*discovery.EndpointSlice, svcPort kapi.ServicePort) LbEndpoints {
 	v4ips := sets.NewString()
 	v6ips := sets.NewString()
+	terminatingV4IPs := sets.NewString()
+	terminatingV6IPs := sets.NewString()
+	zoneHints := map[string]sets.String{}
+	allHinted := true
 
 	out := LbEndpoints{}
 	// return an empty object so the caller don't have to check for nil and can use it as an iterator
@@ -319,12 +420,36 @@ func GetLbEndpoints(slices []*discovery.EndpointSlice, svcPort kapi.ServicePort)
 
 			out.Port = *port.Port
 			for _, endpoint := range slice.Endpoints {
-				// Skip endpoints that are not ready
+				// Skip endpoints that are not ready, but keep the ones still serving
+				// while they terminate as a fallback for the local endpoints
 				if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
-					klog.V(4).Infof("Slice endpoints Not Ready")
+					if !IsServingTerminatingEndpoint(endpoint.Conditions) {
+						klog.V(4).Infof("Slice endpoints Not Ready")
+						continue
+					}
+					klog.V(4).Infof("Adding slice %s terminating endpoints: %v, port: %d", slice.Name, endpoint.Addresses, *port.Port)
+					for _, ip := range endpoint.Addresses {
+						switch slice.AddressType {
+						case discovery.AddressTypeIPv4:
+							terminatingV4IPs.Insert(ip)
+						case discovery.AddressTypeIPv6:
+							terminatingV6IPs.Insert(ip)
+						}
+					}
 					continue
 				}
+				if endpoint.Hints == nil || len(endpoint.Hints.ForZones) == 0 {
+					allHinted = false
+				}
 				for _, ip := range endpoint.Addresses {
+					if allHinted {
+						if zoneHints[ip] == nil {
+							zoneHints[ip] = sets.NewString()
+						}
+						for _, zone := range endpoint.Hints.ForZones {
+							zoneHints[ip].Insert(zone.Name)
+						}
+					}
 					klog.V(4).Infof("Adding slice %s endpoints: %v, port: %d", slice.Name, endpoint.Addresses, *port.Port)
 					switch slice.AddressType {
 					case discovery.AddressTypeIPv4:
@@ -341,6 +466,15 @@ func GetLbEndpoints(slices []*discovery.EndpointSlice, svcPort kapi.ServicePort)
 
 	out.V4IPs = v4ips.List()
 	out.V6IPs = v6ips.List()
+	if terminatingV4IPs.Len() > 0 {
+		out.TerminatingV4IPs = terminatingV4IPs.List()
+	}
+	if terminatingV6IPs.Len() > 0 {
+		out.TerminatingV6IPs = terminatingV6IPs.List()
+	}
+	if allHinted && len(zoneHints) > 0 {
+		out.ZoneHints = zoneHints
+	}
 	klog.V(4).Infof("LB Endpoints for %s/%s are: %v / %v on port: %d",
 		slices[0].Namespace, slices[0].Labels[discovery.LabelServiceName],
 		out.V4IPs, out.V6IPs, out.Port)
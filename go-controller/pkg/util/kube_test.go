@@ -3,6 +3,7 @@ package util
 import (
 	"context"
 	"fmt"
+	"net"
 	"testing"
 
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/cni/types"
@@ -12,6 +13,7 @@ import (
 	discovery "k8s.io/api/discovery/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
 	clientsetfake "k8s.io/client-go/kubernetes/fake"
 	utilpointer "k8s.io/utils/pointer"
 )
@@ -509,7 +511,7 @@ func Test_getLbEndpoints(t *testing.T) {
 					Protocol:   v1.ProtocolTCP,
 				},
 			},
-			want: LbEndpoints{[]string{"10.0.0.2"}, []string{}, 80},
+			want: LbEndpoints{V4IPs: []string{"10.0.0.2"}, V6IPs: []string{}, Port: 80},
 		},
 		{
 			name: "slices with different port name",
@@ -545,7 +547,7 @@ func Test_getLbEndpoints(t *testing.T) {
 					Protocol:   v1.ProtocolTCP,
 				},
 			},
-			want: LbEndpoints{[]string{}, []string{}, 0},
+			want: LbEndpoints{V4IPs: []string{}, V6IPs: []string{}, Port: 0},
 		},
 		{
 			name: "slices and service without port name",
@@ -579,7 +581,7 @@ func Test_getLbEndpoints(t *testing.T) {
 					Protocol:   v1.ProtocolTCP,
 				},
 			},
-			want: LbEndpoints{[]string{"10.0.0.2"}, []string{}, 8080},
+			want: LbEndpoints{V4IPs: []string{"10.0.0.2"}, V6IPs: []string{}, Port: 8080},
 		},
 		{
 			name: "slices with different IP family",
@@ -615,7 +617,7 @@ func Test_getLbEndpoints(t *testing.T) {
 					Protocol:   v1.ProtocolTCP,
 				},
 			},
-			want: LbEndpoints{[]string{}, []string{"2001:db2::2"}, 80},
+			want: LbEndpoints{V4IPs: []string{}, V6IPs: []string{"2001:db2::2"}, Port: 80},
 		},
 		{
 			name: "multiples slices with duplicate endpoints",
@@ -674,7 +676,201 @@ func Test_getLbEndpoints(t *testing.T) {
 					Protocol:   v1.ProtocolTCP,
 				},
 			},
-			want: LbEndpoints{[]string{"10.0.0.2", "10.1.1.2", "10.2.2.2"}, []string{}, 80},
+			want: LbEndpoints{V4IPs: []string{"10.0.0.2", "10.1.1.2", "10.2.2.2"}, V6IPs: []string{}, Port: 80},
+		},
+		{
+			name: "slices with zone hints",
+			args: args{
+				slices: []*discovery.EndpointSlice{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "svc-ab23",
+							Namespace: "ns",
+							Labels:    map[string]string{discovery.LabelServiceName: "svc"},
+						},
+						Ports: []discovery.EndpointPort{
+							{
+								Name:     utilpointer.StringPtr("tcp-example"),
+								Protocol: protoPtr(v1.ProtocolTCP),
+								Port:     utilpointer.Int32Ptr(int32(80)),
+							},
+						},
+						AddressType: discovery.AddressTypeIPv4,
+						Endpoints: []discovery.Endpoint{
+							{
+								Conditions: discovery.EndpointConditions{
+									Ready: utilpointer.BoolPtr(true),
+								},
+								Addresses: []string{"10.0.0.2"},
+								Hints: &discovery.EndpointHints{
+									ForZones: []discovery.ForZone{{Name: "zone-a"}},
+								},
+							},
+							{
+								Conditions: discovery.EndpointConditions{
+									Ready: utilpointer.BoolPtr(true),
+								},
+								Addresses: []string{"10.0.0.3"},
+								Hints: &discovery.EndpointHints{
+									ForZones: []discovery.ForZone{{Name: "zone-b"}},
+								},
+							},
+						},
+					},
+				},
+				svcPort: v1.ServicePort{
+					Name:       "tcp-example",
+					TargetPort: intstr.FromInt(80),
+					Protocol:   v1.ProtocolTCP,
+				},
+			},
+			want: LbEndpoints{V4IPs: []string{"10.0.0.2", "10.0.0.3"}, V6IPs: []string{}, Port: 80, ZoneHints: map[string]sets.String{
+				"10.0.0.2": sets.NewString("zone-a"),
+				"10.0.0.3": sets.NewString("zone-b"),
+			}},
+		},
+		{
+			name: "slices with some endpoints missing zone hints",
+			args: args{
+				slices: []*discovery.EndpointSlice{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "svc-ab23",
+							Namespace: "ns",
+							Labels:    map[string]string{discovery.LabelServiceName: "svc"},
+						},
+						Ports: []discovery.EndpointPort{
+							{
+								Name:     utilpointer.StringPtr("tcp-example"),
+								Protocol: protoPtr(v1.ProtocolTCP),
+								Port:     utilpointer.Int32Ptr(int32(80)),
+							},
+						},
+						AddressType: discovery.AddressTypeIPv4,
+						Endpoints: []discovery.Endpoint{
+							{
+								Conditions: discovery.EndpointConditions{
+									Ready: utilpointer.BoolPtr(true),
+								},
+								Addresses: []string{"10.0.0.2"},
+								Hints: &discovery.EndpointHints{
+									ForZones: []discovery.ForZone{{Name: "zone-a"}},
+								},
+							},
+							{
+								Conditions: discovery.EndpointConditions{
+									Ready: utilpointer.BoolPtr(true),
+								},
+								Addresses: []string{"10.0.0.3"},
+							},
+						},
+					},
+				},
+				svcPort: v1.ServicePort{
+					Name:       "tcp-example",
+					TargetPort: intstr.FromInt(80),
+					Protocol:   v1.ProtocolTCP,
+				},
+			},
+			want: LbEndpoints{V4IPs: []string{"10.0.0.2", "10.0.0.3"}, V6IPs: []string{}, Port: 80},
+		},
+		{
+			name: "slice with ready and serving terminating endpoints",
+			args: args{
+				slices: []*discovery.EndpointSlice{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "svc-ab23",
+							Namespace: "ns",
+							Labels:    map[string]string{discovery.LabelServiceName: "svc"},
+						},
+						Ports: []discovery.EndpointPort{
+							{
+								Name:     utilpointer.StringPtr("tcp-example"),
+								Protocol: protoPtr(v1.ProtocolTCP),
+								Port:     utilpointer.Int32Ptr(int32(80)),
+							},
+						},
+						AddressType: discovery.AddressTypeIPv4,
+						Endpoints: []discovery.Endpoint{
+							{
+								Conditions: discovery.EndpointConditions{
+									Ready: utilpointer.BoolPtr(true),
+								},
+								Addresses: []string{"10.0.0.2"},
+							},
+							{
+								Conditions: discovery.EndpointConditions{
+									Ready:       utilpointer.BoolPtr(false),
+									Serving:     utilpointer.BoolPtr(true),
+									Terminating: utilpointer.BoolPtr(true),
+								},
+								Addresses: []string{"10.0.0.3"},
+							},
+							{
+								Conditions: discovery.EndpointConditions{
+									Ready:       utilpointer.BoolPtr(false),
+									Serving:     utilpointer.BoolPtr(false),
+									Terminating: utilpointer.BoolPtr(true),
+								},
+								Addresses: []string{"10.0.0.4"},
+							},
+						},
+					},
+				},
+				svcPort: v1.ServicePort{
+					Name:       "tcp-example",
+					TargetPort: intstr.FromInt(80),
+					Protocol:   v1.ProtocolTCP,
+				},
+			},
+			want: LbEndpoints{V4IPs: []string{"10.0.0.2"}, V6IPs: []string{}, Port: 80, TerminatingV4IPs: []string{"10.0.0.3"}},
+		},
+		{
+			name: "slice with only terminating endpoints keeps the serving ones apart",
+			args: args{
+				slices: []*discovery.EndpointSlice{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "svc-ab23",
+							Namespace: "ns",
+							Labels:    map[string]string{discovery.LabelServiceName: "svc"},
+						},
+						Ports: []discovery.EndpointPort{
+							{
+								Name:     utilpointer.StringPtr("tcp-example"),
+								Protocol: protoPtr(v1.ProtocolTCP),
+								Port:     utilpointer.Int32Ptr(int32(80)),
+							},
+						},
+						AddressType: discovery.AddressTypeIPv4,
+						Endpoints: []discovery.Endpoint{
+							{
+								Conditions: discovery.EndpointConditions{
+									Ready:       utilpointer.BoolPtr(false),
+									Serving:     utilpointer.BoolPtr(true),
+									Terminating: utilpointer.BoolPtr(true),
+								},
+								Addresses: []string{"10.0.0.3"},
+							},
+							{
+								Conditions: discovery.EndpointConditions{
+									Ready:       utilpointer.BoolPtr(false),
+									Serving:     utilpointer.BoolPtr(false),
+									Terminating: utilpointer.BoolPtr(true),
+								},
+								Addresses: []string{"10.0.0.4"},
+							},
+						},
+					},
+				},
+				svcPort: v1.ServicePort{
+					Name:       "tcp-example",
+					TargetPort: intstr.FromInt(80),
+					Protocol:   v1.ProtocolTCP,
+				},
+			},
+			want: LbEndpoints{V4IPs: []string{}, V6IPs: []string{}, Port: 80, TerminatingV4IPs: []string{"10.0.0.3"}},
 		},
 	}
 	for _, tt := range tests {
@@ -685,6 +881,147 @@ func Test_getLbEndpoints(t *testing.T) {
 	}
 }
 
+func TestLbEndpointsForZone(t *testing.T) {
+	eps := LbEndpoints{
+		V4IPs: []string{"10.0.0.2", "10.0.0.3"},
+		V6IPs: []string{"fd00::2"},
+		Port:  80,
+		ZoneHints: map[string]sets.String{
+			"10.0.0.2": sets.NewString("zone-a"),
+			"10.0.0.3": sets.NewString("zone-b", "zone-c"),
+			"fd00::2":  sets.NewString("zone-b"),
+		},
+	}
+	tests := []struct {
+		name string
+		eps  LbEndpoints
+		zone string
+		want LbEndpoints
+	}{
+		{
+			name: "endpoints hinted for the zone",
+			eps:  eps,
+			zone: "zone-c",
+			want: LbEndpoints{V4IPs: []string{"10.0.0.3"}, V6IPs: []string{"fd00::2"}, Port: 80},
+		},
+		{
+			name: "falls back to all the endpoints of a family without hint for the zone",
+			eps:  eps,
+			zone: "zone-a",
+			want: LbEndpoints{V4IPs: []string{"10.0.0.2"}, V6IPs: []string{"fd00::2"}, Port: 80},
+		},
+		{
+			name: "node without zone",
+			eps:  eps,
+			zone: "",
+			want: eps,
+		},
+		{
+			name: "endpoints without hints",
+			eps:  LbEndpoints{V4IPs: []string{"10.0.0.2"}, V6IPs: []string{}, Port: 80},
+			zone: "zone-b",
+			want: LbEndpoints{V4IPs: []string{"10.0.0.2"}, V6IPs: []string{}, Port: 80},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.eps.ForZone(tt.zone))
+		})
+	}
+}
+
+func TestLbEndpointsFiltersByZone(t *testing.T) {
+	tests := []struct {
+		name string
+		eps  LbEndpoints
+		want bool
+	}{
+		{
+			name: "endpoints without hints",
+			eps:  LbEndpoints{V4IPs: []string{"10.0.0.2", "10.0.0.3"}, Port: 80},
+			want: false,
+		},
+		{
+			name: "every endpoint hinted for the same zone",
+			eps: LbEndpoints{
+				V4IPs: []string{"10.0.0.2", "10.0.0.3"},
+				V6IPs: []string{"fd00::2"},
+				Port:  80,
+				ZoneHints: map[string]sets.String{
+					"10.0.0.2": sets.NewString("zone-a"),
+					"10.0.0.3": sets.NewString("zone-a"),
+					"fd00::2":  sets.NewString("zone-a"),
+				},
+			},
+			want: false,
+		},
+		{
+			name: "endpoints hinted for different zones",
+			eps: LbEndpoints{
+				V4IPs: []string{"10.0.0.2", "10.0.0.3"},
+				Port:  80,
+				ZoneHints: map[string]sets.String{
+					"10.0.0.2": sets.NewString("zone-a"),
+					"10.0.0.3": sets.NewString("zone-a", "zone-b"),
+				},
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.eps.FiltersByZone())
+		})
+	}
+}
+
+func TestLbEndpointsInSubnets(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("10.128.1.0/24")
+	_, subnet6, _ := net.ParseCIDR("fd00:1::/64")
+	subnets := []net.IPNet{*subnet, *subnet6}
+	tests := []struct {
+		name string
+		eps  LbEndpoints
+		want LbEndpoints
+	}{
+		{
+			name: "ready local endpoints",
+			eps: LbEndpoints{
+				V4IPs:            []string{"10.128.0.2", "10.128.1.2"},
+				V6IPs:            []string{"fd00:1::2", "fd00:2::2"},
+				Port:             80,
+				TerminatingV4IPs: []string{"10.128.1.3"},
+			},
+			want: LbEndpoints{V4IPs: []string{"10.128.1.2"}, V6IPs: []string{"fd00:1::2"}, Port: 80},
+		},
+		{
+			name: "no ready local endpoints falls back to the serving terminating ones",
+			eps: LbEndpoints{
+				V4IPs:            []string{"10.128.0.2"},
+				V6IPs:            []string{"fd00:1::2"},
+				Port:             80,
+				TerminatingV4IPs: []string{"10.128.0.3", "10.128.1.3"},
+			},
+			want: LbEndpoints{V4IPs: []string{"10.128.1.3"}, V6IPs: []string{"fd00:1::2"}, Port: 80},
+		},
+		{
+			name: "no local endpoints",
+			eps: LbEndpoints{
+				V4IPs:            []string{"10.128.0.2"},
+				V6IPs:            []string{},
+				Port:             80,
+				TerminatingV4IPs: []string{"10.128.0.3"},
+			},
+			want: LbEndpoints{V4IPs: []string{}, V6IPs: []string{}, Port: 80},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.eps.InSubnets(subnets))
+		})
+	}
+}
+
 // protoPtr takes a Protocol and returns a pointer to it.
 func protoPtr(proto v1.Protocol) *v1.Protocol {
 	return &proto
@@ -87,6 +87,20 @@ func (_m *NetLinkOps) ConntrackDeleteFilter(table netlink.ConntrackTableType, fa
 	return r0, r1
 }
 
+// LinkAdd provides a mock function with given fields: link
+func (_m *NetLinkOps) LinkAdd(link netlink.Link) error {
+	ret := _m.Called(link)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link) error); ok {
+		r0 = rf(link)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkByIndex provides a mock function with given fields: index
 func (_m *NetLinkOps) LinkByIndex(index int) (netlink.Link, error) {
 	ret := _m.Called(index)
@@ -212,6 +226,20 @@ func (_m *NetLinkOps) LinkSetMTU(link netlink.Link, mtu int) error {
 	return r0
 }
 
+// LinkSetMasterByIndex provides a mock function with given fields: link, masterIndex
+func (_m *NetLinkOps) LinkSetMasterByIndex(link netlink.Link, masterIndex int) error {
+	ret := _m.Called(link, masterIndex)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int) error); ok {
+		r0 = rf(link, masterIndex)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkSetName provides a mock function with given fields: link, newName
 func (_m *NetLinkOps) LinkSetName(link netlink.Link, newName string) error {
 	ret := _m.Called(link, newName)
@@ -392,3 +420,40 @@ func (_m *NetLinkOps) RouteListFiltered(family int, filter *netlink.Route, filte
 
 	return r0, r1
 }
+
+// RuleAdd provides a mock function with given fields: rule
+func (_m *NetLinkOps) RuleAdd(rule *netlink.Rule) error {
+	ret := _m.Called(rule)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*netlink.Rule) error); ok {
+		r0 = rf(rule)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RuleList provides a mock function with given fields: family
+func (_m *NetLinkOps) RuleList(family int) ([]netlink.Rule, error) {
+	ret := _m.Called(family)
+
+	var r0 []netlink.Rule
+	if rf, ok := ret.Get(0).(func(int) []netlink.Rule); ok {
+		r0 = rf(family)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]netlink.Rule)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(int) error); ok {
+		r1 = rf(family)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,120 @@
+// +build linux
+
+package util
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+
+	utilnet "k8s.io/utils/net"
+)
+
+var (
+	allNodesMAC    = net.HardwareAddr{0x33, 0x33, 0x00, 0x00, 0x00, 0x01}
+	allNodesIPv6   = net.ParseIP("ff02::1")
+	ethTypeIPv6    = uint16(unix.ETH_P_IPV6)
+	icmpv6ProtoNum = uint8(unix.IPPROTO_ICMPV6)
+)
+
+// BuildUnsolicitedNA returns the ethernet frame of an unsolicited neighbor
+// advertisement to all nodes, overriding the link-layer address of ip to mac
+func BuildUnsolicitedNA(mac net.HardwareAddr, ip net.IP) ([]byte, error) {
+	if !utilnet.IsIPv6(ip) || len(mac) != 6 {
+		return nil, fmt.Errorf("invalid unsolicited neighbor advertisement %s at %s", ip, mac)
+	}
+	ip = ip.To16()
+
+	// neighbor advertisement with the override flag and a target
+	// link-layer address option
+	icmp := make([]byte, 0, 32)
+	icmp = append(icmp, 136, 0)  // type and code
+	icmp = appendUint16(icmp, 0) // checksum
+	icmp = append(icmp, 0x20, 0, 0, 0)
+	icmp = append(icmp, ip...)
+	icmp = append(icmp, 2, 1) // option type and length in units of 8 bytes
+	icmp = append(icmp, mac...)
+
+	// checksum over the IPv6 pseudo header and the ICMPv6 message
+	pseudo := make([]byte, 0, 40+len(icmp))
+	pseudo = append(pseudo, ip...)
+	pseudo = append(pseudo, allNodesIPv6...)
+	pseudo = appendUint16(pseudo, 0)
+	pseudo = appendUint16(pseudo, uint16(len(icmp)))
+	pseudo = append(pseudo, 0, 0, 0, icmpv6ProtoNum)
+	pseudo = append(pseudo, icmp...)
+	binary.BigEndian.PutUint16(icmp[2:], internetChecksum(pseudo))
+
+	frame := make([]byte, 0, 54+len(icmp))
+	frame = append(frame, allNodesMAC...)
+	frame = append(frame, mac...)
+	frame = appendUint16(frame, ethTypeIPv6)
+	frame = append(frame, 0x60, 0, 0, 0) // version, traffic class and flow label
+	frame = appendUint16(frame, uint16(len(icmp)))
+	frame = append(frame, icmpv6ProtoNum, 255) // next header and hop limit
+	frame = append(frame, ip...)
+	frame = append(frame, allNodesIPv6...)
+	frame = append(frame, icmp...)
+	return frame, nil
+}
+
+// SendNeighborAnnouncement announces that ip is at mac, the MAC address of the
+// interface ifName, out of that interface. The address doesn't need to be
+// configured on the interface. IPv4 addresses are announced with a gratuitous
+// ARP sent by arping. arping has no IPv6 counterpart, and the nodes don't
+// ship one, so IPv6 addresses are announced with an unsolicited neighbor
+// advertisement written to a packet socket.
+func SendNeighborAnnouncement(ifName string, mac net.HardwareAddr, ip net.IP) error {
+	if !utilnet.IsIPv6(ip) {
+		if _, stderr, err := RunArping("-q", "-U", "-c", "1", "-I", ifName, ip.String()); err != nil {
+			return fmt.Errorf("failed to announce %s on %s, stderr: %q: %v", ip, ifName, stderr, err)
+		}
+		return nil
+	}
+
+	frame, err := BuildUnsolicitedNA(mac, ip)
+	if err != nil {
+		return err
+	}
+	intf, err := net.InterfaceByName(ifName)
+	if err != nil {
+		return fmt.Errorf("failed to find interface %s: %v", ifName, err)
+	}
+	// protocol 0, the socket is only used to send
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open packet socket: %v", err)
+	}
+	defer unix.Close(fd)
+
+	addr := &unix.SockaddrLinklayer{
+		Ifindex: intf.Index,
+		Halen:   6,
+	}
+	copy(addr.Addr[:], frame[:6])
+	if err := unix.Sendto(fd, frame, 0, addr); err != nil {
+		return fmt.Errorf("failed to announce %s on %s: %v", ip, ifName, err)
+	}
+	return nil
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+// internetChecksum is the RFC 1071 checksum of b
+func internetChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
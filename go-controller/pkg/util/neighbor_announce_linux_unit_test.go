@@ -0,0 +1,80 @@
+package util
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"testing"
+
+	ovntest "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildUnsolicitedNA(t *testing.T) {
+	mac := ovntest.MustParseMAC("0a:58:0a:f4:00:01")
+	tests := []struct {
+		desc   string
+		ip     net.IP
+		expErr bool
+		expHex string
+	}{
+		{
+			desc: "unsolicited neighbor advertisement",
+			ip:   ovntest.MustParseIP("fd03::10"),
+			expHex: "333300000001" + "0a580af40001" + "86dd" +
+				"60000000" + "0020" + "3aff" +
+				"fd030000000000000000000000000010" +
+				"ff020000000000000000000000000001" +
+				"8800" + "472b" + "20000000" +
+				"fd030000000000000000000000000010" +
+				"0201" + "0a580af40001",
+		},
+		{
+			desc:   "unsolicited neighbor advertisement for an IPv4 address",
+			ip:     ovntest.MustParseIP("192.168.100.10"),
+			expErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			frame, err := BuildUnsolicitedNA(mac, tc.ip)
+			if tc.expErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expHex, hex.EncodeToString(frame))
+		})
+	}
+}
+
+func TestSendNeighborAnnouncementIPv4(t *testing.T) {
+	// run the commands of the fake exec rather than a mock runner
+	oldExecRunner := runCmdExecRunner
+	runCmdExecRunner = &defaultExecRunner{}
+	defer func() {
+		runCmdExecRunner = oldExecRunner
+	}()
+
+	fexec := ovntest.NewFakeExec()
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{Cmd: "arping -q -U -c 1 -I breth0 192.168.100.10"})
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+		Cmd: "arping -q -U -c 1 -I breth0 192.168.100.11",
+		Err: fmt.Errorf("interface is down"),
+	})
+	if !assert.NoError(t, SetExec(fexec)) {
+		t.FailNow()
+	}
+
+	mac := ovntest.MustParseMAC("0a:58:0a:f4:00:01")
+	assert.NoError(t, SendNeighborAnnouncement("breth0", mac, ovntest.MustParseIP("192.168.100.10")))
+	assert.Error(t, SendNeighborAnnouncement("breth0", mac, ovntest.MustParseIP("192.168.100.11")))
+	assert.True(t, fexec.CalledMatchesExpected(), fexec.ErrorDesc())
+}
+
+func TestInternetChecksum(t *testing.T) {
+	// RFC 1071 example
+	assert.Equal(t, uint16(0x220d), internetChecksum([]byte{0x00, 0x01, 0xf2, 0x03, 0xf4, 0xf5, 0xf6, 0xf7}))
+	// odd lengths are padded with zero
+	assert.Equal(t, internetChecksum([]byte{0x01, 0x02, 0x03, 0x00}), internetChecksum([]byte{0x01, 0x02, 0x03}))
+}
@@ -136,6 +136,22 @@ func GetNodeHybridOverlayIfAddr(subnet *net.IPNet) *net.IPNet {
 	return &net.IPNet{IP: NextIP(mgmtIfAddr.IP), Mask: subnet.Mask}
 }
 
+// GetNodeServiceMonitorIfAddr returns the node logical switch address OVN
+// sends load balancer health check probes from (the last address before the
+// broadcast address)
+func GetNodeServiceMonitorIfAddr(subnet *net.IPNet) *net.IPNet {
+	subnetIP := subnet.IP
+	if len(subnet.Mask) == net.IPv4len {
+		subnetIP = subnetIP.To4()
+	}
+	ip := make(net.IP, len(subnetIP))
+	for i := range subnetIP {
+		ip[i] = subnetIP[i] | ^subnet.Mask[i]
+	}
+	ip[len(ip)-1]--
+	return &net.IPNet{IP: ip, Mask: subnet.Mask}
+}
+
 // JoinHostPortInt32 is like net.JoinHostPort(), but with an int32 for the port
 func JoinHostPortInt32(host string, port int32) string {
 	return net.JoinHostPort(host, strconv.Itoa(int(port)))
@@ -24,6 +24,8 @@ type NetLinkOps interface {
 	LinkDelete(link netlink.Link) error
 	LinkSetName(link netlink.Link, newName string) error
 	LinkSetUp(link netlink.Link) error
+	LinkAdd(link netlink.Link) error
+	LinkSetMasterByIndex(link netlink.Link, masterIndex int) error
 	LinkSetNsFd(link netlink.Link, fd int) error
 	LinkSetHardwareAddr(link netlink.Link, hwaddr net.HardwareAddr) error
 	LinkSetMTU(link netlink.Link, mtu int) error
@@ -36,6 +38,8 @@ type NetLinkOps interface {
 	RouteAdd(route *netlink.Route) error
 	RouteReplace(route *netlink.Route) error
 	RouteListFiltered(family int, filter *netlink.Route, filterMask uint64) ([]netlink.Route, error)
+	RuleList(family int) ([]netlink.Rule, error)
+	RuleAdd(rule *netlink.Rule) error
 	NeighAdd(neigh *netlink.Neigh) error
 	NeighList(linkIndex, family int) ([]netlink.Neigh, error)
 	ConntrackDeleteFilter(table netlink.ConntrackTableType, family netlink.InetFamily, filter netlink.CustomConntrackFilter) (uint, error)
@@ -85,6 +89,14 @@ func (defaultNetLinkOps) LinkSetUp(link netlink.Link) error {
 	return netlink.LinkSetUp(link)
 }
 
+func (defaultNetLinkOps) LinkAdd(link netlink.Link) error {
+	return netlink.LinkAdd(link)
+}
+
+func (defaultNetLinkOps) LinkSetMasterByIndex(link netlink.Link, masterIndex int) error {
+	return netlink.LinkSetMasterByIndex(link, masterIndex)
+}
+
 func (defaultNetLinkOps) LinkSetName(link netlink.Link, newName string) error {
 	return netlink.LinkSetName(link, newName)
 }
@@ -137,6 +149,14 @@ func (defaultNetLinkOps) RouteListFiltered(family int, filter *netlink.Route, fi
 	return netlink.RouteListFiltered(family, filter, filterMask)
 }
 
+func (defaultNetLinkOps) RuleList(family int) ([]netlink.Rule, error) {
+	return netlink.RuleList(family)
+}
+
+func (defaultNetLinkOps) RuleAdd(rule *netlink.Rule) error {
+	return netlink.RuleAdd(rule)
+}
+
 func (defaultNetLinkOps) NeighAdd(neigh *netlink.Neigh) error {
 	return netlink.NeighAdd(neigh)
 }
@@ -183,6 +203,56 @@ func LinkDelete(interfaceName string) error {
 	return nil
 }
 
+// LinkVRFEnsure returns the VRF device with the given name, routing with the
+// given table, with its state marked up. It creates the device if it does not
+// exist.
+func LinkVRFEnsure(vrfName string, table uint32) (netlink.Link, error) {
+	link, err := netLinkOps.LinkByName(vrfName)
+	if err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); !ok {
+			return nil, fmt.Errorf("failed to lookup link %s: %v", vrfName, err)
+		}
+		vrf := &netlink.Vrf{LinkAttrs: netlink.LinkAttrs{Name: vrfName}, Table: table}
+		if err = netLinkOps.LinkAdd(vrf); err != nil {
+			return nil, fmt.Errorf("failed to add VRF %s with table %d: %v", vrfName, table, err)
+		}
+		if link, err = netLinkOps.LinkByName(vrfName); err != nil {
+			return nil, fmt.Errorf("failed to lookup link %s: %v", vrfName, err)
+		}
+	}
+	vrf, ok := link.(*netlink.Vrf)
+	if !ok {
+		return nil, fmt.Errorf("link %s exists and is not a VRF but a %s", vrfName, link.Type())
+	}
+	if vrf.Table != table {
+		return nil, fmt.Errorf("VRF %s exists with table %d instead of %d", vrfName, vrf.Table, table)
+	}
+	if err = netLinkOps.LinkSetUp(link); err != nil {
+		return nil, fmt.Errorf("failed to set the link %s up: %v", vrfName, err)
+	}
+	return link, nil
+}
+
+// LinkSetMaster enslaves the link to the master device, or releases it from its
+// master device if master is nil. It does nothing if the link already has that
+// master.
+func LinkSetMaster(link netlink.Link, master netlink.Link) error {
+	masterIndex := 0
+	if master != nil {
+		masterIndex = master.Attrs().Index
+	}
+	if link.Attrs().MasterIndex == masterIndex {
+		return nil
+	}
+	if err := netLinkOps.LinkSetMasterByIndex(link, masterIndex); err != nil {
+		if master == nil {
+			return fmt.Errorf("failed to release link %s from its master: %v", link.Attrs().Name, err)
+		}
+		return fmt.Errorf("failed to set the master of link %s to %s: %v", link.Attrs().Name, master.Attrs().Name, err)
+	}
+	return nil
+}
+
 // LinkAddrFlush flushes all the addresses on the given link, except IPv6 link-local addresses
 func LinkAddrFlush(link netlink.Link) error {
 	addrs, err := netLinkOps.AddrList(link, netlink.FAMILY_ALL)
@@ -316,6 +386,80 @@ func LinkRoutesReplace(link netlink.Link, gwIP net.IP, subnets []*net.IPNet, mtu
 	return nil
 }
 
+// LinkDefaultRouteReplaceInTable adds or changes the default route through the
+// gwIP in the given routing table
+func LinkDefaultRouteReplaceInTable(link netlink.Link, gwIP net.IP, table int) error {
+	route := &netlink.Route{
+		LinkIndex: link.Attrs().Index,
+		Scope:     netlink.SCOPE_UNIVERSE,
+		Gw:        gwIP,
+		Table:     table,
+	}
+	if err := netLinkOps.RouteReplace(route); err != nil {
+		return fmt.Errorf("failed to replace the default route via gateway %s in table %d: %v",
+			gwIP.String(), table, err)
+	}
+	return nil
+}
+
+// LinkConnectedRoutesReplaceInTable adds or changes, in the given routing
+// table, the routes of the main table to the subnets directly connected to the
+// link
+func LinkConnectedRoutesReplaceInTable(link netlink.Link, table int) error {
+	filter := &netlink.Route{LinkIndex: link.Attrs().Index, Table: unix.RT_TABLE_MAIN}
+	routes, err := netLinkOps.RouteListFiltered(netlink.FAMILY_ALL, filter, netlink.RT_FILTER_OIF|netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return fmt.Errorf("failed to list the routes of link %s: %v", link.Attrs().Name, err)
+	}
+	for _, route := range routes {
+		if route.Scope != netlink.SCOPE_LINK || route.Gw != nil || route.Dst == nil {
+			continue
+		}
+		connected := &netlink.Route{
+			LinkIndex: route.LinkIndex,
+			Scope:     netlink.SCOPE_LINK,
+			Dst:       route.Dst,
+			Src:       route.Src,
+			Table:     table,
+		}
+		if err := netLinkOps.RouteReplace(connected); err != nil {
+			return fmt.Errorf("failed to replace the route to %s in table %d: %v", route.Dst, table, err)
+		}
+	}
+	return nil
+}
+
+// RuleIifLookupEnsure adds, for both IP families, the rule looking up the
+// traffic entering the host through the named interface in the given table,
+// with the given priority, unless it exists
+func RuleIifLookupEnsure(iifName string, table, priority int) error {
+	for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		rules, err := netLinkOps.RuleList(family)
+		if err != nil {
+			return fmt.Errorf("failed to list the rules: %v", err)
+		}
+		exists := false
+		for _, rule := range rules {
+			if rule.Priority == priority && rule.IifName == iifName && rule.Table == table {
+				exists = true
+				break
+			}
+		}
+		if exists {
+			continue
+		}
+		rule := netlink.NewRule()
+		rule.Family = family
+		rule.Priority = priority
+		rule.IifName = iifName
+		rule.Table = table
+		if err := netLinkOps.RuleAdd(rule); err != nil {
+			return fmt.Errorf("failed to add the rule looking up table %d for %s: %v", table, iifName, err)
+		}
+	}
+	return nil
+}
+
 // LinkRouteGet gets a route for the given subnet with the specified gwIPStr
 // returns nil if route is not found
 func LinkRouteGet(link netlink.Link, gwIP net.IP, subnet *net.IPNet) (*netlink.Route, error) {
@@ -412,6 +556,53 @@ func DeleteConntrack(ip string, port int32, protocol kapi.Protocol) error {
 	return nil
 }
 
+// DeleteConntrackServiceBackend deletes the conntrack entries of the
+// connections to vip:vipPort that were load balanced to backend. An empty vip
+// matches any destination IP, for node ports. Entries aren't filtered by zone,
+// so the ones of the host zone and of the OVN zones are deleted alike.
+func DeleteConntrackServiceBackend(vip string, vipPort int32, backend string, protocol kapi.Protocol) error {
+	backendIP := net.ParseIP(backend)
+	if backendIP == nil {
+		return fmt.Errorf("value %q passed to DeleteConntrackServiceBackend is not an IP address", backend)
+	}
+	var protoNum uint8
+	switch protocol {
+	case kapi.ProtocolUDP:
+		protoNum = 17
+	case kapi.ProtocolSCTP:
+		protoNum = 132
+	default:
+		return fmt.Errorf("unsupported protocol %q passed to DeleteConntrackServiceBackend", protocol)
+	}
+
+	filter := &netlink.ConntrackFilter{}
+	if err := filter.AddProtocol(protoNum); err != nil {
+		return fmt.Errorf("could not add protocol %s to conntrack filter: %v", protocol, err)
+	}
+	if vip != "" {
+		vipIP := net.ParseIP(vip)
+		if vipIP == nil {
+			return fmt.Errorf("value %q passed to DeleteConntrackServiceBackend is not an IP address", vip)
+		}
+		if err := filter.AddIP(netlink.ConntrackOrigDstIP, vipIP); err != nil {
+			return fmt.Errorf("could not add IP: %s to conntrack filter: %v", vipIP, err)
+		}
+	}
+	if err := filter.AddPort(netlink.ConntrackOrigDstPort, uint16(vipPort)); err != nil {
+		return fmt.Errorf("could not add port %d to conntrack filter: %v", vipPort, err)
+	}
+	if err := filter.AddIP(netlink.ConntrackReplySrcIP, backendIP); err != nil {
+		return fmt.Errorf("could not add IP: %s to conntrack filter: %v", backendIP, err)
+	}
+
+	family := netlink.FAMILY_V4
+	if backendIP.To4() == nil {
+		family = netlink.FAMILY_V6
+	}
+	_, err := netLinkOps.ConntrackDeleteFilter(netlink.ConntrackTable, netlink.InetFamily(family), filter)
+	return err
+}
+
 // GetNetworkInterfaceIPs returns the IP addresses for the network interface 'iface'.
 func GetNetworkInterfaceIPs(iface string) ([]*net.IPNet, error) {
 	link, err := netLinkOps.LinkByName(iface)
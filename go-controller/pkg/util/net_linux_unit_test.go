@@ -11,7 +11,9 @@ import (
 	netlink_mocks "github.com/ovn-org/ovn-kubernetes/go-controller/pkg/testing/mocks/github.com/vishvananda/netlink"
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util/mocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 )
 
 func TestGetFamily(t *testing.T) {
@@ -94,6 +96,86 @@ func TestLinkSetUp(t *testing.T) {
 	}
 }
 
+func TestLinkVRFEnsure(t *testing.T) {
+	mockNetLinkOps := new(mocks.NetLinkOps)
+	// below is defined in net_linux.go
+	netLinkOps = mockNetLinkOps
+
+	vrf := &netlink.Vrf{LinkAttrs: netlink.LinkAttrs{Name: "ovn-vrf"}, Table: 100}
+	tests := []struct {
+		desc                     string
+		table                    uint32
+		errExp                   bool
+		onRetArgsNetLinkLibOpers []ovntest.TestifyMockHelper
+	}{
+		{
+			desc:   "fails to look up link",
+			table:  100,
+			errExp: true,
+			onRetArgsNetLinkLibOpers: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "LinkByName", OnCallMethodArgType: []string{"string"}, RetArgList: []interface{}{nil, fmt.Errorf("mock error")}},
+			},
+		},
+		{
+			desc:   "fails to add the VRF",
+			table:  100,
+			errExp: true,
+			onRetArgsNetLinkLibOpers: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "LinkByName", OnCallMethodArgType: []string{"string"}, RetArgList: []interface{}{nil, netlink.LinkNotFoundError{}}},
+				{OnCallMethodName: "LinkAdd", OnCallMethodArgType: []string{"*netlink.Vrf"}, RetArgList: []interface{}{fmt.Errorf("mock error")}},
+			},
+		},
+		{
+			desc:  "adds the VRF and sets it up",
+			table: 100,
+			onRetArgsNetLinkLibOpers: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "LinkByName", OnCallMethodArgType: []string{"string"}, RetArgList: []interface{}{nil, netlink.LinkNotFoundError{}}},
+				{OnCallMethodName: "LinkAdd", OnCallMethodArgType: []string{"*netlink.Vrf"}, RetArgList: []interface{}{nil}},
+				{OnCallMethodName: "LinkByName", OnCallMethodArgType: []string{"string"}, RetArgList: []interface{}{vrf, nil}},
+				{OnCallMethodName: "LinkSetUp", OnCallMethodArgType: []string{"*netlink.Vrf"}, RetArgList: []interface{}{nil}},
+			},
+		},
+		{
+			desc:  "sets up the existing VRF",
+			table: 100,
+			onRetArgsNetLinkLibOpers: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "LinkByName", OnCallMethodArgType: []string{"string"}, RetArgList: []interface{}{vrf, nil}},
+				{OnCallMethodName: "LinkSetUp", OnCallMethodArgType: []string{"*netlink.Vrf"}, RetArgList: []interface{}{nil}},
+			},
+		},
+		{
+			desc:   "fails when the existing VRF has another table",
+			table:  200,
+			errExp: true,
+			onRetArgsNetLinkLibOpers: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "LinkByName", OnCallMethodArgType: []string{"string"}, RetArgList: []interface{}{vrf, nil}},
+			},
+		},
+		{
+			desc:   "fails when the existing link is not a VRF",
+			table:  100,
+			errExp: true,
+			onRetArgsNetLinkLibOpers: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "LinkByName", OnCallMethodArgType: []string{"string"}, RetArgList: []interface{}{&netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "ovn-vrf"}}, nil}},
+			},
+		},
+	}
+	for i, tc := range tests {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.desc), func(t *testing.T) {
+			ovntest.ProcessMockFnList(&mockNetLinkOps.Mock, tc.onRetArgsNetLinkLibOpers)
+			res, err := LinkVRFEnsure("ovn-vrf", tc.table)
+			t.Log(res, err)
+			if tc.errExp {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, vrf, res)
+			}
+			mockNetLinkOps.AssertExpectations(t)
+		})
+	}
+}
+
 func TestLinkAddrFlush(t *testing.T) {
 	mockNetLinkOps := new(mocks.NetLinkOps)
 	mockLink := new(netlink_mocks.Link)
@@ -535,6 +617,120 @@ func TestLinkRoutesAdd(t *testing.T) {
 	}
 }
 
+func TestLinkConnectedRoutesReplaceInTable(t *testing.T) {
+	mockNetLinkOps := new(mocks.NetLinkOps)
+	// below is defined in net_linux.go
+	netLinkOps = mockNetLinkOps
+
+	link := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "breth0", Index: 2}}
+	mainRoutes := []netlink.Route{
+		{LinkIndex: 2, Scope: netlink.SCOPE_LINK, Dst: ovntest.MustParseIPNet("172.18.0.0/16"), Src: ovntest.MustParseIP("172.18.0.2")},
+		{LinkIndex: 2, Scope: netlink.SCOPE_UNIVERSE, Gw: ovntest.MustParseIP("172.18.0.1")},
+		{LinkIndex: 2, Scope: netlink.SCOPE_UNIVERSE, Dst: ovntest.MustParseIPNet("10.96.0.0/16"), Gw: ovntest.MustParseIP("172.18.0.1")},
+	}
+	tests := []struct {
+		desc                     string
+		errExp                   bool
+		onRetArgsNetLinkLibOpers []ovntest.TestifyMockHelper
+	}{
+		{
+			desc:   "fails to list the routes",
+			errExp: true,
+			onRetArgsNetLinkLibOpers: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "RouteListFiltered", OnCallMethodArgType: []string{"int", "*netlink.Route", "uint64"}, RetArgList: []interface{}{nil, fmt.Errorf("mock error")}},
+			},
+		},
+		{
+			desc:   "fails to replace a connected route",
+			errExp: true,
+			onRetArgsNetLinkLibOpers: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "RouteListFiltered", OnCallMethodArgType: []string{"int", "*netlink.Route", "uint64"}, RetArgList: []interface{}{mainRoutes, nil}},
+				{OnCallMethodName: "RouteReplace", OnCallMethodArgType: []string{"*netlink.Route"}, RetArgList: []interface{}{fmt.Errorf("mock error")}},
+			},
+		},
+		{
+			desc: "replaces the connected routes only",
+			onRetArgsNetLinkLibOpers: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "RouteListFiltered", OnCallMethodArgType: []string{"int", "*netlink.Route", "uint64"}, RetArgList: []interface{}{mainRoutes, nil}},
+				{OnCallMethodName: "RouteReplace", OnCallMethodArgType: []string{"*netlink.Route"}, RetArgList: []interface{}{nil}},
+			},
+		},
+	}
+	for i, tc := range tests {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.desc), func(t *testing.T) {
+			ovntest.ProcessMockFnList(&mockNetLinkOps.Mock, tc.onRetArgsNetLinkLibOpers)
+			err := LinkConnectedRoutesReplaceInTable(link, 100)
+			t.Log(err)
+			if tc.errExp {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				mockNetLinkOps.AssertCalled(t, "RouteReplace", &netlink.Route{
+					LinkIndex: 2,
+					Scope:     netlink.SCOPE_LINK,
+					Dst:       ovntest.MustParseIPNet("172.18.0.0/16"),
+					Src:       ovntest.MustParseIP("172.18.0.2"),
+					Table:     100,
+				})
+			}
+			mockNetLinkOps.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRuleIifLookupEnsure(t *testing.T) {
+	mockNetLinkOps := new(mocks.NetLinkOps)
+	// below is defined in net_linux.go
+	netLinkOps = mockNetLinkOps
+
+	existing := netlink.NewRule()
+	existing.Priority = 999
+	existing.IifName = "ovn-vrf"
+	existing.Table = unix.RT_TABLE_LOCAL
+	tests := []struct {
+		desc                     string
+		errExp                   bool
+		onRetArgsNetLinkLibOpers []ovntest.TestifyMockHelper
+	}{
+		{
+			desc:   "fails to list the rules",
+			errExp: true,
+			onRetArgsNetLinkLibOpers: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "RuleList", OnCallMethodArgType: []string{"int"}, RetArgList: []interface{}{nil, fmt.Errorf("mock error")}},
+			},
+		},
+		{
+			desc:   "fails to add the rule",
+			errExp: true,
+			onRetArgsNetLinkLibOpers: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "RuleList", OnCallMethodArgType: []string{"int"}, RetArgList: []interface{}{[]netlink.Rule{}, nil}},
+				{OnCallMethodName: "RuleAdd", OnCallMethodArgType: []string{"*netlink.Rule"}, RetArgList: []interface{}{fmt.Errorf("mock error")}},
+			},
+		},
+		{
+			desc: "adds the rule of the family it is missing in",
+			onRetArgsNetLinkLibOpers: []ovntest.TestifyMockHelper{
+				{OnCallMethodName: "RuleList", OnCallMethodArgType: []string{"int"}, RetArgList: []interface{}{[]netlink.Rule{*existing}, nil}},
+				{OnCallMethodName: "RuleList", OnCallMethodArgType: []string{"int"}, RetArgList: []interface{}{[]netlink.Rule{}, nil}},
+				{OnCallMethodName: "RuleAdd", OnCallMethodArgType: []string{"*netlink.Rule"}, RetArgList: []interface{}{nil}},
+			},
+		},
+	}
+	for i, tc := range tests {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.desc), func(t *testing.T) {
+			ovntest.ProcessMockFnList(&mockNetLinkOps.Mock, tc.onRetArgsNetLinkLibOpers)
+			err := RuleIifLookupEnsure("ovn-vrf", unix.RT_TABLE_LOCAL, 999)
+			t.Log(err)
+			if tc.errExp {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockNetLinkOps.AssertExpectations(t)
+		})
+	}
+}
+
 func TestLinkRouteExists(t *testing.T) {
 	mockNetLinkOps := new(mocks.NetLinkOps)
 	mockLink := new(netlink_mocks.Link)
@@ -881,6 +1077,132 @@ func TestDeleteConntrack(t *testing.T) {
 	}
 }
 
+func TestDeleteConntrackServiceBackend(t *testing.T) {
+	mockNetLinkOps := new(mocks.NetLinkOps)
+	// below is defined in net_linux.go
+	netLinkOps = mockNetLinkOps
+
+	// flow builds a conntrack entry of a connection from a client to
+	// dstIP:dstPort that was DNATed to backend:8080
+	flow := func(proto uint8, dstIP string, dstPort uint16, backend string) *netlink.ConntrackFlow {
+		f := &netlink.ConntrackFlow{}
+		f.Forward.Protocol = proto
+		f.Forward.SrcIP = ovntest.MustParseIP("10.244.1.3")
+		f.Forward.SrcPort = 40000
+		f.Forward.DstIP = ovntest.MustParseIP(dstIP)
+		f.Forward.DstPort = dstPort
+		f.Reverse.Protocol = proto
+		f.Reverse.SrcIP = ovntest.MustParseIP(backend)
+		f.Reverse.SrcPort = 8080
+		f.Reverse.DstIP = f.Forward.SrcIP
+		f.Reverse.DstPort = f.Forward.SrcPort
+		return f
+	}
+
+	tests := []struct {
+		desc            string
+		errExp          bool
+		vip             string
+		vipPort         int32
+		backend         string
+		protocol        kapi.Protocol
+		family          netlink.InetFamily
+		matching        []*netlink.ConntrackFlow
+		notMatching     []*netlink.ConntrackFlow
+		deleteFilterErr error
+	}{
+		{
+			desc:     "invalid backend IP",
+			errExp:   true,
+			vip:      "10.96.0.10",
+			vipPort:  53,
+			backend:  "blah",
+			protocol: kapi.ProtocolUDP,
+		},
+		{
+			desc:     "invalid vip",
+			errExp:   true,
+			vip:      "blah",
+			vipPort:  53,
+			backend:  "10.244.0.5",
+			protocol: kapi.ProtocolUDP,
+		},
+		{
+			desc:     "TCP is not supported",
+			errExp:   true,
+			vip:      "10.96.0.10",
+			vipPort:  53,
+			backend:  "10.244.0.5",
+			protocol: kapi.ProtocolTCP,
+		},
+		{
+			desc:     "UDP vip",
+			vip:      "10.96.0.10",
+			vipPort:  53,
+			backend:  "10.244.0.5",
+			protocol: kapi.ProtocolUDP,
+			family:   netlink.FAMILY_V4,
+			matching: []*netlink.ConntrackFlow{flow(17, "10.96.0.10", 53, "10.244.0.5")},
+			notMatching: []*netlink.ConntrackFlow{
+				flow(17, "10.96.0.10", 53, "10.244.0.6"),
+				flow(17, "10.96.0.11", 53, "10.244.0.5"),
+				flow(17, "10.96.0.10", 54, "10.244.0.5"),
+				flow(6, "10.96.0.10", 53, "10.244.0.5"),
+			},
+		},
+		{
+			desc:     "SCTP node port",
+			vipPort:  30053,
+			backend:  "fd00:10:244::5",
+			protocol: kapi.ProtocolSCTP,
+			family:   netlink.FAMILY_V6,
+			matching: []*netlink.ConntrackFlow{
+				flow(132, "fd00::1", 30053, "fd00:10:244::5"),
+				flow(132, "fd00::2", 30053, "fd00:10:244::5"),
+			},
+			notMatching: []*netlink.ConntrackFlow{
+				flow(132, "fd00::1", 30053, "fd00:10:244::6"),
+				flow(17, "fd00::1", 30053, "fd00:10:244::5"),
+			},
+		},
+		{
+			desc:            "ConntrackDeleteFilter fails",
+			errExp:          true,
+			vip:             "10.96.0.10",
+			vipPort:         53,
+			backend:         "10.244.0.5",
+			protocol:        kapi.ProtocolUDP,
+			family:          netlink.FAMILY_V4,
+			deleteFilterErr: fmt.Errorf("mock error"),
+		},
+	}
+	for i, tc := range tests {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.desc), func(t *testing.T) {
+			var filter *netlink.ConntrackFilter
+			if tc.family != 0 {
+				mockNetLinkOps.On("ConntrackDeleteFilter", netlink.ConntrackTableType(netlink.ConntrackTable), tc.family, mock.AnythingOfType("*netlink.ConntrackFilter")).
+					Run(func(args mock.Arguments) {
+						filter = args.Get(2).(*netlink.ConntrackFilter)
+					}).Return(uint(1), tc.deleteFilterErr).Once()
+			}
+
+			err := DeleteConntrackServiceBackend(tc.vip, tc.vipPort, tc.backend, tc.protocol)
+			if tc.errExp {
+				assert.Error(t, err)
+			} else {
+				assert.Nil(t, err)
+				for _, f := range tc.matching {
+					assert.True(t, filter.MatchConntrackFlow(f), "expected filter to match %s", f)
+				}
+				for _, f := range tc.notMatching {
+					assert.False(t, filter.MatchConntrackFlow(f), "expected filter not to match %s", f)
+				}
+			}
+			mockNetLinkOps.AssertExpectations(t)
+		})
+	}
+}
+
 func TestGetIPv6OnSubnet(t *testing.T) {
 	mockNetLinkOps := new(mocks.NetLinkOps)
 	mockLink := new(netlink_mocks.Link)
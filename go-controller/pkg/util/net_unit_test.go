@@ -534,3 +534,38 @@ func TestJoinIPNetIPs(t *testing.T) {
 		})
 	}
 }
+
+func TestGetNodeServiceMonitorIfAddr(t *testing.T) {
+	tests := []struct {
+		desc   string
+		subnet *net.IPNet
+		outExp string
+	}{
+		{
+			desc:   "IPv4 /24 subnet",
+			subnet: ovntest.MustParseIPNet("10.128.1.0/24"),
+			outExp: "10.128.1.254/24",
+		},
+		{
+			desc:   "IPv4 /23 subnet",
+			subnet: ovntest.MustParseIPNet("10.128.2.0/23"),
+			outExp: "10.128.3.254/23",
+		},
+		{
+			desc:   "IPv4 subnet parsed as 16 bytes",
+			subnet: &net.IPNet{IP: net.ParseIP("10.128.1.0"), Mask: net.CIDRMask(24, 32)},
+			outExp: "10.128.1.254/24",
+		},
+		{
+			desc:   "IPv6 /64 subnet",
+			subnet: ovntest.MustParseIPNet("fd00:10:244:1::/64"),
+			outExp: "fd00:10:244:1:ffff:ffff:ffff:fffe/64",
+		},
+	}
+	for i, tc := range tests {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.desc), func(t *testing.T) {
+			res := GetNodeServiceMonitorIfAddr(tc.subnet)
+			assert.Equal(t, tc.outExp, res.String())
+		})
+	}
+}
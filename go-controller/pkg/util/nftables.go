@@ -0,0 +1,126 @@
+// +build linux
+
+package util
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	kexec "k8s.io/utils/exec"
+)
+
+const nftCommand = "nft"
+
+// NFTablesHelper is an interface that wraps the nft utility to allow mock
+// implementations for unit testing
+type NFTablesHelper interface {
+	// Apply runs a script of nft commands as a single atomic transaction;
+	// either all of its commands are applied, or none
+	Apply(script string) error
+	// ListTables returns the tables of all the families, as "<family> <name>"
+	ListTables() ([]string, error)
+}
+
+var nftHelper NFTablesHelper
+
+// SetNFTablesHelper sets the NFTablesHelper to be used
+func SetNFTablesHelper(nft NFTablesHelper) {
+	nftHelper = nft
+}
+
+// GetNFTablesHelper returns an NFTablesHelper. If SetNFTablesHelper has not yet
+// been called, it will create a new NFTablesHelper running nft through the exec
+// interface set with SetExec
+func GetNFTablesHelper() (NFTablesHelper, error) {
+	if nftHelper == nil {
+		if runner == nil {
+			return nil, fmt.Errorf("the exec interface is not set")
+		}
+		exec := runner.exec
+		path, err := exec.LookPath(nftCommand)
+		if err != nil {
+			return nil, err
+		}
+		SetNFTablesHelper(&nft{exec: exec, path: path})
+	}
+	return nftHelper, nil
+}
+
+type nft struct {
+	exec kexec.Interface
+	path string
+}
+
+func (n *nft) Apply(script string) error {
+	cmd := n.exec.Command(n.path, "-f", "-")
+	cmd.SetStdin(strings.NewReader(script))
+	_, stderr, err := runCmd(cmd, n.path, "-f", "-")
+	if err != nil {
+		return fmt.Errorf("failed to apply nftables script: %s (%v)", strings.TrimSpace(stderr.String()), err)
+	}
+	return nil
+}
+
+func (n *nft) ListTables() ([]string, error) {
+	cmd := n.exec.Command(n.path, "list", "tables")
+	stdout, stderr, err := runCmd(cmd, n.path, "list", "tables")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nftables tables: %s (%v)", strings.TrimSpace(stderr.String()), err)
+	}
+	var tables []string
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if fields := strings.Fields(line); len(fields) == 3 && fields[0] == "table" {
+			tables = append(tables, fields[1]+" "+fields[2])
+		}
+	}
+	return tables, nil
+}
+
+// FakeNFTables is a mock implementation of nft that records the scripts it
+// applies, and can be used for unit tests to verify that the code applies the
+// expected ones
+type FakeNFTables struct {
+	sync.Mutex
+	// Scripts are the scripts applied so far
+	Scripts []string
+	// Err is returned by Apply, which doesn't record the script then
+	Err error
+	// Tables are returned by ListTables
+	Tables []string
+}
+
+// SetFakeNFTablesHelper creates a new FakeNFTables and sets it as the NFTablesHelper
+func SetFakeNFTablesHelper() *FakeNFTables {
+	f := &FakeNFTables{}
+	SetNFTablesHelper(f)
+	return f
+}
+
+// Apply records script
+func (f *FakeNFTables) Apply(script string) error {
+	f.Lock()
+	defer f.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	f.Scripts = append(f.Scripts, script)
+	return nil
+}
+
+// ListTables returns Tables
+func (f *FakeNFTables) ListTables() ([]string, error) {
+	f.Lock()
+	defer f.Unlock()
+	return append([]string{}, f.Tables...), nil
+}
+
+// LastScript returns the last script applied, or "" if there is none
+func (f *FakeNFTables) LastScript() string {
+	f.Lock()
+	defer f.Unlock()
+	if len(f.Scripts) == 0 {
+		return ""
+	}
+	return f.Scripts[len(f.Scripts)-1]
+}
@@ -61,8 +61,37 @@ const (
 
 	// ovnNodeHostAddresses is used to track the different host IP addresses on the node
 	ovnNodeHostAddresses = "k8s.ovn.org/host-addresses"
+
+	// ovnNodeGatewayModeMigration reports the progress of the node switching its gateway to another mode
+	ovnNodeGatewayModeMigration = "k8s.ovn.org/gateway-mode-migration"
+)
+
+// GatewayModeMigrationState is the step a node is at in switching its gateway
+// to another mode
+type GatewayModeMigrationState string
+
+const (
+	// GatewayModeMigrationCleanup is set while the node tears down the flows,
+	// host rules and routes of the previous gateway mode
+	GatewayModeMigrationCleanup GatewayModeMigrationState = "cleanup"
+	// GatewayModeMigrationSetup is set while the node builds the gateway of
+	// the new mode
+	GatewayModeMigrationSetup GatewayModeMigrationState = "setup"
+	// GatewayModeMigrationDone is set once the gateway of the new mode is up
+	GatewayModeMigrationDone GatewayModeMigrationState = "done"
 )
 
+// GatewayModeMigration is the k8s.ovn.org/gateway-mode-migration annotation of
+// a node that switched, or is switching, its gateway from one mode to another:
+//
+//	annotations:
+//	  k8s.ovn.org/gateway-mode-migration: '{"from":"shared","to":"local","state":"setup"}'
+type GatewayModeMigration struct {
+	From  config.GatewayMode        `json:"from"`
+	To    config.GatewayMode        `json:"to"`
+	State GatewayModeMigrationState `json:"state"`
+}
+
 type L3GatewayConfig struct {
 	Mode                config.GatewayMode
 	ChassisID           string
@@ -72,25 +101,44 @@ type L3GatewayConfig struct {
 	EgressGWInterfaceID string
 	EgressGWMACAddress  net.HardwareAddr
 	EgressGWIPAddresses []*net.IPNet
-	NextHops            []net.IP
-	NodePortEnable      bool
-	VLANID              *uint
+	// EgressInterfaces are the named secondary interfaces that namespaces
+	// can select for their egress traffic, by name
+	EgressInterfaces map[string]*L3GatewayEgressInterface
+	NextHops         []net.IP
+	NodePortEnable   bool
+	VLANID           *uint
+}
+
+// L3GatewayEgressInterface is a named secondary gateway interface of a node
+type L3GatewayEgressInterface struct {
+	InterfaceID string
+	MACAddress  net.HardwareAddr
+	IPAddresses []*net.IPNet
+	NextHops    []net.IP
+}
+
+type l3GatewayEgressInterfaceJSON struct {
+	InterfaceID string   `json:"interface-id"`
+	MACAddress  string   `json:"mac-address"`
+	IPAddresses []string `json:"ip-addresses"`
+	NextHops    []string `json:"next-hops"`
 }
 
 type l3GatewayConfigJSON struct {
-	Mode                config.GatewayMode `json:"mode"`
-	InterfaceID         string             `json:"interface-id,omitempty"`
-	MACAddress          string             `json:"mac-address,omitempty"`
-	IPAddresses         []string           `json:"ip-addresses,omitempty"`
-	IPAddress           string             `json:"ip-address,omitempty"`
-	EgressGWInterfaceID string             `json:"exgw-interface-id,omitempty"`
-	EgressGWMACAddress  string             `json:"exgw-mac-address,omitempty"`
-	EgressGWIPAddresses []string           `json:"exgw-ip-addresses,omitempty"`
-	EgressGWIPAddress   string             `json:"exgw-ip-address,omitempty"`
-	NextHops            []string           `json:"next-hops,omitempty"`
-	NextHop             string             `json:"next-hop,omitempty"`
-	NodePortEnable      string             `json:"node-port-enable,omitempty"`
-	VLANID              string             `json:"vlan-id,omitempty"`
+	Mode                config.GatewayMode                       `json:"mode"`
+	InterfaceID         string                                   `json:"interface-id,omitempty"`
+	MACAddress          string                                   `json:"mac-address,omitempty"`
+	IPAddresses         []string                                 `json:"ip-addresses,omitempty"`
+	IPAddress           string                                   `json:"ip-address,omitempty"`
+	EgressGWInterfaceID string                                   `json:"exgw-interface-id,omitempty"`
+	EgressGWMACAddress  string                                   `json:"exgw-mac-address,omitempty"`
+	EgressGWIPAddresses []string                                 `json:"exgw-ip-addresses,omitempty"`
+	EgressGWIPAddress   string                                   `json:"exgw-ip-address,omitempty"`
+	EgressInterfaces    map[string]*l3GatewayEgressInterfaceJSON `json:"egress-interfaces,omitempty"`
+	NextHops            []string                                 `json:"next-hops,omitempty"`
+	NextHop             string                                   `json:"next-hop,omitempty"`
+	NodePortEnable      string                                   `json:"node-port-enable,omitempty"`
+	VLANID              string                                   `json:"vlan-id,omitempty"`
 }
 
 func (cfg *L3GatewayConfig) MarshalJSON() ([]byte, error) {
@@ -131,6 +179,24 @@ func (cfg *L3GatewayConfig) MarshalJSON() ([]byte, error) {
 	if len(cfgjson.NextHops) == 1 {
 		cfgjson.NextHop = cfgjson.NextHops[0]
 	}
+	if len(cfg.EgressInterfaces) > 0 {
+		cfgjson.EgressInterfaces = make(map[string]*l3GatewayEgressInterfaceJSON, len(cfg.EgressInterfaces))
+		for name, intf := range cfg.EgressInterfaces {
+			intfjson := &l3GatewayEgressInterfaceJSON{
+				InterfaceID: intf.InterfaceID,
+				MACAddress:  intf.MACAddress.String(),
+				IPAddresses: make([]string, len(intf.IPAddresses)),
+				NextHops:    make([]string, len(intf.NextHops)),
+			}
+			for i, ip := range intf.IPAddresses {
+				intfjson.IPAddresses[i] = ip.String()
+			}
+			for i, nh := range intf.NextHops {
+				intfjson.NextHops[i] = nh.String()
+			}
+			cfgjson.EgressInterfaces[name] = intfjson
+		}
+	}
 
 	return json.Marshal(&cfgjson)
 }
@@ -235,6 +301,35 @@ func (cfg *L3GatewayConfig) UnmarshalJSON(bytes []byte) error {
 		}
 	}
 
+	if len(cfgjson.EgressInterfaces) > 0 {
+		cfg.EgressInterfaces = make(map[string]*L3GatewayEgressInterface, len(cfgjson.EgressInterfaces))
+		for name, intfjson := range cfgjson.EgressInterfaces {
+			intf := &L3GatewayEgressInterface{
+				InterfaceID: intfjson.InterfaceID,
+				IPAddresses: make([]*net.IPNet, len(intfjson.IPAddresses)),
+				NextHops:    make([]net.IP, len(intfjson.NextHops)),
+			}
+			intf.MACAddress, err = net.ParseMAC(intfjson.MACAddress)
+			if err != nil {
+				return fmt.Errorf("bad egress interface %s 'mac-address' value %q: %v", name, intfjson.MACAddress, err)
+			}
+			for i, ipStr := range intfjson.IPAddresses {
+				ip, ipnet, err := net.ParseCIDR(ipStr)
+				if err != nil {
+					return fmt.Errorf("bad egress interface %s 'ip-addresses' value %q: %v", name, ipStr, err)
+				}
+				intf.IPAddresses[i] = &net.IPNet{IP: ip, Mask: ipnet.Mask}
+			}
+			for i, nextHopStr := range intfjson.NextHops {
+				intf.NextHops[i] = net.ParseIP(nextHopStr)
+				if intf.NextHops[i] == nil {
+					return fmt.Errorf("bad egress interface %s 'next-hops' value %q", name, nextHopStr)
+				}
+			}
+			cfg.EgressInterfaces[name] = intf
+		}
+	}
+
 	return nil
 }
 
@@ -281,6 +376,46 @@ func NodeL3GatewayAnnotationChanged(oldNode, newNode *kapi.Node) bool {
 	return oldNode.Annotations[ovnNodeL3GatewayConfig] != newNode.Annotations[ovnNodeL3GatewayConfig]
 }
 
+// SetNodeGatewayModeMigration sets the gateway mode migration annotation of
+// the node right away, so that the master sees every step of the migration
+func SetNodeGatewayModeMigration(k kube.Interface, nodeName string, migration *GatewayModeMigration) error {
+	bytes, err := json.Marshal(migration)
+	if err != nil {
+		return err
+	}
+	return k.SetAnnotationsOnNode(nodeName, map[string]interface{}{ovnNodeGatewayModeMigration: string(bytes)})
+}
+
+// ParseNodeGatewayModeMigration returns the parsed gateway mode migration
+// annotation of the node
+func ParseNodeGatewayModeMigration(node *kapi.Node) (*GatewayModeMigration, error) {
+	migrationAnnotation, ok := node.Annotations[ovnNodeGatewayModeMigration]
+	if !ok {
+		return nil, newAnnotationNotSetError("%s annotation not found for node %q", ovnNodeGatewayModeMigration, node.Name)
+	}
+
+	migration := &GatewayModeMigration{}
+	if err := json.Unmarshal([]byte(migrationAnnotation), migration); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal gateway mode migration annotation %s for node %q: %v",
+			migrationAnnotation, node.Name, err)
+	}
+	return migration, nil
+}
+
+// NodeGatewayModeMigrating returns true if the node is switching its gateway to
+// another mode, and its gateway router must be left alone until it's done
+func NodeGatewayModeMigrating(node *kapi.Node) bool {
+	migration, err := ParseNodeGatewayModeMigration(node)
+	if err != nil {
+		return false
+	}
+	return migration.State != GatewayModeMigrationDone
+}
+
+func NodeGatewayModeMigrationChanged(oldNode, newNode *kapi.Node) bool {
+	return oldNode.Annotations[ovnNodeGatewayModeMigration] != newNode.Annotations[ovnNodeGatewayModeMigration]
+}
+
 // ParseNodeChassisIDAnnotation returns the node's ovnNodeChassisID annotation
 func ParseNodeChassisIDAnnotation(node *kapi.Node) (string, error) {
 	chassisID, ok := node.Annotations[ovnNodeChassisID]
@@ -72,6 +72,21 @@ func TestL3GatewayConfig_MarshalJSON(t *testing.T) {
 			},
 			expOutput: []byte(`{"mode":"local","interface-id":"INTERFACE-ID","mac-address":"11:22:33:44:55:66","ip-addresses":["192.168.1.10/24","fd01::1234/64"],"next-hops":["192.168.1.1","fd01::1"],"node-port-enable":"false","vlan-id":"1024"}`),
 		},
+		{
+			desc: "test egress interfaces",
+			inpL3GwCfg: &L3GatewayConfig{
+				Mode: config.GatewayModeShared,
+				EgressInterfaces: map[string]*L3GatewayEgressInterface{
+					"compliance": {
+						InterfaceID: "breth2_node1",
+						MACAddress:  ovntest.MustParseMAC("11:22:33:44:55:77"),
+						IPAddresses: []*net.IPNet{ovntest.MustParseIPNet("192.168.20.10/24")},
+						NextHops:    []net.IP{ovntest.MustParseIP("192.168.20.1")},
+					},
+				},
+			},
+			expOutput: []byte(`{"mode":"shared","egress-interfaces":{"compliance":{"interface-id":"breth2_node1","mac-address":"11:22:33:44:55:77","ip-addresses":["192.168.20.10/24"],"next-hops":["192.168.20.1"]}},"node-port-enable":"false"}`),
+		},
 	}
 	for i, tc := range tests {
 		t.Run(fmt.Sprintf("%d:%s", i, tc.desc), func(t *testing.T) {
@@ -401,6 +416,63 @@ func TestNodeL3GatewayAnnotationChanged(t *testing.T) {
 	}
 }
 
+func TestParseNodeGatewayModeMigration(t *testing.T) {
+	tests := []struct {
+		desc      string
+		inpNode   *v1.Node
+		errMatch  error
+		migration *GatewayModeMigration
+		migrating bool
+	}{
+		{
+			desc:     "error: annotation not found for node",
+			inpNode:  &v1.Node{},
+			errMatch: fmt.Errorf("%s annotation not found for node", ovnNodeGatewayModeMigration),
+		},
+		{
+			desc: "error: fail to unmarshal gateway mode migration annotation",
+			inpNode: &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{"k8s.ovn.org/gateway-mode-migration": `{"from":"shared"`},
+				},
+			},
+			errMatch: fmt.Errorf("failed to unmarshal gateway mode migration annotation"),
+		},
+		{
+			desc: "success: migration in progress",
+			inpNode: &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{"k8s.ovn.org/gateway-mode-migration": `{"from":"shared","to":"local","state":"setup"}`},
+				},
+			},
+			migration: &GatewayModeMigration{From: config.GatewayModeShared, To: config.GatewayModeLocal, State: GatewayModeMigrationSetup},
+			migrating: true,
+		},
+		{
+			desc: "success: migration done",
+			inpNode: &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{"k8s.ovn.org/gateway-mode-migration": `{"from":"local","to":"shared","state":"done"}`},
+				},
+			},
+			migration: &GatewayModeMigration{From: config.GatewayModeLocal, To: config.GatewayModeShared, State: GatewayModeMigrationDone},
+		},
+	}
+	for i, tc := range tests {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.desc), func(t *testing.T) {
+			migration, e := ParseNodeGatewayModeMigration(tc.inpNode)
+			if tc.errMatch != nil {
+				assert.Error(t, e)
+				assert.Contains(t, e.Error(), tc.errMatch.Error())
+			} else {
+				assert.NoError(t, e)
+				assert.Equal(t, tc.migration, migration)
+			}
+			assert.Equal(t, tc.migrating, NodeGatewayModeMigrating(tc.inpNode))
+		})
+	}
+}
+
 func TestParseNodeManagementPortMACAddress(t *testing.T) {
 	tests := []struct {
 		desc        string
@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -18,6 +19,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/openflow"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/afero"
@@ -778,6 +780,61 @@ func ReplaceOFFlows(bridgeName string, flows []string) (string, string, error) {
 	return strings.Trim(stdout.String(), "\" \n"), stderr.String(), err
 }
 
+// ModifyOFFlows applies a slice of flow modifications to the bridge in a
+// single atomic bundle. Each modification is a flow prefixed with the
+// command to apply, like "add" or "delete_strict".
+func ModifyOFFlows(bridgeName string, flowMods []string) (string, string, error) {
+	args := []string{"-O", "OpenFlow13", "--bundle", "add-flows", bridgeName, "-"}
+	stdin := &bytes.Buffer{}
+	stdin.Write([]byte(strings.Join(flowMods, "\n")))
+
+	cmd := runner.exec.Command(runner.ofctlPath, args...)
+	cmd.SetStdin(stdin)
+	stdout, stderr, err := runCmd(cmd, runner.ofctlPath, args...)
+	return strings.Trim(stdout.String(), "\" \n"), stderr.String(), err
+}
+
+// GetOFFlowCount returns the number of flows of a bridge, read from the
+// OpenFlow management socket of the bridge, or with ovs-ofctl when the socket
+// can't be used
+func GetOFFlowCount(bridgeName string) (uint32, error) {
+	flowCount, err := getOFFlowCountFromSocket(bridgeName)
+	if err == nil {
+		return flowCount, nil
+	}
+	klog.V(5).Infof("Counting the flows of %s with ovs-ofctl: %v", bridgeName, err)
+
+	stdout, stderr, err := RunOVSOfctl("-t", "5", "dump-aggregate", bridgeName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get flow count for %s, stderr: %q: %v", bridgeName, stderr, err)
+	}
+	for _, kvPair := range strings.Fields(stdout) {
+		if strings.HasPrefix(kvPair, "flow_count=") {
+			count, err := strconv.ParseUint(strings.TrimPrefix(kvPair, "flow_count="), 10, 32)
+			if err != nil {
+				return 0, fmt.Errorf("failed to parse the flow count of %s: %v", bridgeName, err)
+			}
+			return uint32(count), nil
+		}
+	}
+	return 0, fmt.Errorf("ovs-ofctl dump-aggregate %s output didn't contain the flow_count field", bridgeName)
+}
+
+// getOFFlowCountFromSocket returns the number of flows of a bridge, read from
+// its OpenFlow management socket in the OVS run directory
+func getOFFlowCountFromSocket(bridgeName string) (uint32, error) {
+	c, err := openflow.Dial(filepath.Join(ovsRunDir, bridgeName+".mgmt"))
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+	stats, err := c.AggregateStats(&openflow.FlowFilter{Table: openflow.TableAll})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get flow count for %s: %v", bridgeName, err)
+	}
+	return stats.FlowCount, nil
+}
+
 // Get OpenFlow Port names or numbers for a given bridge
 func GetOpenFlowPorts(bridgeName string, namedPorts bool) ([]string, error) {
 	stdout, stderr, err := RunOVSOfctl("show", bridgeName)
@@ -2,8 +2,13 @@ package util
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/config"
@@ -1953,6 +1958,53 @@ func TestReplaceOFFlows(t *testing.T) {
 	}
 }
 
+func TestModifyOFFlows(t *testing.T) {
+	mockKexecIface := new(mock_k8s_io_utils_exec.Interface)
+	mockCmd := new(mock_k8s_io_utils_exec.Cmd)
+	mockExecRunner := new(mocks.ExecRunner)
+	// below is defined in ovs.go
+	runCmdExecRunner = mockExecRunner
+	// note runner is defined in ovs.go file
+	runner = &execHelper{exec: mockKexecIface}
+	tests := []struct {
+		desc                    string
+		expectedErr             error
+		onRetArgsExecUtilsIface *ovntest.TestifyMockHelper
+		onRetArgsKexecIface     *ovntest.TestifyMockHelper
+		onRetArgsCmdList        *ovntest.TestifyMockHelper
+	}{
+		{
+			desc:                    "negative: run `ovs-ofctl` command",
+			expectedErr:             fmt.Errorf("failed to execute ovs-ofctl command"),
+			onRetArgsExecUtilsIface: &ovntest.TestifyMockHelper{OnCallMethodName: "RunCmd", OnCallMethodArgType: []string{"*mocks.Cmd", "string", "[]string", "string", "string", "string", "string", "string", "string"}, RetArgList: []interface{}{nil, nil, fmt.Errorf("failed to execute ovs-ofctl command")}},
+			onRetArgsKexecIface:     &ovntest.TestifyMockHelper{OnCallMethodName: "Command", OnCallMethodArgType: []string{"string", "string", "string", "string", "string", "string", "string"}, RetArgList: []interface{}{mockCmd}},
+			onRetArgsCmdList:        &ovntest.TestifyMockHelper{OnCallMethodName: "SetStdin", OnCallMethodArgType: []string{"*bytes.Buffer"}},
+		},
+		{
+			desc:                    "positive: run `ovs-ofctl` command",
+			expectedErr:             nil,
+			onRetArgsExecUtilsIface: &ovntest.TestifyMockHelper{OnCallMethodName: "RunCmd", OnCallMethodArgType: []string{"*mocks.Cmd", "string", "[]string", "string", "string", "string", "string", "string", "string"}, RetArgList: []interface{}{bytes.NewBuffer([]byte("testblah")), bytes.NewBuffer([]byte("")), nil}},
+			onRetArgsKexecIface:     &ovntest.TestifyMockHelper{OnCallMethodName: "Command", OnCallMethodArgType: []string{"string", "string", "string", "string", "string", "string", "string"}, RetArgList: []interface{}{mockCmd}},
+			onRetArgsCmdList:        &ovntest.TestifyMockHelper{OnCallMethodName: "SetStdin", OnCallMethodArgType: []string{"*bytes.Buffer"}},
+		},
+	}
+	for i, tc := range tests {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.desc), func(t *testing.T) {
+			ovntest.ProcessMockFn(&mockExecRunner.Mock, *tc.onRetArgsExecUtilsIface)
+			ovntest.ProcessMockFn(&mockKexecIface.Mock, *tc.onRetArgsKexecIface)
+			ovntest.ProcessMockFn(&mockCmd.Mock, *tc.onRetArgsCmdList)
+
+			_, _, e := ModifyOFFlows("somename", []string{"add table=0,priority=0,actions=NORMAL"})
+
+			if tc.expectedErr != nil {
+				assert.Error(t, e)
+			}
+			mockExecRunner.AssertExpectations(t)
+			mockKexecIface.AssertExpectations(t)
+		})
+	}
+}
+
 func TestGetOVNDBServerInfo(t *testing.T) {
 	mockKexecIface := new(mock_k8s_io_utils_exec.Interface)
 	mockExecRunner := new(mocks.ExecRunner)
@@ -2128,3 +2180,79 @@ func TestFindMaxArgsUsable(t *testing.T) {
 		})
 	}
 }
+
+func TestGetOFFlowCount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ovs")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	ovsRunDir = dir
+	defer PrepareTestConfig()
+
+	// run the commands of the fake exec rather than a mock runner
+	oldExecRunner := runCmdExecRunner
+	runCmdExecRunner = &defaultExecRunner{}
+	defer func() {
+		runCmdExecRunner = oldExecRunner
+	}()
+
+	// without a management socket, the flows are counted with ovs-ofctl
+	fexec := ovntest.NewFakeExec()
+	fexec.AddFakeCmd(&ovntest.ExpectedCmd{
+		Cmd:    "ovs-ofctl -t 5 dump-aggregate br-int",
+		Output: "NXST_AGGREGATE reply (xid=0x4): packet_count=10 byte_count=840 flow_count=12",
+	})
+	if !assert.NoError(t, SetExec(fexec)) {
+		t.FailNow()
+	}
+	flowCount, err := GetOFFlowCount("br-int")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 12, flowCount)
+	assert.True(t, fexec.CalledMatchesExpected(), fexec.ErrorDesc())
+
+	// a switch on the management socket of the bridge in the OVS run
+	// directory answers an OpenFlow 1.3 hello and aggregate stats request
+	l, err := net.Listen("unix", filepath.Join(dir, "br-int.mgmt"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			h := make([]byte, 8)
+			if _, err := io.ReadFull(conn, h); err != nil {
+				return
+			}
+			body := make([]byte, int(binary.BigEndian.Uint16(h[2:]))-8)
+			if _, err := io.ReadFull(conn, body); err != nil {
+				return
+			}
+			var reply []byte
+			switch h[1] {
+			case 0:
+				// hello with a version bitmap of OpenFlow 1.3
+				reply = []byte{0x04, 0, 0, 16, 0, 0, 0, 1, 0, 1, 0, 8, 0, 0, 0, 0x10}
+			case 18:
+				// aggregate stats: packet, byte and flow counts
+				reply = make([]byte, 40)
+				reply[0], reply[1] = 0x04, 19
+				binary.BigEndian.PutUint16(reply[2:], 40)
+				copy(reply[4:8], h[4:8])
+				binary.BigEndian.PutUint16(reply[8:], 2)
+				binary.BigEndian.PutUint32(reply[32:], 7)
+			}
+			if _, err := conn.Write(reply); err != nil {
+				return
+			}
+		}
+	}()
+	flowCount, err = GetOFFlowCount("br-int")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 7, flowCount)
+}